@@ -0,0 +1,159 @@
+// Package postflow provides functionality for managing user posts and feeds.
+package postflow
+
+import (
+	"context"
+	"time"
+)
+
+// Role represents the privileges of an actor
+type Role uint8
+
+const (
+	RoleOwner     Role = 0 // Regular user; may only change their own posts
+	RoleModerator Role = 1 // May delete, hide or edit any post
+	RoleAdmin     Role = 2 // May delete, hide or edit any post
+)
+
+// String returns the name of the role
+func (r Role) String() string {
+	switch r {
+	case RoleOwner:
+		return "owner"
+	case RoleModerator:
+		return "moderator"
+	case RoleAdmin:
+		return "admin"
+	default:
+		return "unknown"
+	}
+}
+
+// Actor is the user performing an operation
+type Actor struct {
+	UserID string
+	Role   Role
+}
+
+// IsPrivileged reports whether the actor may change posts of other users
+func (a Actor) IsPrivileged() bool {
+	return a.Role == RoleModerator || a.Role == RoleAdmin
+}
+
+// actorContextKey is the context key under which the actor is stored
+type actorContextKey struct{}
+
+// WithActor returns a context carrying the actor performing the operations
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorContextKey{}, actor)
+}
+
+// ActorFromContext returns the actor carried by the context, if any
+func ActorFromContext(ctx context.Context) (Actor, bool) {
+	actor, ok := ctx.Value(actorContextKey{}).(Actor)
+	return actor, ok
+}
+
+// SystemActor is the actor of changes the manager makes on its own, such as hiding a post after reports.
+// Its user ID is reserved.
+var SystemActor = Actor{UserID: "system", Role: RoleModerator}
+
+// actorFor returns the actor a change on behalf of userID is attributed to: the actor in the context when it is
+// the same user (or no user is given), otherwise userID as a regular user. It does not check that the context
+// may act as userID; callers check that with actingAs first, or are trusted as imports are.
+func actorFor(ctx context.Context, userID string) Actor {
+	if actor, ok := ActorFromContext(ctx); ok && (userID == "" || userID == actor.UserID) {
+		return actor
+	}
+	return Actor{UserID: userID, Role: RoleOwner}
+}
+
+// actingAs returns the actor for an action on behalf of userID. Without an actor in the context the caller
+// is trusted and userID acts as a regular user. An actor in the context may only act as itself; acting as
+// another user returns ErrPermissionDenied, except for moderation actions of moderators and administrators.
+func actingAs(ctx context.Context, userID string, action Action) (Actor, error) {
+	actor, ok := ActorFromContext(ctx)
+	if !ok {
+		return Actor{UserID: userID, Role: RoleOwner}, nil
+	}
+	if userID == "" || userID == actor.UserID {
+		return actor, nil
+	}
+	if action == ActionModerate && actor.IsPrivileged() {
+		return actor, nil
+	}
+	return actor, ErrPermissionDenied
+}
+
+// authorizeModerator returns the actor in the context if it may moderate the post. Listings and settings
+// that concern no single post pass nil and are authorized on a post holding only the tenant.
+func (m *PostManagerImpl) authorizeModerator(ctx context.Context, post *Post) (Actor, error) {
+	actor, ok := ActorFromContext(ctx)
+	if !ok {
+		return actor, ErrPermissionDenied
+	}
+	if post == nil {
+		post = &Post{TenantID: TenantFromContext(ctx)}
+	}
+	if err := m.authorizer.Authorize(ctx, actor, ActionModerate, post); err != nil {
+		return actor, err
+	}
+
+	return actor, nil
+}
+
+// HidePost hides a post from all feeds.
+// Only moderators and administrators may hide posts; the actor is taken from the context.
+func (m *PostManagerImpl) HidePost(ctx context.Context, postID string, reason string) error {
+	return m.moderateAs(ctx, postID, ModerationHidden, reason, AuditHide)
+}
+
+// UnhidePost makes a hidden post visible again.
+// Only moderators and administrators may unhide posts; the actor is taken from the context.
+func (m *PostManagerImpl) UnhidePost(ctx context.Context, postID string) error {
+	return m.moderateAs(ctx, postID, ModerationApproved, "", AuditUnhide)
+}
+
+// moderateAs changes the moderation status of a post on behalf of the actor in the context,
+// who must be allowed to moderate it. The change is audited under that actor.
+func (m *PostManagerImpl) moderateAs(ctx context.Context, postID string, status ModerationStatus, reason string, action AuditAction) error {
+	if _, ok := ActorFromContext(ctx); !ok {
+		return ErrPermissionDenied
+	}
+
+	post, err := m.store.GetPost(ctx, postID)
+	if err != nil {
+		return err
+	}
+	actor, err := m.authorizeModerator(ctx, post)
+	if err != nil {
+		return err
+	}
+
+	if err := m.setModerationStatus(ctx, post, actor, status, reason); err != nil {
+		return err
+	}
+	m.markReportsReviewed(ctx, post.ID)
+	m.recordAudit(ctx, post, actor, action, reason)
+
+	return nil
+}
+
+// recordAudit stores an audit entry if the store keeps an audit trail.
+// It runs once the change is stored, so errors are ignored rather than reporting a committed change as failed.
+func (m *PostManagerImpl) recordAudit(ctx context.Context, post *Post, actor Actor, action AuditAction, reason string) {
+	store, ok := m.store.(AuditStore)
+	if !ok {
+		return
+	}
+
+	_ = store.SaveAuditEntry(ctx, &AuditEntry{
+		PostID:    post.ID,
+		AuthorID:  post.UserID,
+		ActorID:   actor.UserID,
+		ActorRole: actor.Role,
+		Action:    action,
+		Reason:    reason,
+		CreatedAt: time.Now(),
+	})
+}
@@ -0,0 +1,216 @@
+package postflow
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPostManagerPrivilegedDelete tests deleting posts as moderators
+func TestPostManagerPrivilegedDelete(t *testing.T) {
+	pm := NewPostManager(NewInMemoryPostStore())
+	ctx := context.Background()
+
+	postID, err := pm.CreatePost(ctx, createTestPostData("user1"))
+	require.NoError(t, err)
+
+	// Test: another user cannot delete the post
+	err = pm.DeletePost(ctx, postID, "user2")
+	assert.Equal(t, ErrPermissionDenied, err)
+
+	// Test: a context actor only applies to its own user ID
+	modCtx := WithActor(ctx, Actor{UserID: "mod1", Role: RoleModerator})
+	err = pm.DeletePost(modCtx, postID, "user2")
+	assert.Equal(t, ErrPermissionDenied, err)
+
+	// Test: a moderator can delete the post
+	err = pm.DeletePost(modCtx, postID, "mod1")
+	assert.NoError(t, err)
+	_, err = pm.GetPost(ctx, postID)
+	assert.Equal(t, ErrPostNotFound, err)
+
+	// Test: the deletion is audited after the post is gone
+	entries, err := pm.ListAuditEntries(modCtx, postID, 10, 0)
+	assert.NoError(t, err)
+	require.Equal(t, 1, len(entries))
+	assert.Equal(t, AuditDelete, entries[0].Action)
+	assert.Equal(t, "user1", entries[0].AuthorID)
+	assert.Equal(t, "mod1", entries[0].ActorID)
+	assert.Equal(t, RoleModerator, entries[0].ActorRole)
+}
+
+// TestPostManagerPrivilegedEdit tests editing posts as administrators
+func TestPostManagerPrivilegedEdit(t *testing.T) {
+	pm := NewPostManager(NewInMemoryPostStore())
+	ctx := context.Background()
+
+	postID, err := pm.CreatePost(ctx, createTestPostData("user1"))
+	require.NoError(t, err)
+
+	adminCtx := WithActor(ctx, Actor{UserID: "admin1", Role: RoleAdmin})
+
+	// Test: an admin updates the post on behalf of the author
+	post, err := pm.GetPost(ctx, postID)
+	require.NoError(t, err)
+	post.UserID = "admin1"
+	post.Content = "Edited by an administrator"
+	err = pm.UpdatePost(adminCtx, post)
+	assert.NoError(t, err)
+
+	updated, err := pm.GetPost(ctx, postID)
+	assert.NoError(t, err)
+	assert.Equal(t, "user1", updated.UserID)
+	assert.Equal(t, "admin1", updated.UpdatedBy)
+	assert.Equal(t, "Edited by an administrator", updated.Content)
+
+	// Test: an admin patches the post
+	patched, err := pm.PatchPost(adminCtx, postID, "admin1", &PostPatch{Fields: PatchContent, Content: "Patched"})
+	assert.NoError(t, err)
+	assert.Equal(t, "user1", patched.UserID)
+	assert.Equal(t, "admin1", patched.UpdatedBy)
+
+	// Test: the author's own edit is not audited
+	_, err = pm.PatchPost(ctx, postID, "user1", &PostPatch{Fields: PatchContent, Content: "Mine again"})
+	assert.NoError(t, err)
+
+	entries, err := pm.ListAuditEntries(adminCtx, postID, 10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(entries))
+	for _, entry := range entries {
+		assert.Equal(t, AuditEdit, entry.Action)
+		assert.Equal(t, "admin1", entry.ActorID)
+	}
+
+	// Test: a regular actor cannot edit other posts
+	userCtx := WithActor(ctx, Actor{UserID: "user2"})
+	_, err = pm.PatchPost(userCtx, postID, "user2", &PostPatch{Fields: PatchContent, Content: "Hijacked"})
+	assert.Equal(t, ErrPermissionDenied, err)
+}
+
+// TestPostManagerImpersonation tests that an actor in the context cannot act as another user
+func TestPostManagerImpersonation(t *testing.T) {
+	pm := NewPostManager(NewInMemoryPostStore())
+	ctx := context.Background()
+	bobCtx := WithActor(ctx, Actor{UserID: "bob"})
+
+	postID, err := pm.CreatePost(ctx, createTestPostData("alice"))
+	require.NoError(t, err)
+	require.NoError(t, pm.AddReaction(ctx, postID, "alice", ReactionLike))
+
+	// Test: bob cannot post, edit or delete as alice
+	_, err = pm.CreatePost(bobCtx, createTestPostData("alice"))
+	assert.Equal(t, ErrPermissionDenied, err)
+
+	post, err := pm.GetPost(ctx, postID)
+	require.NoError(t, err)
+	post.Content = "Hijacked"
+	assert.ErrorIs(t, pm.UpdatePost(bobCtx, post), ErrPermissionDenied)
+	_, err = pm.PatchPost(bobCtx, postID, "alice", &PostPatch{Fields: PatchContent, Content: "Hijacked"})
+	assert.Equal(t, ErrPermissionDenied, err)
+	assert.Equal(t, ErrPermissionDenied, pm.DeletePost(bobCtx, postID, "alice"))
+
+	// Test: bob cannot react as alice
+	assert.Equal(t, ErrPermissionDenied, pm.AddReaction(bobCtx, postID, "alice", ReactionLove))
+	assert.Equal(t, ErrPermissionDenied, pm.RemoveReaction(bobCtx, postID, "alice", ReactionLike))
+
+	post, err = pm.GetPost(ctx, postID)
+	require.NoError(t, err)
+	assert.NotEqual(t, "Hijacked", post.Content)
+	reaction, err := pm.GetUserReaction(ctx, postID, "alice")
+	require.NoError(t, err)
+	require.NotNil(t, reaction)
+	assert.Equal(t, ReactionLike, *reaction)
+
+	// Test: bob still acts as himself
+	assert.NoError(t, pm.AddReaction(bobCtx, postID, "bob", ReactionLove))
+}
+
+// TestPostManagerHidePost tests hiding and unhiding posts
+func TestPostManagerHidePost(t *testing.T) {
+	pm := NewPostManager(NewInMemoryPostStore())
+	ctx := context.Background()
+
+	postID, err := pm.CreatePost(ctx, createTestPostData("user1"))
+	require.NoError(t, err)
+
+	// Test: regular users cannot hide posts
+	err = pm.HidePost(ctx, postID, "off topic")
+	assert.Equal(t, ErrPermissionDenied, err)
+	err = pm.HidePost(WithActor(ctx, Actor{UserID: "user1"}), postID, "off topic")
+	assert.Equal(t, ErrPermissionDenied, err)
+
+	// Test: a moderator hides the post from feeds
+	modCtx := WithActor(ctx, Actor{UserID: "mod1", Role: RoleModerator})
+	err = pm.HidePost(modCtx, postID, "off topic")
+	assert.NoError(t, err)
+
+	post, err := pm.GetPost(ctx, postID)
+	assert.NoError(t, err)
+	assert.Equal(t, ModerationHidden, post.ModerationStatus)
+	assert.Equal(t, "off topic", post.ModerationReason)
+
+	feed, err := pm.GetUserFeed(ctx, "user2", 10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(feed))
+
+	// Test: unhiding restores the post
+	err = pm.UnhidePost(modCtx, postID)
+	assert.NoError(t, err)
+	feed, err = pm.GetUserFeed(ctx, "user2", 10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(feed))
+
+	err = pm.HidePost(modCtx, "nonexistent-id", "")
+	assert.Equal(t, ErrPostNotFound, err)
+
+	entries, err := pm.ListAuditEntries(modCtx, postID, 10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(entries))
+}
+
+// TestPostManagerTenantScopedRecords tests that feedback signals, audit trails and co-authors stay within their tenant
+func TestPostManagerTenantScopedRecords(t *testing.T) {
+	store := NewInMemoryPostStore()
+	pm := NewPostManager(store)
+	ctx := context.Background()
+	tenantA := WithTenant(ctx, "tenant-a")
+	tenantB := WithTenant(ctx, "tenant-b")
+	modA := WithActor(tenantA, Actor{UserID: "mod1", Role: RoleModerator})
+	modB := WithActor(tenantB, Actor{UserID: "mod1", Role: RoleModerator})
+
+	postID, err := pm.CreatePost(tenantA, createTestPostData("alice"))
+	require.NoError(t, err)
+
+	// Test: feedback signals of another tenant's posts are not returned
+	require.NoError(t, pm.HideFromFeed(tenantA, "bob", postID))
+	signals, err := pm.GetFeedbackSignals(tenantA, []string{postID})
+	require.NoError(t, err)
+	assert.Len(t, signals, 1)
+	signals, err = pm.GetFeedbackSignals(tenantB, []string{postID})
+	require.NoError(t, err)
+	assert.Empty(t, signals)
+
+	// Test: audit trails are only listed to moderators of the tenant
+	require.NoError(t, pm.HidePost(modA, postID, "spam"))
+	entries, err := pm.ListAuditEntries(modA, postID, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "tenant-a", entries[0].TenantID)
+	entries, err = pm.ListAuditEntries(modB, postID, 10, 0)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+	_, err = pm.ListAuditEntries(WithActor(tenantA, Actor{UserID: "alice"}), postID, 10, 0)
+	assert.Equal(t, ErrPermissionDenied, err)
+	_, err = pm.ListAuditEntries(tenantA, postID, 10, 0)
+	assert.Equal(t, ErrPermissionDenied, err)
+
+	// Test: co-authors cannot be removed through another tenant
+	require.NoError(t, pm.InviteCoAuthor(tenantA, postID, "alice", "carol"))
+	assert.Equal(t, ErrInviteNotFound, store.DeleteCoAuthor(tenantB, postID, "carol"))
+	coAuthors, err := pm.ListCoAuthors(tenantA, postID)
+	require.NoError(t, err)
+	assert.Len(t, coAuthors, 1)
+	require.NoError(t, store.DeleteCoAuthor(tenantA, postID, "carol"))
+}
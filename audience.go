@@ -0,0 +1,424 @@
+// Package postflow provides functionality for managing user posts and feeds.
+package postflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrAudienceNotFound is returned when an audience list does not exist
+	ErrAudienceNotFound = errors.New("audience not found")
+
+	// ErrInvalidAudience is returned when a post's audience is empty or names lists the author does not own
+	ErrInvalidAudience = errors.New("invalid audience")
+)
+
+// Audience is a named list of users, such as close friends, that posts can be shared with
+type Audience struct {
+	ID        string    `json:"id"`
+	TenantID  string    `json:"tenant_id,omitempty"` // Set by the store from the context
+	OwnerID   string    `json:"owner_id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AudienceStore defines the interface for stores that support custom audiences.
+// Stores only return posts with VisibilityCustom to viewers in their audience, resolving
+// membership at read time, except to moderators and administrators in the context.
+// Audience lists belong to the tenant in the context; lists of other tenants are not found.
+type AudienceStore interface {
+	// SaveAudience creates or renames an audience list
+	SaveAudience(ctx context.Context, audience *Audience) error
+
+	// GetAudience returns an audience list
+	GetAudience(ctx context.Context, audienceID string) (*Audience, error)
+
+	// DeleteAudience removes an audience list and its members
+	DeleteAudience(ctx context.Context, audienceID string) error
+
+	// ListAudiences returns the audience lists of a user, ordered by name
+	ListAudiences(ctx context.Context, ownerID string) ([]*Audience, error)
+
+	// AddAudienceMembers adds users to an audience list, ignoring existing members
+	AddAudienceMembers(ctx context.Context, audienceID string, userIDs []string) error
+
+	// RemoveAudienceMembers removes users from an audience list
+	RemoveAudienceMembers(ctx context.Context, audienceID string, userIDs []string) error
+
+	// ListAudienceMembers returns the members of an audience list, sorted
+	ListAudienceMembers(ctx context.Context, audienceID string) ([]string, error)
+
+	// InAudience reports whether the user may see the post under its current audience
+	InAudience(ctx context.Context, post *Post, userID string) (bool, error)
+}
+
+// seesAllAudiences reports whether the actor in the context may read custom posts without being in their audience
+func seesAllAudiences(ctx context.Context) bool {
+	actor, ok := ActorFromContext(ctx)
+	return ok && actor.IsPrivileged()
+}
+
+// inAudience reports whether the post may be shown to the viewer, using the given membership test for lists
+func (p *Post) inAudience(viewer string, isMember func(audienceID string) bool) bool {
+	if p.Visibility != VisibilityCustom || p.isAuthor(viewer) {
+		return true
+	}
+	if viewer == "" {
+		return false
+	}
+	for _, userID := range p.AudienceUserIDs {
+		if userID == viewer {
+			return true
+		}
+	}
+	for _, audienceID := range p.AudienceIDs {
+		if isMember(audienceID) {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateAudience creates an audience list owned by the user
+func (m *PostManagerImpl) CreateAudience(ctx context.Context, ownerID, name string) (*Audience, error) {
+	store, ok := m.store.(AudienceStore)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	name = strings.TrimSpace(name)
+	if ownerID == "" || name == "" {
+		return nil, fmt.Errorf("%w: owner and name are required", ErrInvalidAudience)
+	}
+
+	audience := &Audience{
+		ID:        uuid.New().String(),
+		OwnerID:   ownerID,
+		Name:      name,
+		CreatedAt: time.Now(),
+	}
+	if err := store.SaveAudience(ctx, audience); err != nil {
+		return nil, err
+	}
+
+	return audience, nil
+}
+
+// RenameAudience changes the name of an audience list owned by the user
+func (m *PostManagerImpl) RenameAudience(ctx context.Context, ownerID, audienceID, name string) error {
+	store, audience, err := m.ownedAudience(ctx, ownerID, audienceID)
+	if err != nil {
+		return err
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("%w: name is required", ErrInvalidAudience)
+	}
+
+	audience.Name = name
+	return store.SaveAudience(ctx, audience)
+}
+
+// DeleteAudience removes an audience list owned by the user.
+// Posts shared with the list are no longer shown to its former members.
+func (m *PostManagerImpl) DeleteAudience(ctx context.Context, ownerID, audienceID string) error {
+	store, _, err := m.ownedAudience(ctx, ownerID, audienceID)
+	if err != nil {
+		return err
+	}
+
+	return store.DeleteAudience(ctx, audienceID)
+}
+
+// ListAudiences returns the audience lists of a user, ordered by name
+func (m *PostManagerImpl) ListAudiences(ctx context.Context, ownerID string) ([]*Audience, error) {
+	store, ok := m.store.(AudienceStore)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	return store.ListAudiences(ctx, ownerID)
+}
+
+// AddAudienceMembers adds users to an audience list owned by the user.
+// New members see the posts shared with the list immediately.
+func (m *PostManagerImpl) AddAudienceMembers(ctx context.Context, ownerID, audienceID string, userIDs ...string) error {
+	store, _, err := m.ownedAudience(ctx, ownerID, audienceID)
+	if err != nil {
+		return err
+	}
+
+	return store.AddAudienceMembers(ctx, audienceID, userIDs)
+}
+
+// RemoveAudienceMembers removes users from an audience list owned by the user.
+// Removed members stop seeing the posts shared with the list immediately.
+func (m *PostManagerImpl) RemoveAudienceMembers(ctx context.Context, ownerID, audienceID string, userIDs ...string) error {
+	store, _, err := m.ownedAudience(ctx, ownerID, audienceID)
+	if err != nil {
+		return err
+	}
+
+	return store.RemoveAudienceMembers(ctx, audienceID, userIDs)
+}
+
+// ListAudienceMembers returns the members of an audience list owned by the user
+func (m *PostManagerImpl) ListAudienceMembers(ctx context.Context, ownerID, audienceID string) ([]string, error) {
+	store, _, err := m.ownedAudience(ctx, ownerID, audienceID)
+	if err != nil {
+		return nil, err
+	}
+
+	return store.ListAudienceMembers(ctx, audienceID)
+}
+
+// ownedAudience returns the audience store and an audience list, checking that the user owns it
+func (m *PostManagerImpl) ownedAudience(ctx context.Context, ownerID, audienceID string) (AudienceStore, *Audience, error) {
+	store, ok := m.store.(AudienceStore)
+	if !ok {
+		return nil, nil, ErrNotSupported
+	}
+
+	audience, err := store.GetAudience(ctx, audienceID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if audience.OwnerID != ownerID {
+		return nil, nil, ErrPermissionDenied
+	}
+
+	return store, audience, nil
+}
+
+// prepareAudience validates the audience of a custom post and clears it for other visibilities.
+// Audience lists must belong to the post's author.
+func (m *PostManagerImpl) prepareAudience(ctx context.Context, post *Post) error {
+	if post.Visibility != VisibilityCustom {
+		post.AudienceIDs = nil
+		post.AudienceUserIDs = nil
+		return nil
+	}
+
+	store, ok := m.store.(AudienceStore)
+	if !ok {
+		return ErrNotSupported
+	}
+
+	post.AudienceIDs = uniqueSorted(post.AudienceIDs)
+	post.AudienceUserIDs = uniqueSorted(post.AudienceUserIDs)
+	if len(post.AudienceIDs) == 0 && len(post.AudienceUserIDs) == 0 {
+		return fmt.Errorf("%w: custom visibility requires audience lists or users", ErrInvalidAudience)
+	}
+
+	for _, audienceID := range post.AudienceIDs {
+		audience, err := store.GetAudience(ctx, audienceID)
+		if errors.Is(err, ErrAudienceNotFound) || err == nil && audience.OwnerID != post.UserID {
+			return fmt.Errorf("%w: audience %s", ErrInvalidAudience, audienceID)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// checkAudience returns ErrPermissionDenied if a custom post is not shared with the user
+func (m *PostManagerImpl) checkAudience(ctx context.Context, post *Post, userID string) error {
+	if post.Visibility != VisibilityCustom || seesAllAudiences(ctx) {
+		return nil
+	}
+
+	store, ok := m.store.(AudienceStore)
+	if !ok {
+		return ErrNotSupported
+	}
+
+	allowed, err := store.InAudience(ctx, post, userID)
+	if err != nil {
+		return err
+	}
+	if !allowed {
+		return ErrPermissionDenied
+	}
+
+	return nil
+}
+
+// uniqueSorted returns the non-empty values without duplicates, sorted
+func uniqueSorted(values []string) []string {
+	if len(values) == 0 {
+		return nil
+	}
+
+	seen := make(map[string]bool, len(values))
+	result := make([]string, 0, len(values))
+	for _, value := range values {
+		if value != "" && !seen[value] {
+			seen[value] = true
+			result = append(result, value)
+		}
+	}
+	sort.Strings(result)
+
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
+
+// lookupAudience returns a stored audience list of the tenant in the context. The caller must hold the lock.
+func (s *InMemoryPostStore) lookupAudience(ctx context.Context, audienceID string) (*Audience, bool) {
+	audience, exists := s.audiences[audienceID]
+	if !exists || audience.TenantID != TenantFromContext(ctx) {
+		return nil, false
+	}
+	return audience, true
+}
+
+// SaveAudience creates or renames an audience list
+func (s *InMemoryPostStore) SaveAudience(ctx context.Context, audience *Audience) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	audience.TenantID = TenantFromContext(ctx)
+	if existing, exists := s.audiences[audience.ID]; exists && existing.TenantID != audience.TenantID {
+		return fmt.Errorf("%w: ID %s is taken", ErrInvalidAudience, audience.ID)
+	}
+
+	audienceCopy := *audience
+	s.audiences[audience.ID] = &audienceCopy
+	return nil
+}
+
+// GetAudience returns an audience list
+func (s *InMemoryPostStore) GetAudience(ctx context.Context, audienceID string) (*Audience, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	audience, exists := s.lookupAudience(ctx, audienceID)
+	if !exists {
+		return nil, ErrAudienceNotFound
+	}
+
+	audienceCopy := *audience
+	return &audienceCopy, nil
+}
+
+// DeleteAudience removes an audience list and its members
+func (s *InMemoryPostStore) DeleteAudience(ctx context.Context, audienceID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.lookupAudience(ctx, audienceID); !exists {
+		return ErrAudienceNotFound
+	}
+
+	delete(s.audiences, audienceID)
+	delete(s.audienceMembers, audienceID)
+	return nil
+}
+
+// ListAudiences returns the audience lists of a user, ordered by name
+func (s *InMemoryPostStore) ListAudiences(ctx context.Context, ownerID string) ([]*Audience, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	tenant := TenantFromContext(ctx)
+	var result []*Audience
+	for _, audience := range s.audiences {
+		if audience.TenantID == tenant && audience.OwnerID == ownerID {
+			audienceCopy := *audience
+			result = append(result, &audienceCopy)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Name != result[j].Name {
+			return result[i].Name < result[j].Name
+		}
+		return result[i].ID < result[j].ID
+	})
+
+	return result, nil
+}
+
+// AddAudienceMembers adds users to an audience list, ignoring existing members
+func (s *InMemoryPostStore) AddAudienceMembers(ctx context.Context, audienceID string, userIDs []string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.lookupAudience(ctx, audienceID); !exists {
+		return ErrAudienceNotFound
+	}
+
+	members, exists := s.audienceMembers[audienceID]
+	if !exists {
+		members = make(map[string]bool)
+		s.audienceMembers[audienceID] = members
+	}
+	for _, userID := range userIDs {
+		if userID != "" {
+			members[userID] = true
+		}
+	}
+
+	return nil
+}
+
+// RemoveAudienceMembers removes users from an audience list
+func (s *InMemoryPostStore) RemoveAudienceMembers(ctx context.Context, audienceID string, userIDs []string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.lookupAudience(ctx, audienceID); !exists {
+		return ErrAudienceNotFound
+	}
+
+	for _, userID := range userIDs {
+		delete(s.audienceMembers[audienceID], userID)
+	}
+
+	return nil
+}
+
+// ListAudienceMembers returns the members of an audience list, sorted
+func (s *InMemoryPostStore) ListAudienceMembers(ctx context.Context, audienceID string) ([]string, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if _, exists := s.lookupAudience(ctx, audienceID); !exists {
+		return nil, ErrAudienceNotFound
+	}
+
+	members := make([]string, 0, len(s.audienceMembers[audienceID]))
+	for userID := range s.audienceMembers[audienceID] {
+		members = append(members, userID)
+	}
+	sort.Strings(members)
+
+	return members, nil
+}
+
+// InAudience reports whether the user may see the post under its current audience
+func (s *InMemoryPostStore) InAudience(ctx context.Context, post *Post, userID string) (bool, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.inAudienceOf(post, userID), nil
+}
+
+// inAudienceOf reports whether the post may be shown to the viewer. The caller must hold the lock.
+func (s *InMemoryPostStore) inAudienceOf(post *Post, viewer string) bool {
+	return post.inAudience(viewer, func(audienceID string) bool {
+		return s.audienceMembers[audienceID][viewer]
+	})
+}
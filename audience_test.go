@@ -0,0 +1,157 @@
+package postflow
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPostManagerAudiences tests custom audiences with the in-memory store
+func TestPostManagerAudiences(t *testing.T) {
+	pm := setupTestPostManager()
+	ctx := context.Background()
+	as := func(userID string) context.Context {
+		return WithActor(ctx, Actor{UserID: userID})
+	}
+
+	// Test: audience lists belong to their owner
+	friends, err := pm.CreateAudience(ctx, "alice", "Close friends")
+	require.NoError(t, err)
+	_, err = pm.CreateAudience(ctx, "alice", " ")
+	assert.True(t, errors.Is(err, ErrInvalidAudience))
+	require.NoError(t, pm.AddAudienceMembers(ctx, "alice", friends.ID, "bob", "bob"))
+	assert.Equal(t, ErrPermissionDenied, pm.AddAudienceMembers(ctx, "mallory", friends.ID, "mallory"))
+	assert.Equal(t, ErrAudienceNotFound, pm.AddAudienceMembers(ctx, "alice", "missing", "bob"))
+
+	members, err := pm.ListAudienceMembers(ctx, "alice", friends.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bob"}, members)
+
+	bobLists, err := pm.CreateAudience(ctx, "bob", "Team")
+	require.NoError(t, err)
+
+	// Test: custom posts need an audience the author owns
+	invalid := createTestPostData("alice")
+	invalid.Visibility = VisibilityCustom
+	_, err = pm.CreatePost(ctx, invalid)
+	assert.True(t, errors.Is(err, ErrInvalidAudience))
+	invalid.AudienceIDs = []string{bobLists.ID}
+	_, err = pm.CreatePost(ctx, invalid)
+	assert.True(t, errors.Is(err, ErrInvalidAudience))
+
+	post := createTestPostData("alice")
+	post.Visibility = VisibilityCustom
+	post.AudienceIDs = []string{friends.ID}
+	post.AudienceUserIDs = []string{"dave", "dave"}
+	postID, err := pm.CreatePost(ctx, post)
+	require.NoError(t, err)
+
+	stored, err := pm.GetPost(ctx, postID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{friends.ID}, stored.AudienceIDs)
+	assert.Equal(t, []string{"dave"}, stored.AudienceUserIDs)
+
+	// Test: members and listed users see the post everywhere, others nowhere
+	for viewer, allowed := range map[string]bool{"alice": true, "bob": true, "dave": true, "carol": false, "": false} {
+		readCtx := ctx
+		if viewer != "" {
+			readCtx = as(viewer)
+		}
+
+		listed, err := pm.ListPosts(readCtx, &PostFilter{UserID: "alice"})
+		assert.NoError(t, err)
+		feed, err := pm.GetUserFeed(ctx, viewer, 10, 0)
+		assert.NoError(t, err)
+		trending, err := pm.GetTrendingPosts(readCtx, 10)
+		assert.NoError(t, err)
+
+		if allowed {
+			assert.Equal(t, []string{postID}, postIDs(listed), viewer)
+			assert.Equal(t, []string{postID}, postIDs(feed), viewer)
+			assert.Equal(t, []string{postID}, postIDs(trending), viewer)
+		} else {
+			assert.Empty(t, listed, viewer)
+			assert.Empty(t, feed, viewer)
+			assert.Empty(t, trending, viewer)
+		}
+	}
+
+	_, err = pm.GetPost(as("carol"), postID)
+	assert.Equal(t, ErrPermissionDenied, err)
+	assert.Equal(t, ErrPermissionDenied, pm.AddReaction(ctx, postID, "carol", ReactionLike))
+	require.NoError(t, pm.AddReaction(ctx, postID, "bob", ReactionLike))
+
+	// Test: reactions are only shown to the audience, or without an actor
+	users, err := pm.GetReactedUsers(as("bob"), postID, nil, 10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bob"}, users)
+	_, err = pm.GetReactedUsers(as("carol"), postID, nil, 10, 0)
+	assert.Equal(t, ErrPermissionDenied, err)
+	users, err = pm.GetReactedUsers(ctx, postID, nil, 10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"bob"}, users)
+	_, err = pm.GetReactionCounts(as("carol"), postID)
+	assert.Equal(t, ErrPermissionDenied, err)
+	reaction, err := pm.GetUserReaction(as("bob"), postID, "bob")
+	assert.NoError(t, err)
+	require.NotNil(t, reaction)
+	assert.Equal(t, ReactionLike, *reaction)
+	_, err = pm.GetUserReaction(as("carol"), postID, "bob")
+	assert.Equal(t, ErrPermissionDenied, err)
+	counts, err := pm.GetReactionCounts(ctx, postID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, counts[ReactionLike])
+
+	// Test: moderators see custom posts regardless of audience
+	moderatorCtx := WithActor(ctx, Actor{UserID: "mod", Role: RoleModerator})
+	_, err = pm.GetPost(moderatorCtx, postID)
+	assert.NoError(t, err)
+	listed, err := pm.ListPosts(moderatorCtx, &PostFilter{UserID: "alice"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{postID}, postIDs(listed))
+
+	// Test: membership changes take effect immediately
+	require.NoError(t, pm.AddAudienceMembers(ctx, "alice", friends.ID, "carol"))
+	_, err = pm.GetPost(as("carol"), postID)
+	assert.NoError(t, err)
+	require.NoError(t, pm.RemoveAudienceMembers(ctx, "alice", friends.ID, "bob"))
+	_, err = pm.GetPost(as("bob"), postID)
+	assert.Equal(t, ErrPermissionDenied, err)
+	feed, err := pm.GetUserFeed(ctx, "bob", 10, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, feed)
+
+	// Test: deleting a list revokes access through it
+	require.NoError(t, pm.DeleteAudience(ctx, "alice", friends.ID))
+	_, err = pm.GetPost(as("carol"), postID)
+	assert.Equal(t, ErrPermissionDenied, err)
+	_, err = pm.GetPost(as("dave"), postID)
+	assert.NoError(t, err)
+
+	lists, err := pm.ListAudiences(ctx, "alice")
+	assert.NoError(t, err)
+	assert.Empty(t, lists)
+
+	// Test: patching to another visibility clears the audience
+	patched, err := pm.PatchPost(ctx, postID, "alice", &PostPatch{Fields: PatchVisibility, Visibility: "public"})
+	require.NoError(t, err)
+	assert.Empty(t, patched.AudienceIDs)
+	assert.Empty(t, patched.AudienceUserIDs)
+	_, err = pm.GetPost(as("carol"), postID)
+	assert.NoError(t, err)
+
+	patched, err = pm.PatchPost(ctx, postID, "alice", &PostPatch{
+		Fields:          PatchVisibility,
+		Visibility:      VisibilityCustom,
+		AudienceUserIDs: []string{"erin"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"erin"}, patched.AudienceUserIDs)
+	_, err = pm.GetPost(as("dave"), postID)
+	assert.Equal(t, ErrPermissionDenied, err)
+	_, err = pm.GetPost(as("erin"), postID)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,120 @@
+// Package postflow provides functionality for managing user posts and feeds.
+package postflow
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuditAction represents an administrative or destructive change to a post
+type AuditAction uint8
+
+const (
+	AuditEdit    AuditAction = 1 // A moderator or administrator edited another user's post
+	AuditDelete  AuditAction = 2
+	AuditHide    AuditAction = 3
+	AuditUnhide  AuditAction = 4
+	AuditApprove AuditAction = 5
+	AuditReject  AuditAction = 6
+
+	AuditGroupRemove AuditAction = 7 // A group moderator removed a post from their group
+)
+
+// AuditEntry records who changed a post and how
+type AuditEntry struct {
+	ID        string      `json:"id"`
+	TenantID  string      `json:"tenant_id,omitempty"` // Set by the store from the context
+	PostID    string      `json:"post_id"`
+	AuthorID  string      `json:"author_id"`
+	ActorID   string      `json:"actor_id"`
+	ActorRole Role        `json:"actor_role"`
+	Action    AuditAction `json:"action"`
+	Reason    string      `json:"reason,omitempty"`
+	CreatedAt time.Time   `json:"created_at"`
+}
+
+// AuditStore defines the interface for stores that keep an audit trail.
+// Audit entries belong to the tenant in the context and outlive the posts they refer to.
+type AuditStore interface {
+	// SaveAuditEntry records an audit entry
+	SaveAuditEntry(ctx context.Context, entry *AuditEntry) error
+
+	// ListAuditEntries returns the audit entries of a post, most recent first
+	ListAuditEntries(ctx context.Context, postID string, limit, offset int) ([]*AuditEntry, error)
+}
+
+// ListAuditEntries returns the audit trail of a post, most recent first.
+// Only actors allowed to moderate the post may read it; the actor is taken from the context.
+// The trail of a deleted post is authorized like listings that concern no single post.
+func (m *PostManagerImpl) ListAuditEntries(ctx context.Context, postID string, limit, offset int) ([]*AuditEntry, error) {
+	store, ok := m.store.(AuditStore)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	if _, ok := ActorFromContext(ctx); !ok {
+		return nil, ErrPermissionDenied
+	}
+	post, err := m.store.GetPost(ctx, postID)
+	if err != nil && !errors.Is(err, ErrPostNotFound) {
+		return nil, err
+	}
+	if _, err := m.authorizeModerator(ctx, post); err != nil {
+		return nil, err
+	}
+
+	return store.ListAuditEntries(ctx, postID, limit, offset)
+}
+
+// SaveAuditEntry records an audit entry
+func (s *InMemoryPostStore) SaveAuditEntry(ctx context.Context, entry *AuditEntry) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if entry.ID == "" {
+		entry.ID = uuid.New().String()
+	}
+
+	entry.TenantID = TenantFromContext(ctx)
+	entryCopy := *entry
+	key := tenantScopedKey(ctx, entry.PostID)
+	s.auditEntries[key] = append(s.auditEntries[key], &entryCopy)
+
+	return nil
+}
+
+// ListAuditEntries returns the audit entries of a post, most recent first
+func (s *InMemoryPostStore) ListAuditEntries(ctx context.Context, postID string, limit, offset int) ([]*AuditEntry, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	key := tenantScopedKey(ctx, postID)
+	entries := make([]*AuditEntry, len(s.auditEntries[key]))
+	for i, entry := range s.auditEntries[key] {
+		entryCopy := *entry
+		entries[i] = &entryCopy
+	}
+
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].CreatedAt.After(entries[j].CreatedAt)
+	})
+
+	// Apply pagination
+	if limit > 0 {
+		end := offset + limit
+		if end > len(entries) {
+			end = len(entries)
+		}
+		if offset < len(entries) {
+			entries = entries[offset:end]
+		} else {
+			entries = []*AuditEntry{}
+		}
+	}
+
+	return entries, nil
+}
@@ -0,0 +1,106 @@
+// Package postflow provides functionality for managing user posts and feeds.
+package postflow
+
+import "context"
+
+// Action represents an operation on a post that is subject to authorization
+type Action uint8
+
+const (
+	ActionRead   Action = 1
+	ActionCreate Action = 2
+	ActionUpdate Action = 3
+	ActionDelete Action = 4
+	ActionReact  Action = 5
+
+	// ActionModerate covers reviewing held posts and other moderation of posts by other users
+	ActionModerate Action = 6
+)
+
+// String returns the name of the action
+func (a Action) String() string {
+	switch a {
+	case ActionRead:
+		return "read"
+	case ActionCreate:
+		return "create"
+	case ActionUpdate:
+		return "update"
+	case ActionDelete:
+		return "delete"
+	case ActionReact:
+		return "react"
+	case ActionModerate:
+		return "moderate"
+	default:
+		return "unknown"
+	}
+}
+
+// Authorizer decides whether an actor may perform an action on a post.
+// Implementations return nil to allow the action, or an error (usually ErrPermissionDenied) to deny it.
+type Authorizer interface {
+	Authorize(ctx context.Context, actor Actor, action Action, post *Post) error
+}
+
+// AuthorizerFunc adapts a function to the Authorizer interface
+type AuthorizerFunc func(ctx context.Context, actor Actor, action Action, post *Post) error
+
+// Authorize calls f(ctx, actor, action, post)
+func (f AuthorizerFunc) Authorize(ctx context.Context, actor Actor, action Action, post *Post) error {
+	return f(ctx, actor, action, post)
+}
+
+// OwnerPolicy lets users create their own posts, update and delete posts they author or co-author,
+// read and react to public and unlisted posts, and read and react to posts they author of any visibility.
+// Followers-only and custom posts are readable here; the manager checks that the actor is in their audience.
+type OwnerPolicy struct{}
+
+// Authorize implements Authorizer
+func (OwnerPolicy) Authorize(ctx context.Context, actor Actor, action Action, post *Post) error {
+	switch action {
+	case ActionRead, ActionReact:
+		switch post.Visibility {
+		case VisibilityPublic, VisibilityUnlisted, VisibilityFollowers, VisibilityCustom:
+			return nil
+		}
+		if post.isAuthor(actor.UserID) {
+			return nil
+		}
+	case ActionCreate:
+		if actor.UserID != "" && actor.UserID == post.UserID {
+			return nil
+		}
+	case ActionUpdate, ActionDelete:
+		if post.isAuthor(actor.UserID) {
+			return nil
+		}
+	}
+
+	return ErrPermissionDenied
+}
+
+// RolePolicy allows moderators and administrators to perform any action
+// and defers to Base for everyone else
+type RolePolicy struct {
+	Base Authorizer
+}
+
+// Authorize implements Authorizer
+func (p RolePolicy) Authorize(ctx context.Context, actor Actor, action Action, post *Post) error {
+	if actor.IsPrivileged() {
+		return nil
+	}
+
+	return p.Base.Authorize(ctx, actor, action, post)
+}
+
+// DefaultAuthorizer is the policy used when no authorizer is configured
+var DefaultAuthorizer Authorizer = RolePolicy{Base: OwnerPolicy{}}
+
+// WithAuthorizer replaces the default authorization policy
+func WithAuthorizer(authorizer Authorizer) PostManagerOption {
+	return func(m *PostManagerImpl) {
+		m.authorizer = authorizer
+	}
+}
@@ -0,0 +1,181 @@
+package postflow
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestOwnerPolicy tests the owner-based authorization policy
+func TestOwnerPolicy(t *testing.T) {
+	ctx := context.Background()
+	policy := OwnerPolicy{}
+	owner := Actor{UserID: "user1"}
+	other := Actor{UserID: "user2"}
+
+	public := &Post{UserID: "user1", Visibility: "public"}
+	private := &Post{UserID: "user1", Visibility: "private"}
+
+	tests := []struct {
+		actor   Actor
+		action  Action
+		post    *Post
+		allowed bool
+	}{
+		{owner, ActionRead, private, true},
+		{other, ActionRead, public, true},
+		{other, ActionRead, private, false},
+		{other, ActionReact, public, true},
+		{other, ActionReact, private, false},
+		{owner, ActionCreate, public, true},
+		{other, ActionCreate, public, false},
+		{owner, ActionUpdate, public, true},
+		{other, ActionUpdate, public, false},
+		{owner, ActionDelete, private, true},
+		{other, ActionDelete, public, false},
+		{owner, ActionModerate, private, false},
+		{Actor{}, ActionUpdate, &Post{}, false},
+	}
+
+	for _, tt := range tests {
+		err := policy.Authorize(ctx, tt.actor, tt.action, tt.post)
+		if tt.allowed {
+			assert.NoError(t, err, "%s %s", tt.actor.UserID, tt.action)
+		} else {
+			assert.Equal(t, ErrPermissionDenied, err, "%s %s", tt.actor.UserID, tt.action)
+		}
+	}
+
+	// Test: privileged actors bypass the base policy
+	rolePolicy := RolePolicy{Base: policy}
+	assert.NoError(t, rolePolicy.Authorize(ctx, Actor{UserID: "mod1", Role: RoleModerator}, ActionDelete, private))
+	assert.Equal(t, ErrPermissionDenied, rolePolicy.Authorize(ctx, other, ActionDelete, private))
+}
+
+// TestPostManagerAuthorizer tests plugging a custom authorizer into the manager
+func TestPostManagerAuthorizer(t *testing.T) {
+	errFrozen := errors.New("post is frozen")
+	var calls []Action
+
+	authorizer := AuthorizerFunc(func(ctx context.Context, actor Actor, action Action, post *Post) error {
+		calls = append(calls, action)
+		if action != ActionCreate && action != ActionRead && post.Content == "frozen" {
+			return errFrozen
+		}
+		return DefaultAuthorizer.Authorize(ctx, actor, action, post)
+	})
+
+	pm := NewPostManager(NewInMemoryPostStore(), WithAuthorizer(authorizer))
+	ctx := context.Background()
+
+	post := createTestPostData("user1")
+	post.Content = "frozen"
+	postID, err := pm.CreatePost(ctx, post)
+	require.NoError(t, err)
+
+	// Test: every action is consulted
+	err = pm.AddReaction(ctx, postID, "user2", ReactionLike)
+	assert.Equal(t, errFrozen, err)
+	err = pm.UpdatePost(ctx, post)
+	assert.ErrorIs(t, err, errFrozen)
+	_, err = pm.PatchPost(ctx, postID, "user1", &PostPatch{Fields: PatchContent, Content: "thawed"})
+	assert.Equal(t, errFrozen, err)
+	err = pm.DeletePost(ctx, postID, "user1")
+	assert.Equal(t, errFrozen, err)
+	_, err = pm.GetPost(WithActor(ctx, Actor{UserID: "user2"}), postID)
+	assert.NoError(t, err)
+
+	assert.Equal(t, []Action{ActionCreate, ActionReact, ActionUpdate, ActionUpdate, ActionDelete, ActionRead}, calls)
+}
+
+// TestPostManagerReadAuthorization tests reading private posts with an actor
+func TestPostManagerReadAuthorization(t *testing.T) {
+	pm := NewPostManager(NewInMemoryPostStore())
+	ctx := context.Background()
+
+	post := createTestPostData("user1")
+	post.Visibility = "private"
+	postID, err := pm.CreatePost(ctx, post)
+	require.NoError(t, err)
+
+	_, err = pm.GetPost(WithActor(ctx, Actor{UserID: "user1"}), postID)
+	assert.NoError(t, err)
+	_, err = pm.GetPost(WithActor(ctx, Actor{UserID: "user2"}), postID)
+	assert.Equal(t, ErrPermissionDenied, err)
+	_, err = pm.GetPost(WithActor(ctx, Actor{UserID: "admin1", Role: RoleAdmin}), postID)
+	assert.NoError(t, err)
+
+	// Test: reacting to a private post of another user is denied
+	err = pm.AddReaction(ctx, postID, "user2", ReactionLike)
+	assert.Equal(t, ErrPermissionDenied, err)
+	err = pm.RemoveReaction(ctx, postID, "user2", ReactionLike)
+	assert.Equal(t, ErrPermissionDenied, err)
+
+	// Test: listings leave out private posts of other users
+	for userID, expected := range map[string]int{"user1": 1, "user2": 0} {
+		posts, err := pm.ListPosts(WithActor(ctx, Actor{UserID: userID}), &PostFilter{UserID: "user1"})
+		assert.NoError(t, err)
+		assert.Equal(t, expected, len(posts), userID)
+	}
+	posts, err := pm.ListPosts(WithActor(ctx, Actor{UserID: "admin1", Role: RoleAdmin}), &PostFilter{UserID: "user1"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(posts))
+
+	// Test: reactions of a post are only shown to readers of the post
+	require.NoError(t, pm.AddReaction(ctx, postID, "user1", ReactionLike))
+	_, err = pm.GetReactedUsers(WithActor(ctx, Actor{UserID: "user2"}), postID, nil, 10, 0)
+	assert.Equal(t, ErrPermissionDenied, err)
+	_, err = pm.GetReactionCounts(WithActor(ctx, Actor{UserID: "user2"}), postID)
+	assert.Equal(t, ErrPermissionDenied, err)
+	users, err := pm.GetReactedUsers(WithActor(ctx, Actor{UserID: "user1"}), postID, nil, 10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"user1"}, users)
+}
+
+// TestPostManagerModerationAuthorizer tests moderation and listings decided by a custom authorizer
+func TestPostManagerModerationAuthorizer(t *testing.T) {
+	// Trusted users moderate without a role; nobody but the author reads drafts
+	authorizer := AuthorizerFunc(func(ctx context.Context, actor Actor, action Action, post *Post) error {
+		if action == ActionModerate && actor.UserID == "trusted" {
+			return nil
+		}
+		if action == ActionRead && post.Content == "draft" && post.UserID != actor.UserID {
+			return ErrPermissionDenied
+		}
+		return DefaultAuthorizer.Authorize(ctx, actor, action, post)
+	})
+
+	pm := NewPostManager(NewInMemoryPostStore(), WithAuthorizer(authorizer))
+	ctx := context.Background()
+	trustedCtx := WithActor(ctx, Actor{UserID: "trusted"})
+
+	draft := createTestPostData("user1")
+	draft.Content = "draft"
+	draftID, err := pm.CreatePost(ctx, draft)
+	require.NoError(t, err)
+	_, err = pm.CreatePost(ctx, createTestPostData("user1"))
+	require.NoError(t, err)
+
+	// Test: listed posts are checked by the authorizer
+	posts, err := pm.ListPosts(WithActor(ctx, Actor{UserID: "user2"}), &PostFilter{UserID: "user1"})
+	assert.NoError(t, err)
+	require.Equal(t, 1, len(posts))
+	assert.NotEqual(t, draftID, posts[0].ID)
+	posts, err = pm.ListPosts(WithActor(ctx, Actor{UserID: "user1"}), &PostFilter{UserID: "user1"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(posts))
+
+	// Test: hiding and shadow bans follow the authorizer rather than roles
+	assert.NoError(t, pm.HidePost(trustedCtx, draftID, "spam"))
+	assert.NoError(t, pm.ShadowBanUser(trustedCtx, "user3", "spam"))
+	assert.Equal(t, ErrPermissionDenied, pm.HidePost(WithActor(ctx, Actor{UserID: "user2"}), draftID, "spam"))
+	assert.Equal(t, ErrPermissionDenied, pm.ShadowBanUser(WithActor(ctx, Actor{UserID: "user2"}), "user3", ""))
+
+	ban, err := pm.GetShadowBan(trustedCtx, "user3")
+	assert.NoError(t, err)
+	require.NotNil(t, ban)
+	assert.Equal(t, "trusted", ban.BannedBy)
+}
@@ -0,0 +1,233 @@
+// Package postflow provides functionality for managing user posts and feeds.
+package postflow
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"sync/atomic"
+)
+
+// ErrBlockedContent is returned when a post matches a blocklist rule
+var ErrBlockedContent = errors.New("blocked content")
+
+// BlockRuleKind represents how a blocklist rule is matched
+type BlockRuleKind uint8
+
+const (
+	BlockRuleWord   BlockRuleKind = 1 // Whole words or phrases, case-insensitive
+	BlockRuleRegex  BlockRuleKind = 2 // Regular expressions in RE2 syntax
+	BlockRuleDomain BlockRuleKind = 3 // Link hosts, including subdomains
+)
+
+// String returns the name of the rule kind
+func (k BlockRuleKind) String() string {
+	switch k {
+	case BlockRuleWord:
+		return "word"
+	case BlockRuleRegex:
+		return "regex"
+	case BlockRuleDomain:
+		return "domain"
+	default:
+		return "unknown"
+	}
+}
+
+// BlockRule identifies a single blocklist entry
+type BlockRule struct {
+	Kind    BlockRuleKind
+	Pattern string
+}
+
+// BlocklistConfig lists the banned words, patterns and domains
+type BlocklistConfig struct {
+	Words   []string // Words or phrases matched on Unicode word boundaries
+	Regexes []string // Patterns matched against the raw text
+	Domains []string // Domains banned in links and media URLs
+}
+
+// BlockedContentError is the validation error returned when a post matches a blocklist rule
+type BlockedContentError struct {
+	Rule  BlockRule
+	Field string // content, tags, media.url or media.thumbnail_url
+}
+
+// Error describes the offending rule
+func (e *BlockedContentError) Error() string {
+	return fmt.Sprintf("blocked content: %s matches %s rule %q", e.Field, e.Rule.Kind, e.Rule.Pattern)
+}
+
+// Is makes errors.Is(err, ErrBlockedContent) match
+func (e *BlockedContentError) Is(target error) bool {
+	return target == ErrBlockedContent
+}
+
+// Blocklist checks posts against banned words, regexes and domains.
+// It is safe for concurrent use and can be reloaded at runtime with Update.
+type Blocklist struct {
+	rules atomic.Pointer[compiledBlocklist]
+}
+
+// compiledBlocklist is an immutable, ready-to-match set of rules
+type compiledBlocklist struct {
+	words   []compiledWord
+	regexes []compiledRegex
+	domains []string
+}
+
+type compiledWord struct {
+	pattern string
+	words   []string
+}
+
+type compiledRegex struct {
+	pattern string
+	re      *regexp.Regexp
+}
+
+// urlPattern finds links in free text
+var urlPattern = regexp.MustCompile(`(?i)\b(?:https?://)?(?:[\p{L}\p{N}-]+\.)+\p{L}{2,}(?::\d+)?(?:/\S*)?`)
+
+// NewBlocklist creates a blocklist from the given configuration
+func NewBlocklist(config BlocklistConfig) (*Blocklist, error) {
+	b := &Blocklist{}
+	if err := b.Update(config); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Update atomically replaces the rules. On error the current rules stay in effect.
+func (b *Blocklist) Update(config BlocklistConfig) error {
+	compiled := &compiledBlocklist{}
+
+	for _, word := range config.Words {
+		words := tokenizeWords(word)
+		if len(words) == 0 {
+			return fmt.Errorf("blocklist word %q contains no letters or digits", word)
+		}
+		compiled.words = append(compiled.words, compiledWord{pattern: word, words: words})
+	}
+
+	for _, pattern := range config.Regexes {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return fmt.Errorf("blocklist regex %q: %w", pattern, err)
+		}
+		compiled.regexes = append(compiled.regexes, compiledRegex{pattern: pattern, re: re})
+	}
+
+	for _, domain := range config.Domains {
+		domain = strings.Trim(strings.ToLower(strings.TrimSpace(domain)), ".")
+		if domain == "" {
+			return errors.New("blocklist domain is empty")
+		}
+		compiled.domains = append(compiled.domains, domain)
+	}
+
+	b.rules.Store(compiled)
+	return nil
+}
+
+// Check returns a *BlockedContentError for the first rule the post matches
+func (b *Blocklist) Check(post *Post) error {
+	rules := b.rules.Load()
+	if rules == nil {
+		return nil
+	}
+
+	if rule, ok := rules.matchText(post.Content); ok {
+		return &BlockedContentError{Rule: rule, Field: "content"}
+	}
+	if rule, ok := rules.matchLinks(urlPattern.FindAllString(post.Content, -1)); ok {
+		return &BlockedContentError{Rule: rule, Field: "content"}
+	}
+
+	for _, tag := range post.Tags {
+		if rule, ok := rules.matchText(tag); ok {
+			return &BlockedContentError{Rule: rule, Field: "tags"}
+		}
+	}
+
+	for _, media := range post.Media {
+		links := []struct{ field, link string }{
+			{"media.url", media.URL},
+			{"media.thumbnail_url", media.ThumbnailURL},
+		}
+		for _, l := range links {
+			if l.link == "" {
+				continue
+			}
+			if rule, ok := rules.matchLinks([]string{l.link}); ok {
+				return &BlockedContentError{Rule: rule, Field: l.field}
+			}
+			if rule, ok := rules.matchRegexes(l.link); ok {
+				return &BlockedContentError{Rule: rule, Field: l.field}
+			}
+		}
+	}
+
+	return nil
+}
+
+// matchText checks text against the word and regex rules
+func (c *compiledBlocklist) matchText(text string) (BlockRule, bool) {
+	if len(c.words) > 0 {
+		words := tokenizeWords(text)
+		for _, word := range c.words {
+			if containsPhrase(words, word.words) {
+				return BlockRule{Kind: BlockRuleWord, Pattern: word.pattern}, true
+			}
+		}
+	}
+
+	return c.matchRegexes(text)
+}
+
+// matchRegexes checks text against the regex rules
+func (c *compiledBlocklist) matchRegexes(text string) (BlockRule, bool) {
+	for _, regex := range c.regexes {
+		if regex.re.MatchString(text) {
+			return BlockRule{Kind: BlockRuleRegex, Pattern: regex.pattern}, true
+		}
+	}
+	return BlockRule{}, false
+}
+
+// matchLinks checks the hosts of links against the domain rules
+func (c *compiledBlocklist) matchLinks(links []string) (BlockRule, bool) {
+	for _, link := range links {
+		host := linkHost(link)
+		if host == "" {
+			continue
+		}
+		for _, domain := range c.domains {
+			if host == domain || strings.HasSuffix(host, "."+domain) {
+				return BlockRule{Kind: BlockRuleDomain, Pattern: domain}, true
+			}
+		}
+	}
+	return BlockRule{}, false
+}
+
+// linkHost returns the lower-cased host of a link, which may lack a scheme
+func linkHost(link string) string {
+	if !strings.Contains(link, "://") {
+		link = "http://" + link
+	}
+	parsed, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSuffix(strings.ToLower(parsed.Hostname()), ".")
+}
+
+// WithBlocklist rejects created and updated posts that match the blocklist
+func WithBlocklist(blocklist *Blocklist) PostManagerOption {
+	return func(m *PostManagerImpl) {
+		m.blocklist = blocklist
+	}
+}
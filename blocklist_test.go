@@ -0,0 +1,116 @@
+package postflow
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestBlocklistCheck tests matching posts against blocklist rules
+func TestBlocklistCheck(t *testing.T) {
+	blocklist, err := NewBlocklist(BlocklistConfig{
+		Words:   []string{"scam", "free money", "垃圾"},
+		Regexes: []string{`(?i)\bv[i1]agra\b`},
+		Domains: []string{"evil.example"},
+	})
+	require.NoError(t, err)
+
+	check := func(post *Post) *BlockedContentError {
+		err := blocklist.Check(post)
+		if err == nil {
+			return nil
+		}
+		var blocked *BlockedContentError
+		require.True(t, errors.As(err, &blocked))
+		assert.ErrorIs(t, err, ErrBlockedContent)
+		return blocked
+	}
+
+	// Test: clean content passes, words inside other words do not match
+	assert.Nil(t, check(&Post{Content: "No scammers here, just freedom"}))
+
+	// Test: whole words and phrases match case-insensitively
+	blocked := check(&Post{Content: "This is a SCAM!"})
+	require.NotNil(t, blocked)
+	assert.Equal(t, BlockRule{Kind: BlockRuleWord, Pattern: "scam"}, blocked.Rule)
+	assert.Equal(t, "content", blocked.Field)
+	assert.NotNil(t, check(&Post{Content: "Get FREE\tmoney now"}))
+	assert.NotNil(t, check(&Post{Content: "这是垃圾广告"}))
+
+	// Test: regexes
+	blocked = check(&Post{Content: "cheap V1agra"})
+	require.NotNil(t, blocked)
+	assert.Equal(t, BlockRuleRegex, blocked.Rule.Kind)
+	assert.Contains(t, blocked.Error(), "regex")
+
+	// Test: domains in content, including subdomains and links without a scheme
+	blocked = check(&Post{Content: "visit https://www.Evil.example/path now"})
+	require.NotNil(t, blocked)
+	assert.Equal(t, BlockRule{Kind: BlockRuleDomain, Pattern: "evil.example"}, blocked.Rule)
+	assert.NotNil(t, check(&Post{Content: "go to evil.example"}))
+	assert.Nil(t, check(&Post{Content: "notevil.example is fine"}))
+
+	// Test: tags and media URLs
+	blocked = check(&Post{Content: "ok", Tags: []string{"free-money"}})
+	require.NotNil(t, blocked)
+	assert.Equal(t, "tags", blocked.Field)
+
+	blocked = check(&Post{Content: "ok", Media: []Media{{URL: "https://cdn.example.com/a.jpg", ThumbnailURL: "https://img.evil.example/t.jpg"}}})
+	require.NotNil(t, blocked)
+	assert.Equal(t, "media.thumbnail_url", blocked.Field)
+}
+
+// TestBlocklistUpdate tests reloading rules at runtime
+func TestBlocklistUpdate(t *testing.T) {
+	blocklist, err := NewBlocklist(BlocklistConfig{Words: []string{"alpha"}})
+	require.NoError(t, err)
+
+	post := &Post{Content: "alpha beta"}
+	assert.Error(t, blocklist.Check(post))
+
+	require.NoError(t, blocklist.Update(BlocklistConfig{Words: []string{"gamma"}}))
+	assert.NoError(t, blocklist.Check(post))
+
+	// Test: invalid rules are rejected and the current rules stay in effect
+	err = blocklist.Update(BlocklistConfig{Regexes: []string{"("}})
+	assert.Error(t, err)
+	assert.Error(t, blocklist.Check(&Post{Content: "gamma"}))
+
+	_, err = NewBlocklist(BlocklistConfig{Words: []string{"!!"}})
+	assert.Error(t, err)
+}
+
+// TestPostManagerBlocklist tests that the manager enforces the blocklist on writes
+func TestPostManagerBlocklist(t *testing.T) {
+	blocklist, err := NewBlocklist(BlocklistConfig{Words: []string{"scam"}})
+	require.NoError(t, err)
+	pm := NewPostManager(NewInMemoryPostStore(), WithBlocklist(blocklist))
+	ctx := context.Background()
+
+	// Test: create
+	post := createTestPostData("user1")
+	post.Content = "total scam"
+	_, err = pm.CreatePost(ctx, post)
+	assert.ErrorIs(t, err, ErrBlockedContent)
+
+	post = createTestPostData("user1")
+	postID, err := pm.CreatePost(ctx, post)
+	require.NoError(t, err)
+
+	// Test: update
+	post.Tags = []string{"scam"}
+	err = pm.UpdatePost(ctx, post)
+	assert.ErrorIs(t, err, ErrBlockedContent)
+
+	// Test: patch
+	_, err = pm.PatchPost(ctx, postID, "user1", &PostPatch{Fields: PatchContent, Content: "scam"})
+	assert.ErrorIs(t, err, ErrBlockedContent)
+
+	// Test: reloaded rules apply immediately
+	require.NoError(t, blocklist.Update(BlocklistConfig{}))
+	_, err = pm.PatchPost(ctx, postID, "user1", &PostPatch{Fields: PatchContent, Content: "scam"})
+	assert.NoError(t, err)
+}
@@ -0,0 +1,188 @@
+// Package postflow provides functionality for managing user posts and feeds.
+package postflow
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrPostExists is returned when a post with the same ID already exists
+var ErrPostExists = errors.New("post already exists")
+
+// BulkPost is a post to import together with the individual reactions it received
+type BulkPost struct {
+	Post *Post
+
+	// Reactions, when set, replace the counts in Post.Reactions
+	Reactions []UserReaction
+}
+
+// BulkCreateResult reports the outcome of one post in a bulk import
+type BulkCreateResult struct {
+	PostID string
+	Err    error
+}
+
+// BulkPostStore defines the interface for stores that can import many posts at once
+type BulkPostStore interface {
+	// BulkSavePosts inserts new posts as they are, preserving IDs and timestamps.
+	// It returns one error per post (nil on success) in input order,
+	// and a non-nil error only if the import as a whole failed.
+	BulkSavePosts(ctx context.Context, posts []*BulkPost) ([]error, error)
+}
+
+// BulkCreatePosts imports many posts at once, preserving supplied IDs and timestamps.
+// Posts without an ID get a new one and posts without timestamps are stamped with the current time.
+// The result holds one entry per input post in the same order. PostCreated hooks run for each post.
+// Imports are admin-only: posts are stored as supplied, without the authorizer, blocklists, moderator,
+// rate limits or duplicate detection, so the actor in the context must be an administrator.
+func (m *PostManagerImpl) BulkCreatePosts(ctx context.Context, posts []*BulkPost) ([]BulkCreateResult, error) {
+	store, ok := m.store.(BulkPostStore)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	if actor, ok := ActorFromContext(ctx); !ok || actor.Role != RoleAdmin {
+		return nil, ErrPermissionDenied
+	}
+
+	results := make([]BulkCreateResult, len(posts))
+	valid := make([]*BulkPost, 0, len(posts))
+	validIndex := make([]int, 0, len(posts))
+	events := make([]*Event, 0, len(posts))
+	now := time.Now()
+
+	for i, item := range posts {
+		// Validate the post
+		if item == nil || item.Post == nil {
+			results[i].Err = errors.New("post is required")
+			continue
+		}
+
+		post := item.Post
+		if post.UserID == "" {
+			results[i].Err = errors.New("user ID is required")
+			continue
+		}
+
+		// Fill in what the source did not provide
+		if post.ID == "" {
+			post.ID = uuid.New().String()
+		}
+		if post.CreatedAt.IsZero() {
+			post.CreatedAt = now
+		}
+		if post.UpdatedAt.IsZero() {
+			post.UpdatedAt = post.CreatedAt
+		}
+		if post.Reactions == nil {
+			post.Reactions = make(map[ReactionType]int)
+		}
+		applyFingerprint(post)
+		if err := validateVisibility(post, VisibilityPublic); err != nil {
+			results[i].Err = err
+			continue
+		}
+		if err := m.prepareAudience(ctx, post); err != nil {
+			results[i].Err = err
+			continue
+		}
+		if err := m.checkGroupPost(ctx, post); err != nil {
+			results[i].Err = err
+			continue
+		}
+
+		event := newEvent(EventPostCreated, post, actorFor(ctx, post.UserID))
+		if err := m.runBeforeHooks(ctx, event); err != nil {
+			results[i].Err = err
+			continue
+		}
+
+		results[i].PostID = post.ID
+		valid = append(valid, item)
+		validIndex = append(validIndex, i)
+		events = append(events, event)
+	}
+
+	if len(valid) == 0 {
+		return results, nil
+	}
+
+	errs, err := store.BulkSavePosts(ctx, valid)
+	if err != nil {
+		return nil, err
+	}
+
+	for j, i := range validIndex {
+		results[i].Err = errs[j]
+		if errs[j] == nil {
+			m.runAfterHooks(ctx, events[j])
+		}
+	}
+
+	return results, nil
+}
+
+// normalizeBulkReactions keeps the last reaction of each user and tallies the result,
+// rejecting invalid reaction types
+func normalizeBulkReactions(reactions []UserReaction) ([]UserReaction, map[ReactionType]int, error) {
+	position := make(map[string]int, len(reactions))
+	unique := make([]UserReaction, 0, len(reactions))
+	for _, reaction := range reactions {
+		if reaction.ReactionType <= ReactionNone || reaction.ReactionType > ReactionAngry {
+			return nil, nil, ErrInvalidReaction
+		}
+		if i, exists := position[reaction.UserID]; exists {
+			unique[i] = reaction
+			continue
+		}
+		position[reaction.UserID] = len(unique)
+		unique = append(unique, reaction)
+	}
+
+	counts := make(map[ReactionType]int)
+	for _, reaction := range unique {
+		counts[reaction.ReactionType]++
+	}
+
+	return unique, counts, nil
+}
+
+// BulkSavePosts inserts new posts under a single lock
+func (s *InMemoryPostStore) BulkSavePosts(ctx context.Context, posts []*BulkPost) ([]error, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	errs := make([]error, len(posts))
+	for i, item := range posts {
+		post := item.Post
+		post.TenantID = TenantFromContext(ctx)
+
+		if _, exists := s.posts[post.ID]; exists {
+			errs[i] = ErrPostExists
+			continue
+		}
+
+		if len(item.Reactions) > 0 {
+			reactions, counts, err := normalizeBulkReactions(item.Reactions)
+			if err != nil {
+				errs[i] = err
+				continue
+			}
+
+			userReactions := make(map[string]*UserReaction, len(reactions))
+			for _, reaction := range reactions {
+				reaction := reaction
+				userReactions[reaction.UserID] = &reaction
+			}
+			s.reactions[post.ID] = userReactions
+			post.Reactions = counts
+		}
+
+		s.insertPost(post)
+	}
+
+	return errs, nil
+}
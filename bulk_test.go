@@ -0,0 +1,82 @@
+package postflow
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createTestBulkPosts creates posts with fixed IDs and timestamps as an importer would supply them
+func createTestBulkPosts(count int) []*BulkPost {
+	created := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+	posts := make([]*BulkPost, count)
+	for i := 0; i < count; i++ {
+		post := createTestPostData(fmt.Sprintf("user%d", i%3))
+		post.ID = fmt.Sprintf("import-%d", i)
+		post.CreatedAt = created.Add(time.Duration(i) * time.Minute)
+		post.UpdatedAt = post.CreatedAt
+		post.Media = []Media{{ID: fmt.Sprintf("media-%d", i), Type: MediaTypeImage, URL: "https://example.com/a.jpg"}}
+		posts[i] = &BulkPost{Post: post}
+	}
+	return posts
+}
+
+// TestPostManagerBulkCreatePosts tests the BulkCreatePosts method
+func TestPostManagerBulkCreatePosts(t *testing.T) {
+	pm := setupTestPostManager()
+	ctx := context.Background()
+
+	existing := createTestPostData("user1")
+	existing.ID = "import-1"
+	_, err := pm.CreatePost(ctx, existing)
+	require.NoError(t, err)
+
+	posts := createTestBulkPosts(4)
+	posts[0].Reactions = []UserReaction{
+		{UserID: "fan1", ReactionType: ReactionLike},
+		{UserID: "fan2", ReactionType: ReactionLove},
+		{UserID: "fan1", ReactionType: ReactionWow}, // the last reaction of a user wins
+	}
+	posts[2].Post.UserID = ""
+	posts = append(posts, nil)
+
+	results, err := pm.BulkCreatePosts(WithActor(ctx, Actor{UserID: "admin", Role: RoleAdmin}), posts)
+	require.NoError(t, err)
+	require.Equal(t, 5, len(results))
+
+	// Test: only administrators may import
+	_, err = pm.BulkCreatePosts(ctx, posts)
+	assert.Equal(t, ErrPermissionDenied, err)
+	_, err = pm.BulkCreatePosts(WithActor(ctx, Actor{UserID: "mod1", Role: RoleModerator}), posts)
+	assert.Equal(t, ErrPermissionDenied, err)
+
+	// Test: per-item outcomes are reported in input order
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, "import-0", results[0].PostID)
+	assert.Equal(t, ErrPostExists, results[1].Err)
+	assert.Error(t, results[2].Err)
+	assert.NoError(t, results[3].Err)
+	assert.Error(t, results[4].Err)
+
+	// Test: supplied IDs, timestamps, media and reactions are preserved
+	imported, err := pm.GetPost(ctx, "import-0")
+	assert.NoError(t, err)
+	assert.Equal(t, posts[0].Post.CreatedAt, imported.CreatedAt)
+	assert.Equal(t, "media-0", imported.Media[0].ID)
+	assert.Equal(t, 1, imported.Reactions[ReactionWow])
+	assert.Equal(t, 1, imported.Reactions[ReactionLove])
+	assert.Equal(t, 0, imported.Reactions[ReactionLike])
+
+	reaction, err := pm.GetUserReaction(ctx, "import-0", "fan1")
+	assert.NoError(t, err)
+	assert.Equal(t, ReactionWow, *reaction)
+
+	// Test: imported posts are indexed
+	tagged, err := pm.ListPosts(ctx, &PostFilter{Tags: []string{"golang"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(tagged))
+}
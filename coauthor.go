@@ -0,0 +1,333 @@
+// Package postflow provides functionality for managing user posts and feeds.
+package postflow
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+)
+
+var (
+	// ErrInviteNotFound is returned when a user has no co-author invitation for a post
+	ErrInviteNotFound = errors.New("co-author invitation not found")
+
+	// ErrAlreadyInvited is returned when a user is already invited to or co-authoring a post
+	ErrAlreadyInvited = errors.New("user already invited")
+)
+
+// CoAuthorStatus represents the state of a co-author invitation
+type CoAuthorStatus uint8
+
+const (
+	CoAuthorPending  CoAuthorStatus = 0
+	CoAuthorAccepted CoAuthorStatus = 1
+	CoAuthorDeclined CoAuthorStatus = 2
+)
+
+// CoAuthor is an invitation to co-author a post.
+// Accepted co-authors are listed in Post.CoAuthors.
+type CoAuthor struct {
+	PostID      string         `json:"post_id"`
+	UserID      string         `json:"user_id"`
+	InvitedBy   string         `json:"invited_by"`
+	Status      CoAuthorStatus `json:"status"`
+	CreatedAt   time.Time      `json:"created_at"`
+	RespondedAt time.Time      `json:"responded_at,omitempty"`
+}
+
+// CoAuthorStore defines the interface for stores that support co-authored posts.
+// Stores fill in Post.CoAuthors with accepted co-authors and include co-authored posts
+// when ListPosts filters by user.
+type CoAuthorStore interface {
+	// SaveCoAuthor creates or updates a co-author invitation
+	SaveCoAuthor(ctx context.Context, coAuthor *CoAuthor) error
+
+	// GetCoAuthor returns the invitation of a user for a post
+	GetCoAuthor(ctx context.Context, postID, userID string) (*CoAuthor, error)
+
+	// DeleteCoAuthor removes a co-author or invitation
+	DeleteCoAuthor(ctx context.Context, postID, userID string) error
+
+	// ListCoAuthors returns the invitations of a post, oldest first
+	ListCoAuthors(ctx context.Context, postID string) ([]*CoAuthor, error)
+
+	// ListCoAuthorInvites returns a user's invitations with the given status, most recent first
+	ListCoAuthorInvites(ctx context.Context, userID string, status CoAuthorStatus, limit, offset int) ([]*CoAuthor, error)
+}
+
+// isAuthor reports whether the user is the author or an accepted co-author of the post
+func (p *Post) isAuthor(userID string) bool {
+	if userID == "" {
+		return false
+	}
+	if p.UserID == userID {
+		return true
+	}
+	for _, coAuthor := range p.CoAuthors {
+		if coAuthor == userID {
+			return true
+		}
+	}
+	return false
+}
+
+// InviteCoAuthor invites a user to co-author a post. Only the post's author may invite.
+func (m *PostManagerImpl) InviteCoAuthor(ctx context.Context, postID, userID, inviteeID string) error {
+	store, ok := m.store.(CoAuthorStore)
+	if !ok {
+		return ErrNotSupported
+	}
+
+	post, err := m.store.GetPost(ctx, postID)
+	if err != nil {
+		return err
+	}
+	if post.UserID != userID {
+		return ErrPermissionDenied
+	}
+	if inviteeID == "" || inviteeID == post.UserID {
+		return ErrAlreadyInvited
+	}
+
+	existing, err := store.GetCoAuthor(ctx, postID, inviteeID)
+	if err != nil && !errors.Is(err, ErrInviteNotFound) {
+		return err
+	}
+	if existing != nil && existing.Status != CoAuthorDeclined {
+		return ErrAlreadyInvited
+	}
+
+	return store.SaveCoAuthor(ctx, &CoAuthor{
+		PostID:    postID,
+		UserID:    inviteeID,
+		InvitedBy: userID,
+		Status:    CoAuthorPending,
+		CreatedAt: time.Now(),
+	})
+}
+
+// AcceptCoAuthorInvite makes the user a co-author of the post
+func (m *PostManagerImpl) AcceptCoAuthorInvite(ctx context.Context, postID, userID string) error {
+	return m.respondToInvite(ctx, postID, userID, CoAuthorAccepted)
+}
+
+// DeclineCoAuthorInvite declines an invitation to co-author the post
+func (m *PostManagerImpl) DeclineCoAuthorInvite(ctx context.Context, postID, userID string) error {
+	return m.respondToInvite(ctx, postID, userID, CoAuthorDeclined)
+}
+
+// RemoveCoAuthor removes a co-author or pending invitation.
+// The post's author may remove anyone; co-authors may remove themselves.
+func (m *PostManagerImpl) RemoveCoAuthor(ctx context.Context, postID, userID, coAuthorID string) error {
+	store, ok := m.store.(CoAuthorStore)
+	if !ok {
+		return ErrNotSupported
+	}
+
+	post, err := m.store.GetPost(ctx, postID)
+	if err != nil {
+		return err
+	}
+	if userID != post.UserID && userID != coAuthorID {
+		return ErrPermissionDenied
+	}
+
+	return store.DeleteCoAuthor(ctx, postID, coAuthorID)
+}
+
+// ListCoAuthors returns the co-authors and invitations of a post, oldest first
+func (m *PostManagerImpl) ListCoAuthors(ctx context.Context, postID string) ([]*CoAuthor, error) {
+	store, ok := m.store.(CoAuthorStore)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	return store.ListCoAuthors(ctx, postID)
+}
+
+// ListCoAuthorInvites returns the pending co-author invitations of a user, most recent first
+func (m *PostManagerImpl) ListCoAuthorInvites(ctx context.Context, userID string, limit, offset int) ([]*CoAuthor, error) {
+	store, ok := m.store.(CoAuthorStore)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	return store.ListCoAuthorInvites(ctx, userID, CoAuthorPending, limit, offset)
+}
+
+// respondToInvite accepts or declines a pending invitation
+func (m *PostManagerImpl) respondToInvite(ctx context.Context, postID, userID string, status CoAuthorStatus) error {
+	store, ok := m.store.(CoAuthorStore)
+	if !ok {
+		return ErrNotSupported
+	}
+
+	coAuthor, err := store.GetCoAuthor(ctx, postID, userID)
+	if err != nil {
+		return err
+	}
+	if coAuthor.Status != CoAuthorPending {
+		return ErrInviteNotFound
+	}
+
+	coAuthor.Status = status
+	coAuthor.RespondedAt = time.Now()
+
+	return store.SaveCoAuthor(ctx, coAuthor)
+}
+
+// syncCoAuthors refreshes Post.CoAuthors and the user index of a post after an invitation changed.
+// The caller must hold the write lock.
+func (s *InMemoryPostStore) syncCoAuthors(postID string) {
+	post, exists := s.posts[postID]
+	if !exists {
+		return
+	}
+
+	var accepted []string
+	for userID, coAuthor := range s.coAuthors[postID] {
+		if coAuthor.Status == CoAuthorAccepted {
+			accepted = append(accepted, userID)
+		}
+	}
+	sort.Strings(accepted)
+
+	// Re-index the post for former and current co-authors
+	for _, userID := range post.CoAuthors {
+		s.removeUserPost(userID, postID)
+	}
+	for _, userID := range accepted {
+		s.userPosts[userID] = append(s.userPosts[userID], postID)
+	}
+
+	// Replace the stored post so that copies handed out earlier keep their co-authors
+	postCopy := *post
+	postCopy.CoAuthors = accepted
+	s.posts[postID] = &postCopy
+}
+
+// removeUserPost removes a post from a user's index. The caller must hold the write lock.
+func (s *InMemoryPostStore) removeUserPost(userID, postID string) {
+	userPosts := s.userPosts[userID]
+	for i, pid := range userPosts {
+		if pid == postID {
+			s.userPosts[userID] = append(userPosts[:i], userPosts[i+1:]...)
+			return
+		}
+	}
+}
+
+// SaveCoAuthor creates or updates a co-author invitation
+func (s *InMemoryPostStore) SaveCoAuthor(ctx context.Context, coAuthor *CoAuthor) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.lookupPost(ctx, coAuthor.PostID); !exists {
+		return ErrPostNotFound
+	}
+
+	if s.coAuthors[coAuthor.PostID] == nil {
+		s.coAuthors[coAuthor.PostID] = make(map[string]*CoAuthor)
+	}
+	coAuthorCopy := *coAuthor
+	s.coAuthors[coAuthor.PostID][coAuthor.UserID] = &coAuthorCopy
+	s.syncCoAuthors(coAuthor.PostID)
+
+	return nil
+}
+
+// GetCoAuthor returns the invitation of a user for a post
+func (s *InMemoryPostStore) GetCoAuthor(ctx context.Context, postID, userID string) (*CoAuthor, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	coAuthor, exists := s.coAuthors[postID][userID]
+	if _, inTenant := s.lookupPost(ctx, postID); !exists || !inTenant {
+		return nil, ErrInviteNotFound
+	}
+
+	coAuthorCopy := *coAuthor
+	return &coAuthorCopy, nil
+}
+
+// DeleteCoAuthor removes a co-author or invitation
+func (s *InMemoryPostStore) DeleteCoAuthor(ctx context.Context, postID, userID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.coAuthors[postID][userID]; !exists {
+		return ErrInviteNotFound
+	}
+	if _, exists := s.lookupPost(ctx, postID); !exists {
+		return ErrInviteNotFound
+	}
+
+	delete(s.coAuthors[postID], userID)
+	s.syncCoAuthors(postID)
+
+	return nil
+}
+
+// ListCoAuthors returns the invitations of a post, oldest first
+func (s *InMemoryPostStore) ListCoAuthors(ctx context.Context, postID string) ([]*CoAuthor, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	coAuthors := make([]*CoAuthor, 0, len(s.coAuthors[postID]))
+	if _, exists := s.lookupPost(ctx, postID); !exists {
+		return coAuthors, nil
+	}
+	for _, coAuthor := range s.coAuthors[postID] {
+		coAuthorCopy := *coAuthor
+		coAuthors = append(coAuthors, &coAuthorCopy)
+	}
+
+	sort.Slice(coAuthors, func(i, j int) bool {
+		if coAuthors[i].CreatedAt.Equal(coAuthors[j].CreatedAt) {
+			return coAuthors[i].UserID < coAuthors[j].UserID
+		}
+		return coAuthors[i].CreatedAt.Before(coAuthors[j].CreatedAt)
+	})
+
+	return coAuthors, nil
+}
+
+// ListCoAuthorInvites returns a user's invitations with the given status, most recent first
+func (s *InMemoryPostStore) ListCoAuthorInvites(ctx context.Context, userID string, status CoAuthorStatus, limit, offset int) ([]*CoAuthor, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	invites := []*CoAuthor{}
+	for postID, postCoAuthors := range s.coAuthors {
+		if _, inTenant := s.lookupPost(ctx, postID); !inTenant {
+			continue
+		}
+		if coAuthor, exists := postCoAuthors[userID]; exists && coAuthor.Status == status {
+			coAuthorCopy := *coAuthor
+			invites = append(invites, &coAuthorCopy)
+		}
+	}
+
+	sort.Slice(invites, func(i, j int) bool {
+		if invites[i].CreatedAt.Equal(invites[j].CreatedAt) {
+			return invites[i].PostID < invites[j].PostID
+		}
+		return invites[i].CreatedAt.After(invites[j].CreatedAt)
+	})
+
+	// Apply pagination
+	if limit > 0 {
+		end := offset + limit
+		if end > len(invites) {
+			end = len(invites)
+		}
+		if offset < len(invites) {
+			invites = invites[offset:end]
+		} else {
+			invites = []*CoAuthor{}
+		}
+	}
+
+	return invites, nil
+}
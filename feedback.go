@@ -0,0 +1,240 @@
+// Package postflow provides functionality for managing user posts and feeds.
+package postflow
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+)
+
+// ErrInvalidFeedback is returned when feedback has an unknown kind or no user
+var ErrInvalidFeedback = errors.New("invalid feedback")
+
+// FeedbackKind represents negative feedback a viewer gave on a post
+type FeedbackKind uint8
+
+const (
+	FeedbackHide          FeedbackKind = 1 // The viewer hid the post
+	FeedbackNotInterested FeedbackKind = 2 // The viewer is not interested in the post
+)
+
+// String returns the name of the feedback kind
+func (k FeedbackKind) String() string {
+	switch k {
+	case FeedbackHide:
+		return "hide"
+	case FeedbackNotInterested:
+		return "not_interested"
+	default:
+		return "unknown"
+	}
+}
+
+// PostFeedback is a viewer's negative feedback on a post.
+// The post never reappears in the viewer's feed or trending posts.
+type PostFeedback struct {
+	UserID    string       `json:"user_id"`
+	PostID    string       `json:"post_id"`
+	AuthorID  string       `json:"author_id"`
+	Kind      FeedbackKind `json:"kind"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+// FeedbackSignal aggregates the negative feedback on a post for feed ranking
+type FeedbackSignal struct {
+	PostID        string `json:"post_id"`
+	Hidden        int    `json:"hidden"`
+	NotInterested int    `json:"not_interested"`
+}
+
+// FeedbackStore defines the interface for stores that keep negative feedback.
+// Stores leave posts a viewer gave feedback on out of that viewer's GetUserFeed and GetTrendingPosts.
+type FeedbackStore interface {
+	// SaveFeedback records feedback, replacing the user's earlier feedback on the post
+	SaveFeedback(ctx context.Context, feedback *PostFeedback) error
+
+	// DeleteFeedback removes a user's feedback on a post
+	DeleteFeedback(ctx context.Context, userID, postID string) error
+
+	// ListFeedback returns a user's feedback, most recent first
+	ListFeedback(ctx context.Context, userID string, limit, offset int) ([]*PostFeedback, error)
+
+	// GetFeedbackSignals returns the aggregated feedback of posts of the tenant in the context;
+	// posts without feedback or of other tenants are omitted
+	GetFeedbackSignals(ctx context.Context, postIDs []string) (map[string]*FeedbackSignal, error)
+}
+
+// HideFromFeed hides a post from the user's feed and trending posts
+func (m *PostManagerImpl) HideFromFeed(ctx context.Context, userID, postID string) error {
+	return m.saveFeedback(ctx, userID, postID, FeedbackHide)
+}
+
+// MarkNotInterested records that the user is not interested in a post, which also hides it
+func (m *PostManagerImpl) MarkNotInterested(ctx context.Context, userID, postID string) error {
+	return m.saveFeedback(ctx, userID, postID, FeedbackNotInterested)
+}
+
+// UndoFeedback removes the user's feedback on a post
+func (m *PostManagerImpl) UndoFeedback(ctx context.Context, userID, postID string) error {
+	store, ok := m.store.(FeedbackStore)
+	if !ok {
+		return ErrNotSupported
+	}
+
+	return store.DeleteFeedback(ctx, userID, postID)
+}
+
+// ListFeedback returns the user's feedback, most recent first
+func (m *PostManagerImpl) ListFeedback(ctx context.Context, userID string, limit, offset int) ([]*PostFeedback, error) {
+	store, ok := m.store.(FeedbackStore)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	return store.ListFeedback(ctx, userID, limit, offset)
+}
+
+// GetFeedbackSignals returns the aggregated negative feedback of posts for feed ranking
+func (m *PostManagerImpl) GetFeedbackSignals(ctx context.Context, postIDs []string) (map[string]*FeedbackSignal, error) {
+	store, ok := m.store.(FeedbackStore)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	return store.GetFeedbackSignals(ctx, postIDs)
+}
+
+// saveFeedback validates and stores feedback on an existing post
+func (m *PostManagerImpl) saveFeedback(ctx context.Context, userID, postID string, kind FeedbackKind) error {
+	store, ok := m.store.(FeedbackStore)
+	if !ok {
+		return ErrNotSupported
+	}
+	if userID == "" {
+		return ErrInvalidFeedback
+	}
+
+	post, err := m.store.GetPost(ctx, postID)
+	if err != nil {
+		return err
+	}
+
+	return store.SaveFeedback(ctx, &PostFeedback{
+		UserID:    userID,
+		PostID:    postID,
+		AuthorID:  post.UserID,
+		Kind:      kind,
+		CreatedAt: time.Now(),
+	})
+}
+
+// addFeedback counts a feedback kind in the signal
+func (f *FeedbackSignal) addFeedback(kind FeedbackKind, count int) {
+	switch kind {
+	case FeedbackHide:
+		f.Hidden += count
+	case FeedbackNotInterested:
+		f.NotInterested += count
+	}
+}
+
+// hasFeedback reports whether the viewer gave feedback on the post. The caller must hold the mutex.
+func (s *InMemoryPostStore) hasFeedback(viewer, postID string) bool {
+	_, exists := s.feedback[postID][viewer]
+	return exists
+}
+
+// SaveFeedback records feedback, replacing the user's earlier feedback on the post
+func (s *InMemoryPostStore) SaveFeedback(ctx context.Context, feedback *PostFeedback) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if feedback.Kind != FeedbackHide && feedback.Kind != FeedbackNotInterested {
+		return ErrInvalidFeedback
+	}
+	if _, exists := s.lookupPost(ctx, feedback.PostID); !exists {
+		return ErrPostNotFound
+	}
+
+	if s.feedback[feedback.PostID] == nil {
+		s.feedback[feedback.PostID] = make(map[string]*PostFeedback)
+	}
+	feedbackCopy := *feedback
+	s.feedback[feedback.PostID][feedback.UserID] = &feedbackCopy
+
+	return nil
+}
+
+// DeleteFeedback removes a user's feedback on a post
+func (s *InMemoryPostStore) DeleteFeedback(ctx context.Context, userID, postID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.feedback[postID], userID)
+
+	return nil
+}
+
+// ListFeedback returns a user's feedback, most recent first
+func (s *InMemoryPostStore) ListFeedback(ctx context.Context, userID string, limit, offset int) ([]*PostFeedback, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	result := []*PostFeedback{}
+	for postID, postFeedback := range s.feedback {
+		if _, inTenant := s.lookupPost(ctx, postID); !inTenant {
+			continue
+		}
+		if feedback, exists := postFeedback[userID]; exists {
+			feedbackCopy := *feedback
+			result = append(result, &feedbackCopy)
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].CreatedAt.Equal(result[j].CreatedAt) {
+			return result[i].PostID < result[j].PostID
+		}
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
+
+	// Apply pagination
+	if limit > 0 {
+		end := offset + limit
+		if end > len(result) {
+			end = len(result)
+		}
+		if offset < len(result) {
+			result = result[offset:end]
+		} else {
+			result = []*PostFeedback{}
+		}
+	}
+
+	return result, nil
+}
+
+// GetFeedbackSignals returns the aggregated feedback of posts of the tenant in the context;
+// posts without feedback or of other tenants are omitted
+func (s *InMemoryPostStore) GetFeedbackSignals(ctx context.Context, postIDs []string) (map[string]*FeedbackSignal, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	signals := make(map[string]*FeedbackSignal)
+	for _, postID := range postIDs {
+		if _, exists := s.lookupPost(ctx, postID); !exists {
+			continue
+		}
+		for _, feedback := range s.feedback[postID] {
+			signal, exists := signals[postID]
+			if !exists {
+				signal = &FeedbackSignal{PostID: postID}
+				signals[postID] = signal
+			}
+			signal.addFeedback(feedback.Kind, 1)
+		}
+	}
+
+	return signals, nil
+}
@@ -0,0 +1,75 @@
+package postflow
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPostManagerFeedback tests feedback with the in-memory store
+func TestPostManagerFeedback(t *testing.T) {
+	pm := setupTestPostManager()
+	ctx := context.Background()
+
+	var ids []string
+	for i := 0; i < 3; i++ {
+		id, err := pm.CreatePost(ctx, createTestPostData("author"))
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+
+	// Test: invalid feedback
+	assert.Equal(t, ErrPostNotFound, pm.HideFromFeed(ctx, "user1", "nonexistent-id"))
+	assert.Equal(t, ErrInvalidFeedback, pm.MarkNotInterested(ctx, "", ids[0]))
+
+	require.NoError(t, pm.HideFromFeed(ctx, "user1", ids[0]))
+	require.NoError(t, pm.MarkNotInterested(ctx, "user1", ids[1]))
+	require.NoError(t, pm.MarkNotInterested(ctx, "user2", ids[1]))
+
+	// Test: the posts never reappear in the viewer's feed or trending
+	feed, err := pm.GetUserFeed(ctx, "user1", 10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{ids[2]}, postIDs(feed))
+
+	trending, err := pm.GetTrendingPosts(WithActor(ctx, Actor{UserID: "user1"}), 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{ids[2]}, postIDs(trending))
+
+	feed, err = pm.GetUserFeed(ctx, "user3", 10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(feed))
+
+	// Test: the feedback is listed with the author
+	feedback, err := pm.ListFeedback(ctx, "user1", 10, 0)
+	assert.NoError(t, err)
+	require.Equal(t, 2, len(feedback))
+	for _, item := range feedback {
+		assert.Equal(t, "author", item.AuthorID)
+	}
+
+	// Test: signals aggregate feedback per post
+	signals, err := pm.GetFeedbackSignals(ctx, ids)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(signals))
+	assert.Equal(t, &FeedbackSignal{PostID: ids[0], Hidden: 1}, signals[ids[0]])
+	assert.Equal(t, &FeedbackSignal{PostID: ids[1], NotInterested: 2}, signals[ids[1]])
+
+	// Test: new feedback replaces the old one, and undo brings the post back
+	require.NoError(t, pm.MarkNotInterested(ctx, "user1", ids[0]))
+	signals, err = pm.GetFeedbackSignals(ctx, []string{ids[0]})
+	assert.NoError(t, err)
+	assert.Equal(t, &FeedbackSignal{PostID: ids[0], NotInterested: 1}, signals[ids[0]])
+
+	require.NoError(t, pm.UndoFeedback(ctx, "user1", ids[0]))
+	feed, err = pm.GetUserFeed(ctx, "user1", 10, 0)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{ids[0], ids[2]}, postIDs(feed))
+
+	// Test: deleting a post removes its feedback
+	require.NoError(t, pm.DeletePost(ctx, ids[1], "author"))
+	feedback, err = pm.ListFeedback(ctx, "user2", 10, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, feedback)
+}
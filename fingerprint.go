@@ -0,0 +1,461 @@
+// Package postflow provides functionality for managing user posts and feeds.
+package postflow
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math/bits"
+	"sort"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrDuplicateContent is returned when a new post repeats one of the author's recent posts
+	ErrDuplicateContent = errors.New("duplicate content")
+
+	// ErrSpamContent is returned when a new post matches a known spam fingerprint
+	ErrSpamContent = errors.New("content matches a spam fingerprint")
+)
+
+const (
+	// DefaultDuplicateWindow is how far back an author's posts are compared when no window is configured
+	DefaultDuplicateWindow = 24 * time.Hour
+
+	// DefaultMaxSimHashDistance is the number of differing SimHash bits still considered a near-duplicate.
+	// GormPostStore finds every near-duplicate up to this distance; larger distances may miss some.
+	DefaultMaxSimHashDistance = 3
+
+	// shingleSize is the number of characters per SimHash feature
+	shingleSize = 3
+)
+
+// ContentFingerprint identifies the normalized text of a post
+type ContentFingerprint struct {
+	Hash    string `json:"hash"`    // Hex SHA-256 of the normalized content; empty for posts without text
+	SimHash uint64 `json:"simhash"` // 64-bit SimHash of the normalized content for near-duplicate matching
+}
+
+// Fingerprint computes the fingerprint of a text.
+// Case, punctuation and whitespace are ignored.
+func Fingerprint(content string) ContentFingerprint {
+	normalized := normalizeContent(content)
+	if normalized == "" {
+		return ContentFingerprint{}
+	}
+
+	sum := sha256.Sum256([]byte(normalized))
+	return ContentFingerprint{
+		Hash:    hex.EncodeToString(sum[:]),
+		SimHash: simHash(normalized),
+	}
+}
+
+// Distance returns the number of differing SimHash bits, or 0 for identical content
+func (f ContentFingerprint) Distance(other ContentFingerprint) int {
+	if f.Hash != "" && f.Hash == other.Hash {
+		return 0
+	}
+	return bits.OnesCount64(f.SimHash ^ other.SimHash)
+}
+
+// matches reports whether two fingerprints are identical or within maxDistance bits
+func (f ContentFingerprint) matches(other ContentFingerprint, maxDistance int) bool {
+	if f.Hash == "" || other.Hash == "" {
+		return false
+	}
+	return f.Distance(other) <= maxDistance
+}
+
+// normalizeContent reduces text to lower-cased words separated by single spaces
+func normalizeContent(content string) string {
+	return strings.Join(tokenizeWords(content), " ")
+}
+
+// simHash computes a 64-bit SimHash over character shingles of normalized text
+func simHash(normalized string) uint64 {
+	runes := []rune(normalized)
+
+	var weights [64]int
+	addFeature := func(feature string) {
+		h := fnv.New64a()
+		h.Write([]byte(feature))
+		sum := h.Sum64()
+		for bit := 0; bit < 64; bit++ {
+			if sum&(1<<uint(bit)) != 0 {
+				weights[bit]++
+			} else {
+				weights[bit]--
+			}
+		}
+	}
+
+	if len(runes) < shingleSize {
+		addFeature(normalized)
+	}
+	for i := 0; i+shingleSize <= len(runes); i++ {
+		addFeature(string(runes[i : i+shingleSize]))
+	}
+
+	var result uint64
+	for bit, weight := range weights {
+		if weight > 0 {
+			result |= 1 << uint(bit)
+		}
+	}
+
+	return result
+}
+
+// simHashBands splits a SimHash into four 16-bit bands.
+// Two hashes within 3 bits of each other share at least one band.
+func simHashBands(hash uint64) [4]uint16 {
+	return [4]uint16{
+		uint16(hash),
+		uint16(hash >> 16),
+		uint16(hash >> 32),
+		uint16(hash >> 48),
+	}
+}
+
+// applyFingerprint records the fingerprint of the post content on the post
+func applyFingerprint(post *Post) {
+	fingerprint := Fingerprint(post.Content)
+	post.ContentHash = fingerprint.Hash
+	post.SimHash = fingerprint.SimHash
+}
+
+// postFingerprint returns the fingerprint stored on a post
+func postFingerprint(post *Post) ContentFingerprint {
+	return ContentFingerprint{Hash: post.ContentHash, SimHash: post.SimHash}
+}
+
+// DuplicateAction decides what happens to duplicate posts
+type DuplicateAction uint8
+
+const (
+	DuplicateReject DuplicateAction = 0 // Fail CreatePost
+	DuplicateFlag   DuplicateAction = 1 // Hold the post for review
+)
+
+// DuplicatePolicy configures duplicate and spam detection in CreatePost
+type DuplicatePolicy struct {
+	Window      time.Duration // How far back the author's posts are compared; DefaultDuplicateWindow when zero
+	MaxDistance int           // Differing SimHash bits still considered a near-duplicate; DefaultMaxSimHashDistance when zero, exact copies only when negative
+	Action      DuplicateAction
+}
+
+// SimilarityQuery selects posts similar to a fingerprint
+type SimilarityQuery struct {
+	Fingerprint   ContentFingerprint
+	MaxDistance   int
+	UserID        string    // Only posts by this user, if set
+	Since         time.Time // Only posts created at or after this time, if set
+	ExcludePostID string
+	Limit         int
+}
+
+// SimilarPost is a post matched by a similarity query
+type SimilarPost struct {
+	Post     *Post `json:"post"`
+	Distance int   `json:"distance"` // Differing SimHash bits; 0 for identical content
+}
+
+// SpamFingerprint is a known spam text that new posts of its tenant are compared against
+type SpamFingerprint struct {
+	ContentFingerprint
+	TenantID  string    `json:"tenant_id,omitempty"` // Set by the store from the context
+	Reason    string    `json:"reason,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// FingerprintStore defines the interface for stores that support duplicate detection
+type FingerprintStore interface {
+	// FindSimilarPosts returns posts matching the query, closest first.
+	// Stores may miss matches more than DefaultMaxSimHashDistance bits apart: GormPostStore only
+	// compares posts sharing a 16-bit SimHash band, while InMemoryPostStore compares every post.
+	FindSimilarPosts(ctx context.Context, query *SimilarityQuery) ([]*SimilarPost, error)
+
+	// SaveSpamFingerprint adds or replaces a spam fingerprint of the tenant in the context
+	SaveSpamFingerprint(ctx context.Context, fingerprint *SpamFingerprint) error
+
+	// DeleteSpamFingerprint removes a spam fingerprint of the tenant in the context by its hash
+	DeleteSpamFingerprint(ctx context.Context, hash string) error
+
+	// ListSpamFingerprints returns the spam fingerprints of the tenant in the context
+	ListSpamFingerprints(ctx context.Context) ([]*SpamFingerprint, error)
+}
+
+// WithDuplicateDetection enables duplicate and spam fingerprint checks in CreatePost
+func WithDuplicateDetection(policy DuplicatePolicy) PostManagerOption {
+	return func(m *PostManagerImpl) {
+		if policy.Window <= 0 {
+			policy.Window = DefaultDuplicateWindow
+		}
+		if policy.MaxDistance == 0 {
+			policy.MaxDistance = DefaultMaxSimHashDistance
+		} else if policy.MaxDistance < 0 {
+			policy.MaxDistance = 0
+		}
+		m.duplicatePolicy = &policy
+	}
+}
+
+// checkDuplicates compares a new post with the author's recent posts and the spam fingerprints.
+// Depending on the policy, a match fails with an error or holds the post for review.
+func (m *PostManagerImpl) checkDuplicates(ctx context.Context, post *Post) error {
+	if m.duplicatePolicy == nil || post.ContentHash == "" {
+		return nil
+	}
+
+	store, ok := m.store.(FingerprintStore)
+	if !ok {
+		return ErrNotSupported
+	}
+
+	policy := m.duplicatePolicy
+	fingerprint := postFingerprint(post)
+
+	var matchErr error
+	var detail string
+
+	spamFingerprints, err := store.ListSpamFingerprints(ctx)
+	if err != nil {
+		return err
+	}
+	for _, spam := range spamFingerprints {
+		if fingerprint.matches(spam.ContentFingerprint, policy.MaxDistance) {
+			matchErr = ErrSpamContent
+			detail = spam.Reason
+			break
+		}
+	}
+
+	if matchErr == nil {
+		similar, err := store.FindSimilarPosts(ctx, &SimilarityQuery{
+			Fingerprint:   fingerprint,
+			MaxDistance:   policy.MaxDistance,
+			UserID:        post.UserID,
+			Since:         post.CreatedAt.Add(-policy.Window),
+			ExcludePostID: post.ID,
+			Limit:         1,
+		})
+		if err != nil {
+			return err
+		}
+		if len(similar) > 0 {
+			matchErr = ErrDuplicateContent
+			detail = "post " + similar[0].Post.ID
+		}
+	}
+
+	if matchErr == nil {
+		return nil
+	}
+	if detail != "" {
+		matchErr = fmt.Errorf("%w: %s", matchErr, detail)
+	}
+
+	if policy.Action == DuplicateFlag {
+		post.ModerationStatus = ModerationPending
+		post.ModerationReason = matchErr.Error()
+		return nil
+	}
+
+	return matchErr
+}
+
+// FindSimilarPosts returns posts with the same or nearly the same content as a post, closest first.
+// It is meant for moderation tools: matches include posts of any visibility and moderation status,
+// so only actors allowed to moderate the post may search; the actor is taken from the context.
+func (m *PostManagerImpl) FindSimilarPosts(ctx context.Context, postID string, maxDistance, limit int) ([]*SimilarPost, error) {
+	store, ok := m.store.(FingerprintStore)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	if _, ok := ActorFromContext(ctx); !ok {
+		return nil, ErrPermissionDenied
+	}
+	post, err := m.store.GetPost(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := m.authorizeModerator(ctx, post); err != nil {
+		return nil, err
+	}
+	if post.ContentHash == "" {
+		return []*SimilarPost{}, nil
+	}
+
+	return store.FindSimilarPosts(ctx, &SimilarityQuery{
+		Fingerprint:   postFingerprint(post),
+		MaxDistance:   maxDistance,
+		ExcludePostID: postID,
+		Limit:         limit,
+	})
+}
+
+// fingerprintStoreAs returns the fingerprint store if the actor in the context may manage spam fingerprints.
+// The spam fingerprint list is authorized as ActionModerate on the tenant.
+func (m *PostManagerImpl) fingerprintStoreAs(ctx context.Context) (FingerprintStore, error) {
+	if _, err := m.authorizeModerator(ctx, nil); err != nil {
+		return nil, err
+	}
+
+	store, ok := m.store.(FingerprintStore)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	return store, nil
+}
+
+// AddSpamFingerprint adds a known spam text to the spam fingerprint list of the tenant.
+// Only actors allowed to moderate may change the list; the actor is taken from the context.
+func (m *PostManagerImpl) AddSpamFingerprint(ctx context.Context, content string, reason string) (*SpamFingerprint, error) {
+	store, err := m.fingerprintStoreAs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	fingerprint := Fingerprint(content)
+	if fingerprint.Hash == "" {
+		return nil, errors.New("content is required")
+	}
+
+	spam := &SpamFingerprint{
+		ContentFingerprint: fingerprint,
+		Reason:             reason,
+		CreatedAt:          time.Now(),
+	}
+	if err := store.SaveSpamFingerprint(ctx, spam); err != nil {
+		return nil, err
+	}
+
+	return spam, nil
+}
+
+// RemoveSpamFingerprint removes a spam fingerprint of the tenant by its hash.
+// Only actors allowed to moderate may change the list; the actor is taken from the context.
+func (m *PostManagerImpl) RemoveSpamFingerprint(ctx context.Context, hash string) error {
+	store, err := m.fingerprintStoreAs(ctx)
+	if err != nil {
+		return err
+	}
+
+	return store.DeleteSpamFingerprint(ctx, hash)
+}
+
+// ListSpamFingerprints returns the spam fingerprint list of the tenant.
+// Only actors allowed to moderate may read the list; the actor is taken from the context.
+func (m *PostManagerImpl) ListSpamFingerprints(ctx context.Context) ([]*SpamFingerprint, error) {
+	store, err := m.fingerprintStoreAs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return store.ListSpamFingerprints(ctx)
+}
+
+// sortSimilarPosts orders matches closest first, then newest first, and applies the limit
+func sortSimilarPosts(similar []*SimilarPost, limit int) []*SimilarPost {
+	sort.Slice(similar, func(i, j int) bool {
+		if similar[i].Distance != similar[j].Distance {
+			return similar[i].Distance < similar[j].Distance
+		}
+		return similar[i].Post.CreatedAt.After(similar[j].Post.CreatedAt)
+	})
+
+	if limit > 0 && len(similar) > limit {
+		similar = similar[:limit]
+	}
+
+	return similar
+}
+
+// FindSimilarPosts returns posts matching the query, closest first
+func (s *InMemoryPostStore) FindSimilarPosts(ctx context.Context, query *SimilarityQuery) ([]*SimilarPost, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	candidates := s.posts
+	if query.UserID != "" {
+		candidates = make(map[string]*Post)
+		for _, pid := range s.userPosts[query.UserID] {
+			if post, exists := s.posts[pid]; exists {
+				candidates[pid] = post
+			}
+		}
+	}
+
+	similar := []*SimilarPost{}
+	tenant := TenantFromContext(ctx)
+	for id, post := range candidates {
+		if id == query.ExcludePostID || post.TenantID != tenant {
+			continue
+		}
+		if !query.Since.IsZero() && post.CreatedAt.Before(query.Since) {
+			continue
+		}
+
+		fingerprint := postFingerprint(post)
+		if !query.Fingerprint.matches(fingerprint, query.MaxDistance) {
+			continue
+		}
+
+		similar = append(similar, &SimilarPost{
+			Post:     s.copyPostFor(post, viewerFromContext(ctx)),
+			Distance: query.Fingerprint.Distance(fingerprint),
+		})
+	}
+
+	return sortSimilarPosts(similar, query.Limit), nil
+}
+
+// SaveSpamFingerprint adds or replaces a spam fingerprint of the tenant in the context
+func (s *InMemoryPostStore) SaveSpamFingerprint(ctx context.Context, fingerprint *SpamFingerprint) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	fingerprint.TenantID = TenantFromContext(ctx)
+	fingerprintCopy := *fingerprint
+	s.spamFingerprints[tenantScopedKey(ctx, fingerprint.Hash)] = &fingerprintCopy
+
+	return nil
+}
+
+// DeleteSpamFingerprint removes a spam fingerprint of the tenant in the context by its hash
+func (s *InMemoryPostStore) DeleteSpamFingerprint(ctx context.Context, hash string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.spamFingerprints, tenantScopedKey(ctx, hash))
+
+	return nil
+}
+
+// ListSpamFingerprints returns the spam fingerprints of the tenant in the context, oldest first
+func (s *InMemoryPostStore) ListSpamFingerprints(ctx context.Context) ([]*SpamFingerprint, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	fingerprints := make([]*SpamFingerprint, 0, len(s.spamFingerprints))
+	tenant := TenantFromContext(ctx)
+	for _, fingerprint := range s.spamFingerprints {
+		if fingerprint.TenantID == tenant {
+			fingerprintCopy := *fingerprint
+			fingerprints = append(fingerprints, &fingerprintCopy)
+		}
+	}
+
+	sort.Slice(fingerprints, func(i, j int) bool {
+		return fingerprints[i].CreatedAt.Before(fingerprints[j].CreatedAt)
+	})
+
+	return fingerprints, nil
+}
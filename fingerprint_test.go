@@ -0,0 +1,218 @@
+package postflow
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	spamText        = "Buy cheap watches now at example shop today, best prices guaranteed"
+	spamVariantText = "buy cheap watches now at the example shop today best prices guaranteed"
+)
+
+// TestFingerprint tests content normalization and near-duplicate distance
+func TestFingerprint(t *testing.T) {
+	original := Fingerprint(spamText)
+	assert.Len(t, original.Hash, 64)
+
+	// Test: case, punctuation and whitespace are ignored
+	same := Fingerprint("  buy CHEAP watches now at example shop today... best prices guaranteed!")
+	assert.Equal(t, original, same)
+	assert.Equal(t, 0, original.Distance(same))
+
+	// Test: small edits stay close, unrelated text is far
+	variant := Fingerprint(spamVariantText)
+	assert.NotEqual(t, original.Hash, variant.Hash)
+	assert.LessOrEqual(t, original.Distance(variant), DefaultMaxSimHashDistance)
+
+	unrelated := Fingerprint("Just had a great lunch with friends at the new place downtown")
+	assert.Greater(t, original.Distance(unrelated), DefaultMaxSimHashDistance)
+
+	// Test: posts without text have no fingerprint
+	assert.Equal(t, ContentFingerprint{}, Fingerprint(" !! "))
+	assert.False(t, Fingerprint("").matches(Fingerprint(""), DefaultMaxSimHashDistance))
+}
+
+// TestPostManagerDuplicateDetection tests rejecting repeated posts of the same author
+func TestPostManagerDuplicateDetection(t *testing.T) {
+	pm := NewPostManager(NewInMemoryPostStore(), WithDuplicateDetection(DuplicatePolicy{
+		MaxDistance: DefaultMaxSimHashDistance,
+	}))
+	ctx := context.Background()
+
+	post := createTestPostData("user1")
+	post.Content = spamText
+	firstID, err := pm.CreatePost(ctx, post)
+	require.NoError(t, err)
+	assert.NotEmpty(t, post.ContentHash)
+
+	// Test: exact and near-duplicates by the same author are rejected
+	duplicate := createTestPostData("user1")
+	duplicate.Content = spamText
+	_, err = pm.CreatePost(ctx, duplicate)
+	assert.True(t, errors.Is(err, ErrDuplicateContent))
+	assert.Contains(t, err.Error(), firstID)
+
+	variant := createTestPostData("user1")
+	variant.Content = spamVariantText
+	_, err = pm.CreatePost(ctx, variant)
+	assert.True(t, errors.Is(err, ErrDuplicateContent))
+
+	// Test: other authors may post the same text
+	other := createTestPostData("user2")
+	other.Content = spamText
+	_, err = pm.CreatePost(ctx, other)
+	assert.NoError(t, err)
+
+	// Test: media-only posts are never duplicates
+	for i := 0; i < 2; i++ {
+		empty := createTestPostData("user1")
+		empty.Content = ""
+		_, err = pm.CreatePost(ctx, empty)
+		assert.NoError(t, err)
+	}
+}
+
+// TestPostManagerDuplicateDistanceDefault tests the default and exact-only near-duplicate distances
+func TestPostManagerDuplicateDistanceDefault(t *testing.T) {
+	ctx := context.Background()
+
+	for _, tt := range []struct {
+		maxDistance int
+		rejected    bool
+	}{
+		{0, true},   // DefaultMaxSimHashDistance
+		{-1, false}, // exact copies only
+	} {
+		pm := NewPostManager(NewInMemoryPostStore(), WithDuplicateDetection(DuplicatePolicy{MaxDistance: tt.maxDistance}))
+
+		post := createTestPostData("user1")
+		post.Content = spamText
+		_, err := pm.CreatePost(ctx, post)
+		require.NoError(t, err)
+
+		variant := createTestPostData("user1")
+		variant.Content = spamVariantText
+		_, err = pm.CreatePost(ctx, variant)
+		assert.Equal(t, tt.rejected, errors.Is(err, ErrDuplicateContent), tt.maxDistance)
+
+		duplicate := createTestPostData("user1")
+		duplicate.Content = spamText
+		_, err = pm.CreatePost(ctx, duplicate)
+		assert.True(t, errors.Is(err, ErrDuplicateContent), tt.maxDistance)
+	}
+}
+
+// TestPostManagerDuplicateFlag tests holding duplicates and spam for review
+func TestPostManagerDuplicateFlag(t *testing.T) {
+	pm := NewPostManager(NewInMemoryPostStore(), WithDuplicateDetection(DuplicatePolicy{
+		MaxDistance: DefaultMaxSimHashDistance,
+		Action:      DuplicateFlag,
+	}))
+	ctx := context.Background()
+	modCtx := WithActor(ctx, Actor{UserID: "mod1", Role: RoleModerator})
+
+	// Test: only moderators manage the spam list
+	_, err := pm.AddSpamFingerprint(WithActor(ctx, Actor{UserID: "user1"}), spamText, "")
+	assert.Equal(t, ErrPermissionDenied, err)
+	_, err = pm.ListSpamFingerprints(ctx)
+	assert.Equal(t, ErrPermissionDenied, err)
+
+	spam, err := pm.AddSpamFingerprint(modCtx, spamText, "watch spam campaign")
+	require.NoError(t, err)
+	assert.Equal(t, ErrPermissionDenied, pm.RemoveSpamFingerprint(WithActor(ctx, Actor{UserID: "user1"}), spam.Hash))
+
+	// Test: spam lists are kept per tenant
+	otherTenant := WithTenant(modCtx, "other")
+	fingerprints, err := pm.ListSpamFingerprints(otherTenant)
+	require.NoError(t, err)
+	assert.Empty(t, fingerprints)
+	require.NoError(t, pm.RemoveSpamFingerprint(otherTenant, spam.Hash))
+	other := createTestPostData("user1")
+	other.Content = spamVariantText
+	otherID, err := pm.CreatePost(WithTenant(ctx, "other"), other)
+	require.NoError(t, err)
+	stored, err := pm.GetPost(WithTenant(ctx, "other"), otherID)
+	require.NoError(t, err)
+	assert.Equal(t, ModerationApproved, stored.ModerationStatus)
+
+	// Test: a post matching the spam list is held for review
+	post := createTestPostData("user1")
+	post.Content = spamVariantText
+	postID, err := pm.CreatePost(ctx, post)
+	require.NoError(t, err)
+
+	held, err := pm.GetPost(ctx, postID)
+	assert.NoError(t, err)
+	assert.Equal(t, ModerationPending, held.ModerationStatus)
+	assert.Contains(t, held.ModerationReason, "watch spam campaign")
+
+	// Test: a duplicate of the author's own post is held for review
+	err = pm.RemoveSpamFingerprint(modCtx, spam.Hash)
+	assert.NoError(t, err)
+	fingerprints, err = pm.ListSpamFingerprints(modCtx)
+	assert.NoError(t, err)
+	assert.Empty(t, fingerprints)
+
+	duplicate := createTestPostData("user1")
+	duplicate.Content = spamVariantText
+	duplicateID, err := pm.CreatePost(ctx, duplicate)
+	require.NoError(t, err)
+	held, err = pm.GetPost(ctx, duplicateID)
+	assert.NoError(t, err)
+	assert.Equal(t, ModerationPending, held.ModerationStatus)
+	assert.Contains(t, held.ModerationReason, postID)
+}
+
+// TestPostManagerFindSimilarPosts tests finding similar posts across authors
+func TestPostManagerFindSimilarPosts(t *testing.T) {
+	pm := setupTestPostManager()
+	ctx := context.Background()
+	modCtx := WithActor(ctx, Actor{UserID: "mod1", Role: RoleModerator})
+
+	var ids []string
+	for i, content := range []string{spamText, spamVariantText, spamText, "Something else entirely, about gardening"} {
+		post := createTestPostData("user" + string(rune('1'+i)))
+		post.Content = content
+		id, err := pm.CreatePost(ctx, post)
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+
+	similar, err := pm.FindSimilarPosts(modCtx, ids[0], DefaultMaxSimHashDistance, 10)
+	assert.NoError(t, err)
+	require.Equal(t, 2, len(similar))
+	assert.Equal(t, ids[2], similar[0].Post.ID)
+	assert.Equal(t, 0, similar[0].Distance)
+	assert.Equal(t, ids[1], similar[1].Post.ID)
+	assert.Greater(t, similar[1].Distance, 0)
+
+	// Test: matches show reaction counts without the reactions of shadow-banned users
+	require.NoError(t, pm.ShadowBanUser(modCtx, "spammer", "spam"))
+	require.NoError(t, pm.AddReaction(ctx, ids[2], "spammer", ReactionLike))
+	require.NoError(t, pm.AddReaction(ctx, ids[2], "user9", ReactionLike))
+	similar, err = pm.FindSimilarPosts(modCtx, ids[0], DefaultMaxSimHashDistance, 10)
+	assert.NoError(t, err)
+	require.Equal(t, 2, len(similar))
+	assert.Equal(t, 1, similar[0].Post.Reactions[ReactionLike])
+
+	// Test: updating the content updates the fingerprint
+	_, err = pm.PatchPost(ctx, ids[1], "user2", &PostPatch{Fields: PatchContent, Content: "Now about gardening instead"})
+	assert.NoError(t, err)
+	similar, err = pm.FindSimilarPosts(modCtx, ids[0], DefaultMaxSimHashDistance, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(similar))
+
+	_, err = pm.FindSimilarPosts(modCtx, "nonexistent-id", DefaultMaxSimHashDistance, 10)
+	assert.Equal(t, ErrPostNotFound, err)
+
+	// Test: only moderators can search, as matches include posts the caller may not read
+	_, err = pm.FindSimilarPosts(WithActor(ctx, Actor{UserID: "user1"}), ids[0], DefaultMaxSimHashDistance, 10)
+	assert.Equal(t, ErrPermissionDenied, err)
+	_, err = pm.FindSimilarPosts(ctx, ids[0], DefaultMaxSimHashDistance, 10)
+	assert.Equal(t, ErrPermissionDenied, err)
+}
@@ -0,0 +1,241 @@
+// Package postflow provides functionality for managing user posts and feeds.
+package postflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Kinds of PostAudienceModel targets
+const (
+	audienceTargetList uint8 = 1
+	audienceTargetUser uint8 = 2
+)
+
+// AudienceModel is the GORM model for storing audience lists
+type AudienceModel struct {
+	ID        string `gorm:"primaryKey"`
+	TenantID  string `gorm:"index;not null;default:''"`
+	OwnerID   string `gorm:"index"`
+	Name      string
+	CreatedAt time.Time
+}
+
+// AudienceMemberModel is the GORM model for storing audience list members
+type AudienceMemberModel struct {
+	AudienceID string `gorm:"primaryKey"`
+	UserID     string `gorm:"primaryKey;index"`
+	CreatedAt  time.Time
+}
+
+// PostAudienceModel is the GORM model for storing the audience lists and users a custom post is shared with
+type PostAudienceModel struct {
+	PostID   string `gorm:"primaryKey"`
+	Kind     uint8  `gorm:"primaryKey"`
+	TargetID string `gorm:"primaryKey;index"`
+}
+
+// toAudience converts the model to an Audience
+func (m *AudienceModel) toAudience() *Audience {
+	return &Audience{
+		ID:        m.ID,
+		TenantID:  m.TenantID,
+		OwnerID:   m.OwnerID,
+		Name:      m.Name,
+		CreatedAt: m.CreatedAt,
+	}
+}
+
+// preloadAudience loads the audience of posts in a stable order
+func preloadAudience(db *gorm.DB) *gorm.DB {
+	return db.Order("kind, target_id")
+}
+
+// toPostAudienceModels converts the audience of a post to rows
+func toPostAudienceModels(post *Post) []PostAudienceModel {
+	var rows []PostAudienceModel
+	for _, audienceID := range post.AudienceIDs {
+		rows = append(rows, PostAudienceModel{PostID: post.ID, Kind: audienceTargetList, TargetID: audienceID})
+	}
+	for _, userID := range post.AudienceUserIDs {
+		rows = append(rows, PostAudienceModel{PostID: post.ID, Kind: audienceTargetUser, TargetID: userID})
+	}
+	return rows
+}
+
+// replacePostAudience replaces the stored audience of a post within a transaction
+func replacePostAudience(tx *gorm.DB, post *Post) error {
+	if err := tx.Where("post_id = ?", post.ID).Delete(&PostAudienceModel{}).Error; err != nil {
+		return err
+	}
+
+	if rows := toPostAudienceModels(post); len(rows) > 0 {
+		return tx.Create(&rows).Error
+	}
+	return nil
+}
+
+// scopeInAudience restricts a query to posts that are not custom or are shared with the viewer,
+// resolving audience list membership at query time
+func scopeInAudience(viewer string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(
+			"(post_models.visibility <> ? OR post_models.user_id = ?"+
+				" OR post_models.id IN (SELECT post_id FROM co_author_models WHERE user_id = ? AND status = ?)"+
+				" OR post_models.id IN (SELECT post_id FROM post_audience_models WHERE kind = ? AND target_id = ?)"+
+				" OR post_models.id IN (SELECT post_id FROM post_audience_models WHERE kind = ? AND target_id IN"+
+				" (SELECT audience_id FROM audience_member_models WHERE user_id = ?)))",
+			string(VisibilityCustom), viewer,
+			viewer, uint8(CoAuthorAccepted),
+			audienceTargetUser, viewer,
+			audienceTargetList, viewer,
+		)
+	}
+}
+
+// SaveAudience creates or renames an audience list
+func (s *GormPostStore) SaveAudience(ctx context.Context, audience *Audience) error {
+	audience.TenantID = TenantFromContext(ctx)
+
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&AudienceModel{}).Where("id = ? AND tenant_id <> ?", audience.ID, audience.TenantID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("%w: ID %s is taken", ErrInvalidAudience, audience.ID)
+	}
+
+	return s.db.WithContext(ctx).Save(&AudienceModel{
+		ID:        audience.ID,
+		TenantID:  audience.TenantID,
+		OwnerID:   audience.OwnerID,
+		Name:      audience.Name,
+		CreatedAt: audience.CreatedAt,
+	}).Error
+}
+
+// GetAudience returns an audience list
+func (s *GormPostStore) GetAudience(ctx context.Context, audienceID string) (*Audience, error) {
+	var model AudienceModel
+	err := s.db.WithContext(ctx).Where("id = ? AND tenant_id = ?", audienceID, TenantFromContext(ctx)).First(&model).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrAudienceNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return model.toAudience(), nil
+}
+
+// DeleteAudience removes an audience list, its members and its use in post audiences
+func (s *GormPostStore) DeleteAudience(ctx context.Context, audienceID string) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND tenant_id = ?", audienceID, TenantFromContext(ctx)).Delete(&AudienceModel{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrAudienceNotFound
+		}
+
+		if err := tx.Where("audience_id = ?", audienceID).Delete(&AudienceMemberModel{}).Error; err != nil {
+			return err
+		}
+
+		return tx.Where("kind = ? AND target_id = ?", audienceTargetList, audienceID).Delete(&PostAudienceModel{}).Error
+	})
+}
+
+// ListAudiences returns the audience lists of a user, ordered by name
+func (s *GormPostStore) ListAudiences(ctx context.Context, ownerID string) ([]*Audience, error) {
+	var models []AudienceModel
+	if err := s.db.WithContext(ctx).Where("tenant_id = ? AND owner_id = ?", TenantFromContext(ctx), ownerID).Order("name, id").Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	audiences := make([]*Audience, len(models))
+	for i := range models {
+		audiences[i] = models[i].toAudience()
+	}
+
+	return audiences, nil
+}
+
+// AddAudienceMembers adds users to an audience list, ignoring existing members
+func (s *GormPostStore) AddAudienceMembers(ctx context.Context, audienceID string, userIDs []string) error {
+	if _, err := s.GetAudience(ctx, audienceID); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var rows []AudienceMemberModel
+	for _, userID := range userIDs {
+		if userID != "" {
+			rows = append(rows, AudienceMemberModel{AudienceID: audienceID, UserID: userID, CreatedAt: now})
+		}
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	return s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error
+}
+
+// RemoveAudienceMembers removes users from an audience list
+func (s *GormPostStore) RemoveAudienceMembers(ctx context.Context, audienceID string, userIDs []string) error {
+	if _, err := s.GetAudience(ctx, audienceID); err != nil {
+		return err
+	}
+	if len(userIDs) == 0 {
+		return nil
+	}
+
+	return s.db.WithContext(ctx).
+		Where("audience_id = ? AND user_id IN ?", audienceID, userIDs).
+		Delete(&AudienceMemberModel{}).Error
+}
+
+// ListAudienceMembers returns the members of an audience list, sorted
+func (s *GormPostStore) ListAudienceMembers(ctx context.Context, audienceID string) ([]string, error) {
+	if _, err := s.GetAudience(ctx, audienceID); err != nil {
+		return nil, err
+	}
+
+	members := []string{}
+	err := s.db.WithContext(ctx).
+		Model(&AudienceMemberModel{}).
+		Where("audience_id = ?", audienceID).
+		Order("user_id").
+		Pluck("user_id", &members).Error
+
+	return members, err
+}
+
+// InAudience reports whether the user may see the post under its current audience
+func (s *GormPostStore) InAudience(ctx context.Context, post *Post, userID string) (bool, error) {
+	var members []string
+	if len(post.AudienceIDs) > 0 && userID != "" {
+		err := s.db.WithContext(ctx).
+			Model(&AudienceMemberModel{}).
+			Where("audience_id IN ? AND user_id = ?", post.AudienceIDs, userID).
+			Pluck("audience_id", &members).Error
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return post.inAudience(userID, func(audienceID string) bool {
+		for _, member := range members {
+			if member == audienceID {
+				return true
+			}
+		}
+		return false
+	}), nil
+}
@@ -0,0 +1,76 @@
+// Package postflow provides functionality for managing user posts and feeds.
+package postflow
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// AuditEntryModel is the GORM model for storing audit entries
+type AuditEntryModel struct {
+	ID        string `gorm:"primaryKey"`
+	TenantID  string `gorm:"not null;default:'';index"`
+	PostID    string `gorm:"index"`
+	AuthorID  string `gorm:"index"`
+	ActorID   string `gorm:"index"`
+	ActorRole uint8
+	Action    uint8
+	Reason    string
+	CreatedAt time.Time
+}
+
+// SaveAuditEntry records an audit entry
+func (s *GormPostStore) SaveAuditEntry(ctx context.Context, entry *AuditEntry) error {
+	if entry.ID == "" {
+		entry.ID = uuid.New().String()
+	}
+	entry.TenantID = TenantFromContext(ctx)
+
+	return s.db.WithContext(ctx).Create(&AuditEntryModel{
+		ID:        entry.ID,
+		TenantID:  entry.TenantID,
+		PostID:    entry.PostID,
+		AuthorID:  entry.AuthorID,
+		ActorID:   entry.ActorID,
+		ActorRole: uint8(entry.ActorRole),
+		Action:    uint8(entry.Action),
+		Reason:    entry.Reason,
+		CreatedAt: entry.CreatedAt,
+	}).Error
+}
+
+// ListAuditEntries returns the audit entries of a post, most recent first
+func (s *GormPostStore) ListAuditEntries(ctx context.Context, postID string, limit, offset int) ([]*AuditEntry, error) {
+	query := s.db.WithContext(ctx).
+		Where("tenant_id = ? AND post_id = ?", TenantFromContext(ctx), postID).
+		Order("created_at DESC")
+
+	// Apply pagination
+	if limit > 0 {
+		query = query.Limit(limit).Offset(offset)
+	}
+
+	var models []AuditEntryModel
+	if err := query.Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	entries := make([]*AuditEntry, len(models))
+	for i, model := range models {
+		entries[i] = &AuditEntry{
+			ID:        model.ID,
+			TenantID:  model.TenantID,
+			PostID:    model.PostID,
+			AuthorID:  model.AuthorID,
+			ActorID:   model.ActorID,
+			ActorRole: Role(model.ActorRole),
+			Action:    AuditAction(model.Action),
+			Reason:    model.Reason,
+			CreatedAt: model.CreatedAt,
+		}
+	}
+
+	return entries, nil
+}
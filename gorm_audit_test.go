@@ -0,0 +1,179 @@
+package postflow
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGormPostStore_AuditEntries tests recording privileged changes
+func TestGormPostStore_AuditEntries(t *testing.T) {
+	store, db := setupTestGormStore(t)
+	defer cleanupTestDB(t, db)
+	pm := NewPostManager(store)
+	ctx := context.Background()
+	modCtx := WithActor(ctx, Actor{UserID: "mod1", Role: RoleModerator})
+
+	postID, err := pm.CreatePost(ctx, createTestGormPost("user1"))
+	require.NoError(t, err)
+
+	// Test: a moderator edits the post
+	_, err = pm.PatchPost(modCtx, postID, "mod1", &PostPatch{Fields: PatchContent, Content: "Moderated"})
+	assert.NoError(t, err)
+	post, err := pm.GetPost(ctx, postID)
+	assert.NoError(t, err)
+	assert.Equal(t, "user1", post.UserID)
+	assert.Equal(t, "mod1", post.UpdatedBy)
+
+	// Test: a moderator hides and deletes the post
+	err = pm.HidePost(modCtx, postID, "spam")
+	assert.NoError(t, err)
+	err = pm.DeletePost(ctx, postID, "user2")
+	assert.Equal(t, ErrPermissionDenied, err)
+	err = pm.DeletePost(modCtx, postID, "mod1")
+	assert.NoError(t, err)
+
+	// Test: the audit trail survives the deletion
+	entries, err := pm.ListAuditEntries(modCtx, postID, 10, 0)
+	assert.NoError(t, err)
+	require.Equal(t, 3, len(entries))
+	actions := map[AuditAction]bool{}
+	for _, entry := range entries {
+		actions[entry.Action] = true
+		assert.Equal(t, "user1", entry.AuthorID)
+		assert.Equal(t, "mod1", entry.ActorID)
+		assert.Equal(t, RoleModerator, entry.ActorRole)
+	}
+	assert.True(t, actions[AuditEdit])
+	assert.True(t, actions[AuditHide])
+	assert.True(t, actions[AuditDelete])
+
+	// Test: pagination
+	entries, err = pm.ListAuditEntries(modCtx, postID, 2, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(entries))
+}
+
+// TestGormPostStore_AuditFailure tests that a failed audit entry does not fail a stored change
+func TestGormPostStore_AuditFailure(t *testing.T) {
+	store, db := setupTestGormStore(t)
+	defer cleanupTestDB(t, db)
+	pm := NewPostManager(store)
+	ctx := context.Background()
+	modCtx := WithActor(ctx, Actor{UserID: "mod1", Role: RoleModerator})
+
+	postID, err := pm.CreatePost(ctx, createTestGormPost("user1"))
+	require.NoError(t, err)
+	require.NoError(t, db.Migrator().DropTable(&AuditEntryModel{}))
+
+	// Test: edits by a moderator are reported as stored
+	post, err := pm.GetPost(ctx, postID)
+	require.NoError(t, err)
+	post.UserID = "mod1"
+	post.Content = "Updated"
+	assert.NoError(t, pm.UpdatePost(modCtx, post))
+
+	patched, err := pm.PatchPost(modCtx, postID, "mod1", &PostPatch{Fields: PatchContent, Content: "Patched"})
+	assert.NoError(t, err)
+	require.NotNil(t, patched)
+	assert.Equal(t, "Patched", patched.Content)
+
+	assert.NoError(t, pm.HidePost(modCtx, postID, "spam"))
+	post, err = store.GetPost(ctx, postID)
+	require.NoError(t, err)
+	assert.Equal(t, "Patched", post.Content)
+	assert.Equal(t, ModerationHidden, post.ModerationStatus)
+
+	// Test: a deletion by a moderator is reported as stored
+	assert.NoError(t, pm.DeletePost(modCtx, postID, "mod1"))
+	_, err = store.GetPost(ctx, postID)
+	assert.Equal(t, ErrPostNotFound, err)
+}
+
+// TestGormPostStore_Impersonation tests that an actor in the context cannot act as another user with the GORM store
+func TestGormPostStore_Impersonation(t *testing.T) {
+	store, db := setupTestGormStore(t)
+	defer cleanupTestDB(t, db)
+	pm := NewPostManager(store)
+	ctx := context.Background()
+	bobCtx := WithActor(ctx, Actor{UserID: "bob"})
+
+	postID, err := pm.CreatePost(ctx, createTestGormPost("alice"))
+	require.NoError(t, err)
+	require.NoError(t, pm.AddReaction(ctx, postID, "alice", ReactionLike))
+
+	// Test: bob cannot post, edit or delete as alice
+	_, err = pm.CreatePost(bobCtx, createTestGormPost("alice"))
+	assert.Equal(t, ErrPermissionDenied, err)
+
+	post, err := pm.GetPost(ctx, postID)
+	require.NoError(t, err)
+	post.Content = "Hijacked"
+	assert.ErrorIs(t, pm.UpdatePost(bobCtx, post), ErrPermissionDenied)
+	_, err = pm.PatchPost(bobCtx, postID, "alice", &PostPatch{Fields: PatchContent, Content: "Hijacked"})
+	assert.Equal(t, ErrPermissionDenied, err)
+	assert.Equal(t, ErrPermissionDenied, pm.DeletePost(bobCtx, postID, "alice"))
+
+	// Test: bob cannot react as alice
+	assert.Equal(t, ErrPermissionDenied, pm.AddReaction(bobCtx, postID, "alice", ReactionLove))
+	assert.Equal(t, ErrPermissionDenied, pm.RemoveReaction(bobCtx, postID, "alice", ReactionLike))
+
+	post, err = pm.GetPost(ctx, postID)
+	require.NoError(t, err)
+	assert.NotEqual(t, "Hijacked", post.Content)
+	reaction, err := pm.GetUserReaction(ctx, postID, "alice")
+	require.NoError(t, err)
+	require.NotNil(t, reaction)
+	assert.Equal(t, ReactionLike, *reaction)
+
+	// Test: bob still acts as himself
+	assert.NoError(t, pm.AddReaction(bobCtx, postID, "bob", ReactionLove))
+}
+
+// TestGormPostStore_TenantScopedRecords tests that feedback signals, audit trails and co-authors stay within their tenant with the GORM store
+func TestGormPostStore_TenantScopedRecords(t *testing.T) {
+	store, db := setupTestGormStore(t)
+	defer cleanupTestDB(t, db)
+	pm := NewPostManager(store)
+	ctx := context.Background()
+	tenantA := WithTenant(ctx, "tenant-a")
+	tenantB := WithTenant(ctx, "tenant-b")
+	modA := WithActor(tenantA, Actor{UserID: "mod1", Role: RoleModerator})
+	modB := WithActor(tenantB, Actor{UserID: "mod1", Role: RoleModerator})
+
+	postID, err := pm.CreatePost(tenantA, createTestGormPost("alice"))
+	require.NoError(t, err)
+
+	// Test: feedback signals of another tenant's posts are not returned
+	require.NoError(t, pm.HideFromFeed(tenantA, "bob", postID))
+	signals, err := pm.GetFeedbackSignals(tenantA, []string{postID})
+	require.NoError(t, err)
+	assert.Len(t, signals, 1)
+	signals, err = pm.GetFeedbackSignals(tenantB, []string{postID})
+	require.NoError(t, err)
+	assert.Empty(t, signals)
+
+	// Test: audit trails are only listed to moderators of the tenant
+	require.NoError(t, pm.HidePost(modA, postID, "spam"))
+	entries, err := pm.ListAuditEntries(modA, postID, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, "tenant-a", entries[0].TenantID)
+	entries, err = pm.ListAuditEntries(modB, postID, 10, 0)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+	_, err = pm.ListAuditEntries(WithActor(tenantA, Actor{UserID: "alice"}), postID, 10, 0)
+	assert.Equal(t, ErrPermissionDenied, err)
+	_, err = pm.ListAuditEntries(tenantA, postID, 10, 0)
+	assert.Equal(t, ErrPermissionDenied, err)
+
+	// Test: co-authors cannot be removed through another tenant
+	require.NoError(t, pm.InviteCoAuthor(tenantA, postID, "alice", "carol"))
+	assert.Equal(t, ErrInviteNotFound, store.DeleteCoAuthor(tenantB, postID, "carol"))
+	coAuthors, err := pm.ListCoAuthors(tenantA, postID)
+	require.NoError(t, err)
+	assert.Len(t, coAuthors, 1)
+	require.NoError(t, store.DeleteCoAuthor(tenantA, postID, "carol"))
+}
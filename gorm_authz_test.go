@@ -0,0 +1,56 @@
+package postflow
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGormPostStore_ReadAuthorization tests that listings and reactions of private posts are only shown to their readers
+func TestGormPostStore_ReadAuthorization(t *testing.T) {
+	store, db := setupTestGormStore(t)
+	defer cleanupTestDB(t, db)
+	pm := NewPostManager(store)
+	ctx := context.Background()
+
+	private := createTestGormPost("user1")
+	private.Visibility = VisibilityPrivate
+	privateID, err := pm.CreatePost(ctx, private)
+	require.NoError(t, err)
+	friends := createTestGormPost("user1")
+	friends.Visibility = VisibilityFriends
+	_, err = pm.CreatePost(ctx, friends)
+	require.NoError(t, err)
+	_, err = pm.CreatePost(ctx, createTestGormPost("user1"))
+	require.NoError(t, err)
+
+	// Test: private and friends-only posts are only listed for their author and privileged actors
+	for actor, expected := range map[Actor]int{
+		{UserID: "user1"}:                   3,
+		{UserID: "user2"}:                   1,
+		{UserID: "admin1", Role: RoleAdmin}: 3,
+	} {
+		posts, err := pm.ListPosts(WithActor(ctx, actor), &PostFilter{UserID: "user1"})
+		assert.NoError(t, err)
+		assert.Equal(t, expected, len(posts), actor.UserID)
+	}
+
+	// Test: co-authors see the post once they accept
+	require.NoError(t, pm.InviteCoAuthor(ctx, privateID, "user1", "user2"))
+	require.NoError(t, pm.AcceptCoAuthorInvite(ctx, privateID, "user2"))
+	posts, err := pm.ListPosts(WithActor(ctx, Actor{UserID: "user2"}), &PostFilter{UserID: "user1"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(posts))
+
+	// Test: reactions of a private post are only shown to its readers
+	require.NoError(t, pm.AddReaction(ctx, privateID, "user1", ReactionLike))
+	_, err = pm.GetReactionCounts(WithActor(ctx, Actor{UserID: "user3"}), privateID)
+	assert.Equal(t, ErrPermissionDenied, err)
+	_, err = pm.GetReactedUsers(WithActor(ctx, Actor{UserID: "user3"}), privateID, nil, 10, 0)
+	assert.Equal(t, ErrPermissionDenied, err)
+	counts, err := pm.GetReactionCounts(WithActor(ctx, Actor{UserID: "user2"}), privateID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, counts[ReactionLike])
+}
@@ -0,0 +1,236 @@
+// Package postflow provides functionality for managing user posts and feeds.
+package postflow
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// bulkBatchSize is the number of posts imported per transaction and rows per INSERT statement
+const bulkBatchSize = 500
+
+// postTagModel is a row of the post_tags join table, used to insert associations in batches
+type postTagModel struct {
+	PostModelID  string
+	TagModelName string
+}
+
+// TableName returns the join table managed by the PostModel.Tags association
+func (postTagModel) TableName() string {
+	return "post_tags"
+}
+
+// bulkRows are the rows inserted for one or more imported posts
+type bulkRows struct {
+	posts     []PostModel
+	media     []MediaModel
+	tags      []TagModel
+	joins     []postTagModel
+	reactions []ReactionModel
+	audience  []PostAudienceModel
+	outbox    []OutboxEventModel
+}
+
+// add appends the rows of another post
+func (r *bulkRows) add(other *bulkRows) {
+	r.posts = append(r.posts, other.posts...)
+	r.media = append(r.media, other.media...)
+	r.tags = append(r.tags, other.tags...)
+	r.joins = append(r.joins, other.joins...)
+	r.reactions = append(r.reactions, other.reactions...)
+	r.audience = append(r.audience, other.audience...)
+	r.outbox = append(r.outbox, other.outbox...)
+}
+
+// BulkSavePosts inserts new posts in batches, one transaction per batch.
+// A batch is inserted with a few multi-row statements; if that fails, its posts are inserted one by one,
+// each under its own savepoint, so that a bad post fails alone. When the transaction of a batch fails,
+// the error is reported for each of its posts and later batches are still imported.
+func (s *GormPostStore) BulkSavePosts(ctx context.Context, posts []*BulkPost) ([]error, error) {
+	errs := make([]error, len(posts))
+	seen := make(map[string]bool, len(posts))
+
+	for start := 0; start < len(posts); start += bulkBatchSize {
+		end := start + bulkBatchSize
+		if end > len(posts) {
+			end = len(posts)
+		}
+
+		var batch []int
+		duplicates := make(map[int]int) // Index of a repeated ID -> index of its first occurrence in the batch
+		err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			// Find posts that already exist
+			ids := make([]string, 0, end-start)
+			for _, item := range posts[start:end] {
+				ids = append(ids, item.Post.ID)
+			}
+			var existingIDs []string
+			if err := tx.Model(&PostModel{}).Where("id IN ?", ids).Pluck("id", &existingIDs).Error; err != nil {
+				return err
+			}
+			for _, id := range existingIDs {
+				seen[id] = true
+			}
+
+			// Build the rows of this batch. IDs are only marked as seen once the batch commits.
+			var all bulkRows
+			items := make([]*bulkRows, 0, end-start)
+			inBatch := make(map[string]int, end-start)
+			for i := start; i < end; i++ {
+				if seen[posts[i].Post.ID] {
+					errs[i] = ErrPostExists
+					continue
+				}
+				if first, exists := inBatch[posts[i].Post.ID]; exists {
+					errs[i] = ErrPostExists
+					duplicates[i] = first
+					continue
+				}
+
+				rows, err := s.bulkPostRows(ctx, posts[i])
+				if err != nil {
+					errs[i] = err
+					continue
+				}
+				inBatch[posts[i].Post.ID] = i
+
+				batch = append(batch, i)
+				items = append(items, rows)
+				all.add(rows)
+			}
+
+			if len(batch) == 0 {
+				return nil
+			}
+
+			if err := tx.SavePoint("bulk_batch").Error; err != nil {
+				return err
+			}
+			if err := s.insertBulkRows(tx, &all); err == nil {
+				return nil
+			}
+			if err := tx.RollbackTo("bulk_batch").Error; err != nil {
+				return err
+			}
+
+			// Find the posts that failed
+			for j, i := range batch {
+				if err := tx.SavePoint("bulk_item").Error; err != nil {
+					return err
+				}
+				if err := s.insertBulkRows(tx, items[j]); err != nil {
+					errs[i] = err
+					if err := tx.RollbackTo("bulk_item").Error; err != nil {
+						return err
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			// Nothing of this batch was stored; earlier batches keep their results
+			for i := start; i < end; i++ {
+				if errs[i] == nil {
+					errs[i] = err
+				}
+			}
+		} else {
+			for _, i := range batch {
+				if errs[i] == nil {
+					seen[posts[i].Post.ID] = true
+					posts[i].Post.Version = 1
+				}
+			}
+		}
+
+		// A repeated ID only exists if its first occurrence was stored
+		for i, first := range duplicates {
+			if errs[first] != nil {
+				errs[i] = errs[first]
+			}
+		}
+	}
+
+	return errs, nil
+}
+
+// bulkPostRows builds the rows of an imported post
+func (s *GormPostStore) bulkPostRows(ctx context.Context, item *BulkPost) (*bulkRows, error) {
+	post := item.Post
+	post.TenantID = TenantFromContext(ctx)
+
+	var reactions []ReactionModel
+	if len(item.Reactions) > 0 {
+		unique, _, err := normalizeBulkReactions(item.Reactions)
+		if err != nil {
+			return nil, err
+		}
+		for _, reaction := range unique {
+			reactions = append(reactions, ReactionModel{
+				PostID:       post.ID,
+				UserID:       reaction.UserID,
+				ReactionType: uint8(reaction.ReactionType),
+				CreatedAt:    reaction.CreatedAt,
+			})
+		}
+	} else {
+		reactions = placeholderReactionModels(post.ID, post.Reactions)
+	}
+
+	rows := &bulkRows{
+		posts:     []PostModel{toPostModel(post)},
+		media:     toMediaModels(post.ID, post.Media),
+		reactions: reactions,
+		audience:  toPostAudienceModels(post),
+	}
+	for _, tag := range post.Tags {
+		name := tenantTag(post.TenantID, tag)
+		rows.tags = append(rows.tags, TagModel{Name: name})
+		rows.joins = append(rows.joins, postTagModel{PostModelID: post.ID, TagModelName: name})
+	}
+	if s.outbox {
+		saved := *post
+		saved.Version = 1
+		event, err := postOutboxEvent(ctx, EventPostCreated, post.ID, post.UserID, &saved)
+		if err != nil {
+			return nil, err
+		}
+		rows.outbox = append(rows.outbox, event)
+	}
+
+	return rows, nil
+}
+
+// insertBulkRows inserts the rows of imported posts with multi-row statements
+func (s *GormPostStore) insertBulkRows(tx *gorm.DB, rows *bulkRows) error {
+	if err := tx.Omit(clause.Associations).CreateInBatches(&rows.posts, bulkBatchSize).Error; err != nil {
+		return err
+	}
+	if len(rows.media) > 0 {
+		if err := tx.CreateInBatches(&rows.media, bulkBatchSize).Error; err != nil {
+			return err
+		}
+	}
+	if len(rows.tags) > 0 {
+		ignore := clause.OnConflict{DoNothing: true}
+		if err := tx.Clauses(ignore).CreateInBatches(&rows.tags, bulkBatchSize).Error; err != nil {
+			return err
+		}
+		if err := tx.Clauses(ignore).CreateInBatches(&rows.joins, bulkBatchSize).Error; err != nil {
+			return err
+		}
+	}
+	if len(rows.reactions) > 0 {
+		if err := tx.CreateInBatches(&rows.reactions, bulkBatchSize).Error; err != nil {
+			return err
+		}
+	}
+	if len(rows.audience) > 0 {
+		if err := tx.CreateInBatches(&rows.audience, bulkBatchSize).Error; err != nil {
+			return err
+		}
+	}
+	return s.recordOutboxEvents(tx, rows.outbox...)
+}
@@ -0,0 +1,152 @@
+package postflow
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// TestGormPostStore_BulkSavePosts tests importing posts in batches
+func TestGormPostStore_BulkSavePosts(t *testing.T) {
+	store, db := setupTestGormStore(t)
+	defer cleanupTestDB(t, db)
+	pm := NewPostManager(store)
+	ctx := context.Background()
+
+	existing := createTestGormPost("user1")
+	existing.ID = "import-1"
+	require.NoError(t, store.SavePost(ctx, existing))
+
+	// Span more than one batch to cover batching
+	posts := createTestBulkPosts(bulkBatchSize + 10)
+	posts[0].Reactions = []UserReaction{
+		{UserID: "fan1", ReactionType: ReactionLike},
+		{UserID: "fan2", ReactionType: ReactionLike},
+		{UserID: "fan2", ReactionType: ReactionHaha},
+	}
+	posts[3].Reactions = []UserReaction{{UserID: "fan1", ReactionType: ReactionType(100)}}
+	posts[4].Post.ID = "import-0" // duplicate within the import
+	posts[5].Post.Reactions = map[ReactionType]int{ReactionLike: 2, ReactionLove: 1}
+
+	results, err := pm.BulkCreatePosts(WithActor(ctx, Actor{UserID: "admin", Role: RoleAdmin}), posts)
+	require.NoError(t, err)
+
+	// Test: only administrators may import
+	_, err = pm.BulkCreatePosts(WithActor(ctx, Actor{UserID: "user1"}), posts)
+	assert.Equal(t, ErrPermissionDenied, err)
+
+	// Test: per-item errors
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, ErrPostExists, results[1].Err)
+	assert.Equal(t, ErrInvalidReaction, results[3].Err)
+	assert.Equal(t, ErrPostExists, results[4].Err)
+	assert.NoError(t, results[len(results)-1].Err)
+
+	// Test: supplied IDs, timestamps, media, tags and reactions are preserved
+	imported, err := store.GetPost(ctx, "import-0")
+	require.NoError(t, err)
+	assert.True(t, posts[0].Post.CreatedAt.Equal(imported.CreatedAt))
+	assert.Equal(t, "media-0", imported.Media[0].ID)
+	assert.ElementsMatch(t, []string{"test", "golang", "postmanager"}, imported.Tags)
+	assert.Equal(t, 1, imported.Reactions[ReactionLike])
+	assert.Equal(t, 1, imported.Reactions[ReactionHaha])
+	assert.Equal(t, int64(1), imported.Version)
+
+	counted, err := store.GetPost(ctx, "import-5")
+	require.NoError(t, err)
+	assert.Equal(t, 2, counted.Reactions[ReactionLike])
+	assert.Equal(t, 1, counted.Reactions[ReactionLove])
+
+	// Test: everything but the three failed items was imported
+	all, err := store.ListPosts(ctx, &PostFilter{Tags: []string{"postmanager"}})
+	require.NoError(t, err)
+	assert.Equal(t, len(posts)-3, len(all))
+
+	// Test: imported posts can be updated normally
+	imported.Content = "Edited after import"
+	assert.NoError(t, store.SavePost(ctx, imported))
+}
+
+// TestGormPostStore_BulkSavePostsIsolatesFailures tests that a post failing to insert does not fail its batch
+func TestGormPostStore_BulkSavePostsIsolatesFailures(t *testing.T) {
+	store, db := setupTestGormStore(t)
+	defer cleanupTestDB(t, db)
+	ctx := context.Background()
+
+	posts := createTestBulkPosts(5)
+	posts[2].Post.Media[0].ID = "media-0" // passes validation but violates the media primary key
+
+	errs, err := store.BulkSavePosts(ctx, posts)
+	require.NoError(t, err)
+
+	// Test: only the bad post failed
+	for i, itemErr := range errs {
+		if i == 2 {
+			assert.Error(t, itemErr)
+			continue
+		}
+		assert.NoError(t, itemErr, i)
+		imported, err := store.GetPost(ctx, posts[i].Post.ID)
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), imported.Version)
+		assert.Len(t, imported.Media, 1)
+	}
+
+	// Test: nothing of the failed post was stored
+	_, err = store.GetPost(ctx, "import-2")
+	assert.Equal(t, ErrPostNotFound, err)
+	var count int64
+	require.NoError(t, db.Table("post_tags").Where("post_model_id = ?", "import-2").Count(&count).Error)
+	assert.Zero(t, count)
+}
+
+// TestGormPostStore_BulkSavePostsFailedBatch tests that a failed batch keeps the results of committed ones
+func TestGormPostStore_BulkSavePostsFailedBatch(t *testing.T) {
+	store, db := setupTestGormStore(t)
+	defer cleanupTestDB(t, db)
+	ctx := context.Background()
+
+	// The lookup of existing posts fails for the second batch
+	lookups := 0
+	lostConnection := errors.New("connection lost")
+	require.NoError(t, db.Callback().Query().Before("gorm:query").Register("test:fail_second_batch", func(tx *gorm.DB) {
+		if tx.Statement.Table == "post_models" {
+			lookups++
+			if lookups == 2 {
+				tx.AddError(lostConnection)
+			}
+		}
+	}))
+
+	posts := createTestBulkPosts(2*bulkBatchSize + 1)
+	posts[bulkBatchSize+20].Post.ID = posts[bulkBatchSize+10].Post.ID
+	posts[bulkBatchSize+20].Post.Media[0].ID = "media-repeated"
+	posts[2*bulkBatchSize].Post.ID = posts[bulkBatchSize].Post.ID
+
+	errs, err := store.BulkSavePosts(ctx, posts)
+	require.NoError(t, err)
+	require.NoError(t, db.Callback().Query().Remove("test:fail_second_batch"))
+
+	// Test: the first batch was stored
+	for i := 0; i < bulkBatchSize; i++ {
+		assert.NoError(t, errs[i], i)
+	}
+	_, err = store.GetPost(ctx, posts[0].Post.ID)
+	assert.NoError(t, err)
+
+	// Test: every post of the failed batch reports the error, including repeated IDs
+	for i := bulkBatchSize; i < 2*bulkBatchSize; i++ {
+		assert.Equal(t, lostConnection, errs[i], i)
+	}
+	_, err = store.GetPost(ctx, posts[bulkBatchSize+1].Post.ID)
+	assert.Equal(t, ErrPostNotFound, err)
+
+	// Test: an ID of the failed batch can still be imported by a later batch
+	assert.NoError(t, errs[2*bulkBatchSize])
+	_, err = store.GetPost(ctx, posts[bulkBatchSize].Post.ID)
+	assert.NoError(t, err)
+}
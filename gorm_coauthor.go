@@ -0,0 +1,136 @@
+// Package postflow provides functionality for managing user posts and feeds.
+package postflow
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// CoAuthorModel is the GORM model for storing co-author invitations
+type CoAuthorModel struct {
+	PostID      string `gorm:"primaryKey"`
+	UserID      string `gorm:"primaryKey;index"`
+	InvitedBy   string
+	Status      uint8 `gorm:"index"`
+	CreatedAt   time.Time
+	RespondedAt *time.Time
+}
+
+// toCoAuthor converts the model to a CoAuthor
+func (m *CoAuthorModel) toCoAuthor() *CoAuthor {
+	coAuthor := &CoAuthor{
+		PostID:    m.PostID,
+		UserID:    m.UserID,
+		InvitedBy: m.InvitedBy,
+		Status:    CoAuthorStatus(m.Status),
+		CreatedAt: m.CreatedAt,
+	}
+	if m.RespondedAt != nil {
+		coAuthor.RespondedAt = *m.RespondedAt
+	}
+	return coAuthor
+}
+
+// preloadCoAuthors loads the accepted co-authors of posts
+func preloadCoAuthors(db *gorm.DB) *gorm.DB {
+	return db.Where("status = ?", uint8(CoAuthorAccepted)).Order("user_id")
+}
+
+// SaveCoAuthor creates or updates a co-author invitation
+func (s *GormPostStore) SaveCoAuthor(ctx context.Context, coAuthor *CoAuthor) error {
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&PostModel{}).Scopes(scopeTenant(ctx)).Where("id = ?", coAuthor.PostID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrPostNotFound
+	}
+
+	model := CoAuthorModel{
+		PostID:    coAuthor.PostID,
+		UserID:    coAuthor.UserID,
+		InvitedBy: coAuthor.InvitedBy,
+		Status:    uint8(coAuthor.Status),
+		CreatedAt: coAuthor.CreatedAt,
+	}
+	if !coAuthor.RespondedAt.IsZero() {
+		respondedAt := coAuthor.RespondedAt
+		model.RespondedAt = &respondedAt
+	}
+
+	return s.db.WithContext(ctx).Save(&model).Error
+}
+
+// GetCoAuthor returns the invitation of a user for a post
+func (s *GormPostStore) GetCoAuthor(ctx context.Context, postID, userID string) (*CoAuthor, error) {
+	var model CoAuthorModel
+	err := s.db.WithContext(ctx).Scopes(scopeTenantPosts(ctx, "co_author_models.post_id")).Where("post_id = ? AND user_id = ?", postID, userID).First(&model).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrInviteNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return model.toCoAuthor(), nil
+}
+
+// DeleteCoAuthor removes a co-author or invitation
+func (s *GormPostStore) DeleteCoAuthor(ctx context.Context, postID, userID string) error {
+	result := s.db.WithContext(ctx).
+		Scopes(scopeTenantPosts(ctx, "co_author_models.post_id")).
+		Where("post_id = ? AND user_id = ?", postID, userID).
+		Delete(&CoAuthorModel{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrInviteNotFound
+	}
+
+	return nil
+}
+
+// ListCoAuthors returns the invitations of a post, oldest first
+func (s *GormPostStore) ListCoAuthors(ctx context.Context, postID string) ([]*CoAuthor, error) {
+	var models []CoAuthorModel
+	query := s.db.WithContext(ctx).Scopes(scopeTenantPosts(ctx, "co_author_models.post_id")).Where("post_id = ?", postID)
+	if err := query.Order("created_at ASC, user_id").Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	coAuthors := make([]*CoAuthor, len(models))
+	for i := range models {
+		coAuthors[i] = models[i].toCoAuthor()
+	}
+
+	return coAuthors, nil
+}
+
+// ListCoAuthorInvites returns a user's invitations with the given status, most recent first
+func (s *GormPostStore) ListCoAuthorInvites(ctx context.Context, userID string, status CoAuthorStatus, limit, offset int) ([]*CoAuthor, error) {
+	query := s.db.WithContext(ctx).
+		Scopes(scopeTenantPosts(ctx, "co_author_models.post_id")).
+		Where("user_id = ? AND status = ?", userID, uint8(status)).
+		Order("created_at DESC, post_id")
+
+	// Apply pagination
+	if limit > 0 {
+		query = query.Limit(limit).Offset(offset)
+	}
+
+	var models []CoAuthorModel
+	if err := query.Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	invites := make([]*CoAuthor, len(models))
+	for i := range models {
+		invites[i] = models[i].toCoAuthor()
+	}
+
+	return invites, nil
+}
@@ -0,0 +1,98 @@
+package postflow
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGormPostStore_CoAuthors tests co-authored posts with the GORM store
+func TestGormPostStore_CoAuthors(t *testing.T) {
+	store, db := setupTestGormStore(t)
+	defer cleanupTestDB(t, db)
+	pm := NewPostManager(store)
+	ctx := context.Background()
+
+	postID, err := pm.CreatePost(ctx, createTestGormPost("brand"))
+	require.NoError(t, err)
+
+	// Test: only the author can invite, and only once
+	assert.Equal(t, ErrPermissionDenied, pm.InviteCoAuthor(ctx, postID, "partner", "partner"))
+	require.NoError(t, pm.InviteCoAuthor(ctx, postID, "brand", "partner"))
+	require.NoError(t, pm.InviteCoAuthor(ctx, postID, "brand", "agency"))
+	assert.Equal(t, ErrAlreadyInvited, pm.InviteCoAuthor(ctx, postID, "brand", "partner"))
+	assert.Equal(t, ErrAlreadyInvited, pm.InviteCoAuthor(ctx, postID, "brand", "brand"))
+
+	invites, err := pm.ListCoAuthorInvites(ctx, "partner", 10, 0)
+	assert.NoError(t, err)
+	require.Equal(t, 1, len(invites))
+	assert.Equal(t, postID, invites[0].PostID)
+	assert.Equal(t, "brand", invites[0].InvitedBy)
+
+	// Test: pending invitees have no rights yet
+	patch := &PostPatch{Fields: PatchContent, Content: "Joint campaign"}
+	_, err = pm.PatchPost(ctx, postID, "partner", patch)
+	assert.Equal(t, ErrPermissionDenied, err)
+
+	// Test: accepting makes the user a co-author
+	require.NoError(t, pm.AcceptCoAuthorInvite(ctx, postID, "partner"))
+	require.NoError(t, pm.DeclineCoAuthorInvite(ctx, postID, "agency"))
+	assert.Equal(t, ErrInviteNotFound, pm.AcceptCoAuthorInvite(ctx, postID, "agency"))
+	assert.Equal(t, ErrInviteNotFound, pm.AcceptCoAuthorInvite(ctx, postID, "stranger"))
+
+	post, err := pm.GetPost(ctx, postID)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"partner"}, post.CoAuthors)
+
+	coAuthors, err := pm.ListCoAuthors(ctx, postID)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(coAuthors))
+
+	// Test: the post appears on the co-author's profile
+	profile, err := pm.ListPosts(ctx, &PostFilter{UserID: "partner"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{postID}, postIDs(profile))
+
+	// Test: co-authors may edit on behalf of the author
+	patched, err := pm.PatchPost(ctx, postID, "partner", patch)
+	require.NoError(t, err)
+	assert.Equal(t, "brand", patched.UserID)
+	assert.Equal(t, "partner", patched.UpdatedBy)
+	assert.Equal(t, []string{"partner"}, patched.CoAuthors)
+
+	post, err = pm.GetPost(ctx, postID)
+	require.NoError(t, err)
+	post.UserID = "partner"
+	post.Content = "Joint campaign, updated"
+	require.NoError(t, pm.UpdatePost(ctx, post))
+
+	post, err = pm.GetPost(ctx, postID)
+	assert.NoError(t, err)
+	assert.Equal(t, "brand", post.UserID)
+	assert.Equal(t, []string{"partner"}, post.CoAuthors)
+
+	// Test: co-author edits are not moderator overrides
+	entries, err := pm.ListAuditEntries(WithActor(ctx, Actor{UserID: "auditor", Role: RoleAdmin}), postID, 10, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, entries)
+
+	// Test: co-authors may leave, after which they lose their rights
+	assert.Equal(t, ErrPermissionDenied, pm.RemoveCoAuthor(ctx, postID, "agency", "partner"))
+	require.NoError(t, pm.RemoveCoAuthor(ctx, postID, "partner", "partner"))
+	profile, err = pm.ListPosts(ctx, &PostFilter{UserID: "partner"})
+	assert.NoError(t, err)
+	assert.Empty(t, profile)
+	assert.Equal(t, ErrPermissionDenied, pm.DeletePost(ctx, postID, "partner"))
+
+	// Test: co-authors may delete the post
+	require.NoError(t, pm.InviteCoAuthor(ctx, postID, "brand", "partner"))
+	require.NoError(t, pm.AcceptCoAuthorInvite(ctx, postID, "partner"))
+	require.NoError(t, pm.DeletePost(ctx, postID, "partner"))
+	_, err = pm.GetPost(ctx, postID)
+	assert.Equal(t, ErrPostNotFound, err)
+	profile, err = pm.ListPosts(ctx, &PostFilter{UserID: "partner"})
+	assert.NoError(t, err)
+	assert.Empty(t, profile)
+}
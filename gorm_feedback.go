@@ -0,0 +1,125 @@
+// Package postflow provides functionality for managing user posts and feeds.
+package postflow
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PostFeedbackModel is the GORM model for storing negative feedback on posts
+type PostFeedbackModel struct {
+	UserID    string `gorm:"primaryKey"`
+	PostID    string `gorm:"primaryKey;index"`
+	AuthorID  string `gorm:"index"`
+	Kind      uint8
+	CreatedAt time.Time
+}
+
+// scopeWithoutFeedback leaves out posts the viewer gave feedback on
+func scopeWithoutFeedback(viewer string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("post_models.id NOT IN (SELECT post_id FROM post_feedback_models WHERE user_id = ?)", viewer)
+	}
+}
+
+// SaveFeedback records feedback, replacing the user's earlier feedback on the post
+func (s *GormPostStore) SaveFeedback(ctx context.Context, feedback *PostFeedback) error {
+	if feedback.Kind != FeedbackHide && feedback.Kind != FeedbackNotInterested {
+		return ErrInvalidFeedback
+	}
+
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&PostModel{}).Scopes(scopeTenant(ctx)).Where("id = ?", feedback.PostID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count == 0 {
+		return ErrPostNotFound
+	}
+
+	return s.db.WithContext(ctx).
+		Clauses(clause.OnConflict{UpdateAll: true}).
+		Create(&PostFeedbackModel{
+			UserID:    feedback.UserID,
+			PostID:    feedback.PostID,
+			AuthorID:  feedback.AuthorID,
+			Kind:      uint8(feedback.Kind),
+			CreatedAt: feedback.CreatedAt,
+		}).Error
+}
+
+// DeleteFeedback removes a user's feedback on a post
+func (s *GormPostStore) DeleteFeedback(ctx context.Context, userID, postID string) error {
+	return s.db.WithContext(ctx).
+		Where("user_id = ? AND post_id = ?", userID, postID).
+		Delete(&PostFeedbackModel{}).Error
+}
+
+// ListFeedback returns a user's feedback, most recent first
+func (s *GormPostStore) ListFeedback(ctx context.Context, userID string, limit, offset int) ([]*PostFeedback, error) {
+	query := s.db.WithContext(ctx).
+		Scopes(scopeTenantPosts(ctx, "post_feedback_models.post_id")).
+		Where("user_id = ?", userID).
+		Order("created_at DESC, post_id")
+
+	// Apply pagination
+	if limit > 0 {
+		query = query.Limit(limit).Offset(offset)
+	}
+
+	var models []PostFeedbackModel
+	if err := query.Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	result := make([]*PostFeedback, len(models))
+	for i, model := range models {
+		result[i] = &PostFeedback{
+			UserID:    model.UserID,
+			PostID:    model.PostID,
+			AuthorID:  model.AuthorID,
+			Kind:      FeedbackKind(model.Kind),
+			CreatedAt: model.CreatedAt,
+		}
+	}
+
+	return result, nil
+}
+
+// GetFeedbackSignals returns the aggregated feedback of posts of the tenant in the context;
+// posts without feedback or of other tenants are omitted
+func (s *GormPostStore) GetFeedbackSignals(ctx context.Context, postIDs []string) (map[string]*FeedbackSignal, error) {
+	signals := make(map[string]*FeedbackSignal)
+	if len(postIDs) == 0 {
+		return signals, nil
+	}
+
+	var rows []struct {
+		PostID string
+		Kind   uint8
+		Count  int
+	}
+	err := s.db.WithContext(ctx).
+		Model(&PostFeedbackModel{}).
+		Select("post_id, kind, COUNT(*) AS count").
+		Scopes(scopeTenantPosts(ctx, "post_feedback_models.post_id")).
+		Where("post_id IN ?", postIDs).
+		Group("post_id, kind").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, row := range rows {
+		signal, exists := signals[row.PostID]
+		if !exists {
+			signal = &FeedbackSignal{PostID: row.PostID}
+			signals[row.PostID] = signal
+		}
+		signal.addFeedback(FeedbackKind(row.Kind), row.Count)
+	}
+
+	return signals, nil
+}
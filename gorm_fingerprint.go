@@ -0,0 +1,126 @@
+// Package postflow provides functionality for managing user posts and feeds.
+package postflow
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// SpamFingerprintModel is the GORM model for storing spam fingerprints.
+// Hash holds the hash prefixed with the tenant, as tag names do, so that tenants keep separate lists.
+type SpamFingerprintModel struct {
+	Hash      string `gorm:"primaryKey"`
+	TenantID  string `gorm:"not null;default:'';index"`
+	SimHash   int64
+	Reason    string
+	CreatedAt time.Time
+}
+
+// FindSimilarPosts returns posts matching the query, closest first.
+// Candidates share the exact hash or a 16-bit SimHash band, so every match
+// within DefaultMaxSimHashDistance bits is found. Posts more bits apart may differ in
+// every band and are then missed, so a larger MaxDistance may miss matches that
+// InMemoryPostStore finds.
+func (s *GormPostStore) FindSimilarPosts(ctx context.Context, query *SimilarityQuery) ([]*SimilarPost, error) {
+	if query.Fingerprint.Hash == "" {
+		return []*SimilarPost{}, nil
+	}
+
+	bands := simHashBands(query.Fingerprint.SimHash)
+	db := s.db.WithContext(ctx).
+		Model(&PostModel{}).
+		Preload("Media", preloadMedia).
+		Preload("Tags").
+		Preload("CoAuthors", preloadCoAuthors).
+		Preload("Audience", preloadAudience).
+		Scopes(scopeTenant(ctx)).
+		Where("content_hash <> ''").
+		Where("content_hash = ? OR sim_band0 = ? OR sim_band1 = ? OR sim_band2 = ? OR sim_band3 = ?",
+			query.Fingerprint.Hash, bands[0], bands[1], bands[2], bands[3])
+
+	if query.UserID != "" {
+		db = db.Where("user_id = ?", query.UserID)
+	}
+	if !query.Since.IsZero() {
+		db = db.Where("created_at >= ?", query.Since)
+	}
+	if query.ExcludePostID != "" {
+		db = db.Where("id <> ?", query.ExcludePostID)
+	}
+
+	var postModels []PostModel
+	if err := db.Find(&postModels).Error; err != nil {
+		return nil, err
+	}
+
+	similar := []*SimilarPost{}
+	for i := range postModels {
+		fingerprint := ContentFingerprint{Hash: postModels[i].ContentHash, SimHash: uint64(postModels[i].SimHash)}
+		if !query.Fingerprint.matches(fingerprint, query.MaxDistance) {
+			continue
+		}
+
+		similar = append(similar, &SimilarPost{
+			Post:     s.toPost(&postModels[i], nil),
+			Distance: query.Fingerprint.Distance(fingerprint),
+		})
+	}
+
+	similar = sortSimilarPosts(similar, query.Limit)
+
+	// Load reaction counts for the returned posts only
+	for _, match := range similar {
+		reactionCounts, err := s.GetReactionCounts(ctx, match.Post.ID)
+		if err != nil {
+			return nil, err
+		}
+		match.Post.Reactions = reactionCounts
+	}
+
+	return similar, nil
+}
+
+// SaveSpamFingerprint adds or replaces a spam fingerprint of the tenant in the context
+func (s *GormPostStore) SaveSpamFingerprint(ctx context.Context, fingerprint *SpamFingerprint) error {
+	fingerprint.TenantID = TenantFromContext(ctx)
+	return s.db.WithContext(ctx).
+		Clauses(clause.OnConflict{UpdateAll: true}).
+		Create(&SpamFingerprintModel{
+			Hash:      tenantScopedKey(ctx, fingerprint.Hash),
+			TenantID:  fingerprint.TenantID,
+			SimHash:   int64(fingerprint.SimHash),
+			Reason:    fingerprint.Reason,
+			CreatedAt: fingerprint.CreatedAt,
+		}).Error
+}
+
+// DeleteSpamFingerprint removes a spam fingerprint of the tenant in the context by its hash
+func (s *GormPostStore) DeleteSpamFingerprint(ctx context.Context, hash string) error {
+	return s.db.WithContext(ctx).Where("hash = ?", tenantScopedKey(ctx, hash)).Delete(&SpamFingerprintModel{}).Error
+}
+
+// ListSpamFingerprints returns the spam fingerprints of the tenant in the context, oldest first
+func (s *GormPostStore) ListSpamFingerprints(ctx context.Context) ([]*SpamFingerprint, error) {
+	var models []SpamFingerprintModel
+	err := s.db.WithContext(ctx).
+		Where("tenant_id = ?", TenantFromContext(ctx)).
+		Order("created_at ASC").
+		Find(&models).Error
+	if err != nil {
+		return nil, err
+	}
+
+	fingerprints := make([]*SpamFingerprint, len(models))
+	for i, model := range models {
+		fingerprints[i] = &SpamFingerprint{
+			ContentFingerprint: ContentFingerprint{Hash: untenantTag(model.Hash), SimHash: uint64(model.SimHash)},
+			TenantID:           model.TenantID,
+			Reason:             model.Reason,
+			CreatedAt:          model.CreatedAt,
+		}
+	}
+
+	return fingerprints, nil
+}
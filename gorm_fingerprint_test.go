@@ -0,0 +1,113 @@
+package postflow
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGormPostStore_FindSimilarPosts tests fingerprint persistence and candidate lookup
+func TestGormPostStore_FindSimilarPosts(t *testing.T) {
+	store, db := setupTestGormStore(t)
+	defer cleanupTestDB(t, db)
+	pm := NewPostManager(store)
+	ctx := context.Background()
+	modCtx := WithActor(ctx, Actor{UserID: "mod1", Role: RoleModerator})
+
+	var ids []string
+	for i, content := range []string{spamText, spamVariantText, spamText, "Something else entirely, about gardening"} {
+		post := createTestGormPost("user" + string(rune('1'+i)))
+		post.Content = content
+		id, err := pm.CreatePost(ctx, post)
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+
+	// Test: the fingerprint round-trips, including the high SimHash bit
+	post, err := pm.GetPost(ctx, ids[0])
+	require.NoError(t, err)
+	assert.Equal(t, Fingerprint(spamText), postFingerprint(post))
+
+	similar, err := pm.FindSimilarPosts(modCtx, ids[0], DefaultMaxSimHashDistance, 10)
+	assert.NoError(t, err)
+	require.Equal(t, 2, len(similar))
+	assert.Equal(t, ids[2], similar[0].Post.ID)
+	assert.Equal(t, ids[1], similar[1].Post.ID)
+
+	// Test: matches show reaction counts without the reactions of shadow-banned users
+	require.NoError(t, pm.ShadowBanUser(modCtx, "spammer", "spam"))
+	require.NoError(t, pm.AddReaction(ctx, ids[2], "spammer", ReactionLike))
+	require.NoError(t, pm.AddReaction(ctx, ids[2], "user9", ReactionLike))
+	similar, err = pm.FindSimilarPosts(modCtx, ids[0], DefaultMaxSimHashDistance, 10)
+	assert.NoError(t, err)
+	require.Equal(t, 2, len(similar))
+	assert.Equal(t, 1, similar[0].Post.Reactions[ReactionLike])
+
+	// Test: updates refresh the fingerprint
+	_, err = pm.PatchPost(ctx, ids[2], "user3", &PostPatch{Fields: PatchContent, Content: "Now about gardening instead"})
+	assert.NoError(t, err)
+	similar, err = pm.FindSimilarPosts(modCtx, ids[0], DefaultMaxSimHashDistance, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(similar))
+}
+
+// TestGormPostStore_DuplicateDetection tests duplicate and spam checks with GORM
+func TestGormPostStore_DuplicateDetection(t *testing.T) {
+	store, db := setupTestGormStore(t)
+	defer cleanupTestDB(t, db)
+	pm := NewPostManager(store, WithDuplicateDetection(DuplicatePolicy{MaxDistance: DefaultMaxSimHashDistance}))
+	ctx := context.Background()
+	modCtx := WithActor(ctx, Actor{UserID: "mod1", Role: RoleModerator})
+
+	post := createTestGormPost("user1")
+	post.Content = spamText
+	_, err := pm.CreatePost(ctx, post)
+	require.NoError(t, err)
+
+	// Test: the author's near-duplicate is rejected
+	variant := createTestGormPost("user1")
+	variant.Content = spamVariantText
+	_, err = pm.CreatePost(ctx, variant)
+	assert.True(t, errors.Is(err, ErrDuplicateContent))
+
+	// Test: spam fingerprints apply to every author
+	_, err = pm.AddSpamFingerprint(modCtx, spamText, "watch spam")
+	require.NoError(t, err)
+	_, err = pm.AddSpamFingerprint(modCtx, spamText, "watch spam campaign")
+	require.NoError(t, err)
+	fingerprints, err := pm.ListSpamFingerprints(modCtx)
+	assert.NoError(t, err)
+	require.Equal(t, 1, len(fingerprints))
+	assert.Equal(t, "watch spam campaign", fingerprints[0].Reason)
+	assert.Equal(t, Fingerprint(spamText).Hash, fingerprints[0].Hash)
+
+	// Test: only moderators manage the spam list
+	_, err = pm.AddSpamFingerprint(WithActor(ctx, Actor{UserID: "user1"}), spamText, "")
+	assert.Equal(t, ErrPermissionDenied, err)
+	_, err = pm.FindSimilarPosts(WithActor(ctx, Actor{UserID: "user1"}), post.ID, DefaultMaxSimHashDistance, 10)
+	assert.Equal(t, ErrPermissionDenied, err)
+
+	// Test: spam lists are kept per tenant
+	otherTenant := WithTenant(modCtx, "other")
+	otherFingerprints, err := pm.ListSpamFingerprints(otherTenant)
+	require.NoError(t, err)
+	assert.Empty(t, otherFingerprints)
+	require.NoError(t, pm.RemoveSpamFingerprint(otherTenant, fingerprints[0].Hash))
+	tenantPost := createTestGormPost("user2")
+	tenantPost.Content = spamVariantText
+	_, err = pm.CreatePost(WithTenant(ctx, "other"), tenantPost)
+	assert.NoError(t, err)
+
+	other := createTestGormPost("user2")
+	other.Content = spamVariantText
+	_, err = pm.CreatePost(ctx, other)
+	assert.True(t, errors.Is(err, ErrSpamContent))
+
+	err = pm.RemoveSpamFingerprint(modCtx, fingerprints[0].Hash)
+	assert.NoError(t, err)
+	_, err = pm.CreatePost(ctx, other)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,199 @@
+// Package postflow provides functionality for managing user posts and feeds.
+package postflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// GroupModel is the GORM model for storing groups
+type GroupModel struct {
+	ID          string `gorm:"primaryKey"`
+	TenantID    string `gorm:"index"`
+	OwnerID     string `gorm:"index"`
+	Name        string
+	Description string
+	Private     bool
+	CreatedAt   time.Time
+}
+
+// GroupMemberModel is the GORM model for storing group members
+type GroupMemberModel struct {
+	GroupID  string `gorm:"primaryKey"`
+	UserID   string `gorm:"primaryKey;index"`
+	Role     uint8
+	JoinedAt time.Time
+}
+
+// toGroup converts the model to a Group
+func (m *GroupModel) toGroup() *Group {
+	return &Group{
+		ID:          m.ID,
+		TenantID:    m.TenantID,
+		OwnerID:     m.OwnerID,
+		Name:        m.Name,
+		Description: m.Description,
+		Private:     m.Private,
+		CreatedAt:   m.CreatedAt,
+	}
+}
+
+// toGroupMember converts the model to a GroupMember
+func (m *GroupMemberModel) toGroupMember() *GroupMember {
+	return &GroupMember{
+		GroupID:  m.GroupID,
+		UserID:   m.UserID,
+		Role:     GroupRole(m.Role),
+		JoinedAt: m.JoinedAt,
+	}
+}
+
+// scopeInGroupOf restricts a query to posts outside private groups, authored or co-authored by the viewer
+// or in a private group the viewer is a member of
+func scopeInGroupOf(viewer string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(
+			"(post_models.group_id NOT IN (SELECT id FROM group_models WHERE private = ?) OR post_models.user_id = ?"+
+				" OR post_models.id IN (SELECT post_id FROM co_author_models WHERE user_id = ? AND status = ?)"+
+				" OR post_models.group_id IN (SELECT group_id FROM group_member_models WHERE user_id = ?))",
+			true, viewer, viewer, uint8(CoAuthorAccepted), viewer,
+		)
+	}
+}
+
+// SaveGroup creates a group; ErrInvalidGroup is returned if its ID is taken
+func (s *GormPostStore) SaveGroup(ctx context.Context, group *Group) error {
+	group.TenantID = TenantFromContext(ctx)
+
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&GroupModel{}).Where("id = ?", group.ID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("%w: ID %s is taken", ErrInvalidGroup, group.ID)
+	}
+
+	return s.db.WithContext(ctx).Create(&GroupModel{
+		ID:          group.ID,
+		TenantID:    group.TenantID,
+		OwnerID:     group.OwnerID,
+		Name:        group.Name,
+		Description: group.Description,
+		Private:     group.Private,
+		CreatedAt:   group.CreatedAt,
+	}).Error
+}
+
+// GetGroup returns a group
+func (s *GormPostStore) GetGroup(ctx context.Context, groupID string) (*Group, error) {
+	var model GroupModel
+	err := s.db.WithContext(ctx).Where("id = ? AND tenant_id = ?", groupID, TenantFromContext(ctx)).First(&model).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrGroupNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return model.toGroup(), nil
+}
+
+// SaveGroupMember adds a member to a group or changes their role
+func (s *GormPostStore) SaveGroupMember(ctx context.Context, member *GroupMember) error {
+	if _, err := s.GetGroup(ctx, member.GroupID); err != nil {
+		return err
+	}
+
+	return s.db.WithContext(ctx).Save(&GroupMemberModel{
+		GroupID:  member.GroupID,
+		UserID:   member.UserID,
+		Role:     uint8(member.Role),
+		JoinedAt: member.JoinedAt,
+	}).Error
+}
+
+// GetGroupMember returns the membership of a user, or ErrNotGroupMember
+func (s *GormPostStore) GetGroupMember(ctx context.Context, groupID, userID string) (*GroupMember, error) {
+	if _, err := s.GetGroup(ctx, groupID); err != nil {
+		return nil, err
+	}
+
+	var model GroupMemberModel
+	err := s.db.WithContext(ctx).Where("group_id = ? AND user_id = ?", groupID, userID).First(&model).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrNotGroupMember
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return model.toGroupMember(), nil
+}
+
+// DeleteGroupMember removes a member from a group
+func (s *GormPostStore) DeleteGroupMember(ctx context.Context, groupID, userID string) error {
+	if _, err := s.GetGroup(ctx, groupID); err != nil {
+		return err
+	}
+
+	result := s.db.WithContext(ctx).Where("group_id = ? AND user_id = ?", groupID, userID).Delete(&GroupMemberModel{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotGroupMember
+	}
+
+	return nil
+}
+
+// ListGroupMembers returns the members of a group, highest role first, then in order of joining
+func (s *GormPostStore) ListGroupMembers(ctx context.Context, groupID string, limit, offset int) ([]*GroupMember, error) {
+	if _, err := s.GetGroup(ctx, groupID); err != nil {
+		return nil, err
+	}
+
+	query := s.db.WithContext(ctx).
+		Where("group_id = ?", groupID).
+		Order("role DESC, joined_at, user_id")
+
+	// Apply pagination
+	if limit > 0 {
+		query = query.Limit(limit).Offset(offset)
+	}
+
+	var models []GroupMemberModel
+	if err := query.Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	members := make([]*GroupMember, len(models))
+	for i := range models {
+		members[i] = models[i].toGroupMember()
+	}
+
+	return members, nil
+}
+
+// ListUserGroups returns the groups a user is a member of, ordered by name
+func (s *GormPostStore) ListUserGroups(ctx context.Context, userID string) ([]*Group, error) {
+	var models []GroupModel
+	err := s.db.WithContext(ctx).
+		Where("tenant_id = ? AND id IN (SELECT group_id FROM group_member_models WHERE user_id = ?)", TenantFromContext(ctx), userID).
+		Order("name, id").
+		Find(&models).Error
+	if err != nil {
+		return nil, err
+	}
+
+	groups := make([]*Group, len(models))
+	for i := range models {
+		groups[i] = models[i].toGroup()
+	}
+
+	return groups, nil
+}
@@ -0,0 +1,253 @@
+package postflow
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGormPostStore_Groups tests community groups with the GORM store
+func TestGormPostStore_Groups(t *testing.T) {
+	store, db := setupTestGormStore(t)
+	defer cleanupTestDB(t, db)
+	pm := NewPostManager(store)
+	ctx := context.Background()
+	as := func(userID string) context.Context {
+		return WithActor(ctx, Actor{UserID: userID})
+	}
+
+	// Test: the owner is the first member
+	_, err := pm.CreateGroup(ctx, &Group{OwnerID: "alice", Name: " "})
+	assert.True(t, errors.Is(err, ErrInvalidGroup))
+
+	clubID, err := pm.CreateGroup(ctx, &Group{OwnerID: "alice", Name: "Book club"})
+	require.NoError(t, err)
+	secretID, err := pm.CreateGroup(ctx, &Group{OwnerID: "alice", Name: "Editors", Private: true})
+	require.NoError(t, err)
+
+	members, err := pm.ListGroupMembers(ctx, clubID, "alice", 10, 0)
+	require.NoError(t, err)
+	require.Len(t, members, 1)
+	assert.Equal(t, "alice", members[0].UserID)
+	assert.Equal(t, GroupRoleOwner, members[0].Role)
+
+	// Test: creating a group never takes over an existing one
+	takeoverID, err := pm.CreateGroup(ctx, &Group{ID: secretID, OwnerID: "mallory", Name: "Mine now"})
+	require.NoError(t, err)
+	assert.NotEqual(t, secretID, takeoverID)
+	secret, err := pm.GetGroup(ctx, secretID)
+	require.NoError(t, err)
+	assert.Equal(t, "alice", secret.OwnerID)
+	assert.Equal(t, "Editors", secret.Name)
+
+	// Test: anyone may join public groups, private groups are joined by invitation
+	require.NoError(t, pm.JoinGroup(ctx, clubID, "bob"))
+	require.NoError(t, pm.JoinGroup(ctx, clubID, "bob"))
+	assert.Equal(t, ErrPermissionDenied, pm.JoinGroup(ctx, secretID, "bob"))
+	assert.Equal(t, ErrGroupNotFound, pm.JoinGroup(ctx, "missing", "bob"))
+	assert.Equal(t, ErrPermissionDenied, pm.AddGroupMember(ctx, secretID, "bob", "carol"))
+	require.NoError(t, pm.AddGroupMember(ctx, secretID, "alice", "bob"))
+
+	groups, err := pm.ListUserGroups(ctx, "bob")
+	assert.NoError(t, err)
+	require.Len(t, groups, 2)
+	assert.Equal(t, "Book club", groups[0].Name)
+	assert.Equal(t, "Editors", groups[1].Name)
+
+	// Test: only members post in a group, and the group sticks to the post
+	outsider := createTestGormPost("carol")
+	outsider.GroupID = clubID
+	_, err = pm.CreatePost(ctx, outsider)
+	assert.Equal(t, ErrNotGroupMember, err)
+
+	clubPost := createTestGormPost("bob")
+	clubPost.GroupID = clubID
+	clubPostID, err := pm.CreatePost(ctx, clubPost)
+	require.NoError(t, err)
+
+	secretPost := createTestGormPost("bob")
+	secretPost.GroupID = secretID
+	secretPostID, err := pm.CreatePost(ctx, secretPost)
+	require.NoError(t, err)
+
+	otherPostID, err := pm.CreatePost(ctx, createTestGormPost("bob"))
+	require.NoError(t, err)
+
+	stored, err := pm.GetPost(ctx, secretPostID)
+	require.NoError(t, err)
+	assert.Equal(t, secretID, stored.GroupID)
+	stored.GroupID = clubID
+	require.NoError(t, pm.UpdatePost(ctx, stored))
+	stored, err = pm.GetPost(ctx, secretPostID)
+	require.NoError(t, err)
+	assert.Equal(t, secretID, stored.GroupID)
+
+	// Test: group feeds hold the posts of their group
+	feed, err := pm.GetGroupFeed(ctx, clubID, "carol", 10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{clubPostID}, postIDs(feed))
+	feed, err = pm.GetGroupFeed(ctx, secretID, "bob", 10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{secretPostID}, postIDs(feed))
+
+	// Test: posts of private groups are hidden from non-members everywhere
+	_, err = pm.GetGroupFeed(ctx, secretID, "carol", 10, 0)
+	assert.Equal(t, ErrPermissionDenied, err)
+	_, err = pm.ListGroupMembers(ctx, secretID, "carol", 10, 0)
+	assert.Equal(t, ErrPermissionDenied, err)
+	_, err = pm.GetPost(as("carol"), secretPostID)
+	assert.Equal(t, ErrPermissionDenied, err)
+	assert.Equal(t, ErrPermissionDenied, pm.AddReaction(ctx, secretPostID, "carol", ReactionLike))
+	require.NoError(t, pm.AddReaction(ctx, secretPostID, "alice", ReactionLike))
+
+	for viewer, expected := range map[string][]string{
+		"alice": {clubPostID, secretPostID, otherPostID},
+		"carol": {clubPostID, otherPostID},
+	} {
+		listed, err := pm.ListPosts(as(viewer), &PostFilter{UserID: "bob"})
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, expected, postIDs(listed), viewer)
+
+		userFeed, err := pm.GetUserFeed(ctx, viewer, 10, 0)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, expected, postIDs(userFeed), viewer)
+
+		trending, err := pm.GetTrendingPosts(as(viewer), 10)
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, expected, postIDs(trending), viewer)
+	}
+
+	moderatorCtx := WithActor(ctx, Actor{UserID: "mod", Role: RoleModerator})
+	_, err = pm.GetPost(moderatorCtx, secretPostID)
+	assert.NoError(t, err)
+
+	// Test: only the owner changes roles, and moderators cannot remove their peers
+	assert.Equal(t, ErrPermissionDenied, pm.SetGroupRole(ctx, clubID, "bob", "bob", GroupRoleModerator))
+	assert.True(t, errors.Is(pm.SetGroupRole(ctx, clubID, "alice", "bob", GroupRoleOwner), ErrInvalidGroup))
+	require.NoError(t, pm.JoinGroup(ctx, clubID, "dave"))
+	require.NoError(t, pm.JoinGroup(ctx, clubID, "erin"))
+	require.NoError(t, pm.SetGroupRole(ctx, clubID, "alice", "dave", GroupRoleModerator))
+	require.NoError(t, pm.SetGroupRole(ctx, clubID, "alice", "erin", GroupRoleModerator))
+	assert.Equal(t, ErrPermissionDenied, pm.RemoveGroupMember(ctx, clubID, "dave", "erin"))
+	assert.Equal(t, ErrPermissionDenied, pm.RemoveGroupMember(ctx, clubID, "dave", "alice"))
+
+	members, err = pm.ListGroupMembers(ctx, clubID, "bob", 0, 0)
+	require.NoError(t, err)
+	require.Len(t, members, 4)
+	assert.Equal(t, []GroupRole{GroupRoleOwner, GroupRoleModerator, GroupRoleModerator, GroupRoleMember},
+		[]GroupRole{members[0].Role, members[1].Role, members[2].Role, members[3].Role})
+	assert.Equal(t, "bob", members[3].UserID)
+
+	// Test: group moderators remove posts in their group only
+	assert.Equal(t, ErrPermissionDenied, pm.RemoveGroupPost(ctx, clubPostID, "bob", "spam"))
+	assert.True(t, errors.Is(pm.RemoveGroupPost(ctx, otherPostID, "dave", "spam"), ErrInvalidGroup))
+	assert.Equal(t, ErrPermissionDenied, pm.RemoveGroupPost(ctx, secretPostID, "dave", "spam"))
+	require.NoError(t, pm.RemoveGroupPost(ctx, clubPostID, "dave", "off topic"))
+	_, err = pm.GetPost(ctx, clubPostID)
+	assert.Equal(t, ErrPostNotFound, err)
+
+	entries, err := pm.ListAuditEntries(moderatorCtx, clubPostID, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, entries, 1)
+	assert.Equal(t, AuditGroupRemove, entries[0].Action)
+	assert.Equal(t, "dave", entries[0].ActorID)
+	assert.Equal(t, "bob", entries[0].AuthorID)
+	assert.Equal(t, "off topic", entries[0].Reason)
+
+	// Test: members leave and are removed, the owner stays
+	assert.True(t, errors.Is(pm.LeaveGroup(ctx, clubID, "alice"), ErrInvalidGroup))
+	require.NoError(t, pm.RemoveGroupMember(ctx, clubID, "dave", "bob"))
+	assert.Equal(t, ErrNotGroupMember, pm.LeaveGroup(ctx, clubID, "bob"))
+	require.NoError(t, pm.LeaveGroup(ctx, secretID, "bob"))
+
+	// Former members lose access to private group posts, except to their own
+	_, err = pm.GetGroupFeed(ctx, secretID, "bob", 10, 0)
+	assert.Equal(t, ErrPermissionDenied, err)
+	_, err = pm.GetPost(as("bob"), secretPostID)
+	assert.NoError(t, err)
+	_, err = pm.GetPost(as("dave"), secretPostID)
+	assert.Equal(t, ErrPermissionDenied, err)
+
+	// Test: groups belong to their tenant
+	_, err = pm.GetGroup(WithTenant(ctx, "tenant-a"), clubID)
+	assert.Equal(t, ErrGroupNotFound, err)
+	groups, err = pm.ListUserGroups(WithTenant(ctx, "tenant-a"), "alice")
+	assert.NoError(t, err)
+	assert.Empty(t, groups)
+}
+
+// TestGormPostStore_GroupCoAuthorAccess tests that co-authors keep access to their posts in a private group after leaving it with the GORM store
+func TestGormPostStore_GroupCoAuthorAccess(t *testing.T) {
+	store, db := setupTestGormStore(t)
+	defer cleanupTestDB(t, db)
+	pm := NewPostManager(store)
+	ctx := context.Background()
+	carolCtx := WithActor(ctx, Actor{UserID: "carol"})
+
+	secretID, err := pm.CreateGroup(ctx, &Group{OwnerID: "alice", Name: "Editors", Private: true})
+	require.NoError(t, err)
+	require.NoError(t, pm.AddGroupMember(ctx, secretID, "alice", "bob"))
+	require.NoError(t, pm.AddGroupMember(ctx, secretID, "alice", "carol"))
+
+	post := createTestGormPost("bob")
+	post.GroupID = secretID
+	postID, err := pm.CreatePost(ctx, post)
+	require.NoError(t, err)
+	require.NoError(t, pm.InviteCoAuthor(ctx, postID, "bob", "carol"))
+	require.NoError(t, pm.AcceptCoAuthorInvite(ctx, postID, "carol"))
+
+	// Test: the co-author still reads and lists the post after leaving the group
+	require.NoError(t, pm.LeaveGroup(ctx, secretID, "carol"))
+	_, err = pm.GetPost(carolCtx, postID)
+	assert.NoError(t, err)
+	posts, err := pm.ListPosts(carolCtx, &PostFilter{UserID: "carol"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{postID}, postIDs(posts))
+
+	// Test: other users who are not members still cannot read it
+	_, err = pm.GetPost(WithActor(ctx, Actor{UserID: "dave"}), postID)
+	assert.Equal(t, ErrPermissionDenied, err)
+	posts, err = pm.ListPosts(WithActor(ctx, Actor{UserID: "dave"}), &PostFilter{UserID: "carol"})
+	require.NoError(t, err)
+	assert.Empty(t, posts)
+}
+
+// TestGormPostStore_GroupFeedAuthorizer tests that group feeds leave out posts the authorizer denies the viewer with the GORM store
+func TestGormPostStore_GroupFeedAuthorizer(t *testing.T) {
+	authorizer := AuthorizerFunc(func(ctx context.Context, actor Actor, action Action, post *Post) error {
+		if action == ActionRead && post.Content == "embargoed" && actor.UserID != post.UserID {
+			return ErrPermissionDenied
+		}
+		return DefaultAuthorizer.Authorize(ctx, actor, action, post)
+	})
+	store, db := setupTestGormStore(t)
+	defer cleanupTestDB(t, db)
+	pm := NewPostManager(store, WithAuthorizer(authorizer))
+	ctx := context.Background()
+
+	groupID, err := pm.CreateGroup(ctx, &Group{OwnerID: "alice", Name: "Book club"})
+	require.NoError(t, err)
+	require.NoError(t, pm.JoinGroup(ctx, groupID, "bob"))
+
+	embargoed := createTestGormPost("alice")
+	embargoed.GroupID = groupID
+	embargoed.Content = "embargoed"
+	_, err = pm.CreatePost(ctx, embargoed)
+	require.NoError(t, err)
+	visible := createTestGormPost("alice")
+	visible.GroupID = groupID
+	visibleID, err := pm.CreatePost(ctx, visible)
+	require.NoError(t, err)
+
+	feed, err := pm.GetGroupFeed(ctx, groupID, "bob", 10, 0)
+	require.NoError(t, err)
+	require.Len(t, feed, 1)
+	assert.Equal(t, visibleID, feed[0].ID)
+
+	feed, err = pm.GetGroupFeed(ctx, groupID, "alice", 10, 0)
+	require.NoError(t, err)
+	assert.Len(t, feed, 2)
+}
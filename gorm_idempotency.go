@@ -0,0 +1,74 @@
+// Package postflow provides functionality for managing user posts and feeds.
+package postflow
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// IdempotencyKeyModel is the GORM model for storing claimed idempotency keys
+type IdempotencyKeyModel struct {
+	IdempotencyKey string `gorm:"primaryKey"`
+	Value          string
+	ExpiresAt      time.Time `gorm:"index"`
+	CreatedAt      time.Time
+}
+
+// ClaimIdempotencyKey stores value under key unless an unexpired entry exists
+func (s *GormPostStore) ClaimIdempotencyKey(ctx context.Context, key string, value string, ttl time.Duration) (string, bool, error) {
+	db := s.db.WithContext(ctx)
+	now := time.Now()
+
+	// Drop an expired claim so the key can be reused
+	if err := db.Where("idempotency_key = ? AND expires_at <= ?", key, now).Delete(&IdempotencyKeyModel{}).Error; err != nil {
+		return "", false, err
+	}
+
+	// The primary key makes the claim atomic across instances
+	model := IdempotencyKeyModel{
+		IdempotencyKey: key,
+		Value:          value,
+		ExpiresAt:      now.Add(ttl),
+		CreatedAt:      now,
+	}
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&model)
+	if result.Error != nil {
+		return "", false, result.Error
+	}
+	if result.RowsAffected == 1 {
+		return value, true, nil
+	}
+
+	// Someone else claimed the key first
+	var existing IdempotencyKeyModel
+	if err := db.Where("idempotency_key = ?", key).First(&existing).Error; err != nil {
+		return "", false, err
+	}
+
+	return existing.Value, false, nil
+}
+
+// CompleteIdempotencyKey replaces the claimed value of a key and keeps it for ttl
+func (s *GormPostStore) CompleteIdempotencyKey(ctx context.Context, key string, claimed string, value string, ttl time.Duration) error {
+	return s.db.WithContext(ctx).
+		Model(&IdempotencyKeyModel{}).
+		Where("idempotency_key = ? AND value = ?", key, claimed).
+		Updates(map[string]interface{}{"value": value, "expires_at": time.Now().Add(ttl)}).Error
+}
+
+// ReleaseIdempotencyKey forgets a key still holding the claimed value
+func (s *GormPostStore) ReleaseIdempotencyKey(ctx context.Context, key string, claimed string) error {
+	return s.db.WithContext(ctx).
+		Where("idempotency_key = ? AND value = ?", key, claimed).
+		Delete(&IdempotencyKeyModel{}).Error
+}
+
+// PurgeExpiredIdempotencyKeys deletes expired idempotency keys and returns how many were removed
+func (s *GormPostStore) PurgeExpiredIdempotencyKeys(ctx context.Context) (int64, error) {
+	result := s.db.WithContext(ctx).
+		Where("expires_at <= ?", time.Now()).
+		Delete(&IdempotencyKeyModel{})
+	return result.RowsAffected, result.Error
+}
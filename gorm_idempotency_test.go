@@ -0,0 +1,86 @@
+package postflow
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestGormPostStore_ClaimIdempotencyKey tests claiming, expiring and releasing idempotency keys
+func TestGormPostStore_ClaimIdempotencyKey(t *testing.T) {
+	store, db := setupTestGormStore(t)
+	defer cleanupTestDB(t, db)
+	ctx := context.Background()
+
+	// Test: first claim wins
+	value, claimed, err := store.ClaimIdempotencyKey(ctx, "key1", "post1", time.Hour)
+	assert.NoError(t, err)
+	assert.True(t, claimed)
+	assert.Equal(t, "post1", value)
+
+	// Test: later claims return the original value
+	value, claimed, err = store.ClaimIdempotencyKey(ctx, "key1", "post2", time.Hour)
+	assert.NoError(t, err)
+	assert.False(t, claimed)
+	assert.Equal(t, "post1", value)
+
+	// Test: keys are only released by the holder of the claim
+	assert.NoError(t, store.ReleaseIdempotencyKey(ctx, "key1", "post2"))
+	_, claimed, err = store.ClaimIdempotencyKey(ctx, "key1", "post2", time.Hour)
+	assert.NoError(t, err)
+	assert.False(t, claimed)
+
+	// Test: released keys can be claimed again
+	err = store.ReleaseIdempotencyKey(ctx, "key1", "post1")
+	assert.NoError(t, err)
+	value, _, err = store.ClaimIdempotencyKey(ctx, "key1", "post3", -time.Second)
+	assert.NoError(t, err)
+	assert.Equal(t, "post3", value)
+
+	// Test: completing a claim replaces its value and expiry, unless it was taken over
+	assert.NoError(t, store.CompleteIdempotencyKey(ctx, "key1", "post1", "post1-done", time.Hour))
+	assert.NoError(t, store.CompleteIdempotencyKey(ctx, "key1", "post3", "post3-done", time.Hour))
+	value, claimed, err = store.ClaimIdempotencyKey(ctx, "key1", "post7", time.Hour)
+	assert.NoError(t, err)
+	assert.False(t, claimed)
+	assert.Equal(t, "post3-done", value)
+
+	// Test: expired keys can be claimed again and are purged
+	_, _, err = store.ClaimIdempotencyKey(ctx, "key2", "post4", -time.Second)
+	assert.NoError(t, err)
+	purged, err := store.PurgeExpiredIdempotencyKeys(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+	_, _, err = store.ClaimIdempotencyKey(ctx, "key2", "post5", -time.Second)
+	assert.NoError(t, err)
+	value, claimed, err = store.ClaimIdempotencyKey(ctx, "key2", "post6", time.Hour)
+	assert.NoError(t, err)
+	assert.True(t, claimed)
+	assert.Equal(t, "post6", value)
+}
+
+// TestGormPostManagerCreatePostIdempotency tests retried CreatePost calls against GormPostStore
+func TestGormPostManagerCreatePostIdempotency(t *testing.T) {
+	store, db := setupTestGormStore(t)
+	defer cleanupTestDB(t, db)
+	pm := NewPostManager(store)
+	ctx := WithIdempotencyKey(context.Background(), "request-1")
+
+	firstID, err := pm.CreatePost(ctx, createTestGormPost("user1"))
+	assert.NoError(t, err)
+	retryID, err := pm.CreatePost(ctx, createTestGormPost("user1"))
+	assert.NoError(t, err)
+	assert.Equal(t, firstID, retryID)
+
+	posts, err := store.ListPosts(ctx, &PostFilter{UserID: "user1"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(posts))
+
+	// Test: reusing the key for a different post is an error
+	changed := createTestGormPost("user1")
+	changed.Content = "Something else"
+	_, err = pm.CreatePost(ctx, changed)
+	assert.Equal(t, ErrIdempotencyKeyReused, err)
+}
@@ -0,0 +1,56 @@
+// Package postflow provides functionality for managing user posts and feeds.
+package postflow
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// SetModerationStatus changes the moderation status of a post if it is still at the given version.
+// The change is recorded in the outbox as a PostUpdated event by the actor in the context.
+func (s *GormPostStore) SetModerationStatus(ctx context.Context, postID string, version int64, status ModerationStatus, reason string) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&PostModel{}).
+			Scopes(scopeTenant(ctx)).
+			Where("id = ? AND version = ?", postID, version).
+			Updates(map[string]interface{}{
+				"moderation_status": uint8(status),
+				"moderation_reason": reason,
+				"version":           gorm.Expr("version + 1"),
+			})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			var count int64
+			if err := tx.Model(&PostModel{}).Scopes(scopeTenant(ctx)).Where("id = ?", postID).Count(&count).Error; err != nil {
+				return err
+			}
+			if count == 0 {
+				return ErrPostNotFound
+			}
+			return ErrVersionConflict
+		}
+		if !s.outbox {
+			return nil
+		}
+
+		// Record the post as saved in the outbox
+		var postModel PostModel
+		if err := tx.Preload("Media", preloadMedia).
+			Preload("Tags").
+			Preload("CoAuthors", preloadCoAuthors).
+			Preload("Audience", preloadAudience).
+			Scopes(scopeTenant(ctx)).
+			Where("id = ?", postID).
+			First(&postModel).Error; err != nil {
+			return err
+		}
+		event, err := postOutboxEvent(ctx, EventPostUpdated, postID, outboxActor(ctx, postModel.UserID), s.toPost(&postModel, nil))
+		if err != nil {
+			return err
+		}
+		return s.recordOutboxEvents(tx, event)
+	})
+}
@@ -0,0 +1,269 @@
+package postflow
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGormPostStore_Moderation tests the moderation queue against GormPostStore
+func TestGormPostStore_Moderation(t *testing.T) {
+	store, db := setupTestGormStore(t)
+	defer cleanupTestDB(t, db)
+	pm := NewPostManager(store, WithModerator(keywordModerator))
+	ctx := context.Background()
+	modCtx := WithActor(ctx, Actor{UserID: "mod1", Role: RoleModerator})
+
+	approvedID, err := pm.CreatePost(ctx, createTestGormPost("user1"))
+	require.NoError(t, err)
+
+	held := createTestGormPost("user1")
+	held.Content = "please review me"
+	heldID, err := pm.CreatePost(ctx, held)
+	require.NoError(t, err)
+
+	// Test: pending posts stay out of feeds, trending and listings
+	feed, err := pm.GetUserFeed(ctx, "user2", 10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(feed))
+	assert.Equal(t, approvedID, feed[0].ID)
+
+	trending, err := pm.GetTrendingPosts(ctx, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(trending))
+
+	pending, err := pm.ListPendingPosts(modCtx, 10, 0)
+	assert.NoError(t, err)
+	require.Equal(t, 1, len(pending))
+	assert.Equal(t, heldID, pending[0].ID)
+	assert.Equal(t, "needs review", pending[0].ModerationReason)
+
+	// Test: only moderators can list the queue
+	_, err = pm.ListPendingPosts(WithActor(ctx, Actor{UserID: "user1"}), 10, 0)
+	assert.Equal(t, ErrPermissionDenied, err)
+
+	// Test: the author cannot approve their own held post
+	err = pm.ApprovePost(WithActor(ctx, Actor{UserID: "user1"}), heldID)
+	assert.Equal(t, ErrPermissionDenied, err)
+
+	// Test: approving publishes the post and bumps its version
+	err = pm.ApprovePost(modCtx, heldID)
+	assert.NoError(t, err)
+	approved, err := store.GetPost(ctx, heldID)
+	assert.NoError(t, err)
+	assert.Equal(t, ModerationApproved, approved.ModerationStatus)
+	assert.Equal(t, int64(2), approved.Version)
+
+	// Test: a stale update cannot revert the verdict
+	held.Content = "edited"
+	err = store.SavePost(ctx, held)
+	assert.Equal(t, ErrVersionConflict, err)
+
+	// Test: rejecting hides the post
+	err = pm.RejectPost(modCtx, heldID, "off-topic")
+	assert.NoError(t, err)
+	feed, err = pm.GetUserFeed(ctx, "user2", 10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(feed))
+
+	err = pm.RejectPost(modCtx, "nonexistent-id", "")
+	assert.Equal(t, ErrPostNotFound, err)
+}
+
+// TestGormPostStore_ModerationAudit tests that verdicts from the queue are audited under the reviewer
+func TestGormPostStore_ModerationAudit(t *testing.T) {
+	store, db := setupTestGormStore(t)
+	defer cleanupTestDB(t, db)
+	pm := NewPostManager(store, WithModerator(keywordModerator))
+	ctx := context.Background()
+
+	held := createTestGormPost("user1")
+	held.Content = "please review me"
+	heldID, err := pm.CreatePost(ctx, held)
+	require.NoError(t, err)
+
+	// Test: regular users and anonymous callers cannot review
+	err = pm.RejectPost(WithActor(ctx, Actor{UserID: "user2"}), heldID, "")
+	assert.Equal(t, ErrPermissionDenied, err)
+	err = pm.RejectPost(ctx, heldID, "")
+	assert.Equal(t, ErrPermissionDenied, err)
+
+	entries, err := pm.ListAuditEntries(WithActor(ctx, Actor{UserID: "auditor", Role: RoleAdmin}), heldID, 10, 0)
+	require.NoError(t, err)
+	assert.Empty(t, entries)
+
+	// Test: the verdict is audited under the moderator
+	err = pm.RejectPost(WithActor(ctx, Actor{UserID: "mod1", Role: RoleModerator}), heldID, "off-topic")
+	require.NoError(t, err)
+
+	entries, err = pm.ListAuditEntries(WithActor(ctx, Actor{UserID: "auditor", Role: RoleAdmin}), heldID, 10, 0)
+	require.NoError(t, err)
+	require.Equal(t, 1, len(entries))
+	assert.Equal(t, AuditReject, entries[0].Action)
+	assert.Equal(t, "mod1", entries[0].ActorID)
+	assert.Equal(t, RoleModerator, entries[0].ActorRole)
+	assert.Equal(t, "off-topic", entries[0].Reason)
+}
+
+// TestGormPostStore_ModerationEditRacesApproval tests that a moderation decision is not applied to content edited since it was read with the GORM store
+func TestGormPostStore_ModerationEditRacesApproval(t *testing.T) {
+	store, db := setupTestGormStore(t)
+	defer cleanupTestDB(t, db)
+	pm := NewPostManager(store, WithModerator(keywordModerator))
+	ctx := context.Background()
+	modCtx := WithActor(ctx, Actor{UserID: "mod1", Role: RoleModerator})
+
+	held := createTestGormPost("user1")
+	held.Content = "please review me"
+	heldID, err := pm.CreatePost(ctx, held)
+	require.NoError(t, err)
+
+	// The author edits the post after the moderator read it
+	edited := false
+	pm.OnBefore(EventPostUpdated, func(ctx context.Context, event *Event) error {
+		if edited || event.Actor.UserID != "mod1" {
+			return nil
+		}
+		edited = true
+		post, err := store.GetPost(ctx, heldID)
+		require.NoError(t, err)
+		post.Content = "please review me, now with a link"
+		return store.SavePost(ctx, post)
+	})
+
+	// Test: the approval is refused and the edited post stays held
+	err = pm.ApprovePost(modCtx, heldID)
+	assert.Equal(t, ErrVersionConflict, err)
+	post, err := pm.GetPost(modCtx, heldID)
+	require.NoError(t, err)
+	assert.Equal(t, ModerationPending, post.ModerationStatus)
+	assert.Equal(t, "please review me, now with a link", post.Content)
+
+	// Test: approving the post as read again succeeds
+	require.NoError(t, pm.ApprovePost(modCtx, heldID))
+	post, err = pm.GetPost(ctx, heldID)
+	require.NoError(t, err)
+	assert.Equal(t, ModerationApproved, post.ModerationStatus)
+}
+
+// TestGormPostStore_ModerationEditKeepsVerdict tests that edits by the author never loosen the moderation status
+func TestGormPostStore_ModerationEditKeepsVerdict(t *testing.T) {
+	store, db := setupTestGormStore(t)
+	defer cleanupTestDB(t, db)
+	pm := NewPostManager(store, WithModerator(keywordModerator), WithReportThreshold(2))
+	ctx := context.Background()
+	modCtx := WithActor(ctx, Actor{UserID: "mod1", Role: RoleModerator})
+	authorCtx := WithActor(ctx, Actor{UserID: "user1"})
+
+	// Test: editing a hidden post keeps it hidden
+	hidden := createTestGormPost("user1")
+	hiddenID, err := pm.CreatePost(ctx, hidden)
+	require.NoError(t, err)
+	require.NoError(t, pm.HidePost(modCtx, hiddenID, "off topic"))
+
+	stored, err := store.GetPost(ctx, hiddenID)
+	require.NoError(t, err)
+	stored.Content = "harmless now"
+	require.NoError(t, pm.UpdatePost(authorCtx, stored))
+	stored, err = store.GetPost(ctx, hiddenID)
+	require.NoError(t, err)
+	assert.Equal(t, ModerationHidden, stored.ModerationStatus)
+	assert.Equal(t, "off topic", stored.ModerationReason)
+
+	_, err = pm.PatchPost(authorCtx, hiddenID, "user1", &PostPatch{Fields: PatchContent, Content: "still harmless"})
+	require.NoError(t, err)
+	stored, err = store.GetPost(ctx, hiddenID)
+	require.NoError(t, err)
+	assert.Equal(t, ModerationHidden, stored.ModerationStatus)
+
+	// Test: editing a post held after reports keeps it held
+	reported := createTestGormPost("user1")
+	reportedID, err := pm.CreatePost(ctx, reported)
+	require.NoError(t, err)
+	for _, reporterID := range []string{"user2", "user3"} {
+		require.NoError(t, pm.ReportPost(ctx, reportedID, reporterID, ReportSpam, ""))
+	}
+
+	stored, err = store.GetPost(ctx, reportedID)
+	require.NoError(t, err)
+	require.Equal(t, ModerationPending, stored.ModerationStatus)
+	stored.Content = "edited after reports"
+	require.NoError(t, pm.UpdatePost(authorCtx, stored))
+	_, err = pm.PatchPost(authorCtx, reportedID, "user1", &PostPatch{Fields: PatchContent, Content: "patched after reports"})
+	require.NoError(t, err)
+	stored, err = store.GetPost(ctx, reportedID)
+	require.NoError(t, err)
+	assert.Equal(t, ModerationPending, stored.ModerationStatus)
+	assert.Equal(t, "hidden after 2 reports", stored.ModerationReason)
+
+	// Test: a rejected post stays rejected
+	require.NoError(t, pm.RejectPost(modCtx, reportedID, "spam"))
+	_, err = pm.PatchPost(authorCtx, reportedID, "user1", &PostPatch{Fields: PatchContent, Content: "patched again"})
+	require.NoError(t, err)
+	stored, err = store.GetPost(ctx, reportedID)
+	require.NoError(t, err)
+	assert.Equal(t, ModerationRejected, stored.ModerationStatus)
+
+	// Test: the moderator can still hold an approved post
+	approvedID, err := pm.CreatePost(ctx, createTestGormPost("user1"))
+	require.NoError(t, err)
+	_, err = pm.PatchPost(authorCtx, approvedID, "user1", &PostPatch{Fields: PatchContent, Content: "please review"})
+	require.NoError(t, err)
+	stored, err = store.GetPost(ctx, approvedID)
+	require.NoError(t, err)
+	assert.Equal(t, ModerationPending, stored.ModerationStatus)
+}
+
+// TestGormPostStore_ModerationReadAccess tests that posts under moderation are only readable by their authors and moderators
+func TestGormPostStore_ModerationReadAccess(t *testing.T) {
+	store, db := setupTestGormStore(t)
+	defer cleanupTestDB(t, db)
+	pm := NewPostManager(store, WithModerator(keywordModerator))
+	ctx := context.Background()
+	modCtx := WithActor(ctx, Actor{UserID: "mod1", Role: RoleModerator})
+	authorCtx := WithActor(ctx, Actor{UserID: "user1"})
+	bobCtx := WithActor(ctx, Actor{UserID: "bob"})
+
+	held := createTestGormPost("user1")
+	held.Content = "please review me"
+	heldID, err := pm.CreatePost(ctx, held)
+	require.NoError(t, err)
+	hiddenID, err := pm.CreatePost(ctx, createTestGormPost("user1"))
+	require.NoError(t, err)
+	require.NoError(t, pm.HidePost(modCtx, hiddenID, "off topic"))
+	rejectedID, err := pm.CreatePost(ctx, createTestGormPost("user1"))
+	require.NoError(t, err)
+	require.NoError(t, pm.RejectPost(modCtx, rejectedID, "spam"))
+
+	// Test: other users cannot read posts under moderation or their reactions
+	for _, postID := range []string{heldID, hiddenID, rejectedID} {
+		_, err = pm.GetPost(bobCtx, postID)
+		assert.Equal(t, ErrPermissionDenied, err)
+		_, err = pm.GetReactionCounts(bobCtx, postID)
+		assert.Equal(t, ErrPermissionDenied, err)
+	}
+
+	// Test: authors and moderators can
+	for _, readCtx := range []context.Context{authorCtx, modCtx} {
+		post, err := pm.GetPost(readCtx, hiddenID)
+		require.NoError(t, err)
+		assert.Equal(t, ModerationHidden, post.ModerationStatus)
+	}
+
+	// Test: only privileged actors can list or watch posts that are not approved
+	hidden := ModerationHidden
+	_, err = pm.ListPosts(bobCtx, &PostFilter{ModerationStatus: &hidden})
+	assert.Equal(t, ErrPermissionDenied, err)
+	_, err = pm.ListPosts(authorCtx, &PostFilter{UserID: "user1", ModerationStatus: &hidden})
+	assert.Equal(t, ErrPermissionDenied, err)
+	listed, err := pm.ListPosts(modCtx, &PostFilter{ModerationStatus: &hidden})
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, hiddenID, listed[0].ID)
+
+	pending := ModerationPending
+	_, err = pm.Watch(bobCtx, &WatchFilter{Posts: &PostFilter{ModerationStatus: &pending}})
+	assert.Equal(t, ErrPermissionDenied, err)
+}
@@ -0,0 +1,171 @@
+// Package postflow provides functionality for managing user posts and feeds.
+package postflow
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// MutedTermModel is the GORM model for storing muted words and tags
+type MutedTermModel struct {
+	TenantID  string `gorm:"primaryKey;default:''"`
+	UserID    string `gorm:"primaryKey"`
+	Kind      uint8  `gorm:"primaryKey"`
+	Value     string `gorm:"primaryKey"`
+	Collapse  bool
+	ExpiresAt *time.Time `gorm:"index"`
+	CreatedAt time.Time
+}
+
+// muteText returns the text of a post that muted terms are matched against: a line of content words,
+// a line of words per tag, and a line per normalized tag prefixed with '#'. Word lines are padded with
+// spaces so that a phrase matches whole words within one line.
+func muteText(post *Post) string {
+	var b strings.Builder
+	b.WriteString("\n " + normalizeContent(post.Content) + " \n")
+	for _, tag := range post.Tags {
+		b.WriteString(" " + normalizeContent(tag) + " \n")
+	}
+	for _, tag := range post.Tags {
+		b.WriteString("#" + normalizeTag(tag) + "\n")
+	}
+	return b.String()
+}
+
+// likeEscaper escapes LIKE wildcards with '!', which works as ESCAPE character on every dialect
+var likeEscaper = strings.NewReplacer("!", "!!", "%", "!%", "_", "!_")
+
+// mutePattern returns the LIKE pattern matching the mute text of posts that match the term
+func mutePattern(term *MutedTerm) string {
+	value := likeEscaper.Replace(term.Value)
+	if term.Kind == MutedTag {
+		return "%\n#" + value + "\n%"
+	}
+	return "% " + value + " %"
+}
+
+// scopeWithoutMutedTerms leaves out posts matching the terms that drop matching posts,
+// except the viewer's own posts. Collapsing terms are applied by the manager.
+func scopeWithoutMutedTerms(viewer string, terms []*MutedTerm) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		var conditions []string
+		args := []interface{}{viewer}
+		for _, term := range terms {
+			if term.Collapse {
+				continue
+			}
+			conditions = append(conditions, "post_models.mute_text LIKE ? ESCAPE '!'")
+			args = append(args, mutePattern(term))
+		}
+		if len(conditions) == 0 {
+			return db
+		}
+
+		return db.Where("(post_models.user_id = ? OR NOT ("+strings.Join(conditions, " OR ")+"))", args...)
+	}
+}
+
+// scopeMutedBy loads the muted terms of the viewer and returns a scope leaving out the posts they drop
+func (s *GormPostStore) scopeMutedBy(ctx context.Context, viewer string) (func(*gorm.DB) *gorm.DB, error) {
+	var terms []*MutedTerm
+	if viewer != "" {
+		var err error
+		if terms, err = s.ListMutedTerms(ctx, viewer); err != nil {
+			return nil, err
+		}
+	}
+
+	return scopeWithoutMutedTerms(viewer, terms), nil
+}
+
+// MigrateMuteText fills in the text muted terms are matched against for posts saved before muted terms
+// were applied in queries. Until then such posts are only muted after paging. It returns the number of posts changed.
+func (s *GormPostStore) MigrateMuteText(ctx context.Context) (int64, error) {
+	var changed int64
+	for {
+		var postModels []PostModel
+		if err := s.db.WithContext(ctx).Preload("Tags").Where("mute_text = ''").Limit(bulkBatchSize).Find(&postModels).Error; err != nil {
+			return changed, err
+		}
+		if len(postModels) == 0 {
+			return changed, nil
+		}
+
+		err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			for _, postModel := range postModels {
+				post := &Post{Content: postModel.Content}
+				for _, tag := range postModel.Tags {
+					post.Tags = append(post.Tags, untenantTag(tag.Name))
+				}
+
+				if err := tx.Model(&PostModel{}).Where("id = ?", postModel.ID).UpdateColumn("mute_text", muteText(post)).Error; err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return changed, err
+		}
+		changed += int64(len(postModels))
+	}
+}
+
+// SaveMutedTerm adds or replaces a muted term
+func (s *GormPostStore) SaveMutedTerm(ctx context.Context, term *MutedTerm) error {
+	model := MutedTermModel{
+		TenantID:  TenantFromContext(ctx),
+		UserID:    term.UserID,
+		Kind:      uint8(term.Kind),
+		Value:     term.Value,
+		Collapse:  term.Collapse,
+		CreatedAt: term.CreatedAt,
+	}
+	if !term.ExpiresAt.IsZero() {
+		expiresAt := term.ExpiresAt
+		model.ExpiresAt = &expiresAt
+	}
+
+	return s.db.WithContext(ctx).
+		Clauses(clause.OnConflict{UpdateAll: true}).
+		Create(&model).Error
+}
+
+// DeleteMutedTerm removes a muted term
+func (s *GormPostStore) DeleteMutedTerm(ctx context.Context, userID string, kind MutedTermKind, value string) error {
+	return s.db.WithContext(ctx).
+		Where("tenant_id = ? AND user_id = ? AND kind = ? AND value = ?", TenantFromContext(ctx), userID, uint8(kind), value).
+		Delete(&MutedTermModel{}).Error
+}
+
+// ListMutedTerms returns the unexpired muted terms of a user, oldest first
+func (s *GormPostStore) ListMutedTerms(ctx context.Context, userID string) ([]*MutedTerm, error) {
+	var models []MutedTermModel
+	err := s.db.WithContext(ctx).
+		Where("tenant_id = ? AND user_id = ? AND (expires_at IS NULL OR expires_at > ?)", TenantFromContext(ctx), userID, time.Now()).
+		Order("created_at ASC, value").
+		Find(&models).Error
+	if err != nil {
+		return nil, err
+	}
+
+	terms := make([]*MutedTerm, len(models))
+	for i, model := range models {
+		terms[i] = &MutedTerm{
+			UserID:    model.UserID,
+			Kind:      MutedTermKind(model.Kind),
+			Value:     model.Value,
+			Collapse:  model.Collapse,
+			CreatedAt: model.CreatedAt,
+		}
+		if model.ExpiresAt != nil {
+			terms[i].ExpiresAt = *model.ExpiresAt
+		}
+	}
+
+	return terms, nil
+}
@@ -0,0 +1,162 @@
+package postflow
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGormPostStore_MutedTerms tests muted words and tags with the GORM store
+func TestGormPostStore_MutedTerms(t *testing.T) {
+	store, db := setupTestGormStore(t)
+	defer cleanupTestDB(t, db)
+	pm := NewPostManager(store)
+	ctx := context.Background()
+
+	create := func(userID, content string, tags ...string) string {
+		post := createTestGormPost(userID)
+		post.Content = content
+		post.Tags = tags
+		id, err := pm.CreatePost(ctx, post)
+		require.NoError(t, err)
+		return id
+	}
+
+	plainID := create("user2", "A quiet day at the park", "weather")
+	spoilerID := create("user2", "Huge SPOILERS: the butler did it!", "movies")
+	politicsID := create("user3", "Debate tonight", "Politics")
+	japaneseID := create("user3", "今日はネタバレ注意です", "anime")
+	ownID := create("user1", "My own spoilers post", "politics")
+
+	// Test: invalid terms
+	_, err := pm.MuteWord(ctx, "user1", " !! ", MuteOptions{})
+	assert.Equal(t, ErrInvalidMutedTerm, err)
+
+	_, err = pm.MuteWord(ctx, "user1", "spoilers", MuteOptions{})
+	require.NoError(t, err)
+	_, err = pm.MuteTag(ctx, "user1", "#politics", MuteOptions{Collapse: true})
+	require.NoError(t, err)
+	_, err = pm.MuteWord(ctx, "user1", "ネタバレ", MuteOptions{})
+	require.NoError(t, err)
+	_, err = pm.MuteWord(ctx, "user1", "butler", MuteOptions{Duration: 20 * time.Millisecond})
+	require.NoError(t, err)
+
+	terms, err := pm.ListMutedTerms(ctx, "user1")
+	assert.NoError(t, err)
+	assert.Equal(t, 4, len(terms))
+
+	// Test: matching posts are dropped or collapsed, own posts are kept
+	feed, err := pm.GetUserFeed(ctx, "user1", 10, 0)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{plainID, politicsID, ownID}, postIDs(feed))
+	for _, post := range feed {
+		if post.ID == politicsID {
+			assert.True(t, post.Collapsed)
+			assert.Equal(t, `muted tag "politics"`, post.CollapseReason)
+		} else {
+			assert.False(t, post.Collapsed)
+		}
+	}
+
+	viewerCtx := WithActor(ctx, Actor{UserID: "user1"})
+	trending, err := pm.GetTrendingPosts(viewerCtx, 10)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{plainID, politicsID, ownID}, postIDs(trending))
+
+	tagFeed, err := pm.ListPosts(viewerCtx, &PostFilter{Tags: []string{"movies"}})
+	assert.NoError(t, err)
+	assert.Empty(t, tagFeed)
+
+	// Test: muted posts are left out before paging, so pages stay full
+	page, err := pm.GetUserFeed(ctx, "user1", 2, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{ownID, politicsID}, postIDs(page))
+	page, err = pm.GetUserFeed(ctx, "user1", 2, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{plainID}, postIDs(page))
+
+	// Test: profiles and other viewers are unaffected
+	profile, err := pm.ListPosts(viewerCtx, &PostFilter{UserID: "user2"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(profile))
+	feed, err = pm.GetUserFeed(ctx, "user4", 10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, len(feed))
+
+	// Test: expired and removed terms no longer apply
+	time.Sleep(30 * time.Millisecond)
+	terms, err = pm.ListMutedTerms(ctx, "user1")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(terms))
+
+	require.NoError(t, pm.UnmuteWord(ctx, "user1", "Spoilers"))
+	require.NoError(t, pm.UnmuteTag(ctx, "user1", "politics"))
+	feed, err = pm.GetUserFeed(ctx, "user1", 10, 0)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{plainID, spoilerID, politicsID, ownID}, postIDs(feed))
+	assert.NotContains(t, postIDs(feed), japaneseID)
+}
+
+// TestGormPostStore_MutedTermsMatchLiterally tests that muted terms are not read as LIKE patterns
+func TestGormPostStore_MutedTermsMatchLiterally(t *testing.T) {
+	store, db := setupTestGormStore(t)
+	defer cleanupTestDB(t, db)
+	pm := NewPostManager(store)
+	ctx := context.Background()
+
+	post := createTestGormPost("user2")
+	post.Tags = []string{"axb"}
+	postID, err := pm.CreatePost(ctx, post)
+	require.NoError(t, err)
+
+	_, err = pm.MuteTag(ctx, "user1", "a_b", MuteOptions{})
+	require.NoError(t, err)
+	_, err = pm.MuteTag(ctx, "user1", "%", MuteOptions{})
+	require.NoError(t, err)
+
+	feed, err := pm.GetUserFeed(ctx, "user1", 10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{postID}, postIDs(feed))
+}
+
+// TestGormPostStore_MigrateMuteText tests filling in the mute text of posts saved before it was stored
+func TestGormPostStore_MigrateMuteText(t *testing.T) {
+	store, db := setupTestGormStore(t)
+	defer cleanupTestDB(t, db)
+	pm := NewPostManager(store)
+	ctx := context.Background()
+
+	var ids []string
+	for _, content := range []string{"Older post", "Spoilers ahead", "Newest spoilers"} {
+		post := createTestGormPost("user2")
+		post.Content = content
+		post.CreatedAt = time.Now().Add(time.Duration(len(ids)) * time.Second)
+		id, err := pm.CreatePost(ctx, post)
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+	require.NoError(t, db.Model(&PostModel{}).Where("1 = 1").UpdateColumn("mute_text", "").Error)
+
+	_, err := pm.MuteWord(ctx, "user1", "spoilers", MuteOptions{})
+	require.NoError(t, err)
+
+	// Test: unmigrated posts are still muted, but only after paging
+	feed, err := pm.GetUserFeed(ctx, "user1", 2, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, feed)
+
+	changed, err := store.MigrateMuteText(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), changed)
+
+	feed, err = pm.GetUserFeed(ctx, "user1", 2, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{ids[0]}, postIDs(feed))
+
+	changed, err = store.MigrateMuteText(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, changed)
+}
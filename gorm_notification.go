@@ -0,0 +1,246 @@
+// Package postflow provides functionality for managing user posts and feeds.
+package postflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// NotificationModel is the GORM model for storing notifications
+type NotificationModel struct {
+	ID         string `gorm:"primaryKey"`
+	TenantID   string `gorm:"index:idx_notification_user;uniqueIndex:idx_notification_unread"`
+	UserID     string `gorm:"index:idx_notification_user;uniqueIndex:idx_notification_unread"`
+	Kind       uint8  `gorm:"uniqueIndex:idx_notification_unread"`
+	PostID     string `gorm:"uniqueIndex:idx_notification_unread"`
+	Actors     string // JSON array of the most recent actors
+	ActorCount int
+	Read       bool
+	Unread     *bool `gorm:"uniqueIndex:idx_notification_unread"` // True while unread, NULL once read, so that only one unread notification exists per key
+	CreatedAt  time.Time
+	UpdatedAt  time.Time `gorm:"autoUpdateTime:false;index"` // Time of the latest activity, set by the store
+}
+
+// NotificationActorModel is the GORM model for storing the users behind a notification
+type NotificationActorModel struct {
+	NotificationID string `gorm:"primaryKey"`
+	ActorID        string `gorm:"primaryKey"`
+	CreatedAt      time.Time
+}
+
+// NotificationPreferenceModel is the GORM model for storing the notification kinds a user turned off
+type NotificationPreferenceModel struct {
+	TenantID string `gorm:"primaryKey"`
+	UserID   string `gorm:"primaryKey"`
+	Kind     uint8  `gorm:"primaryKey"`
+}
+
+// toNotification converts the model to a Notification
+func (m *NotificationModel) toNotification() (*Notification, error) {
+	notification := &Notification{
+		ID:         m.ID,
+		TenantID:   m.TenantID,
+		UserID:     m.UserID,
+		Kind:       NotificationKind(m.Kind),
+		PostID:     m.PostID,
+		ActorCount: m.ActorCount,
+		Read:       m.Read,
+		CreatedAt:  m.CreatedAt,
+		UpdatedAt:  m.UpdatedAt,
+	}
+	if m.Actors != "" {
+		if err := json.Unmarshal([]byte(m.Actors), &notification.Actors); err != nil {
+			return nil, err
+		}
+	}
+	return notification, nil
+}
+
+// RecordNotification adds the actor to the user's unread notification of the kind about the post, creating it if needed.
+// A unique index on unread notifications keeps concurrent calls from creating a second one.
+func (s *GormPostStore) RecordNotification(ctx context.Context, userID string, kind NotificationKind, postID, actorID string, at time.Time) error {
+	tenant := TenantFromContext(ctx)
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var model NotificationModel
+		for {
+			err := tx.Where("tenant_id = ? AND user_id = ? AND kind = ? AND post_id = ? AND read = ?", tenant, userID, uint8(kind), postID, false).
+				First(&model).Error
+			if err == nil {
+				break
+			}
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				return err
+			}
+
+			actors, err := json.Marshal([]string{actorID})
+			if err != nil {
+				return err
+			}
+
+			unread := true
+			model = NotificationModel{
+				ID:         uuid.New().String(),
+				TenantID:   tenant,
+				UserID:     userID,
+				Kind:       uint8(kind),
+				PostID:     postID,
+				Actors:     string(actors),
+				ActorCount: 1,
+				Unread:     &unread,
+				CreatedAt:  at,
+				UpdatedAt:  at,
+			}
+			result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&model)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected > 0 {
+				return tx.Create(&NotificationActorModel{NotificationID: model.ID, ActorID: actorID, CreatedAt: at}).Error
+			}
+
+			// Another call created the notification first; add the actor to it
+			model = NotificationModel{}
+		}
+
+		// Actors already on the notification are not counted again
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&NotificationActorModel{NotificationID: model.ID, ActorID: actorID, CreatedAt: at})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+
+		notification, err := model.toNotification()
+		if err != nil {
+			return err
+		}
+		actors, err := json.Marshal(prependActor(notification.Actors, actorID))
+		if err != nil {
+			return err
+		}
+
+		return tx.Model(&NotificationModel{}).
+			Where("id = ?", model.ID).
+			Updates(map[string]interface{}{
+				"actors":      string(actors),
+				"actor_count": gorm.Expr("actor_count + 1"),
+				"updated_at":  at,
+			}).Error
+	})
+}
+
+// ListNotifications returns a user's notifications after the cursor, most recent activity first
+func (s *GormPostStore) ListNotifications(ctx context.Context, userID string, cursor *NotificationCursor, limit int) ([]*Notification, error) {
+	query := s.db.WithContext(ctx).
+		Where("tenant_id = ? AND user_id = ?", TenantFromContext(ctx), userID).
+		Order("updated_at DESC, id DESC")
+
+	if cursor != nil {
+		query = query.Where("updated_at < ? OR (updated_at = ? AND id < ?)", cursor.UpdatedAt, cursor.UpdatedAt, cursor.ID)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var models []NotificationModel
+	if err := query.Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	notifications := make([]*Notification, len(models))
+	for i := range models {
+		notification, err := models[i].toNotification()
+		if err != nil {
+			return nil, err
+		}
+		notifications[i] = notification
+	}
+
+	return notifications, nil
+}
+
+// MarkNotificationRead marks a notification of the user as read
+func (s *GormPostStore) MarkNotificationRead(ctx context.Context, userID, notificationID string) error {
+	result := s.db.WithContext(ctx).
+		Model(&NotificationModel{}).
+		Where("id = ? AND tenant_id = ? AND user_id = ?", notificationID, TenantFromContext(ctx), userID).
+		Updates(map[string]interface{}{"read": true, "unread": nil})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotificationNotFound
+	}
+
+	return nil
+}
+
+// MarkAllNotificationsRead marks all notifications of the user as read
+func (s *GormPostStore) MarkAllNotificationsRead(ctx context.Context, userID string) error {
+	return s.db.WithContext(ctx).
+		Model(&NotificationModel{}).
+		Where("tenant_id = ? AND user_id = ? AND read = ?", TenantFromContext(ctx), userID, false).
+		Updates(map[string]interface{}{"read": true, "unread": nil}).Error
+}
+
+// CountUnreadNotifications returns the number of unread notifications of the user
+func (s *GormPostStore) CountUnreadNotifications(ctx context.Context, userID string) (int, error) {
+	var count int64
+	err := s.db.WithContext(ctx).
+		Model(&NotificationModel{}).
+		Where("tenant_id = ? AND user_id = ? AND read = ?", TenantFromContext(ctx), userID, false).
+		Count(&count).Error
+
+	return int(count), err
+}
+
+// GetNotificationPreferences returns the preferences of the user
+func (s *GormPostStore) GetNotificationPreferences(ctx context.Context, userID string) (*NotificationPreferences, error) {
+	var models []NotificationPreferenceModel
+	err := s.db.WithContext(ctx).
+		Where("tenant_id = ? AND user_id = ?", TenantFromContext(ctx), userID).
+		Order("kind").
+		Find(&models).Error
+	if err != nil {
+		return nil, err
+	}
+
+	prefs := &NotificationPreferences{UserID: userID}
+	for _, model := range models {
+		prefs.Disabled = append(prefs.Disabled, NotificationKind(model.Kind))
+	}
+	return prefs, nil
+}
+
+// SaveNotificationPreferences replaces the preferences of the user
+func (s *GormPostStore) SaveNotificationPreferences(ctx context.Context, prefs *NotificationPreferences) error {
+	tenant := TenantFromContext(ctx)
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("tenant_id = ? AND user_id = ?", tenant, prefs.UserID).Delete(&NotificationPreferenceModel{}).Error; err != nil {
+			return err
+		}
+		if len(prefs.Disabled) == 0 {
+			return nil
+		}
+
+		models := make([]NotificationPreferenceModel, 0, len(prefs.Disabled))
+		seen := make(map[NotificationKind]bool)
+		for _, kind := range prefs.Disabled {
+			if !seen[kind] {
+				seen[kind] = true
+				models = append(models, NotificationPreferenceModel{TenantID: tenant, UserID: prefs.UserID, Kind: uint8(kind)})
+			}
+		}
+		return tx.Create(&models).Error
+	})
+}
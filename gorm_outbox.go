@@ -0,0 +1,191 @@
+// Package postflow provides functionality for managing user posts and feeds.
+package postflow
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// OutboxEventModel is the GORM model for storing outbox events
+type OutboxEventModel struct {
+	ID               int64 `gorm:"primaryKey;autoIncrement"`
+	Type             uint8
+	TenantID         string
+	PostID           string `gorm:"index"`
+	UserID           string
+	Payload          string // JSON of the post as saved, if any
+	Reaction         uint8
+	PreviousReaction uint8
+	CreatedAt        time.Time
+	DeliveredAt      *time.Time `gorm:"index"`
+	DeadAt           *time.Time `gorm:"index"` // Set when the relay gave up on the event
+	Attempts         int
+	LastError        string
+}
+
+// WithOutbox makes the store record domain events in an outbox table, in the same transaction
+// as SavePost, DeletePost, SaveReaction, DeleteReaction and BulkSavePosts.
+// Use an OutboxRelay to publish them.
+func WithOutbox() GormPostStoreOption {
+	return func(s *GormPostStore) {
+		s.outbox = true
+	}
+}
+
+// toOutboxEvent converts the model to an OutboxEvent
+func (m *OutboxEventModel) toOutboxEvent() (*OutboxEvent, error) {
+	event := &OutboxEvent{
+		ID:               m.ID,
+		Type:             EventType(m.Type),
+		TenantID:         m.TenantID,
+		PostID:           m.PostID,
+		UserID:           m.UserID,
+		Reaction:         ReactionType(m.Reaction),
+		PreviousReaction: ReactionType(m.PreviousReaction),
+		CreatedAt:        m.CreatedAt,
+		Attempts:         m.Attempts,
+		LastError:        m.LastError,
+	}
+	if m.Payload != "" {
+		if err := json.Unmarshal([]byte(m.Payload), &event.Post); err != nil {
+			return nil, err
+		}
+	}
+	return event, nil
+}
+
+// postOutboxEvent returns the outbox row of a post event. post is the post as saved, or nil.
+func postOutboxEvent(ctx context.Context, eventType EventType, postID, userID string, post *Post) (OutboxEventModel, error) {
+	model := OutboxEventModel{
+		Type:      uint8(eventType),
+		TenantID:  TenantFromContext(ctx),
+		PostID:    postID,
+		UserID:    userID,
+		CreatedAt: time.Now(),
+	}
+	if post != nil {
+		payload, err := json.Marshal(post)
+		if err != nil {
+			return model, err
+		}
+		model.Payload = string(payload)
+	}
+	return model, nil
+}
+
+// reactionOutboxEvent returns the outbox row of a reaction event
+func reactionOutboxEvent(ctx context.Context, eventType EventType, postID, userID string, reaction, previous ReactionType) OutboxEventModel {
+	return OutboxEventModel{
+		Type:             uint8(eventType),
+		TenantID:         TenantFromContext(ctx),
+		PostID:           postID,
+		UserID:           userID,
+		Reaction:         uint8(reaction),
+		PreviousReaction: uint8(previous),
+		CreatedAt:        time.Now(),
+	}
+}
+
+// recordOutboxEvents adds events to the outbox within the transaction, if the outbox is enabled
+func (s *GormPostStore) recordOutboxEvents(tx *gorm.DB, events ...OutboxEventModel) error {
+	if !s.outbox || len(events) == 0 {
+		return nil
+	}
+	return tx.CreateInBatches(&events, bulkBatchSize).Error
+}
+
+// outboxActor returns the user recorded for a change: the actor in the context, or fallback
+func outboxActor(ctx context.Context, fallback string) string {
+	if actor, ok := ActorFromContext(ctx); ok && actor.UserID != "" {
+		return actor.UserID
+	}
+	return fallback
+}
+
+// ListOutboxEvents returns undelivered events, oldest first
+func (s *GormPostStore) ListOutboxEvents(ctx context.Context, limit int) ([]*OutboxEvent, error) {
+	return s.listOutboxEvents(s.db.WithContext(ctx).Where("delivered_at IS NULL AND dead_at IS NULL"), limit)
+}
+
+// ListDeadOutboxEvents returns dead letters, oldest first
+func (s *GormPostStore) ListDeadOutboxEvents(ctx context.Context, limit int) ([]*OutboxEvent, error) {
+	return s.listOutboxEvents(s.db.WithContext(ctx).Where("dead_at IS NOT NULL"), limit)
+}
+
+// listOutboxEvents returns the events selected by the query, oldest first
+func (s *GormPostStore) listOutboxEvents(query *gorm.DB, limit int) ([]*OutboxEvent, error) {
+	query = query.Order("id")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var models []OutboxEventModel
+	if err := query.Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	events := make([]*OutboxEvent, len(models))
+	for i := range models {
+		event, err := models[i].toOutboxEvent()
+		if err != nil {
+			return nil, err
+		}
+		events[i] = event
+	}
+
+	return events, nil
+}
+
+// MarkOutboxEventDelivered records that an event was published
+func (s *GormPostStore) MarkOutboxEventDelivered(ctx context.Context, eventID int64) error {
+	return s.db.WithContext(ctx).
+		Model(&OutboxEventModel{}).
+		Where("id = ?", eventID).
+		Updates(map[string]interface{}{"delivered_at": time.Now(), "last_error": ""}).Error
+}
+
+// MarkOutboxEventFailed records a failed attempt to publish an event
+func (s *GormPostStore) MarkOutboxEventFailed(ctx context.Context, eventID int64, cause error) error {
+	message := ""
+	if cause != nil {
+		message = cause.Error()
+	}
+
+	return s.db.WithContext(ctx).
+		Model(&OutboxEventModel{}).
+		Where("id = ?", eventID).
+		Updates(map[string]interface{}{"attempts": gorm.Expr("attempts + 1"), "last_error": message}).Error
+}
+
+// MarkOutboxEventDead records the last failed attempt to publish an event and sets it aside as a dead letter
+func (s *GormPostStore) MarkOutboxEventDead(ctx context.Context, eventID int64, cause error) error {
+	message := ""
+	if cause != nil {
+		message = cause.Error()
+	}
+
+	return s.db.WithContext(ctx).
+		Model(&OutboxEventModel{}).
+		Where("id = ?", eventID).
+		Updates(map[string]interface{}{"attempts": gorm.Expr("attempts + 1"), "last_error": message, "dead_at": time.Now()}).Error
+}
+
+// RequeueOutboxEvent returns a dead letter to the outbox with no failed attempts
+func (s *GormPostStore) RequeueOutboxEvent(ctx context.Context, eventID int64) error {
+	return s.db.WithContext(ctx).
+		Model(&OutboxEventModel{}).
+		Where("id = ? AND dead_at IS NOT NULL", eventID).
+		Updates(map[string]interface{}{"attempts": 0, "dead_at": nil}).Error
+}
+
+// PurgeOutboxEvents removes events delivered before the given time, returning how many were removed
+func (s *GormPostStore) PurgeOutboxEvents(ctx context.Context, deliveredBefore time.Time) (int64, error) {
+	result := s.db.WithContext(ctx).
+		Where("delivered_at IS NOT NULL AND delivered_at < ?", deliveredBefore).
+		Delete(&OutboxEventModel{})
+
+	return result.RowsAffected, result.Error
+}
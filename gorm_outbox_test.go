@@ -0,0 +1,310 @@
+package postflow
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// outboxTypes returns the types of outbox events
+func outboxTypes(events []*OutboxEvent) []EventType {
+	types := make([]EventType, len(events))
+	for i, event := range events {
+		types[i] = event.Type
+	}
+	return types
+}
+
+// TestGormPostStore_Outbox tests that changes are recorded in the outbox
+func TestGormPostStore_Outbox(t *testing.T) {
+	_, db := setupTestGormStore(t)
+	defer cleanupTestDB(t, db)
+
+	store, err := NewGormPostStore(db, WithOutbox())
+	require.NoError(t, err)
+	pm := NewPostManager(store)
+	ctx := WithTenant(context.Background(), "tenant-a")
+
+	// Test: post and reaction changes are recorded in order
+	post := createTestGormPost("user1")
+	postID, err := pm.CreatePost(ctx, post)
+	require.NoError(t, err)
+
+	post.Content = "Edited"
+	require.NoError(t, pm.UpdatePost(ctx, post))
+
+	require.NoError(t, pm.AddReaction(ctx, postID, "user2", ReactionLike))
+	require.NoError(t, pm.AddReaction(ctx, postID, "user2", ReactionLike))
+	require.NoError(t, pm.AddReaction(ctx, postID, "user2", ReactionLove))
+	require.NoError(t, pm.RemoveReaction(ctx, postID, "user2", ReactionLike))
+	require.NoError(t, pm.RemoveReaction(ctx, postID, "user2", ReactionLove))
+
+	moderatorCtx := WithActor(ctx, Actor{UserID: "mod", Role: RoleModerator})
+	require.NoError(t, pm.DeletePost(moderatorCtx, postID, "mod"))
+
+	events, err := store.ListOutboxEvents(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []EventType{
+		EventPostCreated, EventPostUpdated,
+		EventReactionAdded, EventReactionChanged, EventReactionRemoved,
+		EventPostDeleted,
+	}, outboxTypes(events))
+
+	for i, event := range events {
+		assert.Equal(t, postID, event.PostID)
+		assert.Equal(t, "tenant-a", event.TenantID)
+		if i > 0 {
+			assert.Greater(t, event.ID, events[i-1].ID)
+		}
+	}
+
+	assert.Equal(t, "user1", events[0].UserID)
+	require.NotNil(t, events[0].Post)
+	assert.Equal(t, int64(1), events[0].Post.Version)
+	require.NotNil(t, events[1].Post)
+	assert.Equal(t, "Edited", events[1].Post.Content)
+	assert.Equal(t, int64(2), events[1].Post.Version)
+
+	assert.Equal(t, "user2", events[3].UserID)
+	assert.Equal(t, ReactionLove, events[3].Reaction)
+	assert.Equal(t, ReactionLike, events[3].PreviousReaction)
+	assert.Equal(t, ReactionLove, events[4].Reaction)
+
+	assert.Equal(t, "mod", events[5].UserID)
+	assert.Nil(t, events[5].Post)
+
+	// Test: failed changes leave no events behind
+	stale := createTestGormPost("user1")
+	_, err = pm.CreatePost(ctx, stale)
+	require.NoError(t, err)
+	stale.Version = 42
+	assert.Equal(t, ErrVersionConflict, store.SavePost(ctx, stale))
+
+	events, err = store.ListOutboxEvents(ctx, 0)
+	require.NoError(t, err)
+	assert.Len(t, events, 7)
+
+	// Test: bulk imports are recorded
+	results, err := pm.BulkCreatePosts(WithActor(ctx, Actor{UserID: "admin", Role: RoleAdmin}), []*BulkPost{{Post: createTestGormPost("user3")}})
+	require.NoError(t, err)
+	require.NoError(t, results[0].Err)
+
+	events, err = store.ListOutboxEvents(ctx, 0)
+	require.NoError(t, err)
+	require.Len(t, events, 8)
+	assert.Equal(t, EventPostCreated, events[7].Type)
+	assert.Equal(t, results[0].PostID, events[7].PostID)
+
+	// Test: stores without the outbox record nothing
+	plain, err := NewGormPostStore(db)
+	require.NoError(t, err)
+	_, err = NewPostManager(plain).CreatePost(ctx, createTestGormPost("user1"))
+	require.NoError(t, err)
+
+	events, err = store.ListOutboxEvents(ctx, 0)
+	require.NoError(t, err)
+	assert.Len(t, events, 8)
+}
+
+// TestGormPostStore_OutboxModeration tests that moderation status changes are recorded in the outbox
+func TestGormPostStore_OutboxModeration(t *testing.T) {
+	_, db := setupTestGormStore(t)
+	defer cleanupTestDB(t, db)
+
+	store, err := NewGormPostStore(db, WithOutbox())
+	require.NoError(t, err)
+	pm := NewPostManager(store, WithReportThreshold(1))
+	ctx := WithTenant(context.Background(), "tenant-a")
+	modCtx := WithActor(ctx, Actor{UserID: "mod", Role: RoleModerator})
+
+	postID, err := pm.CreatePost(ctx, createTestGormPost("user1"))
+	require.NoError(t, err)
+
+	// Test: moderator decisions are recorded under the moderator
+	require.NoError(t, pm.HidePost(modCtx, postID, "off-topic"))
+	require.NoError(t, pm.UnhidePost(modCtx, postID))
+
+	// Test: holding a post after reports is recorded under the reporter
+	require.NoError(t, pm.ReportPost(ctx, postID, "user2", ReportSpam, ""))
+
+	events, err := store.ListOutboxEvents(ctx, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []EventType{EventPostCreated, EventPostUpdated, EventPostUpdated, EventPostUpdated}, outboxTypes(events))
+
+	assert.Equal(t, "mod", events[1].UserID)
+	require.NotNil(t, events[1].Post)
+	assert.Equal(t, ModerationHidden, events[1].Post.ModerationStatus)
+	assert.Equal(t, "off-topic", events[1].Post.ModerationReason)
+	assert.Equal(t, int64(2), events[1].Post.Version)
+
+	assert.Equal(t, ModerationApproved, events[2].Post.ModerationStatus)
+	assert.Equal(t, int64(3), events[2].Post.Version)
+
+	assert.Equal(t, SystemActor.UserID, events[3].UserID)
+	assert.Equal(t, ModerationPending, events[3].Post.ModerationStatus)
+	assert.Equal(t, "tenant-a", events[3].TenantID)
+
+	// Test: changes to missing posts leave no events behind
+	assert.Equal(t, ErrPostNotFound, store.SetModerationStatus(modCtx, "missing", 1, ModerationHidden, ""))
+	events, err = store.ListOutboxEvents(ctx, 0)
+	require.NoError(t, err)
+	assert.Len(t, events, 4)
+}
+
+// TestGormPostStore_OutboxRelay tests relaying outbox events to a publisher
+func TestGormPostStore_OutboxRelay(t *testing.T) {
+	_, db := setupTestGormStore(t)
+	defer cleanupTestDB(t, db)
+
+	store, err := NewGormPostStore(db, WithOutbox())
+	require.NoError(t, err)
+	pm := NewPostManager(store)
+	ctx := context.Background()
+
+	var postIDs []string
+	for i := 0; i < 3; i++ {
+		postID, err := pm.CreatePost(ctx, createTestGormPost("user1"))
+		require.NoError(t, err)
+		postIDs = append(postIDs, postID)
+	}
+
+	// Test: delivery stops at the first rejected event and resumes with it
+	errUnavailable := errors.New("broker unavailable")
+	var published []string
+	failOn := postIDs[1]
+	relay := NewOutboxRelay(store, OutboxPublisherFunc(func(ctx context.Context, event *OutboxEvent) error {
+		if event.PostID == failOn {
+			return errUnavailable
+		}
+		published = append(published, event.PostID)
+		return nil
+	}))
+
+	delivered, err := relay.RelayOnce(ctx)
+	assert.Equal(t, errUnavailable, err)
+	assert.Equal(t, 1, delivered)
+	assert.Equal(t, postIDs[:1], published)
+
+	pending, err := store.ListOutboxEvents(ctx, 0)
+	require.NoError(t, err)
+	require.Len(t, pending, 2)
+	assert.Equal(t, postIDs[1], pending[0].PostID)
+	assert.Equal(t, 1, pending[0].Attempts)
+
+	failOn = ""
+	delivered, err = relay.RelayOnce(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, delivered)
+	assert.Equal(t, postIDs, published)
+
+	delivered, err = relay.RelayOnce(ctx)
+	assert.NoError(t, err)
+	assert.Zero(t, delivered)
+
+	// Test: Run drains the outbox in batches until cancelled
+	for i := 0; i < 5; i++ {
+		postID, err := pm.CreatePost(ctx, createTestGormPost("user2"))
+		require.NoError(t, err)
+		postIDs = append(postIDs, postID)
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	relay.BatchSize = 2
+	relay.publisher = OutboxPublisherFunc(func(ctx context.Context, event *OutboxEvent) error {
+		published = append(published, event.PostID)
+		if len(published) == len(postIDs) {
+			cancel()
+		}
+		return nil
+	})
+	assert.Equal(t, context.Canceled, relay.Run(runCtx, time.Hour))
+	assert.Equal(t, postIDs, published)
+
+	// Test: delivered events can be purged
+	purged, err := store.PurgeOutboxEvents(ctx, time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(len(postIDs)), purged)
+}
+
+// TestGormPostStore_OutboxDeadLetters tests setting aside events the publisher keeps rejecting
+func TestGormPostStore_OutboxDeadLetters(t *testing.T) {
+	_, db := setupTestGormStore(t)
+	defer cleanupTestDB(t, db)
+
+	store, err := NewGormPostStore(db, WithOutbox())
+	require.NoError(t, err)
+	pm := NewPostManager(store)
+	ctx := context.Background()
+
+	poisonID, err := pm.CreatePost(ctx, createTestGormPost("user1"))
+	require.NoError(t, err)
+	nextID, err := pm.CreatePost(ctx, createTestGormPost("user1"))
+	require.NoError(t, err)
+
+	errRejected := errors.New("rejected")
+	var published []string
+	var dead []*OutboxEvent
+	relay := NewOutboxRelay(store, OutboxPublisherFunc(func(ctx context.Context, event *OutboxEvent) error {
+		if event.PostID == poisonID {
+			return errRejected
+		}
+		published = append(published, event.PostID)
+		return nil
+	}))
+	relay.MaxAttempts = 3
+	relay.OnDeadLetter = func(event *OutboxEvent, err error) {
+		dead = append(dead, event)
+	}
+
+	// Test: the event holds up the outbox until its last attempt
+	for i := 0; i < 2; i++ {
+		delivered, err := relay.RelayOnce(ctx)
+		assert.Equal(t, errRejected, err)
+		assert.Zero(t, delivered)
+	}
+	assert.Empty(t, published)
+	assert.Empty(t, dead)
+
+	// Test: the last attempt sets the event aside and the outbox moves on
+	delivered, err := relay.RelayOnce(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, delivered)
+	assert.Equal(t, []string{nextID}, published)
+	require.Len(t, dead, 1)
+	assert.Equal(t, poisonID, dead[0].PostID)
+	assert.Equal(t, 3, dead[0].Attempts)
+
+	pending, err := store.ListOutboxEvents(ctx, 0)
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+
+	letters, err := store.ListDeadOutboxEvents(ctx, 0)
+	require.NoError(t, err)
+	require.Len(t, letters, 1)
+	assert.Equal(t, poisonID, letters[0].PostID)
+	assert.Equal(t, 3, letters[0].Attempts)
+	assert.Equal(t, "rejected", letters[0].LastError)
+
+	// Test: dead letters are not purged and can be requeued
+	purged, err := store.PurgeOutboxEvents(ctx, time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+
+	require.NoError(t, store.RequeueOutboxEvent(ctx, letters[0].ID))
+	pending, err = store.ListOutboxEvents(ctx, 0)
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Zero(t, pending[0].Attempts)
+
+	poisonID = ""
+	delivered, err = relay.RelayOnce(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, delivered)
+	letters, err = store.ListDeadOutboxEvents(ctx, 0)
+	require.NoError(t, err)
+	assert.Empty(t, letters)
+}
@@ -12,21 +12,43 @@ import (
 
 // GormPostStore implements PostStore interface with GORM as the underlying storage
 type GormPostStore struct {
-	db *gorm.DB
+	db     *gorm.DB
+	outbox bool // Record domain events in the outbox table
 }
 
+// GormPostStoreOption configures optional behavior of a GormPostStore
+type GormPostStoreOption func(*GormPostStore)
+
 // PostModel is the GORM model for storing posts
 type PostModel struct {
 	ID         string `gorm:"primaryKey"`
+	TenantID   string `gorm:"index;not null;default:''"` // Rows created before tenants belong to the default tenant
 	UserID     string `gorm:"index"`
+	GroupID    string `gorm:"index;not null;default:''"`
 	Content    string
-	Media      []MediaModel `gorm:"foreignKey:PostID"`
-	Tags       []TagModel   `gorm:"many2many:post_tags;"`
+	Media      []MediaModel        `gorm:"foreignKey:PostID"`
+	Tags       []TagModel          `gorm:"many2many:post_tags;"`
+	CoAuthors  []CoAuthorModel     `gorm:"foreignKey:PostID"`
+	Audience   []PostAudienceModel `gorm:"foreignKey:PostID"`
 	CreatedAt  time.Time
 	UpdatedAt  time.Time
 	Visibility string
 	Comments   int
 	Shares     int
+	Version    int64 `gorm:"not null;default:1"` // Rows created before versioning start at version 1
+
+	ModerationStatus uint8 `gorm:"index"`
+	ModerationReason string
+	UpdatedBy        string
+
+	ContentHash string `gorm:"index"`
+	SimHash     int64  // Stored signed; SQL drivers do not support uint64 values with the high bit set
+	SimBand0    uint16 `gorm:"index"` // 16-bit bands of SimHash for near-duplicate candidate lookup
+	SimBand1    uint16 `gorm:"index"`
+	SimBand2    uint16 `gorm:"index"`
+	SimBand3    uint16 `gorm:"index"`
+
+	MuteText string `gorm:"not null;default:''"` // Normalized words and tags matched by muted terms, see muteText
 	// Reactions will be stored in a separate table
 }
 
@@ -44,6 +66,7 @@ type MediaModel struct {
 	FileSize     int64
 	FileName     string
 	MimeType     string
+	Position     int // Order of the media item within the post
 	CreatedAt    time.Time
 }
 
@@ -61,30 +84,46 @@ type ReactionModel struct {
 }
 
 // NewGormPostStore creates a new instance of GormPostStore
-func NewGormPostStore(db *gorm.DB) (*GormPostStore, error) {
+func NewGormPostStore(db *gorm.DB, opts ...GormPostStoreOption) (*GormPostStore, error) {
 	// Auto-migrate the models to ensure tables exist
-	err := db.AutoMigrate(&PostModel{}, &MediaModel{}, &TagModel{}, &ReactionModel{})
+	err := db.AutoMigrate(&PostModel{}, &MediaModel{}, &TagModel{}, &ReactionModel{}, &ReportModel{}, &AuditEntryModel{}, &SpamFingerprintModel{}, &ShadowBanModel{}, &UserRelationModel{}, &MutedTermModel{}, &PostFeedbackModel{}, &CoAuthorModel{}, &AudienceModel{}, &AudienceMemberModel{}, &PostAudienceModel{}, &GroupModel{}, &GroupMemberModel{}, &WebhookEndpointModel{}, &WebhookDeliveryModel{}, &NotificationModel{}, &NotificationActorModel{}, &NotificationPreferenceModel{}, &IdempotencyKeyModel{}, &OutboxEventModel{})
 	if err != nil {
 		return nil, err
 	}
 
-	return &GormPostStore{
+	s := &GormPostStore{
 		db: db,
-	}, nil
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s, nil
 }
 
 // Convert PostModel to Post
 func (s *GormPostStore) toPost(postModel *PostModel, reactions map[ReactionType]int) *Post {
 	post := &Post{
 		ID:         postModel.ID,
+		TenantID:   postModel.TenantID,
 		UserID:     postModel.UserID,
+		GroupID:    postModel.GroupID,
 		Content:    postModel.Content,
 		CreatedAt:  postModel.CreatedAt,
 		UpdatedAt:  postModel.UpdatedAt,
 		Reactions:  reactions,
 		Comments:   postModel.Comments,
 		Shares:     postModel.Shares,
-		Visibility: postModel.Visibility,
+		Visibility: Visibility(postModel.Visibility),
+		Version:    postModel.Version,
+
+		ModerationStatus: ModerationStatus(postModel.ModerationStatus),
+		ModerationReason: postModel.ModerationReason,
+		UpdatedBy:        postModel.UpdatedBy,
+
+		ContentHash: postModel.ContentHash,
+		SimHash:     uint64(postModel.SimHash),
 	}
 
 	// Convert MediaModel to Media
@@ -108,64 +147,138 @@ func (s *GormPostStore) toPost(postModel *PostModel, reactions map[ReactionType]
 		}
 	}
 
+	// Accepted co-authors, as loaded by preloadCoAuthors
+	for _, coAuthor := range postModel.CoAuthors {
+		post.CoAuthors = append(post.CoAuthors, coAuthor.UserID)
+	}
+
+	// Audience of custom posts
+	for _, target := range postModel.Audience {
+		switch target.Kind {
+		case audienceTargetList:
+			post.AudienceIDs = append(post.AudienceIDs, target.TargetID)
+		case audienceTargetUser:
+			post.AudienceUserIDs = append(post.AudienceUserIDs, target.TargetID)
+		}
+	}
+
 	// Convert TagModel to string tags
 	if len(postModel.Tags) > 0 {
 		post.Tags = make([]string, len(postModel.Tags))
 		for i, tagModel := range postModel.Tags {
-			post.Tags[i] = tagModel.Name
+			post.Tags[i] = untenantTag(tagModel.Name)
 		}
 	}
 
 	return post
 }
 
+// Convert a new Post to PostModel, without associations
+func toPostModel(post *Post) PostModel {
+	bands := simHashBands(post.SimHash)
+	return PostModel{
+		ID:         post.ID,
+		TenantID:   post.TenantID,
+		UserID:     post.UserID,
+		GroupID:    post.GroupID,
+		Content:    post.Content,
+		CreatedAt:  post.CreatedAt,
+		UpdatedAt:  post.UpdatedAt,
+		Visibility: string(post.Visibility),
+		Comments:   post.Comments,
+		Shares:     post.Shares,
+		Version:    1,
+
+		ModerationStatus: uint8(post.ModerationStatus),
+		ModerationReason: post.ModerationReason,
+		UpdatedBy:        post.UpdatedBy,
+
+		ContentHash: post.ContentHash,
+		SimHash:     int64(post.SimHash),
+		SimBand0:    bands[0],
+		SimBand1:    bands[1],
+		SimBand2:    bands[2],
+		SimBand3:    bands[3],
+
+		MuteText: muteText(post),
+	}
+}
+
+// placeholderReactionModels simulates reaction counts supplied with a new post.
+// This is a placeholder - in a real system, you would need to create individual reactions.
+func placeholderReactionModels(postID string, reactions map[ReactionType]int) []ReactionModel {
+	var reactionModels []ReactionModel
+	now := time.Now()
+	for reactionType, count := range reactions {
+		for i := 0; i < count; i++ {
+			reactionModels = append(reactionModels, ReactionModel{
+				PostID:       postID,
+				UserID:       fmt.Sprintf("system-%d-%d", reactionType, i),
+				ReactionType: uint8(reactionType),
+				CreatedAt:    now,
+			})
+		}
+	}
+	return reactionModels
+}
+
+// Convert Media to MediaModel, recording the position of each item
+func toMediaModels(postID string, media []Media) []MediaModel {
+	mediaModels := make([]MediaModel, len(media))
+	for i, item := range media {
+		mediaModels[i] = MediaModel{
+			ID:           item.ID,
+			PostID:       postID,
+			Type:         uint8(item.Type),
+			URL:          item.URL,
+			ThumbnailURL: item.ThumbnailURL,
+			Description:  item.Description,
+			Width:        item.Width,
+			Height:       item.Height,
+			Duration:     item.Duration,
+			FileSize:     item.FileSize,
+			FileName:     item.FileName,
+			MimeType:     item.MimeType,
+			Position:     i,
+			CreatedAt:    item.CreatedAt,
+		}
+	}
+	return mediaModels
+}
+
+// preloadMedia loads the media of a post in their stored order
+func preloadMedia(db *gorm.DB) *gorm.DB {
+	return db.Order("position ASC")
+}
+
 // SavePost saves a new post or updates an existing post
 func (s *GormPostStore) SavePost(ctx context.Context, post *Post) error {
+	var version int64
+	post.TenantID = TenantFromContext(ctx)
+
 	// Use transaction to ensure data consistency
-	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		var existingPost PostModel
 
 		// Check if the post already exists
 		err := tx.Where("id = ?", post.ID).First(&existingPost).Error
 		isNew := errors.Is(err, gorm.ErrRecordNotFound)
+		if !isNew && err == nil && existingPost.TenantID != post.TenantID {
+			return ErrPostExists
+		}
 
 		if isNew {
 			// Create new post
-			postModel := PostModel{
-				ID:         post.ID,
-				UserID:     post.UserID,
-				Content:    post.Content,
-				CreatedAt:  post.CreatedAt,
-				UpdatedAt:  post.UpdatedAt,
-				Visibility: post.Visibility,
-				Comments:   post.Comments,
-				Shares:     post.Shares,
-			}
+			postModel := toPostModel(post)
 
 			if err := tx.Create(&postModel).Error; err != nil {
 				return err
 			}
+			version = postModel.Version
 
 			// Create media entries
 			if len(post.Media) > 0 {
-				mediaModels := make([]MediaModel, len(post.Media))
-				for i, media := range post.Media {
-					mediaModels[i] = MediaModel{
-						ID:           media.ID,
-						PostID:       post.ID,
-						Type:         uint8(media.Type),
-						URL:          media.URL,
-						ThumbnailURL: media.ThumbnailURL,
-						Description:  media.Description,
-						Width:        media.Width,
-						Height:       media.Height,
-						Duration:     media.Duration,
-						FileSize:     media.FileSize,
-						FileName:     media.FileName,
-						MimeType:     media.MimeType,
-						CreatedAt:    media.CreatedAt,
-					}
-				}
+				mediaModels := toMediaModels(post.ID, post.Media)
 				if err := tx.Create(&mediaModels).Error; err != nil {
 					return err
 				}
@@ -176,7 +289,8 @@ func (s *GormPostStore) SavePost(ctx context.Context, post *Post) error {
 				// First ensure all tags exist
 				for _, tag := range post.Tags {
 					var tagModel TagModel
-					if err := tx.Where("name = ?", tag).FirstOrCreate(&tagModel, TagModel{Name: tag}).Error; err != nil {
+					name := tenantTag(post.TenantID, tag)
+					if err := tx.Where("name = ?", name).FirstOrCreate(&tagModel, TagModel{Name: name}).Error; err != nil {
 						return err
 					}
 				}
@@ -184,43 +298,56 @@ func (s *GormPostStore) SavePost(ctx context.Context, post *Post) error {
 				// Then associate tags with post
 				var tags []TagModel
 				for _, tag := range post.Tags {
-					tags = append(tags, TagModel{Name: tag})
+					tags = append(tags, TagModel{Name: tenantTag(post.TenantID, tag)})
 				}
 				if err := tx.Model(&postModel).Association("Tags").Replace(tags); err != nil {
 					return err
 				}
 			}
 
+			// Create audience entries
+			if err := replacePostAudience(tx, post); err != nil {
+				return err
+			}
+
 			// Create reaction counts if any
-			if len(post.Reactions) > 0 {
-				for reactionType, count := range post.Reactions {
-					// This is a placeholder - in a real system, you would need to create individual reactions
-					// Here we're just simulating the reaction counts for a new post
-					for i := 0; i < count; i++ {
-						reactionModel := ReactionModel{
-							PostID:       post.ID,
-							UserID:       fmt.Sprintf("system-%d", i), // Fixed: Proper conversion of int to string
-							ReactionType: uint8(reactionType),
-							CreatedAt:    time.Now(),
-						}
-						if err := tx.Create(&reactionModel).Error; err != nil {
-							return err
-						}
-					}
+			if reactionModels := placeholderReactionModels(post.ID, post.Reactions); len(reactionModels) > 0 {
+				if err := tx.Create(&reactionModels).Error; err != nil {
+					return err
 				}
 			}
 		} else {
-			// Update existing post
-			existingPost.UserID = post.UserID
-			existingPost.Content = post.Content
-			existingPost.UpdatedAt = post.UpdatedAt
-			existingPost.Visibility = post.Visibility
-			existingPost.Comments = post.Comments
-			existingPost.Shares = post.Shares
-
-			if err := tx.Save(&existingPost).Error; err != nil {
-				return err
+			// Update existing post only if nobody else has saved it since it was read
+			bands := simHashBands(post.SimHash)
+			result := tx.Model(&PostModel{}).
+				Where("id = ? AND version = ?", post.ID, post.Version).
+				Updates(map[string]interface{}{
+					"user_id":    post.UserID,
+					"content":    post.Content,
+					"updated_at": post.UpdatedAt,
+					"visibility": string(post.Visibility),
+					"comments":   post.Comments,
+					"shares":     post.Shares,
+					"version":    post.Version + 1,
+
+					"moderation_status": uint8(post.ModerationStatus),
+					"moderation_reason": post.ModerationReason,
+					"updated_by":        post.UpdatedBy,
+					"content_hash":      post.ContentHash,
+					"sim_hash":          int64(post.SimHash),
+					"sim_band0":         bands[0],
+					"sim_band1":         bands[1],
+					"sim_band2":         bands[2],
+					"sim_band3":         bands[3],
+					"mute_text":         muteText(post),
+				})
+			if result.Error != nil {
+				return result.Error
 			}
+			if result.RowsAffected == 0 {
+				return ErrVersionConflict
+			}
+			version = post.Version + 1
 
 			// Update media: delete existing and create new
 			if err := tx.Where("post_id = ?", post.ID).Delete(&MediaModel{}).Error; err != nil {
@@ -228,24 +355,7 @@ func (s *GormPostStore) SavePost(ctx context.Context, post *Post) error {
 			}
 
 			if len(post.Media) > 0 {
-				mediaModels := make([]MediaModel, len(post.Media))
-				for i, media := range post.Media {
-					mediaModels[i] = MediaModel{
-						ID:           media.ID,
-						PostID:       post.ID,
-						Type:         uint8(media.Type),
-						URL:          media.URL,
-						ThumbnailURL: media.ThumbnailURL,
-						Description:  media.Description,
-						Width:        media.Width,
-						Height:       media.Height,
-						Duration:     media.Duration,
-						FileSize:     media.FileSize,
-						FileName:     media.FileName,
-						MimeType:     media.MimeType,
-						CreatedAt:    media.CreatedAt,
-					}
-				}
+				mediaModels := toMediaModels(post.ID, post.Media)
 				if err := tx.Create(&mediaModels).Error; err != nil {
 					return err
 				}
@@ -256,7 +366,8 @@ func (s *GormPostStore) SavePost(ctx context.Context, post *Post) error {
 			for _, tag := range post.Tags {
 				// Ensure the tag exists
 				var tagModel TagModel
-				if err := tx.Where("name = ?", tag).FirstOrCreate(&tagModel, TagModel{Name: tag}).Error; err != nil {
+				name := tenantTag(post.TenantID, tag)
+				if err := tx.Where("name = ?", name).FirstOrCreate(&tagModel, TagModel{Name: name}).Error; err != nil {
 					return err
 				}
 				tags = append(tags, tagModel)
@@ -267,11 +378,44 @@ func (s *GormPostStore) SavePost(ctx context.Context, post *Post) error {
 				return err
 			}
 
+			// Replace the audience
+			if err := replacePostAudience(tx, post); err != nil {
+				return err
+			}
+
 			// Note: We don't update reactions here as they are managed separately via SaveReaction
 		}
 
-		return nil
+		// Record the change in the outbox
+		eventType, userID := EventPostUpdated, post.UpdatedBy
+		if isNew {
+			eventType = EventPostCreated
+		}
+		if userID == "" {
+			userID = post.UserID
+		}
+		saved := *post
+		saved.Version = version
+		event, err := postOutboxEvent(ctx, eventType, post.ID, userID, &saved)
+		if err != nil {
+			return err
+		}
+		return s.recordOutboxEvents(tx, event)
 	})
+	if err != nil {
+		return err
+	}
+
+	post.Version = version
+	return nil
+}
+
+// MigrateVersions gives posts stored without a version, such as rows written before the version column
+// had a default, the initial version 1. Tables created before versioning need no migration: the column
+// is added with that default. It returns the number of posts changed.
+func (s *GormPostStore) MigrateVersions(ctx context.Context) (int64, error) {
+	result := s.db.WithContext(ctx).Model(&PostModel{}).Where("version = 0").UpdateColumn("version", 1)
+	return result.RowsAffected, result.Error
 }
 
 // GetPost retrieves a post by its ID
@@ -279,8 +423,11 @@ func (s *GormPostStore) GetPost(ctx context.Context, postID string) (*Post, erro
 	// Get post with preloaded associations
 	var postModel PostModel
 	err := s.db.WithContext(ctx).
-		Preload("Media").
+		Preload("Media", preloadMedia).
 		Preload("Tags").
+		Preload("CoAuthors", preloadCoAuthors).
+		Preload("Audience", preloadAudience).
+		Scopes(scopeTenant(ctx)).
 		Where("id = ?", postID).
 		First(&postModel).Error
 
@@ -306,11 +453,11 @@ func (s *GormPostStore) DeletePost(ctx context.Context, postID string, userID st
 	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		// Check if post exists and belongs to the user
 		var postModel PostModel
-		if err := tx.Where("id = ? AND user_id = ?", postID, userID).First(&postModel).Error; err != nil {
+		if err := tx.Scopes(scopeTenant(ctx)).Where("id = ? AND user_id = ?", postID, userID).First(&postModel).Error; err != nil {
 			if errors.Is(err, gorm.ErrRecordNotFound) {
 				// Either post doesn't exist or user doesn't own it, check which one
 				var count int64
-				if err := tx.Model(&PostModel{}).Where("id = ?", postID).Count(&count).Error; err != nil {
+				if err := tx.Model(&PostModel{}).Scopes(scopeTenant(ctx)).Where("id = ?", postID).Count(&count).Error; err != nil {
 					return err
 				}
 
@@ -327,6 +474,26 @@ func (s *GormPostStore) DeletePost(ctx context.Context, postID string, userID st
 			return err
 		}
 
+		// Delete reports
+		if err := tx.Where("post_id = ?", postID).Delete(&ReportModel{}).Error; err != nil {
+			return err
+		}
+
+		// Delete feedback
+		if err := tx.Where("post_id = ?", postID).Delete(&PostFeedbackModel{}).Error; err != nil {
+			return err
+		}
+
+		// Delete co-authors
+		if err := tx.Where("post_id = ?", postID).Delete(&CoAuthorModel{}).Error; err != nil {
+			return err
+		}
+
+		// Delete the audience
+		if err := tx.Where("post_id = ?", postID).Delete(&PostAudienceModel{}).Error; err != nil {
+			return err
+		}
+
 		// Delete media
 		if err := tx.Where("post_id = ?", postID).Delete(&MediaModel{}).Error; err != nil {
 			return err
@@ -342,7 +509,12 @@ func (s *GormPostStore) DeletePost(ctx context.Context, postID string, userID st
 			return err
 		}
 
-		return nil
+		// Record the deletion in the outbox
+		event, err := postOutboxEvent(ctx, EventPostDeleted, postID, outboxActor(ctx, userID), nil)
+		if err != nil {
+			return err
+		}
+		return s.recordOutboxEvents(tx, event)
 	})
 }
 
@@ -350,29 +522,58 @@ func (s *GormPostStore) DeletePost(ctx context.Context, postID string, userID st
 func (s *GormPostStore) ListPosts(ctx context.Context, filter *PostFilter) ([]*Post, error) {
 	query := s.db.WithContext(ctx).
 		Model(&PostModel{}).
-		Preload("Media").
-		Preload("Tags")
+		Preload("Media", preloadMedia).
+		Preload("Tags").
+		Preload("CoAuthors", preloadCoAuthors).
+		Preload("Audience", preloadAudience).
+		Scopes(scopeTenant(ctx))
 
 	// Apply filters
 	if filter.UserID != "" {
-		query = query.Where("user_id = ?", filter.UserID)
+		// Include posts the user co-authors
+		query = query.Where("(user_id = ? OR id IN (SELECT post_id FROM co_author_models WHERE user_id = ? AND status = ?))",
+			filter.UserID, filter.UserID, uint8(CoAuthorAccepted))
+	}
+
+	if filter.GroupID != "" {
+		query = query.Where("group_id = ?", filter.GroupID)
 	}
 
 	if filter.Visibility != "" {
-		query = query.Where("visibility = ?", filter.Visibility)
+		query = query.Where("visibility = ?", string(filter.Visibility))
+	}
+
+	// Unlisted posts are only listed on their authors' profiles
+	if filter.UserID == "" && !seesAllAudiences(ctx) {
+		query = query.Where("visibility <> ?", string(VisibilityUnlisted))
 	}
 
 	if filter.TimeRange != nil {
 		query = query.Where("created_at BETWEEN ? AND ?", filter.TimeRange.Start, filter.TimeRange.End)
 	}
 
+	// Only approved posts unless asked otherwise
+	query = query.Where("moderation_status = ?", uint8(filter.moderationStatus()))
+
+	// Hide shadow-banned authors from other viewers and restricted posts from outside their audience
+	query = query.Scopes(scopeVisibleTo(ctx, "post_models.user_id", viewerFromContext(ctx)), scopeReadableBy(ctx))
+
+	// Tag and group feeds leave out posts muted by the viewer
+	if filter.honorsMutedTerms() {
+		muted, err := s.scopeMutedBy(ctx, viewerFromContext(ctx))
+		if err != nil {
+			return nil, err
+		}
+		query = query.Scopes(muted)
+	}
+
 	// Apply tag filters if any
 	if len(filter.Tags) > 0 {
 		// Find posts with ALL the specified tags
 		for _, tag := range filter.Tags {
 			// Create a subquery for each tag
 			// The join table is post_tags with post_model_id and tag_model_name columns
-			query = query.Where("id IN (SELECT post_model_id FROM post_tags WHERE tag_model_name = ?)", tag)
+			query = query.Where("id IN (SELECT post_model_id FROM post_tags WHERE tag_model_name = ?)", tenantTag(TenantFromContext(ctx), tag))
 		}
 	}
 
@@ -436,12 +637,20 @@ func (s *GormPostStore) GetUserFeed(ctx context.Context, userID string, limit, o
 	// In a real implementation, this would consider followed users, algorithms, etc.
 	// This simple version just returns recent public posts
 
+	muted, err := s.scopeMutedBy(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
 	// Get public posts sorted by creation time, newest first
 	query := s.db.WithContext(ctx).
 		Model(&PostModel{}).
-		Preload("Media").
+		Preload("Media", preloadMedia).
 		Preload("Tags").
-		Where("visibility = ?", "public").
+		Preload("CoAuthors", preloadCoAuthors).
+		Preload("Audience", preloadAudience).
+		Where("visibility IN ? AND moderation_status = ?", feedVisibilities, uint8(ModerationApproved)).
+		Scopes(scopeTenant(ctx), scopeFeedOf(ctx, "post_models.user_id", userID), scopeWithoutFeedback(userID), scopeViewableBy(userID), muted).
 		Order("created_at DESC")
 
 	// Apply pagination
@@ -475,13 +684,21 @@ func (s *GormPostStore) GetTrendingPosts(ctx context.Context, limit int) ([]*Pos
 	// In a real system, this would be more complex, possibly using a scoring algorithm
 	// For simplicity, we'll get posts with the most reactions + comments + shares
 
+	muted, err := s.scopeMutedBy(ctx, viewerFromContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+
 	// Query to get public posts with reaction, comment, and share counts
 	query := s.db.WithContext(ctx).
 		Model(&PostModel{}).
-		Preload("Media").
+		Preload("Media", preloadMedia).
 		Preload("Tags").
-		Joins("LEFT JOIN (SELECT post_id, COUNT(*) as reaction_count FROM reaction_models GROUP BY post_id) r ON post_models.id = r.post_id").
-		Where("visibility = ?", "public").
+		Preload("CoAuthors", preloadCoAuthors).
+		Preload("Audience", preloadAudience).
+		Joins("LEFT JOIN (SELECT post_id, COUNT(*) as reaction_count FROM reaction_models WHERE user_id NOT IN (SELECT user_id FROM shadow_ban_models WHERE tenant_id = ? AND user_id <> ?) GROUP BY post_id) r ON post_models.id = r.post_id", TenantFromContext(ctx), viewerFromContext(ctx)).
+		Where("visibility IN ? AND moderation_status = ?", feedVisibilities, uint8(ModerationApproved)).
+		Scopes(scopeTenant(ctx), scopeFeedOf(ctx, "post_models.user_id", viewerFromContext(ctx)), scopeWithoutFeedback(viewerFromContext(ctx)), scopeViewableBy(viewerFromContext(ctx)), muted).
 		Select("post_models.*, COALESCE(r.reaction_count, 0) + post_models.comments + post_models.shares as engagement").
 		Order("engagement DESC")
 
@@ -514,11 +731,11 @@ func (s *GormPostStore) GetTrendingPosts(ctx context.Context, limit int) ([]*Pos
 // SaveReaction saves a reaction to a post
 func (s *GormPostStore) SaveReaction(ctx context.Context, postID string, userID string, reactionType ReactionType) error {
 	// Check if post exists
-	var count int64
-	if err := s.db.WithContext(ctx).Model(&PostModel{}).Where("id = ?", postID).Count(&count).Error; err != nil {
+	var authorIDs []string
+	if err := s.db.WithContext(ctx).Model(&PostModel{}).Scopes(scopeTenant(ctx)).Where("id = ?", postID).Pluck("user_id", &authorIDs).Error; err != nil {
 		return err
 	}
-	if count == 0 {
+	if len(authorIDs) == 0 {
 		return ErrPostNotFound
 	}
 
@@ -527,6 +744,15 @@ func (s *GormPostStore) SaveReaction(ctx context.Context, postID string, userID
 		return ErrInvalidReaction
 	}
 
+	// Users blocked by the author cannot react
+	blocked, err := hasRelation(s.db.WithContext(ctx), TenantFromContext(ctx), authorIDs[0], userID, RelationBlock)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return ErrBlocked
+	}
+
 	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
 		// Check if user already has a reaction to this post
 		var existingReaction ReactionModel
@@ -544,17 +770,22 @@ func (s *GormPostStore) SaveReaction(ctx context.Context, postID string, userID
 			if err := tx.Create(&newReaction).Error; err != nil {
 				return err
 			}
+
+			return s.recordOutboxEvents(tx, reactionOutboxEvent(ctx, EventReactionAdded, postID, userID, reactionType, ReactionNone))
 		} else if err != nil {
 			return err
 		} else {
 			// Update existing reaction if different
 			if uint8(reactionType) != existingReaction.ReactionType {
+				previous := ReactionType(existingReaction.ReactionType)
 				existingReaction.ReactionType = uint8(reactionType)
 				existingReaction.CreatedAt = time.Now()
 
 				if err := tx.Save(&existingReaction).Error; err != nil {
 					return err
 				}
+
+				return s.recordOutboxEvents(tx, reactionOutboxEvent(ctx, EventReactionChanged, postID, userID, reactionType, previous))
 			}
 		}
 
@@ -566,24 +797,30 @@ func (s *GormPostStore) SaveReaction(ctx context.Context, postID string, userID
 func (s *GormPostStore) DeleteReaction(ctx context.Context, postID string, userID string, reactionType ReactionType) error {
 	// Check if post exists
 	var count int64
-	if err := s.db.WithContext(ctx).Model(&PostModel{}).Where("id = ?", postID).Count(&count).Error; err != nil {
+	if err := s.db.WithContext(ctx).Model(&PostModel{}).Scopes(scopeTenant(ctx)).Where("id = ?", postID).Count(&count).Error; err != nil {
 		return err
 	}
 	if count == 0 {
 		return ErrPostNotFound
 	}
 
-	// Delete the reaction
-	return s.db.WithContext(ctx).
-		Where("post_id = ? AND user_id = ? AND reaction_type = ?", postID, userID, uint8(reactionType)).
-		Delete(&ReactionModel{}).Error
+	// Delete the reaction, recording the removal in the outbox
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("post_id = ? AND user_id = ? AND reaction_type = ?", postID, userID, uint8(reactionType)).
+			Delete(&ReactionModel{})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		return s.recordOutboxEvents(tx, reactionOutboxEvent(ctx, EventReactionRemoved, postID, userID, reactionType, ReactionNone))
+	})
 }
 
 // GetUserReaction gets the current reaction of a user for a post
 func (s *GormPostStore) GetUserReaction(ctx context.Context, postID string, userID string) (*ReactionType, error) {
 	// Check if post exists
 	var postCount int64
-	if err := s.db.WithContext(ctx).Model(&PostModel{}).Where("id = ?", postID).Count(&postCount).Error; err != nil {
+	if err := s.db.WithContext(ctx).Model(&PostModel{}).Scopes(scopeTenant(ctx)).Where("id = ?", postID).Count(&postCount).Error; err != nil {
 		return nil, err
 	}
 	if postCount == 0 {
@@ -611,7 +848,7 @@ func (s *GormPostStore) GetUserReaction(ctx context.Context, postID string, user
 func (s *GormPostStore) GetReactedUsers(ctx context.Context, postID string, reactionType *ReactionType, limit, offset int) ([]string, error) {
 	// Check if post exists
 	var postCount int64
-	if err := s.db.WithContext(ctx).Model(&PostModel{}).Where("id = ?", postID).Count(&postCount).Error; err != nil {
+	if err := s.db.WithContext(ctx).Model(&PostModel{}).Scopes(scopeTenant(ctx)).Where("id = ?", postID).Count(&postCount).Error; err != nil {
 		return nil, err
 	}
 	if postCount == 0 {
@@ -621,7 +858,8 @@ func (s *GormPostStore) GetReactedUsers(ctx context.Context, postID string, reac
 	// Build query
 	query := s.db.WithContext(ctx).
 		Model(&ReactionModel{}).
-		Where("post_id = ?", postID)
+		Where("post_id = ?", postID).
+		Scopes(scopeVisibleTo(ctx, "reaction_models.user_id", viewerFromContext(ctx)))
 
 	// Filter by reaction type if specified
 	if reactionType != nil {
@@ -655,7 +893,7 @@ func (s *GormPostStore) GetReactedUsers(ctx context.Context, postID string, reac
 func (s *GormPostStore) GetReactionCounts(ctx context.Context, postID string) (map[ReactionType]int, error) {
 	// Check if post exists
 	var postCount int64
-	if err := s.db.WithContext(ctx).Model(&PostModel{}).Where("id = ?", postID).Count(&postCount).Error; err != nil {
+	if err := s.db.WithContext(ctx).Model(&PostModel{}).Scopes(scopeTenant(ctx)).Where("id = ?", postID).Count(&postCount).Error; err != nil {
 		return nil, err
 	}
 	if postCount == 0 {
@@ -673,6 +911,7 @@ func (s *GormPostStore) GetReactionCounts(ctx context.Context, postID string) (m
 		Model(&ReactionModel{}).
 		Select("reaction_type, count(*) as count").
 		Where("post_id = ?", postID).
+		Where("user_id NOT IN (SELECT user_id FROM shadow_ban_models WHERE tenant_id = ? AND user_id <> ?)", TenantFromContext(ctx), viewerFromContext(ctx)).
 		Group("reaction_type").
 		Find(&results).Error
 
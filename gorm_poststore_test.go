@@ -283,7 +283,7 @@ func TestGormPostStore_ListPosts(t *testing.T) {
 	posts, err = store.ListPosts(ctx, filter)
 	assert.NoError(t, err)
 	assert.Equal(t, 1, len(posts))
-	assert.Equal(t, "private", posts[0].Visibility)
+	assert.Equal(t, VisibilityPrivate, posts[0].Visibility)
 
 	// Test: list posts with time range filter
 	now := time.Now()
@@ -326,7 +326,7 @@ func TestGormPostStore_GetUserFeed(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, 5, len(posts))
 	for _, post := range posts {
-		assert.Equal(t, "public", post.Visibility)
+		assert.Equal(t, VisibilityPublic, post.Visibility)
 	}
 
 	// Test: pagination
@@ -598,3 +598,104 @@ func TestGormPostStore_GetReactionCounts(t *testing.T) {
 	assert.Equal(t, ErrPostNotFound, err)
 	assert.Nil(t, counts)
 }
+
+// TestGormPostStore_SavePostVersionConflict tests optimistic concurrency control in SavePost
+func TestGormPostStore_SavePostVersionConflict(t *testing.T) {
+	store, db := setupTestGormStore(t)
+	defer cleanupTestDB(t, db)
+	ctx := context.Background()
+
+	post := createTestGormPost("user1")
+	err := store.SavePost(ctx, post)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), post.Version)
+
+	// Two readers get the same version
+	first, err := store.GetPost(ctx, post.ID)
+	assert.NoError(t, err)
+	second, err := store.GetPost(ctx, post.ID)
+	assert.NoError(t, err)
+
+	// The first writer wins and bumps the version
+	first.Comments = 5
+	err = store.SavePost(ctx, first)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), first.Version)
+
+	// The second writer holds a stale version and must fail
+	second.Shares = 3
+	err = store.SavePost(ctx, second)
+	assert.Equal(t, ErrVersionConflict, err)
+
+	saved, err := store.GetPost(ctx, post.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, saved.Comments)
+	assert.Equal(t, 0, saved.Shares)
+	assert.Equal(t, int64(2), saved.Version)
+}
+
+// unversionedPostModel is the posts table as created before versioning
+type unversionedPostModel struct {
+	ID         string `gorm:"primaryKey"`
+	UserID     string
+	Content    string
+	Visibility string
+}
+
+// TableName returns the posts table
+func (unversionedPostModel) TableName() string {
+	return "post_models"
+}
+
+// TestGormPostStore_UpdateLegacyPost tests that posts stored before versioning can be updated
+func TestGormPostStore_UpdateLegacyPost(t *testing.T) {
+	dbName := fmt.Sprintf("file:memdb%d?mode=memory&cache=shared", time.Now().UnixNano())
+	db, err := gorm.Open(sqlite.Open(dbName), &gorm.Config{})
+	require.NoError(t, err)
+	defer cleanupTestDB(t, db)
+
+	require.NoError(t, db.AutoMigrate(&unversionedPostModel{}))
+	require.NoError(t, db.Create(&unversionedPostModel{ID: "legacy", UserID: "user1", Content: "Old", Visibility: "public"}).Error)
+
+	store, err := NewGormPostStore(db)
+	require.NoError(t, err)
+
+	ctx := context.Background()
+	post, err := store.GetPost(ctx, "legacy")
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), post.Version)
+
+	post.Content = "Edited"
+	require.NoError(t, store.SavePost(ctx, post))
+
+	updated, err := store.GetPost(ctx, "legacy")
+	require.NoError(t, err)
+	assert.Equal(t, "Edited", updated.Content)
+	assert.Equal(t, int64(2), updated.Version)
+}
+
+// TestGormPostStore_MigrateVersions tests giving posts stored without a version the initial version
+func TestGormPostStore_MigrateVersions(t *testing.T) {
+	store, db := setupTestGormStore(t)
+	defer cleanupTestDB(t, db)
+	ctx := context.Background()
+
+	post := createTestGormPost("user1")
+	require.NoError(t, store.SavePost(ctx, post))
+	other := createTestGormPost("user1")
+	require.NoError(t, store.SavePost(ctx, other))
+	require.NoError(t, db.Model(&PostModel{}).Where("id = ?", post.ID).UpdateColumn("version", 0).Error)
+
+	changed, err := store.MigrateVersions(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), changed)
+
+	migrated, err := store.GetPost(ctx, post.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), migrated.Version)
+
+	// Test: migrating again changes nothing
+	changed, err = store.MigrateVersions(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), changed)
+}
@@ -0,0 +1,135 @@
+// Package postflow provides functionality for managing user posts and feeds.
+package postflow
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// maxRateLimitAttempts bounds the retries of a contended bucket update
+const maxRateLimitAttempts = 10
+
+// ErrRateLimiterContention is returned when a bucket is updated concurrently too often to take a token
+var ErrRateLimiterContention = errors.New("rate limiter bucket contended")
+
+// RateLimitBucketModel is the GORM model for storing token buckets
+type RateLimitBucketModel struct {
+	BucketKey string `gorm:"primaryKey"`
+	Tokens    float64
+	UpdatedAt int64 `gorm:"autoUpdateTime:false"` // Unix nanoseconds of the last refill
+	FullAt    int64 `gorm:"index"`                // Unix nanoseconds when the bucket is refilled and no different from a new one
+}
+
+// GormRateLimiter keeps token buckets in the database so that several instances share them.
+// Buckets that have refilled completely are purged, so idle users take no rows.
+type GormRateLimiter struct {
+	db    *gorm.DB
+	swept time.Time // Last purge of refilled buckets by this instance
+	mutex sync.Mutex
+	now   func() time.Time
+}
+
+// NewGormRateLimiter creates a new GORM-backed rate limiter
+func NewGormRateLimiter(db *gorm.DB) (*GormRateLimiter, error) {
+	if err := db.AutoMigrate(&RateLimitBucketModel{}); err != nil {
+		return nil, err
+	}
+
+	return &GormRateLimiter{
+		db:  db,
+		now: time.Now,
+	}, nil
+}
+
+// Take takes a token from the bucket identified by key.
+// Concurrent updates are detected by comparing the refill time and retried with a short backoff;
+// ErrRateLimiterContention is returned if the bucket stays contended.
+func (l *GormRateLimiter) Take(ctx context.Context, key string, limit RateLimit) (time.Duration, error) {
+	db := l.db.WithContext(ctx)
+
+	// Purge refilled buckets at most once a minute; a failed purge is retried on the next sweep
+	l.mutex.Lock()
+	sweep := l.now().Sub(l.swept) > time.Minute
+	if sweep {
+		l.swept = l.now()
+	}
+	l.mutex.Unlock()
+	if sweep {
+		_, _ = l.PurgeRefilledBuckets(ctx)
+	}
+
+	for attempt := 0; attempt < maxRateLimitAttempts; attempt++ {
+		if attempt > 0 {
+			// Back off with jitter so that contending callers spread out
+			backoff := time.Duration(rand.Int63n(int64(attempt) * int64(time.Millisecond)))
+			select {
+			case <-ctx.Done():
+				return 0, ctx.Err()
+			case <-time.After(backoff):
+			}
+		}
+
+		now := l.now()
+
+		var bucket RateLimitBucketModel
+		err := db.Where("bucket_key = ?", key).First(&bucket).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Create a full bucket and take the first token
+			tokens := float64(limit.Limit - 1)
+			result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&RateLimitBucketModel{
+				BucketKey: key,
+				Tokens:    tokens,
+				UpdatedAt: now.UnixNano(),
+				FullAt:    fullAt(tokens, now, limit).UnixNano(),
+			})
+			if result.Error != nil {
+				return 0, result.Error
+			}
+			if result.RowsAffected == 1 {
+				return 0, nil
+			}
+			continue
+		}
+		if err != nil {
+			return 0, err
+		}
+
+		tokens, retryAfter := takeToken(bucket.Tokens, time.Unix(0, bucket.UpdatedAt), now, limit)
+
+		updated := now.UnixNano()
+		if updated <= bucket.UpdatedAt {
+			updated = bucket.UpdatedAt + 1
+		}
+
+		result := db.Model(&RateLimitBucketModel{}).
+			Where("bucket_key = ? AND updated_at = ?", key, bucket.UpdatedAt).
+			Updates(map[string]interface{}{
+				"tokens":     tokens,
+				"updated_at": updated,
+				"full_at":    fullAt(tokens, now, limit).UnixNano(),
+			})
+		if result.Error != nil {
+			return 0, result.Error
+		}
+		if result.RowsAffected == 1 {
+			return retryAfter, nil
+		}
+	}
+
+	return 0, ErrRateLimiterContention
+}
+
+// PurgeRefilledBuckets deletes buckets that have refilled completely and returns how many were removed.
+// Take purges them at most once a minute; a purged bucket is recreated full when it is next used.
+func (l *GormRateLimiter) PurgeRefilledBuckets(ctx context.Context) (int64, error) {
+	result := l.db.WithContext(ctx).
+		Where("full_at <= ?", l.now().UnixNano()).
+		Delete(&RateLimitBucketModel{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,113 @@
+package postflow
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGormRateLimiter tests token buckets shared through the database
+func TestGormRateLimiter(t *testing.T) {
+	store, db := setupTestGormStore(t)
+	defer cleanupTestDB(t, db)
+	ctx := context.Background()
+
+	limiter, err := NewGormRateLimiter(db)
+	require.NoError(t, err)
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter.now = func() time.Time { return now }
+	limit := RateLimit{Limit: 2, Period: time.Minute}
+
+	// Test: the bucket starts full and empties
+	for i := 0; i < 2; i++ {
+		retryAfter, err := limiter.Take(ctx, "react:user1", limit)
+		assert.NoError(t, err)
+		assert.Equal(t, time.Duration(0), retryAfter)
+	}
+	retryAfter, err := limiter.Take(ctx, "react:user1", limit)
+	assert.NoError(t, err)
+	assert.Equal(t, 30*time.Second, retryAfter)
+
+	// Test: a second limiter instance shares the bucket
+	other, err := NewGormRateLimiter(db)
+	require.NoError(t, err)
+	other.now = limiter.now
+	retryAfter, err = other.Take(ctx, "react:user1", limit)
+	assert.NoError(t, err)
+	assert.Equal(t, 30*time.Second, retryAfter)
+
+	now = now.Add(30 * time.Second)
+	retryAfter, err = other.Take(ctx, "react:user1", limit)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), retryAfter)
+
+	// Test: the manager rate limits with the shared buckets
+	pm := NewPostManager(store, WithRateLimiter(limiter, map[Action]RateLimit{
+		ActionCreate: {Limit: 1, Period: time.Hour},
+	}))
+	_, err = pm.CreatePost(ctx, createTestGormPost("user1"))
+	assert.NoError(t, err)
+	_, err = pm.CreatePost(ctx, createTestGormPost("user1"))
+	assert.True(t, errors.Is(err, ErrRateLimited))
+
+	// Test: refilled buckets are purged, others are kept
+	now = now.Add(time.Minute)
+	purged, err := limiter.PurgeRefilledBuckets(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), purged)
+	var keys []string
+	require.NoError(t, db.Model(&RateLimitBucketModel{}).Order("bucket_key").Pluck("bucket_key", &keys).Error)
+	assert.Equal(t, []string{"create:user1"}, keys)
+
+	// Test: a purged bucket starts full again
+	retryAfter, err = limiter.Take(ctx, "react:user1", limit)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), retryAfter)
+
+	// Test: Take purges refilled buckets at most once a minute
+	now = now.Add(2 * time.Hour)
+	_, err = limiter.Take(ctx, "react:user3", limit)
+	require.NoError(t, err)
+	keys = nil
+	require.NoError(t, db.Model(&RateLimitBucketModel{}).Order("bucket_key").Pluck("bucket_key", &keys).Error)
+	assert.Equal(t, []string{"react:user3"}, keys)
+}
+
+// TestGormRateLimiterConcurrent tests that concurrent callers never exceed the limit
+func TestGormRateLimiterConcurrent(t *testing.T) {
+	_, db := setupTestGormStore(t)
+	defer cleanupTestDB(t, db)
+	ctx := context.Background()
+
+	limiter, err := NewGormRateLimiter(db)
+	require.NoError(t, err)
+	limit := RateLimit{Limit: 5, Period: time.Hour}
+
+	var wg sync.WaitGroup
+	var mutex sync.Mutex
+	allowed := 0
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			retryAfter, err := limiter.Take(ctx, "create:user1", limit)
+			if err != nil {
+				assert.ErrorIs(t, err, ErrRateLimiterContention)
+			}
+			if err == nil && retryAfter == 0 {
+				mutex.Lock()
+				allowed++
+				mutex.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.LessOrEqual(t, allowed, 5)
+	assert.Greater(t, allowed, 0)
+}
@@ -0,0 +1,105 @@
+// Package postflow provides functionality for managing user posts and feeds.
+package postflow
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UserRelationModel is the GORM model for storing relations between users
+type UserRelationModel struct {
+	TenantID  string `gorm:"primaryKey;default:''"`
+	UserID    string `gorm:"primaryKey"`
+	TargetID  string `gorm:"primaryKey;index"`
+	Kind      uint8  `gorm:"primaryKey"`
+	CreatedAt time.Time
+}
+
+// scopeVisibleTo restricts a query to rows whose user column may be shown to the viewer:
+// the user is not shadow-banned in the tenant in the context and neither user blocked the other there.
+// column is the qualified user ID column, e.g. post_models.user_id.
+func scopeVisibleTo(ctx context.Context, column string, viewer string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		tenant := TenantFromContext(ctx)
+		return db.
+			Where(column+" NOT IN (SELECT user_id FROM shadow_ban_models WHERE tenant_id = ? AND user_id <> ?)", tenant, viewer).
+			Where(column+" NOT IN (SELECT user_id FROM user_relation_models WHERE tenant_id = ? AND target_id = ? AND kind = ?)", tenant, viewer, uint8(RelationBlock)).
+			Where(column+" NOT IN (SELECT target_id FROM user_relation_models WHERE tenant_id = ? AND user_id = ? AND kind = ?)", tenant, viewer, uint8(RelationBlock))
+	}
+}
+
+// scopeFeedOf restricts a query to posts that may appear in the viewer's feed and trending posts
+func scopeFeedOf(ctx context.Context, column string, viewer string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.
+			Scopes(scopeVisibleTo(ctx, column, viewer)).
+			Where(column+" NOT IN (SELECT target_id FROM user_relation_models WHERE tenant_id = ? AND user_id = ? AND kind = ?)", TenantFromContext(ctx), viewer, uint8(RelationMute))
+	}
+}
+
+// SaveRelation creates a relation, keeping the existing one if present
+func (s *GormPostStore) SaveRelation(ctx context.Context, relation *UserRelation) error {
+	return s.db.WithContext(ctx).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&UserRelationModel{
+			TenantID:  TenantFromContext(ctx),
+			UserID:    relation.UserID,
+			TargetID:  relation.TargetID,
+			Kind:      uint8(relation.Kind),
+			CreatedAt: relation.CreatedAt,
+		}).Error
+}
+
+// DeleteRelation removes a relation
+func (s *GormPostStore) DeleteRelation(ctx context.Context, userID, targetID string, kind RelationKind) error {
+	return s.db.WithContext(ctx).
+		Where("tenant_id = ? AND user_id = ? AND target_id = ? AND kind = ?", TenantFromContext(ctx), userID, targetID, uint8(kind)).
+		Delete(&UserRelationModel{}).Error
+}
+
+// HasRelation reports whether the relation exists
+func (s *GormPostStore) HasRelation(ctx context.Context, userID, targetID string, kind RelationKind) (bool, error) {
+	return hasRelation(s.db.WithContext(ctx), TenantFromContext(ctx), userID, targetID, kind)
+}
+
+// hasRelation reports whether the relation exists in the tenant, using the given connection or transaction
+func hasRelation(db *gorm.DB, tenantID, userID, targetID string, kind RelationKind) (bool, error) {
+	var count int64
+	err := db.Model(&UserRelationModel{}).
+		Where("tenant_id = ? AND user_id = ? AND target_id = ? AND kind = ?", tenantID, userID, targetID, uint8(kind)).
+		Count(&count).Error
+
+	return count > 0, err
+}
+
+// ListRelations returns the relations of a kind from a user, most recent first
+func (s *GormPostStore) ListRelations(ctx context.Context, userID string, kind RelationKind, limit, offset int) ([]*UserRelation, error) {
+	query := s.db.WithContext(ctx).
+		Where("tenant_id = ? AND user_id = ? AND kind = ?", TenantFromContext(ctx), userID, uint8(kind)).
+		Order("created_at DESC, target_id")
+
+	// Apply pagination
+	if limit > 0 {
+		query = query.Limit(limit).Offset(offset)
+	}
+
+	var models []UserRelationModel
+	if err := query.Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	relations := make([]*UserRelation, len(models))
+	for i, model := range models {
+		relations[i] = &UserRelation{
+			UserID:    model.UserID,
+			TargetID:  model.TargetID,
+			Kind:      RelationKind(model.Kind),
+			CreatedAt: model.CreatedAt,
+		}
+	}
+
+	return relations, nil
+}
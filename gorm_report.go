@@ -0,0 +1,168 @@
+// Package postflow provides functionality for managing user posts and feeds.
+package postflow
+
+import (
+	"context"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ReportModel is the GORM model for storing user reports of posts
+type ReportModel struct {
+	PostID     string `gorm:"primaryKey;index"`
+	ReporterID string `gorm:"primaryKey"`
+	Reason     uint8
+	Severity   int
+	Note       string
+	Reviewed   bool `gorm:"not null;default:false"`
+	CreatedAt  time.Time
+}
+
+// Convert ReportModel to Report
+func (m *ReportModel) toReport() *Report {
+	return &Report{
+		PostID:     m.PostID,
+		ReporterID: m.ReporterID,
+		Reason:     ReportReason(m.Reason),
+		Note:       m.Note,
+		Reviewed:   m.Reviewed,
+		CreatedAt:  m.CreatedAt,
+	}
+}
+
+// SaveReport records a report.
+// The post row is locked while the report is saved and counted, so that concurrent reports
+// of a post see consecutive counts.
+func (s *GormPostStore) SaveReport(ctx context.Context, report *Report) (*ReportSummary, error) {
+	var summary *ReportSummary
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// Check if post exists
+		var postIDs []string
+		if err := tx.Model(&PostModel{}).
+			Clauses(clause.Locking{Strength: "UPDATE"}).
+			Scopes(scopeTenant(ctx)).
+			Where("id = ?", report.PostID).
+			Pluck("id", &postIDs).Error; err != nil {
+			return err
+		}
+		if len(postIDs) == 0 {
+			return ErrPostNotFound
+		}
+
+		// The primary key deduplicates reports per reporter
+		model := ReportModel{
+			PostID:     report.PostID,
+			ReporterID: report.ReporterID,
+			Reason:     uint8(report.Reason),
+			Severity:   report.Reason.Severity(),
+			Note:       report.Note,
+			CreatedAt:  report.CreatedAt,
+		}
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&model)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrAlreadyReported
+		}
+
+		summaries, err := summarizeReportModels(tx, []string{report.PostID})
+		if err != nil {
+			return err
+		}
+		summary = summaries[report.PostID]
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return summary, nil
+}
+
+// GetReports returns the reports of a post, most recent first
+func (s *GormPostStore) GetReports(ctx context.Context, postID string) ([]*Report, error) {
+	// Check if post exists
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&PostModel{}).Scopes(scopeTenant(ctx)).Where("id = ?", postID).Count(&count).Error; err != nil {
+		return nil, err
+	}
+	if count == 0 {
+		return nil, ErrPostNotFound
+	}
+
+	var models []ReportModel
+	if err := s.db.WithContext(ctx).Where("post_id = ?", postID).Order("created_at DESC").Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	reports := make([]*Report, len(models))
+	for i := range models {
+		reports[i] = models[i].toReport()
+	}
+
+	return reports, nil
+}
+
+// ListReportedPosts returns report summaries, most severe first
+func (s *GormPostStore) ListReportedPosts(ctx context.Context, limit, offset int) ([]*ReportSummary, error) {
+	// Rank posts in the database, then load the reports of the requested page
+	query := s.db.WithContext(ctx).
+		Model(&ReportModel{}).
+		Scopes(scopeTenantPosts(ctx, "report_models.post_id")).
+		Select("post_id").
+		Group("post_id").
+		Order("SUM(severity) DESC, COUNT(*) DESC, MAX(created_at) DESC, post_id ASC")
+
+	if limit > 0 {
+		query = query.Limit(limit).Offset(offset)
+	}
+
+	var postIDs []string
+	if err := query.Pluck("post_id", &postIDs).Error; err != nil {
+		return nil, err
+	}
+
+	summaries, err := summarizeReportModels(s.db.WithContext(ctx), postIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*ReportSummary, 0, len(postIDs))
+	for _, postID := range postIDs {
+		result = append(result, summaries[postID])
+	}
+
+	return result, nil
+}
+
+// MarkReportsReviewed marks the reports of a post as reviewed
+func (s *GormPostStore) MarkReportsReviewed(ctx context.Context, postID string) error {
+	return s.db.WithContext(ctx).
+		Model(&ReportModel{}).
+		Scopes(scopeTenantPosts(ctx, "report_models.post_id")).
+		Where("post_id = ? AND reviewed = ?", postID, false).
+		Update("reviewed", true).Error
+}
+
+// summarizeReportModels loads and aggregates the reports of the given posts
+func summarizeReportModels(db *gorm.DB, postIDs []string) (map[string]*ReportSummary, error) {
+	var models []ReportModel
+	if err := db.Where("post_id IN ?", postIDs).Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	reports := make(map[string][]*Report, len(postIDs))
+	for i := range models {
+		reports[models[i].PostID] = append(reports[models[i].PostID], models[i].toReport())
+	}
+
+	summaries := make(map[string]*ReportSummary, len(postIDs))
+	for _, postID := range postIDs {
+		summaries[postID] = summarizeReports(postID, reports[postID])
+	}
+
+	return summaries, nil
+}
@@ -0,0 +1,218 @@
+package postflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGormPostStore_SaveReport tests recording and deduplicating reports
+func TestGormPostStore_SaveReport(t *testing.T) {
+	store, db := setupTestGormStore(t)
+	defer cleanupTestDB(t, db)
+	pm := NewPostManager(store, WithReportThreshold(2))
+	ctx := context.Background()
+	modCtx := WithActor(ctx, Actor{UserID: "mod1", Role: RoleModerator})
+
+	postID, err := pm.CreatePost(ctx, createTestGormPost("user1"))
+	require.NoError(t, err)
+
+	// Test: report and deduplicate
+	err = pm.ReportPost(ctx, postID, "reporter1", ReportSpam, "link farm")
+	assert.NoError(t, err)
+	err = pm.ReportPost(ctx, postID, "reporter1", ReportSpam, "")
+	assert.Equal(t, ErrAlreadyReported, err)
+	err = pm.ReportPost(ctx, "nonexistent-id", "reporter1", ReportSpam, "")
+	assert.Equal(t, ErrPostNotFound, err)
+
+	// Test: crossing the threshold hides the post
+	err = pm.ReportPost(ctx, postID, "reporter2", ReportNudity, "")
+	assert.NoError(t, err)
+	post, err := store.GetPost(ctx, postID)
+	assert.NoError(t, err)
+	assert.Equal(t, ModerationPending, post.ModerationStatus)
+
+	reports, err := pm.GetPostReports(modCtx, postID)
+	assert.NoError(t, err)
+	require.Equal(t, 2, len(reports))
+	assert.Equal(t, "reporter2", reports[0].ReporterID)
+	assert.Equal(t, "link farm", reports[1].Note)
+
+	// Test: reports are only shown to moderators
+	_, err = pm.GetPostReports(WithActor(ctx, Actor{UserID: "reporter1"}), postID)
+	assert.Equal(t, ErrPermissionDenied, err)
+
+	// Test: held posts can no longer be reported by other users
+	err = pm.ReportPost(ctx, postID, "reporter3", ReportSpam, "")
+	assert.Equal(t, ErrPermissionDenied, err)
+
+	// Test: a post approved after review is not hidden again by further reports
+	require.NoError(t, pm.ApprovePost(modCtx, postID))
+	err = pm.ReportPost(ctx, postID, "reporter3", ReportSpam, "")
+	assert.NoError(t, err)
+	post, err = store.GetPost(ctx, postID)
+	assert.NoError(t, err)
+	assert.Equal(t, ModerationApproved, post.ModerationStatus)
+
+	reports, err = pm.GetPostReports(modCtx, postID)
+	assert.NoError(t, err)
+	require.Equal(t, 3, len(reports))
+	assert.False(t, reports[0].Reviewed)
+	assert.True(t, reports[1].Reviewed)
+	assert.True(t, reports[2].Reviewed)
+}
+
+// TestGormPostStore_ReportPostRetriesHide tests that a hide that failed at the threshold is retried by later reports
+func TestGormPostStore_ReportPostRetriesHide(t *testing.T) {
+	store, db := setupTestGormStore(t)
+	defer cleanupTestDB(t, db)
+	pm := NewPostManager(store, WithReportThreshold(1))
+	ctx := context.Background()
+
+	postID, err := pm.CreatePost(ctx, createTestGormPost("user1"))
+	require.NoError(t, err)
+
+	// The hide at the threshold is vetoed once
+	errVetoed := errors.New("vetoed")
+	vetoed := false
+	pm.OnBefore(EventPostUpdated, func(ctx context.Context, event *Event) error {
+		if !vetoed {
+			vetoed = true
+			return errVetoed
+		}
+		return nil
+	})
+	var hiddenBy []Actor
+	pm.OnAfter(EventPostUpdated, func(ctx context.Context, event *Event) {
+		hiddenBy = append(hiddenBy, event.Actor)
+	})
+
+	assert.Equal(t, errVetoed, pm.ReportPost(ctx, postID, "reporter1", ReportSpam, ""))
+	post, err := store.GetPost(ctx, postID)
+	require.NoError(t, err)
+	assert.Equal(t, ModerationApproved, post.ModerationStatus)
+
+	// Test: the next report hides the post, as the system
+	require.NoError(t, pm.ReportPost(ctx, postID, "reporter2", ReportSpam, ""))
+	post, err = store.GetPost(ctx, postID)
+	require.NoError(t, err)
+	assert.Equal(t, ModerationPending, post.ModerationStatus)
+	assert.Equal(t, []Actor{SystemActor}, hiddenBy)
+}
+
+// TestGormPostStore_ListReportedPosts tests the admin listing of reported posts
+func TestGormPostStore_ListReportedPosts(t *testing.T) {
+	store, db := setupTestGormStore(t)
+	defer cleanupTestDB(t, db)
+	pm := NewPostManager(store)
+	ctx := context.Background()
+	modCtx := WithActor(ctx, Actor{UserID: "mod1", Role: RoleModerator})
+
+	spamID, err := pm.CreatePost(ctx, createTestGormPost("user1"))
+	require.NoError(t, err)
+	hateID, err := pm.CreatePost(ctx, createTestGormPost("user1"))
+	require.NoError(t, err)
+	_, err = pm.CreatePost(ctx, createTestGormPost("user1"))
+	require.NoError(t, err)
+
+	// Three spam reports weigh less than one hate speech report
+	for i := 0; i < 3; i++ {
+		require.NoError(t, pm.ReportPost(ctx, spamID, fmt.Sprintf("reporter%d", i), ReportSpam, ""))
+	}
+	require.NoError(t, pm.ReportPost(ctx, hateID, "reporter1", ReportHateSpeech, "slurs"))
+
+	_, err = pm.ListReportedPosts(ctx, 10, 0)
+	assert.Equal(t, ErrPermissionDenied, err)
+
+	summaries, err := pm.ListReportedPosts(modCtx, 10, 0)
+	assert.NoError(t, err)
+	require.Equal(t, 2, len(summaries))
+	assert.Equal(t, hateID, summaries[0].PostID)
+	assert.Equal(t, 5, summaries[0].Severity)
+	assert.Equal(t, spamID, summaries[1].PostID)
+	assert.Equal(t, 3, summaries[1].Count)
+	assert.Equal(t, 3, summaries[1].Reasons[ReportSpam])
+
+	// Test: reports are removed with the post
+	require.NoError(t, pm.DeletePost(ctx, hateID, "user1"))
+	summaries, err = pm.ListReportedPosts(modCtx, 10, 0)
+	assert.NoError(t, err)
+	require.Equal(t, 1, len(summaries))
+	assert.Equal(t, spamID, summaries[0].PostID)
+}
+
+// TestGormPostStore_ListReportedPostsByRecency tests that equally reported posts are paged most recently reported first
+func TestGormPostStore_ListReportedPostsByRecency(t *testing.T) {
+	store, db := setupTestGormStore(t)
+	defer cleanupTestDB(t, db)
+	ctx := context.Background()
+
+	var postIDs []string
+	for i := 0; i < 4; i++ {
+		post := createTestGormPost("user1")
+		require.NoError(t, store.SavePost(ctx, post))
+		postIDs = append(postIDs, post.ID)
+	}
+
+	// Equal severity and count; only the time of the last report differs
+	reportedAt := time.Now().Add(-time.Hour)
+	for i, postID := range postIDs {
+		_, err := store.SaveReport(ctx, &Report{
+			PostID:     postID,
+			ReporterID: "reporter1",
+			Reason:     ReportSpam,
+			CreatedAt:  reportedAt.Add(time.Duration(i) * time.Minute),
+		})
+		require.NoError(t, err)
+	}
+
+	// Test: every page is ordered in the database, not re-sorted after paging
+	for page := 0; page < len(postIDs); page++ {
+		summaries, err := store.ListReportedPosts(ctx, 1, page)
+		require.NoError(t, err)
+		require.Equal(t, 1, len(summaries))
+		assert.Equal(t, postIDs[len(postIDs)-1-page], summaries[0].PostID, page)
+	}
+}
+
+// TestGormPostStore_ConcurrentReports tests that concurrent reports cannot skip the threshold
+func TestGormPostStore_ConcurrentReports(t *testing.T) {
+	store, db := setupTestGormStore(t)
+	defer cleanupTestDB(t, db)
+	pm := NewPostManager(store, WithReportThreshold(5))
+	ctx := context.Background()
+
+	postID, err := pm.CreatePost(ctx, createTestGormPost("user1"))
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+	errs := make([]error, 10)
+	for i := range errs {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			errs[i] = pm.ReportPost(ctx, postID, fmt.Sprintf("reporter%d", i), ReportSpam, "")
+		}(i)
+	}
+	wg.Wait()
+
+	// Reports arriving after the post was held are refused, as the reporters can no longer read it
+	reported := 0
+	for _, err := range errs {
+		if err != ErrPermissionDenied {
+			require.NoError(t, err)
+			reported++
+		}
+	}
+	assert.GreaterOrEqual(t, reported, 5)
+	post, err := store.GetPost(ctx, postID)
+	require.NoError(t, err)
+	assert.Equal(t, ModerationPending, post.ModerationStatus)
+	assert.Equal(t, "hidden after 5 reports", post.ModerationReason)
+}
@@ -0,0 +1,86 @@
+// Package postflow provides functionality for managing user posts and feeds.
+package postflow
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ShadowBanModel is the GORM model for storing shadow bans
+type ShadowBanModel struct {
+	TenantID  string `gorm:"primaryKey;default:''"`
+	UserID    string `gorm:"primaryKey"`
+	Reason    string
+	BannedBy  string
+	CreatedAt time.Time `gorm:"index"`
+}
+
+// toShadowBan converts the model to a ShadowBan
+func (m *ShadowBanModel) toShadowBan() *ShadowBan {
+	return &ShadowBan{
+		UserID:    m.UserID,
+		TenantID:  m.TenantID,
+		Reason:    m.Reason,
+		BannedBy:  m.BannedBy,
+		CreatedAt: m.CreatedAt,
+	}
+}
+
+// SaveShadowBan shadow-bans a user, replacing any existing ban
+func (s *GormPostStore) SaveShadowBan(ctx context.Context, ban *ShadowBan) error {
+	ban.TenantID = TenantFromContext(ctx)
+	return s.db.WithContext(ctx).
+		Clauses(clause.OnConflict{UpdateAll: true}).
+		Create(&ShadowBanModel{
+			TenantID:  ban.TenantID,
+			UserID:    ban.UserID,
+			Reason:    ban.Reason,
+			BannedBy:  ban.BannedBy,
+			CreatedAt: ban.CreatedAt,
+		}).Error
+}
+
+// DeleteShadowBan lifts the shadow ban of a user
+func (s *GormPostStore) DeleteShadowBan(ctx context.Context, userID string) error {
+	return s.db.WithContext(ctx).Where("tenant_id = ? AND user_id = ?", TenantFromContext(ctx), userID).Delete(&ShadowBanModel{}).Error
+}
+
+// GetShadowBan returns the shadow ban of a user, or nil if the user is not banned
+func (s *GormPostStore) GetShadowBan(ctx context.Context, userID string) (*ShadowBan, error) {
+	var model ShadowBanModel
+	err := s.db.WithContext(ctx).Where("tenant_id = ? AND user_id = ?", TenantFromContext(ctx), userID).First(&model).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return model.toShadowBan(), nil
+}
+
+// ListShadowBans returns shadow bans, most recent first
+func (s *GormPostStore) ListShadowBans(ctx context.Context, limit, offset int) ([]*ShadowBan, error) {
+	query := s.db.WithContext(ctx).Where("tenant_id = ?", TenantFromContext(ctx)).Order("created_at DESC")
+
+	// Apply pagination
+	if limit > 0 {
+		query = query.Limit(limit).Offset(offset)
+	}
+
+	var models []ShadowBanModel
+	if err := query.Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	bans := make([]*ShadowBan, len(models))
+	for i := range models {
+		bans[i] = models[i].toShadowBan()
+	}
+
+	return bans, nil
+}
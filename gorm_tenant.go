@@ -0,0 +1,23 @@
+// Package postflow provides functionality for managing user posts and feeds.
+package postflow
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// scopeTenant restricts a query on posts to the tenant in the context
+func scopeTenant(ctx context.Context) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("post_models.tenant_id = ?", TenantFromContext(ctx))
+	}
+}
+
+// scopeTenantPosts restricts a query to rows whose post column refers to a post of the tenant in the context.
+// column is the qualified post ID column, e.g. report_models.post_id.
+func scopeTenantPosts(ctx context.Context, column string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(column+" IN (SELECT id FROM post_models WHERE tenant_id = ?)", TenantFromContext(ctx))
+	}
+}
@@ -0,0 +1,104 @@
+// Package postflow provides functionality for managing user posts and feeds.
+package postflow
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// feedVisibilities are the visibilities of posts that may appear in feeds and trending posts
+var feedVisibilities = []string{string(VisibilityPublic), string(VisibilityFollowers), string(VisibilityCustom)}
+
+// scopeFollowersOf restricts a query to posts that are not followers-only or whose author
+// or an accepted co-author is followed by the viewer in the post's tenant
+func scopeFollowersOf(viewer string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(
+			"(post_models.visibility <> ? OR post_models.user_id = ?"+
+				" OR post_models.id IN (SELECT post_id FROM co_author_models WHERE user_id = ? AND status = ?)"+
+				" OR post_models.user_id IN (SELECT target_id FROM user_relation_models WHERE tenant_id = post_models.tenant_id AND user_id = ? AND kind = ?)"+
+				" OR post_models.id IN (SELECT post_id FROM co_author_models WHERE status = ? AND user_id IN"+
+				" (SELECT target_id FROM user_relation_models WHERE tenant_id = post_models.tenant_id AND user_id = ? AND kind = ?)))",
+			string(VisibilityFollowers), viewer,
+			viewer, uint8(CoAuthorAccepted),
+			viewer, uint8(RelationFollow),
+			uint8(CoAuthorAccepted), viewer, uint8(RelationFollow),
+		)
+	}
+}
+
+// scopeViewableBy restricts a query to posts whose followers-only or custom audience includes the viewer
+// and that are outside private groups the viewer is not a member of
+func scopeViewableBy(viewer string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Scopes(scopeFollowersOf(viewer), scopeInAudience(viewer), scopeInGroupOf(viewer))
+	}
+}
+
+// scopeAuthoredIfPrivate restricts a query to posts that are not private or friends-only,
+// or that the viewer authors or co-authors
+func scopeAuthoredIfPrivate(viewer string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(
+			"(post_models.visibility NOT IN ? OR post_models.user_id = ?"+
+				" OR post_models.id IN (SELECT post_id FROM co_author_models WHERE user_id = ? AND status = ?))",
+			[]string{string(VisibilityPrivate), string(VisibilityFriends)}, viewer,
+			viewer, uint8(CoAuthorAccepted),
+		)
+	}
+}
+
+// scopeReadableBy applies scopeViewableBy to the actor in the context, unless the actor sees all audiences.
+// Private and friends-only posts of others are left out when the context carries an actor.
+func scopeReadableBy(ctx context.Context) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if seesAllAudiences(ctx) {
+			return db
+		}
+		viewer := viewerFromContext(ctx)
+		if _, ok := ActorFromContext(ctx); ok {
+			db = db.Scopes(scopeAuthoredIfPrivate(viewer))
+		}
+		return db.Scopes(scopeViewableBy(viewer))
+	}
+}
+
+// MigrateVisibility converts stored visibility values written before Visibility was validated.
+// Values are normalized with ParseVisibility; values it does not recognize are set to fallback,
+// which must be a valid visibility. It returns the number of posts changed.
+func (s *GormPostStore) MigrateVisibility(ctx context.Context, fallback Visibility) (int64, error) {
+	if !fallback.Valid() {
+		return 0, ErrInvalidVisibility
+	}
+
+	var values []string
+	if err := s.db.WithContext(ctx).Model(&PostModel{}).Distinct("visibility").Pluck("visibility", &values).Error; err != nil {
+		return 0, err
+	}
+
+	var changed int64
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, value := range values {
+			visibility, err := ParseVisibility(value)
+			if err != nil {
+				visibility = fallback
+			}
+			if string(visibility) == value {
+				continue
+			}
+
+			result := tx.Model(&PostModel{}).Where("visibility = ?", value).UpdateColumn("visibility", string(visibility))
+			if result.Error != nil {
+				return result.Error
+			}
+			changed += result.RowsAffected
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	return changed, nil
+}
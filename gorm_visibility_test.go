@@ -0,0 +1,177 @@
+package postflow
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGormPostStore_Visibility tests visibility modes with the GORM store
+func TestGormPostStore_Visibility(t *testing.T) {
+	store, db := setupTestGormStore(t)
+	defer cleanupTestDB(t, db)
+	pm := NewPostManager(store)
+	ctx := context.Background()
+	as := func(userID string) context.Context {
+		return WithActor(ctx, Actor{UserID: userID})
+	}
+
+	// Test: unknown visibilities are rejected, empty ones default to public
+	typo := createTestGormPost("alice")
+	typo.Visibility = "pubic"
+	_, err := pm.CreatePost(ctx, typo)
+	assert.True(t, errors.Is(err, ErrInvalidVisibility))
+
+	defaulted := createTestGormPost("alice")
+	defaulted.Visibility = ""
+	defaultedID, err := pm.CreatePost(ctx, defaulted)
+	require.NoError(t, err)
+	assert.Equal(t, VisibilityPublic, defaulted.Visibility)
+	require.NoError(t, pm.DeletePost(ctx, defaultedID, "alice"))
+
+	// Test: unlisted posts are reachable by ID and profile only
+	unlisted := createTestGormPost("alice")
+	unlisted.Visibility = VisibilityUnlisted
+	unlistedID, err := pm.CreatePost(ctx, unlisted)
+	require.NoError(t, err)
+
+	_, err = pm.GetPost(as("carol"), unlistedID)
+	assert.NoError(t, err)
+	require.NoError(t, pm.AddReaction(ctx, unlistedID, "carol", ReactionLike))
+
+	profile, err := pm.ListPosts(as("carol"), &PostFilter{UserID: "alice"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{unlistedID}, postIDs(profile))
+
+	all, err := pm.ListPosts(as("carol"), &PostFilter{})
+	assert.NoError(t, err)
+	assert.Empty(t, all)
+	all, err = pm.ListPosts(as("carol"), &PostFilter{Visibility: VisibilityUnlisted})
+	assert.NoError(t, err)
+	assert.Empty(t, all)
+
+	feed, err := pm.GetUserFeed(ctx, "carol", 10, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, feed)
+	trending, err := pm.GetTrendingPosts(as("carol"), 10)
+	assert.NoError(t, err)
+	assert.Empty(t, trending)
+	require.NoError(t, pm.DeletePost(ctx, unlistedID, "alice"))
+
+	// Test: followers-only posts reach followers of the author and co-authors
+	followersOnly := createTestGormPost("alice")
+	followersOnly.Visibility = VisibilityFollowers
+	postID, err := pm.CreatePost(ctx, followersOnly)
+	require.NoError(t, err)
+	require.NoError(t, pm.InviteCoAuthor(ctx, postID, "alice", "partner"))
+	require.NoError(t, pm.AcceptCoAuthorInvite(ctx, postID, "partner"))
+
+	require.NoError(t, pm.Follow(ctx, "bob", "alice"))
+	require.NoError(t, pm.Follow(ctx, "dave", "partner"))
+	assert.Equal(t, ErrInvalidRelation, pm.Follow(ctx, "bob", "bob"))
+
+	following, err := pm.ListFollowing(ctx, "bob", 10, 0)
+	assert.NoError(t, err)
+	require.Equal(t, 1, len(following))
+	assert.Equal(t, "alice", following[0].TargetID)
+
+	for viewer, allowed := range map[string]bool{"alice": true, "partner": true, "bob": true, "dave": true, "carol": false, "": false} {
+		readCtx := ctx
+		if viewer != "" {
+			readCtx = as(viewer)
+		}
+
+		listed, err := pm.ListPosts(readCtx, &PostFilter{UserID: "alice"})
+		assert.NoError(t, err)
+		feed, err := pm.GetUserFeed(ctx, viewer, 10, 0)
+		assert.NoError(t, err)
+		trending, err := pm.GetTrendingPosts(readCtx, 10)
+		assert.NoError(t, err)
+
+		if allowed {
+			assert.Equal(t, []string{postID}, postIDs(listed), viewer)
+			assert.Equal(t, []string{postID}, postIDs(feed), viewer)
+			assert.Equal(t, []string{postID}, postIDs(trending), viewer)
+		} else {
+			assert.Empty(t, listed, viewer)
+			assert.Empty(t, feed, viewer)
+			assert.Empty(t, trending, viewer)
+		}
+	}
+
+	_, err = pm.GetPost(as("carol"), postID)
+	assert.Equal(t, ErrPermissionDenied, err)
+	assert.Equal(t, ErrPermissionDenied, pm.AddReaction(ctx, postID, "carol", ReactionLike))
+	require.NoError(t, pm.AddReaction(ctx, postID, "dave", ReactionLike))
+
+	// Test: unfollowing and blocking revoke access
+	require.NoError(t, pm.Unfollow(ctx, "dave", "partner"))
+	_, err = pm.GetPost(as("dave"), postID)
+	assert.Equal(t, ErrPermissionDenied, err)
+
+	require.NoError(t, pm.Block(ctx, "alice", "bob"))
+	following, err = pm.ListFollowing(ctx, "bob", 10, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, following)
+	assert.Equal(t, ErrBlocked, pm.Follow(ctx, "bob", "alice"))
+
+	// Test: updates and patches are validated, keeping the visibility when none is given
+	post, err := pm.GetPost(ctx, postID)
+	require.NoError(t, err)
+	post.Visibility = "everyone"
+	assert.True(t, errors.Is(pm.UpdatePost(ctx, post), ErrInvalidVisibility))
+
+	post.Visibility = ""
+	require.NoError(t, pm.UpdatePost(ctx, post))
+	assert.Equal(t, VisibilityFollowers, post.Visibility)
+
+	_, err = pm.PatchPost(ctx, postID, "alice", &PostPatch{Fields: PatchVisibility, Visibility: "pubic"})
+	assert.True(t, errors.Is(err, ErrInvalidVisibility))
+	patched, err := pm.PatchPost(ctx, postID, "alice", &PostPatch{Fields: PatchVisibility, Visibility: VisibilityPublic})
+	require.NoError(t, err)
+	assert.Equal(t, VisibilityPublic, patched.Visibility)
+	_, err = pm.GetPost(as("carol"), postID)
+	assert.NoError(t, err)
+}
+
+// TestGormPostStore_MigrateVisibility tests normalizing legacy visibility values
+func TestGormPostStore_MigrateVisibility(t *testing.T) {
+	store, db := setupTestGormStore(t)
+	defer cleanupTestDB(t, db)
+
+	ctx := context.Background()
+	legacy := map[string]string{"Public": "", "pubic": "", "friends": "", "": "", " private": ""}
+	for value := range legacy {
+		post := createTestGormPost("user1")
+		require.NoError(t, store.SavePost(ctx, post))
+		require.NoError(t, db.Model(&PostModel{}).Where("id = ?", post.ID).UpdateColumn("visibility", value).Error)
+		legacy[value] = post.ID
+	}
+
+	_, err := store.MigrateVisibility(ctx, "pubic")
+	assert.ErrorIs(t, err, ErrInvalidVisibility)
+
+	changed, err := store.MigrateVisibility(ctx, VisibilityPrivate)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), changed)
+
+	for value, expected := range map[string]Visibility{
+		"Public":   VisibilityPublic,
+		"pubic":    VisibilityPrivate,
+		"friends":  VisibilityFriends,
+		"":         VisibilityPublic,
+		" private": VisibilityPrivate,
+	} {
+		post, err := store.GetPost(ctx, legacy[value])
+		require.NoError(t, err)
+		assert.Equal(t, expected, post.Visibility, value)
+	}
+
+	// Test: migrating again changes nothing
+	changed, err = store.MigrateVisibility(ctx, VisibilityPrivate)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), changed)
+}
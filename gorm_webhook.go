@@ -0,0 +1,243 @@
+// Package postflow provides functionality for managing user posts and feeds.
+package postflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// WebhookEndpointModel is the GORM model for storing webhook endpoints
+type WebhookEndpointModel struct {
+	ID           string `gorm:"primaryKey"`
+	TenantID     string `gorm:"index"`
+	URL          string
+	Events       string // Comma-separated event types; empty means all
+	Secret       string
+	Disabled     bool
+	FailureCount int
+	CreatedAt    time.Time
+	DisabledAt   *time.Time
+}
+
+// WebhookDeliveryModel is the GORM model for storing webhook delivery attempts
+type WebhookDeliveryModel struct {
+	ID         string `gorm:"primaryKey"`
+	EndpointID string `gorm:"index:idx_webhook_delivery_endpoint_created"`
+	EventID    string `gorm:"index"`
+	EventType  uint8
+	Attempt    int
+	StatusCode int
+	Error      string
+	Succeeded  bool
+	Duration   int64     // Nanoseconds
+	CreatedAt  time.Time `gorm:"index:idx_webhook_delivery_endpoint_created"`
+}
+
+// toWebhookEndpointModel converts a WebhookEndpoint to its model
+func toWebhookEndpointModel(endpoint *WebhookEndpoint) *WebhookEndpointModel {
+	events := make([]string, len(endpoint.Events))
+	for i, eventType := range endpoint.Events {
+		events[i] = strconv.Itoa(int(eventType))
+	}
+
+	model := &WebhookEndpointModel{
+		ID:           endpoint.ID,
+		TenantID:     endpoint.TenantID,
+		URL:          endpoint.URL,
+		Events:       strings.Join(events, ","),
+		Secret:       endpoint.Secret,
+		Disabled:     endpoint.Disabled,
+		FailureCount: endpoint.FailureCount,
+		CreatedAt:    endpoint.CreatedAt,
+	}
+	if !endpoint.DisabledAt.IsZero() {
+		disabledAt := endpoint.DisabledAt
+		model.DisabledAt = &disabledAt
+	}
+	return model
+}
+
+// toWebhookEndpoint converts the model to a WebhookEndpoint
+func (m *WebhookEndpointModel) toWebhookEndpoint() *WebhookEndpoint {
+	endpoint := &WebhookEndpoint{
+		ID:           m.ID,
+		TenantID:     m.TenantID,
+		URL:          m.URL,
+		Secret:       m.Secret,
+		Disabled:     m.Disabled,
+		FailureCount: m.FailureCount,
+		CreatedAt:    m.CreatedAt,
+	}
+	if m.Events != "" {
+		for _, event := range strings.Split(m.Events, ",") {
+			eventType, err := strconv.Atoi(event)
+			if err == nil {
+				endpoint.Events = append(endpoint.Events, EventType(eventType))
+			}
+		}
+	}
+	if m.DisabledAt != nil {
+		endpoint.DisabledAt = *m.DisabledAt
+	}
+	return endpoint
+}
+
+// toWebhookDelivery converts the model to a WebhookDelivery
+func (m *WebhookDeliveryModel) toWebhookDelivery() *WebhookDelivery {
+	return &WebhookDelivery{
+		ID:         m.ID,
+		EndpointID: m.EndpointID,
+		EventID:    m.EventID,
+		EventType:  EventType(m.EventType),
+		Attempt:    m.Attempt,
+		StatusCode: m.StatusCode,
+		Error:      m.Error,
+		Succeeded:  m.Succeeded,
+		Duration:   time.Duration(m.Duration),
+		CreatedAt:  m.CreatedAt,
+	}
+}
+
+// SaveWebhook creates or updates a webhook endpoint
+func (s *GormPostStore) SaveWebhook(ctx context.Context, endpoint *WebhookEndpoint) error {
+	endpoint.TenantID = TenantFromContext(ctx)
+
+	var count int64
+	if err := s.db.WithContext(ctx).Model(&WebhookEndpointModel{}).Where("id = ? AND tenant_id <> ?", endpoint.ID, endpoint.TenantID).Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return fmt.Errorf("%w: ID %s is taken", ErrInvalidWebhook, endpoint.ID)
+	}
+
+	return s.db.WithContext(ctx).Save(toWebhookEndpointModel(endpoint)).Error
+}
+
+// GetWebhook returns a webhook endpoint
+func (s *GormPostStore) GetWebhook(ctx context.Context, endpointID string) (*WebhookEndpoint, error) {
+	var model WebhookEndpointModel
+	err := s.db.WithContext(ctx).Where("id = ? AND tenant_id = ?", endpointID, TenantFromContext(ctx)).First(&model).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrWebhookNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return model.toWebhookEndpoint(), nil
+}
+
+// DeleteWebhook removes a webhook endpoint and its delivery attempts
+func (s *GormPostStore) DeleteWebhook(ctx context.Context, endpointID string) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("id = ? AND tenant_id = ?", endpointID, TenantFromContext(ctx)).Delete(&WebhookEndpointModel{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrWebhookNotFound
+		}
+
+		return tx.Where("endpoint_id = ?", endpointID).Delete(&WebhookDeliveryModel{}).Error
+	})
+}
+
+// ListWebhooks returns the webhook endpoints, oldest first
+func (s *GormPostStore) ListWebhooks(ctx context.Context) ([]*WebhookEndpoint, error) {
+	var models []WebhookEndpointModel
+	err := s.db.WithContext(ctx).
+		Where("tenant_id = ?", TenantFromContext(ctx)).
+		Order("created_at, id").
+		Find(&models).Error
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints := make([]*WebhookEndpoint, len(models))
+	for i := range models {
+		endpoints[i] = models[i].toWebhookEndpoint()
+	}
+
+	return endpoints, nil
+}
+
+// RecordWebhookOutcome resets or increments the failure count of an endpoint, disabling it at disableAfter failures
+func (s *GormPostStore) RecordWebhookOutcome(ctx context.Context, endpointID string, delivered bool, disableAfter int) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		query := tx.Model(&WebhookEndpointModel{}).Where("id = ? AND tenant_id = ?", endpointID, TenantFromContext(ctx))
+
+		var result *gorm.DB
+		if delivered {
+			result = query.Update("failure_count", 0)
+		} else {
+			result = query.Update("failure_count", gorm.Expr("failure_count + 1"))
+		}
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrWebhookNotFound
+		}
+		if delivered || disableAfter <= 0 {
+			return nil
+		}
+
+		return tx.Model(&WebhookEndpointModel{}).
+			Where("id = ? AND disabled = ? AND failure_count >= ?", endpointID, false, disableAfter).
+			Updates(map[string]interface{}{"disabled": true, "disabled_at": time.Now()}).Error
+	})
+}
+
+// SaveWebhookDelivery records a delivery attempt
+func (s *GormPostStore) SaveWebhookDelivery(ctx context.Context, delivery *WebhookDelivery) error {
+	if _, err := s.GetWebhook(ctx, delivery.EndpointID); err != nil {
+		return err
+	}
+
+	return s.db.WithContext(ctx).Create(&WebhookDeliveryModel{
+		ID:         delivery.ID,
+		EndpointID: delivery.EndpointID,
+		EventID:    delivery.EventID,
+		EventType:  uint8(delivery.EventType),
+		Attempt:    delivery.Attempt,
+		StatusCode: delivery.StatusCode,
+		Error:      delivery.Error,
+		Succeeded:  delivery.Succeeded,
+		Duration:   int64(delivery.Duration),
+		CreatedAt:  delivery.CreatedAt,
+	}).Error
+}
+
+// ListWebhookDeliveries returns the delivery attempts of an endpoint, most recent first
+func (s *GormPostStore) ListWebhookDeliveries(ctx context.Context, endpointID string, limit, offset int) ([]*WebhookDelivery, error) {
+	if _, err := s.GetWebhook(ctx, endpointID); err != nil {
+		return nil, err
+	}
+
+	query := s.db.WithContext(ctx).
+		Where("endpoint_id = ?", endpointID).
+		Order("created_at DESC, attempt DESC, id")
+
+	// Apply pagination
+	if limit > 0 {
+		query = query.Limit(limit).Offset(offset)
+	}
+
+	var models []WebhookDeliveryModel
+	if err := query.Find(&models).Error; err != nil {
+		return nil, err
+	}
+
+	deliveries := make([]*WebhookDelivery, len(models))
+	for i := range models {
+		deliveries[i] = models[i].toWebhookDelivery()
+	}
+
+	return deliveries, nil
+}
@@ -0,0 +1,244 @@
+package postflow
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGormPostStore_Webhooks tests webhook registration and signed delivery with the GORM store
+func TestGormPostStore_Webhooks(t *testing.T) {
+	store, db := setupTestGormStore(t)
+	defer cleanupTestDB(t, db)
+	ctx := WithTenant(context.Background(), "partner")
+	admin := Actor{UserID: "admin", Role: RoleAdmin}
+	adminCtx := WithActor(ctx, admin)
+
+	dispatcher := NewWebhookDispatcher(store, nil)
+	dispatcher.MaxAttempts = 3
+	dispatcher.InitialBackoff = time.Millisecond
+	dispatcher.DisableAfter = 2
+	defer dispatcher.Close(context.Background())
+	pm := NewPostManager(store, WithWebhooks(dispatcher))
+
+	// Test: endpoints need an absolute HTTP URL and known event types
+	_, err := pm.RegisterWebhook(adminCtx, &WebhookEndpoint{URL: "ftp://example.com"})
+	assert.True(t, errors.Is(err, ErrInvalidWebhook))
+	_, err = pm.RegisterWebhook(adminCtx, &WebhookEndpoint{URL: "/hooks"})
+	assert.True(t, errors.Is(err, ErrInvalidWebhook))
+	_, err = pm.RegisterWebhook(adminCtx, &WebhookEndpoint{URL: "https://example.com", Events: []EventType{42}})
+	assert.True(t, errors.Is(err, ErrInvalidWebhook))
+
+	// Test: only moderators and administrators manage endpoints
+	_, err = pm.RegisterWebhook(ctx, &WebhookEndpoint{URL: "https://example.com"})
+	assert.Equal(t, ErrPermissionDenied, err)
+	_, err = pm.RegisterWebhook(WithActor(ctx, Actor{UserID: "alice"}), &WebhookEndpoint{URL: "https://example.com"})
+	assert.Equal(t, ErrPermissionDenied, err)
+	_, err = pm.ListWebhooks(WithActor(ctx, Actor{UserID: "alice"}))
+	assert.Equal(t, ErrPermissionDenied, err)
+
+	// Test: secrets are generated when missing
+	generated := &WebhookEndpoint{URL: "https://example.com/hooks", Events: []EventType{EventPostDeleted}}
+	generatedID, err := pm.RegisterWebhook(adminCtx, generated)
+	require.NoError(t, err)
+	assert.Len(t, generated.Secret, 64)
+	require.NoError(t, pm.DeleteWebhook(adminCtx, generatedID))
+	assert.Equal(t, ErrWebhookNotFound, pm.DeleteWebhook(adminCtx, generatedID))
+
+	// Test: signed payloads are delivered for the events in the filter
+	receiver := newWebhookReceiver(t, "s3cret")
+	endpointID, err := pm.RegisterWebhook(adminCtx, &WebhookEndpoint{
+		URL:    receiver.URL,
+		Secret: "s3cret",
+		Events: []EventType{EventPostCreated, EventReactionAdded},
+	})
+	require.NoError(t, err)
+
+	post := createTestGormPost("alice")
+	postID, err := pm.CreatePost(ctx, post)
+	require.NoError(t, err)
+	dispatcher.Wait()
+
+	post.Content = "Edited"
+	require.NoError(t, pm.UpdatePost(ctx, post))
+	require.NoError(t, pm.AddReaction(ctx, postID, "bob", ReactionLike))
+	dispatcher.Wait()
+
+	receiver.mutex.Lock()
+	require.Len(t, receiver.payloads, 2)
+	created, reacted := receiver.payloads[0], receiver.payloads[1]
+	receiver.mutex.Unlock()
+	assert.Equal(t, "post.created", created.Type)
+	assert.Equal(t, "partner", created.TenantID)
+	assert.Equal(t, postID, created.PostID)
+	assert.Equal(t, "alice", created.UserID)
+	require.NotNil(t, created.Post)
+	assert.Equal(t, postID, created.Post.ID)
+	assert.Equal(t, "reaction.added", reacted.Type)
+	assert.Equal(t, "bob", reacted.UserID)
+	assert.Equal(t, ReactionLike, reacted.Reaction)
+	assert.NotEqual(t, created.ID, reacted.ID)
+	receiver.types()
+
+	deliveries, err := pm.ListWebhookDeliveries(adminCtx, endpointID, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 2)
+	assert.Equal(t, EventReactionAdded, deliveries[0].EventType)
+	assert.True(t, deliveries[0].Succeeded)
+	assert.Equal(t, http.StatusOK, deliveries[0].StatusCode)
+	assert.Equal(t, 1, deliveries[0].Attempt)
+
+	// Test: payloads leave out posts that are not approved and public
+	private := createTestGormPost("alice")
+	private.Visibility = VisibilityPrivate
+	privateID, err := pm.CreatePost(ctx, private)
+	require.NoError(t, err)
+	dispatcher.Wait()
+
+	receiver.mutex.Lock()
+	require.Len(t, receiver.payloads, 1)
+	assert.Equal(t, privateID, receiver.payloads[0].PostID)
+	assert.Nil(t, receiver.payloads[0].Post)
+	receiver.mutex.Unlock()
+	receiver.types()
+
+	// Test: endpoints of other tenants receive nothing
+	_, err = pm.CreatePost(context.Background(), createTestGormPost("alice"))
+	require.NoError(t, err)
+	dispatcher.Wait()
+	assert.Empty(t, receiver.types())
+
+	endpoints, err := pm.ListWebhooks(WithActor(context.Background(), admin))
+	require.NoError(t, err)
+	assert.Empty(t, endpoints)
+	_, err = pm.GetWebhook(WithActor(context.Background(), admin), endpointID)
+	assert.Equal(t, ErrWebhookNotFound, err)
+
+	// Test: server errors are retried with backoff until delivered
+	receiver.respond(http.StatusServiceUnavailable)
+	_, err = pm.CreatePost(ctx, createTestGormPost("alice"))
+	require.NoError(t, err)
+	dispatcher.Wait()
+	assert.Equal(t, []string{"post.created", "post.created"}, receiver.types())
+
+	deliveries, err = pm.ListWebhookDeliveries(adminCtx, endpointID, 2, 0)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 2)
+	assert.Equal(t, 2, deliveries[0].Attempt)
+	assert.True(t, deliveries[0].Succeeded)
+	assert.Equal(t, 1, deliveries[1].Attempt)
+	assert.False(t, deliveries[1].Succeeded)
+	assert.Equal(t, http.StatusServiceUnavailable, deliveries[1].StatusCode)
+	assert.Equal(t, deliveries[0].EventID, deliveries[1].EventID)
+
+	// Test: client errors are not retried and count as failures
+	receiver.respond(http.StatusBadRequest)
+	_, err = pm.CreatePost(ctx, createTestGormPost("alice"))
+	require.NoError(t, err)
+	dispatcher.Wait()
+	assert.Len(t, receiver.types(), 1)
+
+	endpoint, err := pm.GetWebhook(adminCtx, endpointID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, endpoint.FailureCount)
+	assert.False(t, endpoint.Disabled)
+
+	// Test: endpoints are disabled after repeated undeliverable events
+	receiver.respond(http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError)
+	_, err = pm.CreatePost(ctx, createTestGormPost("alice"))
+	require.NoError(t, err)
+	dispatcher.Wait()
+	assert.Len(t, receiver.types(), 3)
+
+	endpoint, err = pm.GetWebhook(adminCtx, endpointID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, endpoint.FailureCount)
+	assert.True(t, endpoint.Disabled)
+	assert.False(t, endpoint.DisabledAt.IsZero())
+
+	_, err = pm.CreatePost(ctx, createTestGormPost("alice"))
+	require.NoError(t, err)
+	dispatcher.Wait()
+	assert.Empty(t, receiver.types())
+
+	// Test: re-enabled endpoints receive events again
+	require.NoError(t, pm.EnableWebhook(adminCtx, endpointID))
+	endpoint, err = pm.GetWebhook(adminCtx, endpointID)
+	require.NoError(t, err)
+	assert.False(t, endpoint.Disabled)
+	assert.Zero(t, endpoint.FailureCount)
+
+	require.NoError(t, pm.AddReaction(ctx, postID, "carol", ReactionLove))
+	dispatcher.Wait()
+	assert.Equal(t, []string{"reaction.added"}, receiver.types())
+
+	// Test: requests signed with another secret are rejected by the receiver
+	receiver.mutex.Lock()
+	receiver.secret = "rotated"
+	receiver.mutex.Unlock()
+	require.NoError(t, dispatcher.Deliver(ctx, newEvent(EventPostCreated, post, Actor{UserID: "alice"})))
+	receiver.mutex.Lock()
+	assert.Equal(t, 1, receiver.invalid)
+	receiver.mutex.Unlock()
+
+	// Test: deleting an endpoint removes its delivery attempts
+	require.NoError(t, pm.DeleteWebhook(adminCtx, endpointID))
+	_, err = pm.ListWebhookDeliveries(adminCtx, endpointID, 10, 0)
+	assert.Equal(t, ErrWebhookNotFound, err)
+}
+
+// TestGormPostStore_WebhookOutbox tests delivering outbox events to webhooks through a relay
+func TestGormPostStore_WebhookOutbox(t *testing.T) {
+	_, db := setupTestGormStore(t)
+	defer cleanupTestDB(t, db)
+
+	store, err := NewGormPostStore(db, WithOutbox())
+	require.NoError(t, err)
+	pm := NewPostManager(store)
+	ctx := WithTenant(context.Background(), "partner")
+	adminCtx := WithActor(ctx, Actor{UserID: "admin", Role: RoleAdmin})
+
+	receiver := newWebhookReceiver(t, "s3cret")
+	_, err = pm.RegisterWebhook(adminCtx, &WebhookEndpoint{URL: receiver.URL, Secret: "s3cret"})
+	require.NoError(t, err)
+
+	postID, err := pm.CreatePost(ctx, createTestGormPost("alice"))
+	require.NoError(t, err)
+	require.NoError(t, pm.AddReaction(ctx, postID, "bob", ReactionWow))
+	_, err = pm.CreatePost(context.Background(), createTestGormPost("carol"))
+	require.NoError(t, err)
+
+	// Test: events are delivered to the endpoints of their tenant
+	dispatcher := NewWebhookDispatcher(store, nil)
+	delivered, err := NewOutboxRelay(store, dispatcher).RelayOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 3, delivered)
+
+	receiver.mutex.Lock()
+	payloads := receiver.payloads
+	receiver.mutex.Unlock()
+	require.Len(t, payloads, 2)
+	assert.Equal(t, "post.created", payloads[0].Type)
+	assert.Equal(t, postID, payloads[0].PostID)
+	assert.Equal(t, "reaction.added", payloads[1].Type)
+	assert.Equal(t, ReactionWow, payloads[1].Reaction)
+
+	// Test: event IDs are stable across redeliveries
+	events, err := store.ListOutboxEvents(ctx, 0)
+	require.NoError(t, err)
+	assert.Empty(t, events)
+	assert.Regexp(t, `^outbox-\d+$`, payloads[0].ID)
+
+	// Test: undeliverable events do not block the relay
+	receiver.respond(http.StatusGone)
+	_, err = pm.CreatePost(ctx, createTestGormPost("alice"))
+	require.NoError(t, err)
+	delivered, err = NewOutboxRelay(store, dispatcher).RelayOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, delivered)
+}
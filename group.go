@@ -0,0 +1,597 @@
+// Package postflow provides functionality for managing user posts and feeds.
+package postflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrGroupNotFound is returned when a group does not exist
+	ErrGroupNotFound = errors.New("group not found")
+
+	// ErrNotGroupMember is returned when a user acts in a group they are not a member of
+	ErrNotGroupMember = errors.New("not a group member")
+
+	// ErrInvalidGroup is returned when a group or a change to its members is invalid
+	ErrInvalidGroup = errors.New("invalid group")
+)
+
+// GroupRole represents the privileges of a member within a group
+type GroupRole uint8
+
+const (
+	GroupRoleMember    GroupRole = 1 // May post in the group
+	GroupRoleModerator GroupRole = 2 // May also add and remove members and remove posts
+	GroupRoleOwner     GroupRole = 3 // May also change the roles of members
+)
+
+// String returns the name of the group role
+func (r GroupRole) String() string {
+	switch r {
+	case GroupRoleMember:
+		return "member"
+	case GroupRoleModerator:
+		return "moderator"
+	case GroupRoleOwner:
+		return "owner"
+	default:
+		return "unknown"
+	}
+}
+
+// Group is a community space whose members post to a shared feed.
+// Posts of private groups are only shown to members; anyone may join and read public groups.
+type Group struct {
+	ID          string    `json:"id"`
+	TenantID    string    `json:"tenant_id,omitempty"` // Set by the store from the context
+	OwnerID     string    `json:"owner_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description,omitempty"`
+	Private     bool      `json:"private"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// GroupMember is the membership of a user in a group
+type GroupMember struct {
+	GroupID  string    `json:"group_id"`
+	UserID   string    `json:"user_id"`
+	Role     GroupRole `json:"role"`
+	JoinedAt time.Time `json:"joined_at"`
+}
+
+// GroupStore defines the interface for stores that support community groups.
+// Groups belong to the tenant in the context. Stores only return posts of private groups
+// to their members and authors, except to moderators and administrators in the context,
+// and filter ListPosts by PostFilter.GroupID.
+type GroupStore interface {
+	// SaveGroup creates a group; ErrInvalidGroup is returned if its ID is taken
+	SaveGroup(ctx context.Context, group *Group) error
+
+	// GetGroup returns a group
+	GetGroup(ctx context.Context, groupID string) (*Group, error)
+
+	// SaveGroupMember adds a member to a group or changes their role
+	SaveGroupMember(ctx context.Context, member *GroupMember) error
+
+	// GetGroupMember returns the membership of a user, or ErrNotGroupMember
+	GetGroupMember(ctx context.Context, groupID, userID string) (*GroupMember, error)
+
+	// DeleteGroupMember removes a member from a group
+	DeleteGroupMember(ctx context.Context, groupID, userID string) error
+
+	// ListGroupMembers returns the members of a group, highest role first, then in order of joining
+	ListGroupMembers(ctx context.Context, groupID string, limit, offset int) ([]*GroupMember, error)
+
+	// ListUserGroups returns the groups a user is a member of, ordered by name
+	ListUserGroups(ctx context.Context, userID string) ([]*Group, error)
+}
+
+// CreateGroup creates a group owned by group.OwnerID, who becomes its first member.
+// The group is given a new ID; an ID set by the caller is replaced.
+func (m *PostManagerImpl) CreateGroup(ctx context.Context, group *Group) (string, error) {
+	store, ok := m.store.(GroupStore)
+	if !ok {
+		return "", ErrNotSupported
+	}
+
+	group.Name = strings.TrimSpace(group.Name)
+	if group.OwnerID == "" || group.Name == "" {
+		return "", fmt.Errorf("%w: owner and name are required", ErrInvalidGroup)
+	}
+
+	group.ID = uuid.New().String()
+	group.CreatedAt = time.Now()
+
+	if err := store.SaveGroup(ctx, group); err != nil {
+		return "", err
+	}
+
+	err := store.SaveGroupMember(ctx, &GroupMember{
+		GroupID:  group.ID,
+		UserID:   group.OwnerID,
+		Role:     GroupRoleOwner,
+		JoinedAt: group.CreatedAt,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return group.ID, nil
+}
+
+// GetGroup returns a group
+func (m *PostManagerImpl) GetGroup(ctx context.Context, groupID string) (*Group, error) {
+	store, ok := m.store.(GroupStore)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	return store.GetGroup(ctx, groupID)
+}
+
+// ListUserGroups returns the groups a user is a member of, ordered by name
+func (m *PostManagerImpl) ListUserGroups(ctx context.Context, userID string) ([]*Group, error) {
+	store, ok := m.store.(GroupStore)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	return store.ListUserGroups(ctx, userID)
+}
+
+// JoinGroup makes the user a member of a public group.
+// Private groups only gain members through AddGroupMember.
+func (m *PostManagerImpl) JoinGroup(ctx context.Context, groupID, userID string) error {
+	store, ok := m.store.(GroupStore)
+	if !ok {
+		return ErrNotSupported
+	}
+
+	group, err := store.GetGroup(ctx, groupID)
+	if err != nil {
+		return err
+	}
+	if group.Private {
+		return ErrPermissionDenied
+	}
+
+	return m.addGroupMember(ctx, store, groupID, userID)
+}
+
+// AddGroupMember adds memberID to a group. Only group moderators and the owner may add members.
+func (m *PostManagerImpl) AddGroupMember(ctx context.Context, groupID, userID, memberID string) error {
+	store, _, err := m.managedGroup(ctx, groupID, userID, GroupRoleModerator)
+	if err != nil {
+		return err
+	}
+
+	return m.addGroupMember(ctx, store, groupID, memberID)
+}
+
+// LeaveGroup removes the user from a group. The owner cannot leave their group.
+func (m *PostManagerImpl) LeaveGroup(ctx context.Context, groupID, userID string) error {
+	store, ok := m.store.(GroupStore)
+	if !ok {
+		return ErrNotSupported
+	}
+
+	member, err := store.GetGroupMember(ctx, groupID, userID)
+	if err != nil {
+		return err
+	}
+	if member.Role == GroupRoleOwner {
+		return fmt.Errorf("%w: the owner cannot leave the group", ErrInvalidGroup)
+	}
+
+	return store.DeleteGroupMember(ctx, groupID, userID)
+}
+
+// RemoveGroupMember removes memberID from a group.
+// Moderators may remove members and only the owner may remove moderators; the owner cannot be removed.
+func (m *PostManagerImpl) RemoveGroupMember(ctx context.Context, groupID, userID, memberID string) error {
+	store, role, err := m.managedGroup(ctx, groupID, userID, GroupRoleModerator)
+	if err != nil {
+		return err
+	}
+
+	member, err := store.GetGroupMember(ctx, groupID, memberID)
+	if err != nil {
+		return err
+	}
+	if member.Role >= role {
+		return ErrPermissionDenied
+	}
+
+	return store.DeleteGroupMember(ctx, groupID, memberID)
+}
+
+// SetGroupRole makes memberID a moderator or a regular member of a group. Only the owner may change roles.
+func (m *PostManagerImpl) SetGroupRole(ctx context.Context, groupID, userID, memberID string, role GroupRole) error {
+	store, _, err := m.managedGroup(ctx, groupID, userID, GroupRoleOwner)
+	if err != nil {
+		return err
+	}
+	if role != GroupRoleMember && role != GroupRoleModerator {
+		return fmt.Errorf("%w: role %s cannot be assigned", ErrInvalidGroup, role)
+	}
+
+	member, err := store.GetGroupMember(ctx, groupID, memberID)
+	if err != nil {
+		return err
+	}
+	if member.Role == GroupRoleOwner {
+		return fmt.Errorf("%w: the owner's role cannot be changed", ErrInvalidGroup)
+	}
+
+	member.Role = role
+	return store.SaveGroupMember(ctx, member)
+}
+
+// ListGroupMembers returns the members of a group, highest role first.
+// Members of private groups are only listed to other members.
+func (m *PostManagerImpl) ListGroupMembers(ctx context.Context, groupID, userID string, limit, offset int) ([]*GroupMember, error) {
+	store, _, err := m.readableGroup(ctx, groupID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return store.ListGroupMembers(ctx, groupID, limit, offset)
+}
+
+// GetGroupFeed returns the posts of a group as seen by the user, newest first.
+// Only members may read the feed of a private group. Posts the Authorizer does not let the user read
+// are left out, and the user's muted words and tags are honored.
+func (m *PostManagerImpl) GetGroupFeed(ctx context.Context, groupID, userID string, limit, offset int) ([]*Post, error) {
+	if _, _, err := m.readableGroup(ctx, groupID, userID); err != nil {
+		return nil, err
+	}
+
+	viewerCtx := WithActor(ctx, actorFor(ctx, userID))
+	posts, err := m.store.ListPosts(viewerCtx, &PostFilter{
+		GroupID:   groupID,
+		Limit:     limit,
+		Offset:    offset,
+		SortBy:    "created_at",
+		SortOrder: "desc",
+	})
+	if err == nil {
+		posts, err = m.authorizedPosts(viewerCtx, posts)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return m.applyMutedTerms(ctx, userID, posts)
+}
+
+// RemoveGroupPost deletes a post from its group on behalf of a group moderator or the owner
+// and records the removal in the audit trail
+func (m *PostManagerImpl) RemoveGroupPost(ctx context.Context, postID, userID, reason string) error {
+	post, err := m.store.GetPost(ctx, postID)
+	if err != nil {
+		return err
+	}
+	if post.GroupID == "" {
+		return fmt.Errorf("%w: post is not in a group", ErrInvalidGroup)
+	}
+
+	if _, _, err := m.managedGroup(ctx, post.GroupID, userID, GroupRoleModerator); err != nil {
+		return err
+	}
+
+	actor := actorFor(ctx, userID)
+	if err := m.deletePost(ctx, post, actor); err != nil {
+		return err
+	}
+
+	m.recordAudit(ctx, post, actor, AuditGroupRemove, reason)
+
+	return nil
+}
+
+// addGroupMember adds a regular member to a group, keeping the role of existing members
+func (m *PostManagerImpl) addGroupMember(ctx context.Context, store GroupStore, groupID, userID string) error {
+	if userID == "" {
+		return fmt.Errorf("%w: user is required", ErrInvalidGroup)
+	}
+
+	_, err := store.GetGroupMember(ctx, groupID, userID)
+	if err == nil {
+		return nil
+	}
+	if !errors.Is(err, ErrNotGroupMember) {
+		return err
+	}
+
+	return store.SaveGroupMember(ctx, &GroupMember{
+		GroupID:  groupID,
+		UserID:   userID,
+		Role:     GroupRoleMember,
+		JoinedAt: time.Now(),
+	})
+}
+
+// managedGroup returns the group store and the user's role in a group, checking that the role is at least minRole.
+// Moderators and administrators in the context act as the group's owner.
+func (m *PostManagerImpl) managedGroup(ctx context.Context, groupID, userID string, minRole GroupRole) (GroupStore, GroupRole, error) {
+	store, ok := m.store.(GroupStore)
+	if !ok {
+		return nil, 0, ErrNotSupported
+	}
+
+	if _, err := store.GetGroup(ctx, groupID); err != nil {
+		return nil, 0, err
+	}
+	actor, err := actingAs(ctx, userID, ActionModerate)
+	if err != nil {
+		return nil, 0, err
+	}
+	if actor.IsPrivileged() {
+		return store, GroupRoleOwner, nil
+	}
+
+	member, err := store.GetGroupMember(ctx, groupID, userID)
+	if errors.Is(err, ErrNotGroupMember) || err == nil && member.Role < minRole {
+		return nil, 0, ErrPermissionDenied
+	}
+	if err != nil {
+		return nil, 0, err
+	}
+
+	return store, member.Role, nil
+}
+
+// readableGroup returns the group store and a group, checking that the user may read it
+func (m *PostManagerImpl) readableGroup(ctx context.Context, groupID, userID string) (GroupStore, *Group, error) {
+	store, ok := m.store.(GroupStore)
+	if !ok {
+		return nil, nil, ErrNotSupported
+	}
+
+	group, err := store.GetGroup(ctx, groupID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := m.checkGroupMember(ctx, store, group, userID); err != nil {
+		return nil, nil, err
+	}
+
+	return store, group, nil
+}
+
+// checkGroupMember returns ErrPermissionDenied if the group is private and the user is not a member
+func (m *PostManagerImpl) checkGroupMember(ctx context.Context, store GroupStore, group *Group, userID string) error {
+	if !group.Private {
+		return nil
+	}
+	actor, err := actingAs(ctx, userID, ActionRead)
+	if err != nil {
+		return err
+	}
+	if actor.IsPrivileged() {
+		return nil
+	}
+	if userID == "" {
+		return ErrPermissionDenied
+	}
+
+	_, err = store.GetGroupMember(ctx, group.ID, userID)
+	if errors.Is(err, ErrNotGroupMember) {
+		return ErrPermissionDenied
+	}
+	return err
+}
+
+// checkGroupPost returns ErrNotGroupMember unless the author of a group post is a member of the group
+func (m *PostManagerImpl) checkGroupPost(ctx context.Context, post *Post) error {
+	if post.GroupID == "" {
+		return nil
+	}
+
+	store, ok := m.store.(GroupStore)
+	if !ok {
+		return ErrNotSupported
+	}
+
+	_, err := store.GetGroupMember(ctx, post.GroupID, post.UserID)
+	return err
+}
+
+// checkGroup returns ErrPermissionDenied if the post belongs to a private group the user is not a member of.
+// Authors always see their own posts.
+func (m *PostManagerImpl) checkGroup(ctx context.Context, post *Post, userID string) error {
+	if post.GroupID == "" || post.isAuthor(userID) || seesAllAudiences(ctx) {
+		return nil
+	}
+
+	store, ok := m.store.(GroupStore)
+	if !ok {
+		return ErrNotSupported
+	}
+
+	group, err := store.GetGroup(ctx, post.GroupID)
+	if err != nil {
+		return err
+	}
+
+	return m.checkGroupMember(ctx, store, group, userID)
+}
+
+// inGroupOf reports whether the post is outside private groups, authored or co-authored by the viewer
+// or in a private group the viewer is a member of. The caller must hold the lock.
+func (s *InMemoryPostStore) inGroupOf(post *Post, viewer string) bool {
+	if post.GroupID == "" || post.isAuthor(viewer) {
+		return true
+	}
+
+	group, exists := s.groups[post.GroupID]
+	if !exists || !group.Private {
+		return true
+	}
+
+	_, member := s.groupMembers[post.GroupID][viewer]
+	return member
+}
+
+// lookupGroup returns a stored group of the tenant in the context. The caller must hold the lock.
+func (s *InMemoryPostStore) lookupGroup(ctx context.Context, groupID string) (*Group, bool) {
+	group, exists := s.groups[groupID]
+	if !exists || group.TenantID != TenantFromContext(ctx) {
+		return nil, false
+	}
+	return group, true
+}
+
+// SaveGroup creates a group; ErrInvalidGroup is returned if its ID is taken
+func (s *InMemoryPostStore) SaveGroup(ctx context.Context, group *Group) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	group.TenantID = TenantFromContext(ctx)
+	if _, exists := s.groups[group.ID]; exists {
+		return fmt.Errorf("%w: ID %s is taken", ErrInvalidGroup, group.ID)
+	}
+
+	groupCopy := *group
+	s.groups[group.ID] = &groupCopy
+	return nil
+}
+
+// GetGroup returns a group
+func (s *InMemoryPostStore) GetGroup(ctx context.Context, groupID string) (*Group, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	group, exists := s.lookupGroup(ctx, groupID)
+	if !exists {
+		return nil, ErrGroupNotFound
+	}
+
+	groupCopy := *group
+	return &groupCopy, nil
+}
+
+// SaveGroupMember adds a member to a group or changes their role
+func (s *InMemoryPostStore) SaveGroupMember(ctx context.Context, member *GroupMember) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.lookupGroup(ctx, member.GroupID); !exists {
+		return ErrGroupNotFound
+	}
+
+	if s.groupMembers[member.GroupID] == nil {
+		s.groupMembers[member.GroupID] = make(map[string]*GroupMember)
+	}
+	memberCopy := *member
+	s.groupMembers[member.GroupID][member.UserID] = &memberCopy
+
+	return nil
+}
+
+// GetGroupMember returns the membership of a user, or ErrNotGroupMember
+func (s *InMemoryPostStore) GetGroupMember(ctx context.Context, groupID, userID string) (*GroupMember, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if _, exists := s.lookupGroup(ctx, groupID); !exists {
+		return nil, ErrGroupNotFound
+	}
+
+	member, exists := s.groupMembers[groupID][userID]
+	if !exists {
+		return nil, ErrNotGroupMember
+	}
+
+	memberCopy := *member
+	return &memberCopy, nil
+}
+
+// DeleteGroupMember removes a member from a group
+func (s *InMemoryPostStore) DeleteGroupMember(ctx context.Context, groupID, userID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.lookupGroup(ctx, groupID); !exists {
+		return ErrGroupNotFound
+	}
+	if _, exists := s.groupMembers[groupID][userID]; !exists {
+		return ErrNotGroupMember
+	}
+
+	delete(s.groupMembers[groupID], userID)
+	return nil
+}
+
+// ListGroupMembers returns the members of a group, highest role first, then in order of joining
+func (s *InMemoryPostStore) ListGroupMembers(ctx context.Context, groupID string, limit, offset int) ([]*GroupMember, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if _, exists := s.lookupGroup(ctx, groupID); !exists {
+		return nil, ErrGroupNotFound
+	}
+
+	members := make([]*GroupMember, 0, len(s.groupMembers[groupID]))
+	for _, member := range s.groupMembers[groupID] {
+		memberCopy := *member
+		members = append(members, &memberCopy)
+	}
+
+	sort.Slice(members, func(i, j int) bool {
+		if members[i].Role != members[j].Role {
+			return members[i].Role > members[j].Role
+		}
+		if !members[i].JoinedAt.Equal(members[j].JoinedAt) {
+			return members[i].JoinedAt.Before(members[j].JoinedAt)
+		}
+		return members[i].UserID < members[j].UserID
+	})
+
+	// Apply pagination
+	if limit > 0 {
+		end := offset + limit
+		if end > len(members) {
+			end = len(members)
+		}
+		if offset < len(members) {
+			members = members[offset:end]
+		} else {
+			members = []*GroupMember{}
+		}
+	}
+
+	return members, nil
+}
+
+// ListUserGroups returns the groups a user is a member of, ordered by name
+func (s *InMemoryPostStore) ListUserGroups(ctx context.Context, userID string) ([]*Group, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	groups := []*Group{}
+	for groupID, members := range s.groupMembers {
+		group, exists := s.lookupGroup(ctx, groupID)
+		if _, member := members[userID]; !exists || !member {
+			continue
+		}
+		groupCopy := *group
+		groups = append(groups, &groupCopy)
+	}
+
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Name != groups[j].Name {
+			return groups[i].Name < groups[j].Name
+		}
+		return groups[i].ID < groups[j].ID
+	})
+
+	return groups, nil
+}
@@ -0,0 +1,207 @@
+// Package postflow provides functionality for managing user posts and feeds.
+package postflow
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// EventType identifies something that happened to a post
+type EventType uint8
+
+const (
+	EventPostCreated     EventType = 1
+	EventPostUpdated     EventType = 2 // Also fired for patches
+	EventPostDeleted     EventType = 3
+	EventReactionAdded   EventType = 4
+	EventReactionChanged EventType = 5 // A user replaced their reaction with another type
+	EventReactionRemoved EventType = 6
+)
+
+// String returns the name of the event type
+func (t EventType) String() string {
+	switch t {
+	case EventPostCreated:
+		return "post.created"
+	case EventPostUpdated:
+		return "post.updated"
+	case EventPostDeleted:
+		return "post.deleted"
+	case EventReactionAdded:
+		return "reaction.added"
+	case EventReactionChanged:
+		return "reaction.changed"
+	case EventReactionRemoved:
+		return "reaction.removed"
+	default:
+		return "unknown"
+	}
+}
+
+// Event describes a change to a post. Hooks must not modify the event or the posts it refers to.
+type Event struct {
+	Type       EventType
+	PostID     string
+	Post       *Post // The post as saved; for PostDeleted and reaction events, the post as read before the change
+	Previous   *Post // The post before the change, for PostUpdated
+	Actor      Actor // The user who caused the event
+	OccurredAt time.Time
+
+	// Reaction events
+	Reaction         ReactionType // The added, new or removed reaction
+	PreviousReaction ReactionType // The replaced reaction, for ReactionChanged
+}
+
+// BeforeHook runs before a change is stored. Returning an error vetoes the change;
+// the operation fails with that error.
+type BeforeHook func(ctx context.Context, event *Event) error
+
+// AfterHook runs after a change was stored successfully
+type AfterHook func(ctx context.Context, event *Event)
+
+// hookRegistry holds the hooks registered on a manager
+type hookRegistry struct {
+	mutex  sync.RWMutex
+	before map[EventType][]BeforeHook
+	after  map[EventType][]AfterHook
+}
+
+// WithBeforeHook registers a hook that runs before changes of the given type are stored
+func WithBeforeHook(eventType EventType, hook BeforeHook) PostManagerOption {
+	return func(m *PostManagerImpl) {
+		m.OnBefore(eventType, hook)
+	}
+}
+
+// WithAfterHook registers a hook that runs after changes of the given type are stored
+func WithAfterHook(eventType EventType, hook AfterHook) PostManagerOption {
+	return func(m *PostManagerImpl) {
+		m.OnAfter(eventType, hook)
+	}
+}
+
+// OnBefore registers a hook that runs before changes of the given type are stored.
+// Hooks run in registration order and the first error stops the change. The store may still
+// reject a change that all before-hooks allowed, and retried patches run the hooks again.
+func (m *PostManagerImpl) OnBefore(eventType EventType, hook BeforeHook) {
+	m.hooks.mutex.Lock()
+	defer m.hooks.mutex.Unlock()
+
+	if m.hooks.before == nil {
+		m.hooks.before = make(map[EventType][]BeforeHook)
+	}
+	m.hooks.before[eventType] = append(m.hooks.before[eventType], hook)
+}
+
+// OnAfter registers a hook that runs after changes of the given type are stored.
+// Hooks run synchronously in registration order on the goroutine that made the change.
+func (m *PostManagerImpl) OnAfter(eventType EventType, hook AfterHook) {
+	m.hooks.mutex.Lock()
+	defer m.hooks.mutex.Unlock()
+
+	if m.hooks.after == nil {
+		m.hooks.after = make(map[EventType][]AfterHook)
+	}
+	m.hooks.after[eventType] = append(m.hooks.after[eventType], hook)
+}
+
+// hasHooks reports whether any hook or running watch is interested in one of the event types
+func (m *PostManagerImpl) hasHooks(eventTypes ...EventType) bool {
+	m.hooks.mutex.RLock()
+	defer m.hooks.mutex.RUnlock()
+
+	for _, eventType := range eventTypes {
+		if len(m.hooks.before[eventType]) > 0 || len(m.hooks.after[eventType]) > 0 {
+			return true
+		}
+	}
+	return m.watches.watching(eventTypes...)
+}
+
+// newEvent returns an event about a post
+func newEvent(eventType EventType, post *Post, actor Actor) *Event {
+	return &Event{
+		Type:       eventType,
+		PostID:     post.ID,
+		Post:       post,
+		Actor:      actor,
+		OccurredAt: time.Now(),
+	}
+}
+
+// runBeforeHooks calls the before-hooks of the event, stopping at the first error
+func (m *PostManagerImpl) runBeforeHooks(ctx context.Context, event *Event) error {
+	m.hooks.mutex.RLock()
+	hooks := m.hooks.before[event.Type]
+	m.hooks.mutex.RUnlock()
+
+	for _, hook := range hooks {
+		if err := hook(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// runAfterHooks calls the after-hooks of the event, then hands it to the watches
+func (m *PostManagerImpl) runAfterHooks(ctx context.Context, event *Event) {
+	m.hooks.mutex.RLock()
+	hooks := m.hooks.after[event.Type]
+	m.hooks.mutex.RUnlock()
+
+	for _, hook := range hooks {
+		hook(ctx, event)
+	}
+	m.queueWatchChange(ctx, event)
+}
+
+// reactionEvent returns the ReactionAdded or ReactionChanged event for saving a reaction,
+// or nil if no hooks are interested or the user already has this reaction
+func (m *PostManagerImpl) reactionEvent(ctx context.Context, post *Post, userID string, reactionType ReactionType) (*Event, error) {
+	if !m.hasHooks(EventReactionAdded, EventReactionChanged) {
+		return nil, nil
+	}
+	if reactionType <= ReactionNone || reactionType > ReactionAngry {
+		return nil, ErrInvalidReaction
+	}
+
+	previous, err := m.store.GetUserReaction(ctx, post.ID, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	event := newEvent(EventReactionAdded, post, actorFor(ctx, userID))
+	event.Reaction = reactionType
+	if previous != nil {
+		if *previous == reactionType {
+			return nil, nil
+		}
+		event.Type = EventReactionChanged
+		event.PreviousReaction = *previous
+	}
+
+	return event, nil
+}
+
+// reactionRemovedEvent returns the ReactionRemoved event for removing a reaction,
+// or nil if no hooks are interested or the user does not have this reaction
+func (m *PostManagerImpl) reactionRemovedEvent(ctx context.Context, postID, userID string, reactionType ReactionType) (*Event, error) {
+	if !m.hasHooks(EventReactionRemoved) {
+		return nil, nil
+	}
+
+	previous, err := m.store.GetUserReaction(ctx, postID, userID)
+	if err != nil || previous == nil || *previous != reactionType {
+		return nil, err
+	}
+
+	post, err := m.store.GetPost(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	event := newEvent(EventReactionRemoved, post, actorFor(ctx, userID))
+	event.Reaction = reactionType
+	return event, nil
+}
@@ -0,0 +1,172 @@
+package postflow
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPostManagerHooks tests lifecycle event hooks with the in-memory store
+func TestPostManagerHooks(t *testing.T) {
+	pm := setupTestPostManager()
+	ctx := context.Background()
+
+	var fired []EventType
+	var last *Event
+	for _, eventType := range []EventType{
+		EventPostCreated, EventPostUpdated, EventPostDeleted,
+		EventReactionAdded, EventReactionChanged, EventReactionRemoved,
+	} {
+		pm.OnAfter(eventType, func(ctx context.Context, event *Event) {
+			fired = append(fired, event.Type)
+			last = event
+		})
+	}
+
+	errVetoed := errors.New("vetoed")
+	pm.OnBefore(EventPostCreated, func(ctx context.Context, event *Event) error {
+		if event.Post.Content == "forbidden" {
+			return errVetoed
+		}
+		return nil
+	})
+
+	// Test: before-hooks veto changes, which are then neither stored nor observed
+	vetoed := createTestPostData("user1")
+	vetoed.Content = "forbidden"
+	_, err := pm.CreatePost(ctx, vetoed)
+	assert.Equal(t, errVetoed, err)
+	_, err = pm.GetPost(ctx, vetoed.ID)
+	assert.Equal(t, ErrPostNotFound, err)
+	assert.Empty(t, fired)
+
+	// Test: after-hooks observe stored changes
+	postID, err := pm.CreatePost(ctx, createTestPostData("user1"))
+	require.NoError(t, err)
+	assert.Equal(t, []EventType{EventPostCreated}, fired)
+	assert.Equal(t, postID, last.PostID)
+	assert.Equal(t, "user1", last.Actor.UserID)
+	assert.Equal(t, int64(1), last.Post.Version)
+
+	post, err := pm.GetPost(ctx, postID)
+	require.NoError(t, err)
+	post.Content = "Edited"
+	require.NoError(t, pm.UpdatePost(ctx, post))
+	assert.Equal(t, EventPostUpdated, last.Type)
+	assert.Equal(t, "Edited", last.Post.Content)
+	assert.NotEqual(t, "Edited", last.Previous.Content)
+
+	_, err = pm.PatchPost(ctx, postID, "user1", &PostPatch{Fields: PatchContent, Content: "Patched"})
+	require.NoError(t, err)
+	assert.Equal(t, EventPostUpdated, last.Type)
+	assert.Equal(t, "Patched", last.Post.Content)
+	assert.Equal(t, "Edited", last.Previous.Content)
+
+	// Test: reactions fire added, changed and removed, and repeats fire nothing
+	fired = nil
+	require.NoError(t, pm.AddReaction(ctx, postID, "user2", ReactionLike))
+	require.NoError(t, pm.AddReaction(ctx, postID, "user2", ReactionLike))
+	require.NoError(t, pm.AddReaction(ctx, postID, "user2", ReactionLove))
+	assert.Equal(t, ReactionLike, last.PreviousReaction)
+	assert.Equal(t, ReactionLove, last.Reaction)
+	require.NoError(t, pm.RemoveReaction(ctx, postID, "user2", ReactionLike))
+	require.NoError(t, pm.RemoveReaction(ctx, postID, "user2", ReactionLove))
+	assert.Equal(t, "user2", last.Actor.UserID)
+	assert.Equal(t, postID, last.Post.ID)
+	assert.Equal(t, []EventType{EventReactionAdded, EventReactionChanged, EventReactionRemoved}, fired)
+
+	// Test: a vetoed reaction is not stored
+	pm.OnBefore(EventReactionAdded, func(ctx context.Context, event *Event) error {
+		return errVetoed
+	})
+	assert.Equal(t, errVetoed, pm.AddReaction(ctx, postID, "user3", ReactionWow))
+	reaction, err := pm.GetUserReaction(ctx, postID, "user3")
+	assert.NoError(t, err)
+	assert.Nil(t, reaction)
+
+	// Test: failed changes are not observed
+	fired = nil
+	assert.Equal(t, ErrPermissionDenied, pm.DeletePost(ctx, postID, "user2"))
+	assert.Empty(t, fired)
+
+	require.NoError(t, pm.DeletePost(ctx, postID, "user1"))
+	assert.Equal(t, []EventType{EventPostDeleted}, fired)
+	assert.Equal(t, "Patched", last.Post.Content)
+}
+
+// TestPostManagerHookOptions tests registering hooks as manager options
+func TestPostManagerHookOptions(t *testing.T) {
+	var created []string
+	pm := NewPostManager(NewInMemoryPostStore(),
+		WithBeforeHook(EventPostCreated, func(ctx context.Context, event *Event) error {
+			if event.Post.UserID == "banned" {
+				return ErrPermissionDenied
+			}
+			return nil
+		}),
+		WithAfterHook(EventPostCreated, func(ctx context.Context, event *Event) {
+			created = append(created, event.PostID)
+		}),
+	)
+	ctx := context.Background()
+
+	_, err := pm.CreatePost(ctx, createTestPostData("banned"))
+	assert.Equal(t, ErrPermissionDenied, err)
+
+	results, err := pm.BulkCreatePosts(WithActor(ctx, Actor{UserID: "admin", Role: RoleAdmin}), []*BulkPost{
+		{Post: createTestPostData("user1")},
+		{Post: createTestPostData("banned")},
+	})
+	require.NoError(t, err)
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, ErrPermissionDenied, results[1].Err)
+	assert.Equal(t, []string{results[0].PostID}, created)
+}
+
+// TestPostManagerModerationHooks tests that moderation decisions fire PostUpdated hooks
+func TestPostManagerModerationHooks(t *testing.T) {
+	pm := NewPostManager(NewInMemoryPostStore(), WithReportThreshold(1))
+	ctx := context.Background()
+	modCtx := WithActor(ctx, Actor{UserID: "mod1", Role: RoleModerator})
+
+	var events []*Event
+	pm.OnAfter(EventPostUpdated, func(ctx context.Context, event *Event) {
+		events = append(events, event)
+	})
+	postID, err := pm.CreatePost(ctx, createTestPostData("user1"))
+	require.NoError(t, err)
+
+	// Test: hiding and unhiding carry the status before and after the change
+	require.NoError(t, pm.HidePost(modCtx, postID, "off-topic"))
+	require.Len(t, events, 1)
+	assert.Equal(t, "mod1", events[0].Actor.UserID)
+	assert.Equal(t, ModerationApproved, events[0].Previous.ModerationStatus)
+	assert.Equal(t, ModerationHidden, events[0].Post.ModerationStatus)
+	assert.Equal(t, "off-topic", events[0].Post.ModerationReason)
+	assert.Equal(t, events[0].Previous.Version+1, events[0].Post.Version)
+
+	require.NoError(t, pm.UnhidePost(modCtx, postID))
+	require.Len(t, events, 2)
+	assert.Equal(t, ModerationHidden, events[1].Previous.ModerationStatus)
+	assert.Equal(t, ModerationApproved, events[1].Post.ModerationStatus)
+
+	// Test: holding a post after reports is attributed to the system, not the reporter
+	require.NoError(t, pm.ReportPost(ctx, postID, "user2", ReportSpam, ""))
+	require.Len(t, events, 3)
+	assert.Equal(t, SystemActor, events[2].Actor)
+	assert.Equal(t, ModerationPending, events[2].Post.ModerationStatus)
+
+	// Test: before-hooks veto moderation decisions
+	errVetoed := errors.New("vetoed")
+	pm.OnBefore(EventPostUpdated, func(ctx context.Context, event *Event) error {
+		return errVetoed
+	})
+	assert.Equal(t, errVetoed, pm.ApprovePost(modCtx, postID))
+	assert.Len(t, events, 3)
+	post, err := pm.GetPost(ctx, postID)
+	require.NoError(t, err)
+	assert.Equal(t, ModerationPending, post.ModerationStatus)
+}
@@ -0,0 +1,245 @@
+// Package postflow provides functionality for managing user posts and feeds.
+package postflow
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultIdempotencyTTL is how long idempotency keys of completed requests are remembered unless configured otherwise
+	DefaultIdempotencyTTL = 24 * time.Hour
+
+	// DefaultIdempotencyLease is how long a running request holds its idempotency key unless configured otherwise.
+	// A retry arriving after the lease ran out takes the key over, e.g. when the first attempt crashed.
+	DefaultIdempotencyLease = time.Minute
+)
+
+var (
+	// ErrIdempotencyKeyReused is returned when an idempotency key is sent again with a different request
+	ErrIdempotencyKeyReused = errors.New("idempotency key reused with a different request")
+
+	// ErrRequestInProgress is returned when a retry arrives while the first attempt still holds its lease
+	ErrRequestInProgress = errors.New("request with this idempotency key is in progress")
+)
+
+// idempotencyKeyContextKey is the context key under which the idempotency key is stored
+type idempotencyKeyContextKey struct{}
+
+// IdempotencyStore defines the interface for remembering idempotency keys
+type IdempotencyStore interface {
+	// ClaimIdempotencyKey atomically stores value under key for ttl unless an unexpired entry exists.
+	// It returns the value stored under the key and whether this call claimed it.
+	ClaimIdempotencyKey(ctx context.Context, key string, value string, ttl time.Duration) (string, bool, error)
+
+	// CompleteIdempotencyKey replaces the claimed value of a key with value and keeps it for ttl,
+	// once the operation it guards has succeeded. Keys no longer holding the claimed value are left alone.
+	CompleteIdempotencyKey(ctx context.Context, key string, claimed string, value string, ttl time.Duration) error
+
+	// ReleaseIdempotencyKey forgets a key still holding the claimed value, e.g. when the operation it guarded failed
+	ReleaseIdempotencyKey(ctx context.Context, key string, claimed string) error
+}
+
+// idempotencyClaim is the value the manager stores under an idempotency key: a fingerprint of the
+// request, whether it completed, and its result. Pending claims also carry the time they were made,
+// which tells the claims of two attempts apart.
+type idempotencyClaim struct {
+	fingerprint string
+	done        bool
+	claimedAt   time.Time
+	result      string
+}
+
+// String encodes the claim as "fingerprint:state:result", where state is "done" or "pending@<unix nanoseconds>"
+func (c idempotencyClaim) String() string {
+	state := "pending@" + strconv.FormatInt(c.claimedAt.UnixNano(), 10)
+	if c.done {
+		state = "done"
+	}
+	return c.fingerprint + ":" + state + ":" + c.result
+}
+
+// parseIdempotencyClaim decodes a stored claim and reports whether the value is a valid claim
+func parseIdempotencyClaim(value string) (idempotencyClaim, bool) {
+	parts := strings.SplitN(value, ":", 3)
+	if len(parts) != 3 {
+		return idempotencyClaim{}, false
+	}
+
+	claim := idempotencyClaim{fingerprint: parts[0], result: parts[2]}
+	if parts[1] == "done" {
+		claim.done = true
+		return claim, true
+	}
+
+	nanos, found := strings.CutPrefix(parts[1], "pending@")
+	if !found {
+		return idempotencyClaim{}, false
+	}
+	n, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return idempotencyClaim{}, false
+	}
+	claim.claimedAt = time.Unix(0, n)
+	return claim, true
+}
+
+// requestFingerprint returns a hash identifying the parameters of a request
+func requestFingerprint(params ...interface{}) string {
+	data, _ := json.Marshal(params)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:16])
+}
+
+// createRequestFingerprint returns the fingerprint of a CreatePost request; generated IDs and timestamps are left out
+func createRequestFingerprint(post *Post) string {
+	media := make([]Media, len(post.Media))
+	for i, item := range post.Media {
+		item.ID = ""
+		item.CreatedAt = time.Time{}
+		media[i] = item
+	}
+	return requestFingerprint(post.GroupID, post.Content, media, post.Tags, post.Visibility, post.AudienceIDs, post.AudienceUserIDs)
+}
+
+// idempotencyEntry is a claimed idempotency key in the in-memory store
+type idempotencyEntry struct {
+	value     string
+	expiresAt time.Time
+}
+
+// WithIdempotencyKey returns a context carrying an idempotency key.
+// CreatePost and AddReaction calls retried with the same key and user are applied only once.
+func WithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyContextKey{}, key)
+}
+
+// IdempotencyKeyFromContext returns the idempotency key carried by the context, if any
+func IdempotencyKeyFromContext(ctx context.Context) string {
+	key, _ := ctx.Value(idempotencyKeyContextKey{}).(string)
+	return key
+}
+
+// WithIdempotencyTTL sets how long idempotency keys of completed requests are remembered
+func WithIdempotencyTTL(ttl time.Duration) PostManagerOption {
+	return func(m *PostManagerImpl) {
+		m.idempotencyTTL = ttl
+	}
+}
+
+// WithIdempotencyLease sets how long a running request holds its idempotency key before a retry may take it over.
+// The lease should comfortably exceed the time a request takes.
+func WithIdempotencyLease(lease time.Duration) PostManagerOption {
+	return func(m *PostManagerImpl) {
+		m.idempotencyLease = lease
+	}
+}
+
+// claimIdempotencyKey claims a key of the tenant in the context for a request with the given fingerprint
+// and result, for the lease of a running request. It returns the claim made by this call, which the caller
+// completes or releases, or nil with the result of the completed first attempt. While the first attempt
+// holds its lease ErrRequestInProgress is returned, and ErrIdempotencyKeyReused when the key was used
+// for a different request.
+func (m *PostManagerImpl) claimIdempotencyKey(ctx context.Context, key string, fingerprint string, result string) (string, *idempotencyClaim, error) {
+	store, ok := m.store.(IdempotencyStore)
+	if !ok {
+		return "", nil, ErrNotSupported
+	}
+
+	claim := &idempotencyClaim{fingerprint: fingerprint, claimedAt: time.Now(), result: result}
+	value, claimed, err := store.ClaimIdempotencyKey(ctx, tenantScopedKey(ctx, key), claim.String(), m.idempotencyLease)
+	if err != nil {
+		return "", nil, err
+	}
+	if claimed {
+		return result, claim, nil
+	}
+
+	// A value that is not a claim cannot belong to this request
+	first, ok := parseIdempotencyClaim(value)
+	if !ok || first.fingerprint != fingerprint {
+		return "", nil, ErrIdempotencyKeyReused
+	}
+	if !first.done {
+		return "", nil, ErrRequestInProgress
+	}
+
+	return first.result, nil, nil
+}
+
+// completeIdempotencyKey records that the request holding a claim succeeded, so that retries return its result
+// for the idempotency TTL. Errors are ignored; the pending claim then runs out with its lease.
+func (m *PostManagerImpl) completeIdempotencyKey(ctx context.Context, key string, claim *idempotencyClaim, result string) {
+	if store, ok := m.store.(IdempotencyStore); ok {
+		done := idempotencyClaim{fingerprint: claim.fingerprint, done: true, result: result}
+		_ = store.CompleteIdempotencyKey(ctx, tenantScopedKey(ctx, key), claim.String(), done.String(), m.idempotencyTTL)
+	}
+}
+
+// releaseIdempotencyKey forgets a claim so that a failed request can be retried.
+// Errors are ignored because the claim runs out with its lease.
+func (m *PostManagerImpl) releaseIdempotencyKey(ctx context.Context, key string, claim *idempotencyClaim) {
+	if store, ok := m.store.(IdempotencyStore); ok {
+		_ = store.ReleaseIdempotencyKey(ctx, tenantScopedKey(ctx, key), claim.String())
+	}
+}
+
+// ClaimIdempotencyKey stores value under key unless an unexpired entry exists
+func (s *InMemoryPostStore) ClaimIdempotencyKey(ctx context.Context, key string, value string, ttl time.Duration) (string, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	now := time.Now()
+
+	// Purge expired keys at most once a minute
+	if now.Sub(s.idempotencySweep) > time.Minute {
+		for k, entry := range s.idempotencyKeys {
+			if !now.Before(entry.expiresAt) {
+				delete(s.idempotencyKeys, k)
+			}
+		}
+		s.idempotencySweep = now
+	}
+
+	if entry, exists := s.idempotencyKeys[key]; exists && now.Before(entry.expiresAt) {
+		return entry.value, false, nil
+	}
+
+	s.idempotencyKeys[key] = idempotencyEntry{
+		value:     value,
+		expiresAt: now.Add(ttl),
+	}
+
+	return value, true, nil
+}
+
+// CompleteIdempotencyKey replaces the claimed value of a key and keeps it for ttl
+func (s *InMemoryPostStore) CompleteIdempotencyKey(ctx context.Context, key string, claimed string, value string, ttl time.Duration) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if entry, exists := s.idempotencyKeys[key]; exists && entry.value == claimed {
+		s.idempotencyKeys[key] = idempotencyEntry{
+			value:     value,
+			expiresAt: time.Now().Add(ttl),
+		}
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey forgets a key still holding the claimed value
+func (s *InMemoryPostStore) ReleaseIdempotencyKey(ctx context.Context, key string, claimed string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if entry, exists := s.idempotencyKeys[key]; exists && entry.value == claimed {
+		delete(s.idempotencyKeys, key)
+	}
+	return nil
+}
@@ -0,0 +1,162 @@
+package postflow
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPostManagerCreatePostIdempotency tests that retried CreatePost calls do not create duplicates
+func TestPostManagerCreatePostIdempotency(t *testing.T) {
+	pm := setupTestPostManager()
+	ctx := WithIdempotencyKey(context.Background(), "request-1")
+
+	// Test: first attempt creates the post
+	first := createTestPostData("user1")
+	first.ID = ""
+	firstID, err := pm.CreatePost(ctx, first)
+	require.NoError(t, err)
+
+	// Test: retry with the same key returns the original post ID
+	retry := createTestPostData("user1")
+	retry.ID = ""
+	retryID, err := pm.CreatePost(ctx, retry)
+	assert.NoError(t, err)
+	assert.Equal(t, firstID, retryID)
+	assert.Equal(t, firstID, retry.ID)
+
+	posts, err := pm.ListPosts(ctx, &PostFilter{UserID: "user1"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(posts))
+
+	// Test: keys are scoped per user
+	other := createTestPostData("user2")
+	otherID, err := pm.CreatePost(ctx, other)
+	assert.NoError(t, err)
+	assert.NotEqual(t, firstID, otherID)
+
+	// Test: a different key creates a new post
+	next := createTestPostData("user1")
+	nextID, err := pm.CreatePost(WithIdempotencyKey(context.Background(), "request-2"), next)
+	assert.NoError(t, err)
+	assert.NotEqual(t, firstID, nextID)
+
+	// Test: reusing a key for a different post is an error
+	changed := createTestPostData("user1")
+	changed.Content = "Something else"
+	_, err = pm.CreatePost(ctx, changed)
+	assert.Equal(t, ErrIdempotencyKeyReused, err)
+}
+
+// TestPostManagerCreatePostIdempotencyInProgress tests retries that arrive before the first attempt finished
+func TestPostManagerCreatePostIdempotencyInProgress(t *testing.T) {
+	pm := setupTestPostManager()
+	ctx := WithIdempotencyKey(context.Background(), "request-1")
+
+	// The first attempt has claimed the key but not saved its post yet
+	post := createTestPostData("user1")
+	_, claim, err := pm.claimIdempotencyKey(ctx, "create_post:user1:request-1", createRequestFingerprint(post), post.ID)
+	require.NoError(t, err)
+	require.NotNil(t, claim)
+
+	_, err = pm.CreatePost(ctx, createTestPostData("user1"))
+	assert.Equal(t, ErrRequestInProgress, err)
+
+	_, err = pm.GetPost(context.Background(), post.ID)
+	assert.Equal(t, ErrPostNotFound, err)
+}
+
+// TestPostManagerCreatePostIdempotencyLease tests that a retry takes over the key of an attempt that never finished
+func TestPostManagerCreatePostIdempotencyLease(t *testing.T) {
+	pm := NewPostManager(NewInMemoryPostStore(), WithIdempotencyLease(10*time.Millisecond))
+	ctx := WithIdempotencyKey(context.Background(), "request-1")
+
+	// The first attempt claimed the key and crashed before completing or releasing it
+	crashed := createTestPostData("user1")
+	_, claim, err := pm.claimIdempotencyKey(ctx, "create_post:user1:request-1", createRequestFingerprint(crashed), crashed.ID)
+	require.NoError(t, err)
+	require.NotNil(t, claim)
+
+	_, err = pm.CreatePost(ctx, createTestPostData("user1"))
+	assert.Equal(t, ErrRequestInProgress, err)
+
+	// Test: once the lease ran out a retry takes the key over
+	time.Sleep(20 * time.Millisecond)
+	retry := createTestPostData("user1")
+	retryID, err := pm.CreatePost(ctx, retry)
+	require.NoError(t, err)
+
+	// Test: the late attempt cannot complete or release the claim it lost
+	pm.completeIdempotencyKey(ctx, "create_post:user1:request-1", claim, crashed.ID)
+	pm.releaseIdempotencyKey(ctx, "create_post:user1:request-1", claim)
+
+	// Test: the completed retry is remembered beyond the lease
+	time.Sleep(20 * time.Millisecond)
+	againID, err := pm.CreatePost(ctx, createTestPostData("user1"))
+	assert.NoError(t, err)
+	assert.Equal(t, retryID, againID)
+}
+
+// TestPostManagerCreatePostIdempotencyInvalidClaim tests keys whose stored value is not a claim
+func TestPostManagerCreatePostIdempotencyInvalidClaim(t *testing.T) {
+	store := NewInMemoryPostStore()
+	pm := NewPostManager(store)
+	ctx := WithIdempotencyKey(context.Background(), "request-1")
+
+	_, claimed, err := store.ClaimIdempotencyKey(ctx, tenantScopedKey(ctx, "create_post:user1:request-1"), "some-post-id", time.Hour)
+	require.NoError(t, err)
+	require.True(t, claimed)
+
+	_, err = pm.CreatePost(ctx, createTestPostData("user1"))
+	assert.Equal(t, ErrIdempotencyKeyReused, err)
+}
+
+// TestPostManagerCreatePostIdempotencyTTL tests that idempotency keys expire
+func TestPostManagerCreatePostIdempotencyTTL(t *testing.T) {
+	pm := NewPostManager(NewInMemoryPostStore(), WithIdempotencyTTL(10*time.Millisecond))
+	ctx := WithIdempotencyKey(context.Background(), "request-1")
+
+	firstID, err := pm.CreatePost(ctx, createTestPostData("user1"))
+	require.NoError(t, err)
+
+	time.Sleep(20 * time.Millisecond)
+
+	secondID, err := pm.CreatePost(ctx, createTestPostData("user1"))
+	assert.NoError(t, err)
+	assert.NotEqual(t, firstID, secondID)
+}
+
+// TestPostManagerAddReactionIdempotency tests that retried AddReaction calls are applied once
+func TestPostManagerAddReactionIdempotency(t *testing.T) {
+	pm := setupTestPostManager()
+	ctx := context.Background()
+
+	postID, err := pm.CreatePost(ctx, createTestPostData("user1"))
+	require.NoError(t, err)
+
+	// The user reacts, then changes their mind before a delayed retry of the first request arrives
+	keyed := WithIdempotencyKey(ctx, "reaction-1")
+	require.NoError(t, pm.AddReaction(keyed, postID, "user2", ReactionLike))
+	require.NoError(t, pm.AddReaction(ctx, postID, "user2", ReactionLove))
+	assert.NoError(t, pm.AddReaction(keyed, postID, "user2", ReactionLike))
+
+	reaction, err := pm.GetUserReaction(ctx, postID, "user2")
+	assert.NoError(t, err)
+	assert.Equal(t, ReactionLove, *reaction)
+
+	// Test: reusing a key for another post or reaction is an error
+	assert.Equal(t, ErrIdempotencyKeyReused, pm.AddReaction(keyed, postID, "user2", ReactionWow))
+	otherID, err := pm.CreatePost(ctx, createTestPostData("user1"))
+	require.NoError(t, err)
+	assert.Equal(t, ErrIdempotencyKeyReused, pm.AddReaction(keyed, otherID, "user2", ReactionLike))
+
+	// Test: a failed attempt releases the key so the retry is applied
+	failing := WithIdempotencyKey(ctx, "reaction-2")
+	err = pm.AddReaction(failing, "nonexistent-id", "user2", ReactionLike)
+	assert.Equal(t, ErrPostNotFound, err)
+	err = pm.AddReaction(failing, "nonexistent-id", "user2", ReactionLike)
+	assert.Equal(t, ErrPostNotFound, err)
+}
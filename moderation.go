@@ -0,0 +1,217 @@
+// Package postflow provides functionality for managing user posts and feeds.
+package postflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// ErrPostRejected is returned when a moderator rejects a post on creation or update
+var ErrPostRejected = errors.New("post rejected by moderation")
+
+// ModerationStatus represents the moderation state of a post
+type ModerationStatus uint8
+
+const (
+	// ModerationApproved is the zero value so that posts created without moderation are visible
+	ModerationApproved ModerationStatus = 0
+	ModerationPending  ModerationStatus = 1
+	ModerationRejected ModerationStatus = 2
+	ModerationHidden   ModerationStatus = 3 // Hidden by a moderator or administrator
+)
+
+// ModerationDecision is the verdict of a Moderator
+type ModerationDecision uint8
+
+const (
+	DecisionApprove ModerationDecision = 0
+	DecisionReject  ModerationDecision = 1
+	DecisionHold    ModerationDecision = 2 // Keep the post out of feeds until a moderator reviews it
+)
+
+// ModerationResult is the outcome of screening a post
+type ModerationResult struct {
+	Decision ModerationDecision
+	Reason   string
+}
+
+// Moderator screens posts before they become public
+type Moderator interface {
+	// Moderate is called with the post about to be created or updated
+	Moderate(ctx context.Context, post *Post) (ModerationResult, error)
+}
+
+// ModeratorFunc adapts a function to the Moderator interface
+type ModeratorFunc func(ctx context.Context, post *Post) (ModerationResult, error)
+
+// Moderate calls f(ctx, post)
+func (f ModeratorFunc) Moderate(ctx context.Context, post *Post) (ModerationResult, error) {
+	return f(ctx, post)
+}
+
+// ModerationStore defines the interface for stores that keep a moderation queue
+type ModerationStore interface {
+	// SetModerationStatus changes the moderation status of a post and bumps its version.
+	// The version must match the stored version, so that a decision is never applied to content the moderator
+	// did not see; otherwise ErrVersionConflict is returned.
+	// Stores with an outbox record the change as a PostUpdated event by the actor in the context.
+	SetModerationStatus(ctx context.Context, postID string, version int64, status ModerationStatus, reason string) error
+}
+
+// WithModerator screens every created and updated post with the given moderator
+func WithModerator(moderator Moderator) PostManagerOption {
+	return func(m *PostManagerImpl) {
+		m.moderator = moderator
+	}
+}
+
+// moderationStatus returns the moderation status the filter selects
+func (f *PostFilter) moderationStatus() ModerationStatus {
+	if f.ModerationStatus == nil {
+		return ModerationApproved
+	}
+	return *f.ModerationStatus
+}
+
+// checkModerationReadable lets only the authors of a post and privileged actors read it while it is
+// held, rejected or hidden
+func checkModerationReadable(actor Actor, post *Post) error {
+	if post.ModerationStatus == ModerationApproved || actor.IsPrivileged() || post.isAuthor(actor.UserID) {
+		return nil
+	}
+	return ErrPermissionDenied
+}
+
+// checkModerationFilter lets only privileged actors list posts that are not approved.
+// A context without an actor is trusted.
+func checkModerationFilter(ctx context.Context, filter *PostFilter) error {
+	if filter == nil || filter.moderationStatus() == ModerationApproved {
+		return nil
+	}
+	if actor, ok := ActorFromContext(ctx); ok && !actor.IsPrivileged() {
+		return ErrPermissionDenied
+	}
+	return nil
+}
+
+// screenPost checks a post against the blocklist and then the moderator
+func (m *PostManagerImpl) screenPost(ctx context.Context, post *Post) error {
+	if m.blocklist != nil {
+		if err := m.blocklist.Check(post); err != nil {
+			return err
+		}
+	}
+
+	return m.moderatePost(ctx, post)
+}
+
+// moderatePost runs the configured moderator and records its verdict on the post.
+// Without a moderator the post keeps its current status. The moderator can only make the status
+// stricter: an edit never lifts a hold, rejection or hide, which only ApprovePost and UnhidePost do.
+func (m *PostManagerImpl) moderatePost(ctx context.Context, post *Post) error {
+	if m.moderator == nil {
+		return nil
+	}
+
+	result, err := m.moderator.Moderate(ctx, post)
+	if err != nil {
+		return err
+	}
+
+	switch result.Decision {
+	case DecisionApprove:
+	case DecisionHold:
+		if post.ModerationStatus == ModerationApproved {
+			post.ModerationStatus = ModerationPending
+			post.ModerationReason = result.Reason
+		}
+	case DecisionReject:
+		if result.Reason == "" {
+			return ErrPostRejected
+		}
+		return fmt.Errorf("%w: %s", ErrPostRejected, result.Reason)
+	default:
+		return fmt.Errorf("unknown moderation decision %d", result.Decision)
+	}
+
+	return nil
+}
+
+// ListPendingPosts returns posts waiting for review, oldest first.
+// Only actors allowed to moderate may list them; the actor is taken from the context.
+func (m *PostManagerImpl) ListPendingPosts(ctx context.Context, limit, offset int) ([]*Post, error) {
+	if _, err := m.authorizeModerator(ctx, nil); err != nil {
+		return nil, err
+	}
+
+	pending := ModerationPending
+	return m.store.ListPosts(ctx, &PostFilter{
+		ModerationStatus: &pending,
+		SortBy:           "created_at",
+		SortOrder:        "asc",
+		Limit:            limit,
+		Offset:           offset,
+	})
+}
+
+// ApprovePost publishes a post held for review.
+// Only actors allowed to moderate the post may approve it; the actor is taken from the context.
+func (m *PostManagerImpl) ApprovePost(ctx context.Context, postID string) error {
+	return m.moderateAs(ctx, postID, ModerationApproved, "", AuditApprove)
+}
+
+// RejectPost hides a post from all feeds, recording the reason.
+// Only actors allowed to moderate the post may reject it; the actor is taken from the context.
+func (m *PostManagerImpl) RejectPost(ctx context.Context, postID string, reason string) error {
+	return m.moderateAs(ctx, postID, ModerationRejected, reason, AuditReject)
+}
+
+// setModerationStatus updates the moderation status of a post through the store on behalf of the actor,
+// running the PostUpdated hooks with the post as read before the change
+func (m *PostManagerImpl) setModerationStatus(ctx context.Context, post *Post, actor Actor, status ModerationStatus, reason string) error {
+	store, ok := m.store.(ModerationStore)
+	if !ok {
+		return ErrNotSupported
+	}
+
+	updated := *post
+	updated.ModerationStatus = status
+	updated.ModerationReason = reason
+	updated.Version = post.Version + 1
+	event := newEvent(EventPostUpdated, &updated, actor)
+	event.Previous = post
+	if err := m.runBeforeHooks(ctx, event); err != nil {
+		return err
+	}
+
+	if err := store.SetModerationStatus(WithActor(ctx, actor), post.ID, post.Version, status, reason); err != nil {
+		return err
+	}
+
+	m.runAfterHooks(ctx, event)
+	return nil
+}
+
+// SetModerationStatus changes the moderation status of a post if it is still at the given version
+func (s *InMemoryPostStore) SetModerationStatus(ctx context.Context, postID string, version int64, status ModerationStatus, reason string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	post, exists := s.lookupPost(ctx, postID)
+	if !exists {
+		return ErrPostNotFound
+	}
+	if post.Version != version {
+		return ErrVersionConflict
+	}
+
+	// Replace rather than modify the stored post, which may be shared with callers
+	updated := *post
+	updated.ModerationStatus = status
+	updated.ModerationReason = reason
+	updated.Version = post.Version + 1
+	s.posts[postID] = &updated
+
+	return nil
+}
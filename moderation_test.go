@@ -0,0 +1,299 @@
+package postflow
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// keywordModerator rejects posts containing "spam" and holds posts containing "review"
+var keywordModerator = ModeratorFunc(func(ctx context.Context, post *Post) (ModerationResult, error) {
+	switch {
+	case strings.Contains(post.Content, "spam"):
+		return ModerationResult{Decision: DecisionReject, Reason: "spam"}, nil
+	case strings.Contains(post.Content, "review"):
+		return ModerationResult{Decision: DecisionHold, Reason: "needs review"}, nil
+	default:
+		return ModerationResult{Decision: DecisionApprove}, nil
+	}
+})
+
+// TestPostManagerModeration tests screening posts on create and update
+func TestPostManagerModeration(t *testing.T) {
+	pm := NewPostManager(NewInMemoryPostStore(), WithModerator(keywordModerator))
+	ctx := context.Background()
+
+	// Test: rejected posts are not created
+	post := createTestPostData("user1")
+	post.Content = "buy spam now"
+	_, err := pm.CreatePost(ctx, post)
+	assert.ErrorIs(t, err, ErrPostRejected)
+	assert.Contains(t, err.Error(), "spam")
+	_, err = pm.GetPost(ctx, post.ID)
+	assert.Equal(t, ErrPostNotFound, err)
+
+	// Test: approved posts are public
+	approved := createTestPostData("user1")
+	approvedID, err := pm.CreatePost(ctx, approved)
+	require.NoError(t, err)
+
+	// Test: held posts are pending and stay out of feeds and trending
+	held := createTestPostData("user1")
+	held.Content = "please review me"
+	heldID, err := pm.CreatePost(ctx, held)
+	require.NoError(t, err)
+
+	stored, err := pm.GetPost(ctx, heldID)
+	assert.NoError(t, err)
+	assert.Equal(t, ModerationPending, stored.ModerationStatus)
+	assert.Equal(t, "needs review", stored.ModerationReason)
+
+	feed, err := pm.GetUserFeed(ctx, "user2", 10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(feed))
+	assert.Equal(t, approvedID, feed[0].ID)
+
+	trending, err := pm.GetTrendingPosts(ctx, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(trending))
+
+	listed, err := pm.ListPosts(ctx, &PostFilter{UserID: "user1"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(listed))
+
+	// Test: updates are screened again
+	approved.Content = "now with spam"
+	err = pm.UpdatePost(ctx, approved)
+	assert.ErrorIs(t, err, ErrPostRejected)
+
+	_, err = pm.PatchPost(ctx, approvedID, "user1", &PostPatch{Fields: PatchContent, Content: "under review"})
+	assert.NoError(t, err)
+	stored, err = pm.GetPost(ctx, approvedID)
+	assert.NoError(t, err)
+	assert.Equal(t, ModerationPending, stored.ModerationStatus)
+}
+
+// TestPostManagerModerationQueue tests listing, approving and rejecting pending posts
+func TestPostManagerModerationQueue(t *testing.T) {
+	pm := NewPostManager(NewInMemoryPostStore(), WithModerator(keywordModerator))
+	ctx := context.Background()
+	modCtx := WithActor(ctx, Actor{UserID: "mod1", Role: RoleModerator})
+
+	var pendingIDs []string
+	for i := 0; i < 3; i++ {
+		post := createTestPostData("user1")
+		post.Content = "review"
+		postID, err := pm.CreatePost(ctx, post)
+		require.NoError(t, err)
+		pendingIDs = append(pendingIDs, postID)
+	}
+
+	// Test: list pending posts
+	pending, err := pm.ListPendingPosts(modCtx, 10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(pending))
+
+	// Test: only moderators can list the queue
+	authorCtx := WithActor(ctx, Actor{UserID: "user1"})
+	_, err = pm.ListPendingPosts(authorCtx, 10, 0)
+	assert.Equal(t, ErrPermissionDenied, err)
+	_, err = pm.ListPendingPosts(ctx, 10, 0)
+	assert.Equal(t, ErrPermissionDenied, err)
+
+	// Test: authors cannot review their own held posts
+	assert.Equal(t, ErrPermissionDenied, pm.ApprovePost(authorCtx, pendingIDs[0]))
+	assert.Equal(t, ErrPermissionDenied, pm.RejectPost(authorCtx, pendingIDs[0], ""))
+	assert.Equal(t, ErrPermissionDenied, pm.ApprovePost(ctx, pendingIDs[0]))
+	stored, err := pm.GetPost(ctx, pendingIDs[0])
+	assert.NoError(t, err)
+	assert.Equal(t, ModerationPending, stored.ModerationStatus)
+
+	// Test: approve a post
+	err = pm.ApprovePost(modCtx, pendingIDs[0])
+	assert.NoError(t, err)
+	feed, err := pm.GetUserFeed(ctx, "user2", 10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(feed))
+
+	// Test: reject a post with a reason
+	err = pm.RejectPost(modCtx, pendingIDs[1], "off-topic")
+	assert.NoError(t, err)
+	rejected, err := pm.GetPost(ctx, pendingIDs[1])
+	assert.NoError(t, err)
+	assert.Equal(t, ModerationRejected, rejected.ModerationStatus)
+	assert.Equal(t, "off-topic", rejected.ModerationReason)
+
+	rejectedStatus := ModerationRejected
+	listed, err := pm.ListPosts(ctx, &PostFilter{ModerationStatus: &rejectedStatus})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(listed))
+
+	pending, err = pm.ListPendingPosts(modCtx, 10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(pending))
+	assert.Equal(t, pendingIDs[2], pending[0].ID)
+
+	// Test: nonexistent post
+	err = pm.ApprovePost(modCtx, "nonexistent-id")
+	assert.Equal(t, ErrPostNotFound, err)
+}
+
+// TestPostManagerModerationEditKeepsVerdict tests that edits by the author never loosen the moderation status
+func TestPostManagerModerationEditKeepsVerdict(t *testing.T) {
+	store := NewInMemoryPostStore()
+	pm := NewPostManager(store, WithModerator(keywordModerator), WithReportThreshold(2))
+	ctx := context.Background()
+	modCtx := WithActor(ctx, Actor{UserID: "mod1", Role: RoleModerator})
+	authorCtx := WithActor(ctx, Actor{UserID: "user1"})
+
+	// Test: editing a hidden post keeps it hidden
+	hidden := createTestPostData("user1")
+	hiddenID, err := pm.CreatePost(ctx, hidden)
+	require.NoError(t, err)
+	require.NoError(t, pm.HidePost(modCtx, hiddenID, "off topic"))
+
+	stored, err := store.GetPost(ctx, hiddenID)
+	require.NoError(t, err)
+	stored.Content = "harmless now"
+	require.NoError(t, pm.UpdatePost(authorCtx, stored))
+	stored, err = store.GetPost(ctx, hiddenID)
+	require.NoError(t, err)
+	assert.Equal(t, ModerationHidden, stored.ModerationStatus)
+	assert.Equal(t, "off topic", stored.ModerationReason)
+
+	_, err = pm.PatchPost(authorCtx, hiddenID, "user1", &PostPatch{Fields: PatchContent, Content: "still harmless"})
+	require.NoError(t, err)
+	stored, err = store.GetPost(ctx, hiddenID)
+	require.NoError(t, err)
+	assert.Equal(t, ModerationHidden, stored.ModerationStatus)
+
+	// Test: editing a post held after reports keeps it held
+	reported := createTestPostData("user1")
+	reportedID, err := pm.CreatePost(ctx, reported)
+	require.NoError(t, err)
+	for _, reporterID := range []string{"user2", "user3"} {
+		require.NoError(t, pm.ReportPost(ctx, reportedID, reporterID, ReportSpam, ""))
+	}
+
+	stored, err = store.GetPost(ctx, reportedID)
+	require.NoError(t, err)
+	require.Equal(t, ModerationPending, stored.ModerationStatus)
+	stored.Content = "edited after reports"
+	require.NoError(t, pm.UpdatePost(authorCtx, stored))
+	_, err = pm.PatchPost(authorCtx, reportedID, "user1", &PostPatch{Fields: PatchContent, Content: "patched after reports"})
+	require.NoError(t, err)
+	stored, err = store.GetPost(ctx, reportedID)
+	require.NoError(t, err)
+	assert.Equal(t, ModerationPending, stored.ModerationStatus)
+	assert.Equal(t, "hidden after 2 reports", stored.ModerationReason)
+
+	// Test: a rejected post stays rejected
+	require.NoError(t, pm.RejectPost(modCtx, reportedID, "spam"))
+	_, err = pm.PatchPost(authorCtx, reportedID, "user1", &PostPatch{Fields: PatchContent, Content: "patched again"})
+	require.NoError(t, err)
+	stored, err = store.GetPost(ctx, reportedID)
+	require.NoError(t, err)
+	assert.Equal(t, ModerationRejected, stored.ModerationStatus)
+
+	// Test: the moderator can still hold an approved post
+	approvedID, err := pm.CreatePost(ctx, createTestPostData("user1"))
+	require.NoError(t, err)
+	_, err = pm.PatchPost(authorCtx, approvedID, "user1", &PostPatch{Fields: PatchContent, Content: "please review"})
+	require.NoError(t, err)
+	stored, err = store.GetPost(ctx, approvedID)
+	require.NoError(t, err)
+	assert.Equal(t, ModerationPending, stored.ModerationStatus)
+}
+
+// TestPostManagerModerationReadAccess tests that posts under moderation are only readable by their authors and moderators
+func TestPostManagerModerationReadAccess(t *testing.T) {
+	pm := NewPostManager(NewInMemoryPostStore(), WithModerator(keywordModerator))
+	ctx := context.Background()
+	modCtx := WithActor(ctx, Actor{UserID: "mod1", Role: RoleModerator})
+	authorCtx := WithActor(ctx, Actor{UserID: "user1"})
+	bobCtx := WithActor(ctx, Actor{UserID: "bob"})
+
+	held := createTestPostData("user1")
+	held.Content = "please review me"
+	heldID, err := pm.CreatePost(ctx, held)
+	require.NoError(t, err)
+	hiddenID, err := pm.CreatePost(ctx, createTestPostData("user1"))
+	require.NoError(t, err)
+	require.NoError(t, pm.HidePost(modCtx, hiddenID, "off topic"))
+	rejectedID, err := pm.CreatePost(ctx, createTestPostData("user1"))
+	require.NoError(t, err)
+	require.NoError(t, pm.RejectPost(modCtx, rejectedID, "spam"))
+
+	// Test: other users cannot read posts under moderation or their reactions
+	for _, postID := range []string{heldID, hiddenID, rejectedID} {
+		_, err = pm.GetPost(bobCtx, postID)
+		assert.Equal(t, ErrPermissionDenied, err)
+		_, err = pm.GetReactionCounts(bobCtx, postID)
+		assert.Equal(t, ErrPermissionDenied, err)
+	}
+
+	// Test: authors and moderators can
+	for _, readCtx := range []context.Context{authorCtx, modCtx} {
+		post, err := pm.GetPost(readCtx, hiddenID)
+		require.NoError(t, err)
+		assert.Equal(t, ModerationHidden, post.ModerationStatus)
+	}
+
+	// Test: only privileged actors can list or watch posts that are not approved
+	hidden := ModerationHidden
+	_, err = pm.ListPosts(bobCtx, &PostFilter{ModerationStatus: &hidden})
+	assert.Equal(t, ErrPermissionDenied, err)
+	_, err = pm.ListPosts(authorCtx, &PostFilter{UserID: "user1", ModerationStatus: &hidden})
+	assert.Equal(t, ErrPermissionDenied, err)
+	listed, err := pm.ListPosts(modCtx, &PostFilter{ModerationStatus: &hidden})
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, hiddenID, listed[0].ID)
+
+	pending := ModerationPending
+	_, err = pm.Watch(bobCtx, &WatchFilter{Posts: &PostFilter{ModerationStatus: &pending}})
+	assert.Equal(t, ErrPermissionDenied, err)
+}
+
+// TestPostManagerModerationEditRacesApproval tests that a moderation decision is not applied to content edited since it was read
+func TestPostManagerModerationEditRacesApproval(t *testing.T) {
+	store := NewInMemoryPostStore()
+	pm := NewPostManager(store, WithModerator(keywordModerator))
+	ctx := context.Background()
+	modCtx := WithActor(ctx, Actor{UserID: "mod1", Role: RoleModerator})
+
+	held := createTestPostData("user1")
+	held.Content = "please review me"
+	heldID, err := pm.CreatePost(ctx, held)
+	require.NoError(t, err)
+
+	// The author edits the post after the moderator read it
+	edited := false
+	pm.OnBefore(EventPostUpdated, func(ctx context.Context, event *Event) error {
+		if edited || event.Actor.UserID != "mod1" {
+			return nil
+		}
+		edited = true
+		post, err := store.GetPost(ctx, heldID)
+		require.NoError(t, err)
+		post.Content = "please review me, now with a link"
+		return store.SavePost(ctx, post)
+	})
+
+	// Test: the approval is refused and the edited post stays held
+	err = pm.ApprovePost(modCtx, heldID)
+	assert.Equal(t, ErrVersionConflict, err)
+	post, err := pm.GetPost(modCtx, heldID)
+	require.NoError(t, err)
+	assert.Equal(t, ModerationPending, post.ModerationStatus)
+	assert.Equal(t, "please review me, now with a link", post.Content)
+
+	// Test: approving the post as read again succeeds
+	require.NoError(t, pm.ApprovePost(modCtx, heldID))
+	post, err = pm.GetPost(ctx, heldID)
+	require.NoError(t, err)
+	assert.Equal(t, ModerationApproved, post.ModerationStatus)
+}
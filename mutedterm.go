@@ -0,0 +1,303 @@
+// Package postflow provides functionality for managing user posts and feeds.
+package postflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrInvalidMutedTerm is returned when a muted word or tag is empty
+var ErrInvalidMutedTerm = errors.New("invalid muted term")
+
+// MutedTermKind represents what a muted term is matched against
+type MutedTermKind uint8
+
+const (
+	MutedWord MutedTermKind = 1 // Words or phrases in the content and tags
+	MutedTag  MutedTermKind = 2 // Exact tags, case-insensitive
+)
+
+// String returns the name of the muted term kind
+func (k MutedTermKind) String() string {
+	switch k {
+	case MutedWord:
+		return "word"
+	case MutedTag:
+		return "tag"
+	default:
+		return "unknown"
+	}
+}
+
+// MutedTerm hides posts about a topic from one viewer's feed, trending and tag feeds
+type MutedTerm struct {
+	UserID    string        `json:"user_id"`
+	Kind      MutedTermKind `json:"kind"`
+	Value     string        `json:"value"`                // Lower-cased word, phrase or tag
+	Collapse  bool          `json:"collapse"`             // Collapse matching posts instead of dropping them
+	ExpiresAt time.Time     `json:"expires_at,omitempty"` // Zero for terms that never expire
+	CreatedAt time.Time     `json:"created_at"`
+}
+
+// MuteOptions configures a muted term
+type MuteOptions struct {
+	Duration time.Duration // How long the term stays muted; zero mutes it until removed
+	Collapse bool          // Collapse matching posts instead of dropping them
+}
+
+// MutedTermStore defines the interface for stores that keep muted words and tags.
+// Muted terms belong to the tenant in the context.
+type MutedTermStore interface {
+	// SaveMutedTerm adds or replaces a muted term
+	SaveMutedTerm(ctx context.Context, term *MutedTerm) error
+
+	// DeleteMutedTerm removes a muted term
+	DeleteMutedTerm(ctx context.Context, userID string, kind MutedTermKind, value string) error
+
+	// ListMutedTerms returns the unexpired muted terms of a user, oldest first
+	ListMutedTerms(ctx context.Context, userID string) ([]*MutedTerm, error)
+}
+
+// MuteWord hides posts containing a word or phrase from the user
+func (m *PostManagerImpl) MuteWord(ctx context.Context, userID string, word string, opts MuteOptions) (*MutedTerm, error) {
+	return m.muteTerm(ctx, userID, MutedWord, normalizeContent(word), opts)
+}
+
+// MuteTag hides posts with a tag from the user
+func (m *PostManagerImpl) MuteTag(ctx context.Context, userID string, tag string, opts MuteOptions) (*MutedTerm, error) {
+	return m.muteTerm(ctx, userID, MutedTag, normalizeTag(tag), opts)
+}
+
+// UnmuteWord removes a muted word or phrase
+func (m *PostManagerImpl) UnmuteWord(ctx context.Context, userID string, word string) error {
+	return m.unmuteTerm(ctx, userID, MutedWord, normalizeContent(word))
+}
+
+// UnmuteTag removes a muted tag
+func (m *PostManagerImpl) UnmuteTag(ctx context.Context, userID string, tag string) error {
+	return m.unmuteTerm(ctx, userID, MutedTag, normalizeTag(tag))
+}
+
+// ListMutedTerms returns the unexpired muted words and tags of a user
+func (m *PostManagerImpl) ListMutedTerms(ctx context.Context, userID string) ([]*MutedTerm, error) {
+	store, ok := m.store.(MutedTermStore)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	return store.ListMutedTerms(ctx, userID)
+}
+
+// muteTerm validates and stores a muted term
+func (m *PostManagerImpl) muteTerm(ctx context.Context, userID string, kind MutedTermKind, value string, opts MuteOptions) (*MutedTerm, error) {
+	store, ok := m.store.(MutedTermStore)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	if userID == "" || value == "" {
+		return nil, ErrInvalidMutedTerm
+	}
+
+	now := time.Now()
+	term := &MutedTerm{
+		UserID:    userID,
+		Kind:      kind,
+		Value:     value,
+		Collapse:  opts.Collapse,
+		CreatedAt: now,
+	}
+	if opts.Duration > 0 {
+		term.ExpiresAt = now.Add(opts.Duration)
+	}
+
+	if err := store.SaveMutedTerm(ctx, term); err != nil {
+		return nil, err
+	}
+
+	return term, nil
+}
+
+// unmuteTerm removes a muted term
+func (m *PostManagerImpl) unmuteTerm(ctx context.Context, userID string, kind MutedTermKind, value string) error {
+	store, ok := m.store.(MutedTermStore)
+	if !ok {
+		return ErrNotSupported
+	}
+
+	return store.DeleteMutedTerm(ctx, userID, kind, value)
+}
+
+// normalizeTag lower-cases a tag and strips a leading hash sign
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+}
+
+// honorsMutedTerms reports whether the filter selects a tag or group feed, where the muted words
+// and tags of the viewer apply
+func (f *PostFilter) honorsMutedTerms() bool {
+	return len(f.Tags) > 0 || f.GroupID != ""
+}
+
+// applyMutedTerms collapses posts matching the viewer's muted terms. Stores already leave out posts
+// matching terms that drop them, so that pages stay full; posts matching a term muted since the store
+// read them are dropped here. The viewer's own posts are never muted.
+func (m *PostManagerImpl) applyMutedTerms(ctx context.Context, viewer string, posts []*Post) ([]*Post, error) {
+	store, ok := m.store.(MutedTermStore)
+	if !ok || viewer == "" || len(posts) == 0 {
+		return posts, nil
+	}
+
+	terms, err := store.ListMutedTerms(ctx, viewer)
+	if err != nil {
+		return nil, err
+	}
+	if len(terms) == 0 {
+		return posts, nil
+	}
+
+	result := posts[:0]
+	for _, post := range posts {
+		if post.UserID == viewer {
+			result = append(result, post)
+			continue
+		}
+
+		collapse, matched := matchMutedTerms(post, terms)
+		if matched == nil {
+			result = append(result, post)
+			continue
+		}
+		if collapse {
+			post.Collapsed = true
+			post.CollapseReason = fmt.Sprintf("muted %s %q", matched.Kind, matched.Value)
+			result = append(result, post)
+		}
+	}
+
+	return result, nil
+}
+
+// matchMutedTerms returns the first term matching the post and whether the post should be
+// collapsed rather than dropped, which is only the case when every matching term collapses
+func matchMutedTerms(post *Post, terms []*MutedTerm) (bool, *MutedTerm) {
+	var words []string
+	var tagWords [][]string
+	tags := make(map[string]bool, len(post.Tags))
+	for _, tag := range post.Tags {
+		tags[normalizeTag(tag)] = true
+	}
+
+	var matched *MutedTerm
+	for _, term := range terms {
+		hit := false
+		switch term.Kind {
+		case MutedTag:
+			hit = tags[term.Value]
+		case MutedWord:
+			if words == nil {
+				words = tokenizeWords(post.Content)
+				for _, tag := range post.Tags {
+					tagWords = append(tagWords, tokenizeWords(tag))
+				}
+			}
+			phrase := strings.Fields(term.Value)
+			hit = containsPhrase(words, phrase)
+			for i := 0; !hit && i < len(tagWords); i++ {
+				hit = containsPhrase(tagWords[i], phrase)
+			}
+		}
+
+		if !hit {
+			continue
+		}
+		if !term.Collapse {
+			return false, term
+		}
+		if matched == nil {
+			matched = term
+		}
+	}
+
+	return matched != nil, matched
+}
+
+// mutedFor reports whether the post matches a muted term of the viewer that drops matching posts.
+// The viewer's own posts are never muted. The caller must hold the mutex.
+func (s *InMemoryPostStore) mutedFor(post *Post, viewer string) bool {
+	viewerTerms := s.mutedTerms[tenantTag(post.TenantID, viewer)]
+	if post.UserID == viewer || len(viewerTerms) == 0 {
+		return false
+	}
+
+	now := time.Now()
+	var terms []*MutedTerm
+	for _, term := range viewerTerms {
+		if term.Collapse || !term.ExpiresAt.IsZero() && !term.ExpiresAt.After(now) {
+			continue
+		}
+		terms = append(terms, term)
+	}
+
+	_, matched := matchMutedTerms(post, terms)
+	return matched != nil
+}
+
+// mutedTermKey identifies a muted term of a user in the in-memory store
+func mutedTermKey(kind MutedTermKind, value string) string {
+	return fmt.Sprintf("%d:%s", kind, value)
+}
+
+// SaveMutedTerm adds or replaces a muted term
+func (s *InMemoryPostStore) SaveMutedTerm(ctx context.Context, term *MutedTerm) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	userKey := tenantScopedKey(ctx, term.UserID)
+	if s.mutedTerms[userKey] == nil {
+		s.mutedTerms[userKey] = make(map[string]*MutedTerm)
+	}
+	termCopy := *term
+	s.mutedTerms[userKey][mutedTermKey(term.Kind, term.Value)] = &termCopy
+
+	return nil
+}
+
+// DeleteMutedTerm removes a muted term
+func (s *InMemoryPostStore) DeleteMutedTerm(ctx context.Context, userID string, kind MutedTermKind, value string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.mutedTerms[tenantScopedKey(ctx, userID)], mutedTermKey(kind, value))
+
+	return nil
+}
+
+// ListMutedTerms returns the unexpired muted terms of a user, oldest first
+func (s *InMemoryPostStore) ListMutedTerms(ctx context.Context, userID string) ([]*MutedTerm, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	now := time.Now()
+	userTerms := s.mutedTerms[tenantScopedKey(ctx, userID)]
+	terms := make([]*MutedTerm, 0, len(userTerms))
+	for _, term := range userTerms {
+		if !term.ExpiresAt.IsZero() && !term.ExpiresAt.After(now) {
+			continue
+		}
+		termCopy := *term
+		terms = append(terms, &termCopy)
+	}
+
+	sort.Slice(terms, func(i, j int) bool {
+		if terms[i].CreatedAt.Equal(terms[j].CreatedAt) {
+			return terms[i].Value < terms[j].Value
+		}
+		return terms[i].CreatedAt.Before(terms[j].CreatedAt)
+	})
+
+	return terms, nil
+}
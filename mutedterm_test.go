@@ -0,0 +1,123 @@
+package postflow
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPostManagerMutedTerms tests muted words and tags with the in-memory store
+func TestPostManagerMutedTerms(t *testing.T) {
+	pm := setupTestPostManager()
+	ctx := context.Background()
+
+	create := func(userID, content string, tags ...string) string {
+		post := createTestPostData(userID)
+		post.Content = content
+		post.Tags = tags
+		id, err := pm.CreatePost(ctx, post)
+		require.NoError(t, err)
+		return id
+	}
+
+	plainID := create("user2", "A quiet day at the park", "weather")
+	spoilerID := create("user2", "Huge SPOILERS: the butler did it!", "movies")
+	politicsID := create("user3", "Debate tonight", "Politics")
+	japaneseID := create("user3", "今日はネタバレ注意です", "anime")
+	ownID := create("user1", "My own spoilers post", "politics")
+
+	// Test: invalid terms
+	_, err := pm.MuteWord(ctx, "user1", " !! ", MuteOptions{})
+	assert.Equal(t, ErrInvalidMutedTerm, err)
+
+	_, err = pm.MuteWord(ctx, "user1", "spoilers", MuteOptions{})
+	require.NoError(t, err)
+	_, err = pm.MuteTag(ctx, "user1", "#politics", MuteOptions{Collapse: true})
+	require.NoError(t, err)
+	_, err = pm.MuteWord(ctx, "user1", "ネタバレ", MuteOptions{})
+	require.NoError(t, err)
+	_, err = pm.MuteWord(ctx, "user1", "butler", MuteOptions{Duration: 20 * time.Millisecond})
+	require.NoError(t, err)
+
+	terms, err := pm.ListMutedTerms(ctx, "user1")
+	assert.NoError(t, err)
+	assert.Equal(t, 4, len(terms))
+
+	// Test: matching posts are dropped or collapsed, own posts are kept
+	feed, err := pm.GetUserFeed(ctx, "user1", 10, 0)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{plainID, politicsID, ownID}, postIDs(feed))
+	for _, post := range feed {
+		if post.ID == politicsID {
+			assert.True(t, post.Collapsed)
+			assert.Equal(t, `muted tag "politics"`, post.CollapseReason)
+		} else {
+			assert.False(t, post.Collapsed)
+		}
+	}
+
+	viewerCtx := WithActor(ctx, Actor{UserID: "user1"})
+	trending, err := pm.GetTrendingPosts(viewerCtx, 10)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{plainID, politicsID, ownID}, postIDs(trending))
+
+	tagFeed, err := pm.ListPosts(viewerCtx, &PostFilter{Tags: []string{"movies"}})
+	assert.NoError(t, err)
+	assert.Empty(t, tagFeed)
+
+	// Test: muted posts are left out before paging, so pages stay full
+	page, err := pm.GetUserFeed(ctx, "user1", 2, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{ownID, politicsID}, postIDs(page))
+	page, err = pm.GetUserFeed(ctx, "user1", 2, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{plainID}, postIDs(page))
+
+	// Test: profiles and other viewers are unaffected
+	profile, err := pm.ListPosts(viewerCtx, &PostFilter{UserID: "user2"})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(profile))
+	feed, err = pm.GetUserFeed(ctx, "user4", 10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, len(feed))
+
+	// Test: expired and removed terms no longer apply
+	time.Sleep(30 * time.Millisecond)
+	terms, err = pm.ListMutedTerms(ctx, "user1")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(terms))
+
+	require.NoError(t, pm.UnmuteWord(ctx, "user1", "Spoilers"))
+	require.NoError(t, pm.UnmuteTag(ctx, "user1", "politics"))
+	feed, err = pm.GetUserFeed(ctx, "user1", 10, 0)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{plainID, spoilerID, politicsID, ownID}, postIDs(feed))
+	assert.NotContains(t, postIDs(feed), japaneseID)
+}
+
+// TestMatchMutedTerms tests matching posts against muted terms
+func TestMatchMutedTerms(t *testing.T) {
+	post := &Post{Content: "Season finale tonight", Tags: []string{"TV-Shows"}}
+
+	collapse, matched := matchMutedTerms(post, []*MutedTerm{{Kind: MutedWord, Value: "season finale", Collapse: true}})
+	assert.True(t, collapse)
+	require.NotNil(t, matched)
+
+	// Test: a dropping term wins over a collapsing one
+	collapse, matched = matchMutedTerms(post, []*MutedTerm{
+		{Kind: MutedWord, Value: "finale", Collapse: true},
+		{Kind: MutedTag, Value: "tv-shows"},
+	})
+	assert.False(t, collapse)
+	require.NotNil(t, matched)
+	assert.Equal(t, MutedTag, matched.Kind)
+
+	// Test: words match inside tags, but only whole words
+	_, matched = matchMutedTerms(post, []*MutedTerm{{Kind: MutedWord, Value: "shows"}})
+	assert.NotNil(t, matched)
+	_, matched = matchMutedTerms(post, []*MutedTerm{{Kind: MutedWord, Value: "season fin"}})
+	assert.Nil(t, matched)
+}
@@ -0,0 +1,603 @@
+// Package postflow provides functionality for managing user posts and feeds.
+package postflow
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrNotificationNotFound is returned when a notification does not exist or belongs to another user
+	ErrNotificationNotFound = errors.New("notification not found")
+
+	// ErrInvalidNotification is returned for unknown notification kinds or malformed cursors
+	ErrInvalidNotification = errors.New("invalid notification")
+)
+
+// Limits of notifications
+const (
+	DefaultNotificationPageSize = 20 // Notifications per page when no limit is given
+	NotificationActorLimit      = 3  // Most recent actors kept on a notification for display
+)
+
+// NotificationKind is the category of a notification
+type NotificationKind uint8
+
+const (
+	NotificationReaction NotificationKind = 1 // Someone reacted to the user's post
+	NotificationComment  NotificationKind = 2 // Someone commented on the user's post
+	NotificationShare    NotificationKind = 3 // Someone shared the user's post
+	NotificationMention  NotificationKind = 4 // Someone mentioned the user in a post
+)
+
+// String returns the name of the notification kind
+func (k NotificationKind) String() string {
+	switch k {
+	case NotificationReaction:
+		return "reaction"
+	case NotificationComment:
+		return "comment"
+	case NotificationShare:
+		return "share"
+	case NotificationMention:
+		return "mention"
+	default:
+		return "unknown"
+	}
+}
+
+// Notification tells a user about activity on a post. Activity of the same kind on the same post is
+// aggregated into the user's unread notification; once read, further activity starts a new one.
+type Notification struct {
+	ID         string           `json:"id"`
+	TenantID   string           `json:"tenant_id,omitempty"`
+	UserID     string           `json:"user_id"` // The notified user
+	Kind       NotificationKind `json:"kind"`
+	PostID     string           `json:"post_id"`
+	Actors     []string         `json:"actors"`      // Most recent actors first, up to NotificationActorLimit
+	ActorCount int              `json:"actor_count"` // Distinct users behind the notification
+	Read       bool             `json:"read"`
+	CreatedAt  time.Time        `json:"created_at"`
+	UpdatedAt  time.Time        `json:"updated_at"` // Time of the latest activity
+}
+
+// Summary describes the notification, e.g. "alice and 12 others reacted to your post"
+func (n *Notification) Summary() string {
+	var action string
+	switch n.Kind {
+	case NotificationReaction:
+		action = "reacted to your post"
+	case NotificationComment:
+		action = "commented on your post"
+	case NotificationShare:
+		action = "shared your post"
+	case NotificationMention:
+		action = "mentioned you in a post"
+	default:
+		action = "interacted with your post"
+	}
+
+	switch {
+	case len(n.Actors) == 0:
+		return "Someone " + action
+	case n.ActorCount <= 1:
+		return fmt.Sprintf("%s %s", n.Actors[0], action)
+	case n.ActorCount == 2 && len(n.Actors) >= 2:
+		return fmt.Sprintf("%s and %s %s", n.Actors[0], n.Actors[1], action)
+	case n.ActorCount == 2:
+		return fmt.Sprintf("%s and 1 other %s", n.Actors[0], action)
+	default:
+		return fmt.Sprintf("%s and %d others %s", n.Actors[0], n.ActorCount-1, action)
+	}
+}
+
+// NotificationCursor is the position after a notification in a listing, which is ordered
+// by the time of the latest activity, most recent first
+type NotificationCursor struct {
+	UpdatedAt time.Time
+	ID        string
+}
+
+// String encodes the cursor as an opaque token
+func (c NotificationCursor) String() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(c.UpdatedAt.UnixNano(), 10) + ":" + c.ID))
+}
+
+// before reports whether a notification comes after the cursor in a listing
+func (c *NotificationCursor) before(n *Notification) bool {
+	if c == nil {
+		return true
+	}
+	if !n.UpdatedAt.Equal(c.UpdatedAt) {
+		return n.UpdatedAt.Before(c.UpdatedAt)
+	}
+	return n.ID < c.ID
+}
+
+// ParseNotificationCursor decodes a cursor returned by ListNotifications. An empty token means the first page.
+func ParseNotificationCursor(token string) (*NotificationCursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidNotification)
+	}
+	nanos, id, found := strings.Cut(string(raw), ":")
+	if !found {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidNotification)
+	}
+	unixNano, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidNotification)
+	}
+
+	return &NotificationCursor{UpdatedAt: time.Unix(0, unixNano), ID: id}, nil
+}
+
+// NotificationPage is a page of notifications
+type NotificationPage struct {
+	Notifications []*Notification `json:"notifications"`
+	NextCursor    string          `json:"next_cursor,omitempty"` // Empty on the last page
+}
+
+// NotificationPreferences are the notification categories a user turned off
+type NotificationPreferences struct {
+	UserID   string             `json:"user_id"`
+	Disabled []NotificationKind `json:"disabled,omitempty"`
+}
+
+// Enabled reports whether the user receives notifications of the kind
+func (p *NotificationPreferences) Enabled(kind NotificationKind) bool {
+	for _, disabled := range p.Disabled {
+		if disabled == kind {
+			return false
+		}
+	}
+	return true
+}
+
+// NotificationStore defines the interface for stores that keep notifications.
+// Notifications belong to the tenant in the context.
+type NotificationStore interface {
+	// RecordNotification adds the actor to the user's unread notification of the kind about the post,
+	// creating it if there is none. Actors already on the notification are not counted again.
+	RecordNotification(ctx context.Context, userID string, kind NotificationKind, postID, actorID string, at time.Time) error
+
+	// ListNotifications returns a user's notifications after the cursor, most recent activity first
+	ListNotifications(ctx context.Context, userID string, cursor *NotificationCursor, limit int) ([]*Notification, error)
+
+	// MarkNotificationRead marks a notification of the user as read
+	MarkNotificationRead(ctx context.Context, userID, notificationID string) error
+
+	// MarkAllNotificationsRead marks all notifications of the user as read
+	MarkAllNotificationsRead(ctx context.Context, userID string) error
+
+	// CountUnreadNotifications returns the number of unread notifications of the user
+	CountUnreadNotifications(ctx context.Context, userID string) (int, error)
+
+	// GetNotificationPreferences returns the preferences of the user; users without preferences receive everything
+	GetNotificationPreferences(ctx context.Context, userID string) (*NotificationPreferences, error)
+
+	// SaveNotificationPreferences replaces the preferences of the user
+	SaveNotificationPreferences(ctx context.Context, prefs *NotificationPreferences) error
+}
+
+// WithNotifications makes the manager notify post authors and co-authors of reactions,
+// and users mentioned as @userID in new or edited posts.
+// Notifications are recorded by after-hooks on a best-effort basis; failures do not affect the change.
+func WithNotifications() PostManagerOption {
+	return func(m *PostManagerImpl) {
+		m.OnAfter(EventReactionAdded, m.notifyReaction)
+		m.OnAfter(EventPostCreated, m.notifyMentions)
+		m.OnAfter(EventPostUpdated, m.notifyMentions)
+	}
+}
+
+// NotifyPostActivity notifies the authors of a post that the actor commented on or shared it.
+// Comments and shares are kept by the application, which reports them here.
+func (m *PostManagerImpl) NotifyPostActivity(ctx context.Context, postID, actorID string, kind NotificationKind) error {
+	if kind != NotificationComment && kind != NotificationShare {
+		return fmt.Errorf("%w: kind %s is recorded by the manager", ErrInvalidNotification, kind)
+	}
+	if _, ok := m.store.(NotificationStore); !ok {
+		return ErrNotSupported
+	}
+
+	post, err := m.store.GetPost(ctx, postID)
+	if err != nil {
+		return err
+	}
+	if err := m.checkVisibility(ctx, post, actorID); err != nil {
+		return err
+	}
+	if err := m.checkBlocked(ctx, post.UserID, actorID); err != nil {
+		return err
+	}
+
+	return m.notifyAuthors(ctx, post, kind, actorID)
+}
+
+// ListNotifications returns a page of the user's notifications, most recent activity first.
+// Pass the NextCursor of a page to get the following one.
+func (m *PostManagerImpl) ListNotifications(ctx context.Context, userID, cursor string, limit int) (*NotificationPage, error) {
+	store, ok := m.store.(NotificationStore)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	after, err := ParseNotificationCursor(cursor)
+	if err != nil {
+		return nil, err
+	}
+	if limit <= 0 {
+		limit = DefaultNotificationPageSize
+	}
+
+	// Read one more to learn whether there is a next page
+	notifications, err := store.ListNotifications(ctx, userID, after, limit+1)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &NotificationPage{Notifications: notifications}
+	if len(notifications) > limit {
+		page.Notifications = notifications[:limit]
+		last := page.Notifications[limit-1]
+		page.NextCursor = NotificationCursor{UpdatedAt: last.UpdatedAt, ID: last.ID}.String()
+	}
+
+	return page, nil
+}
+
+// MarkNotificationRead marks a notification of the user as read
+func (m *PostManagerImpl) MarkNotificationRead(ctx context.Context, userID, notificationID string) error {
+	store, ok := m.store.(NotificationStore)
+	if !ok {
+		return ErrNotSupported
+	}
+
+	return store.MarkNotificationRead(ctx, userID, notificationID)
+}
+
+// MarkAllNotificationsRead marks all notifications of the user as read
+func (m *PostManagerImpl) MarkAllNotificationsRead(ctx context.Context, userID string) error {
+	store, ok := m.store.(NotificationStore)
+	if !ok {
+		return ErrNotSupported
+	}
+
+	return store.MarkAllNotificationsRead(ctx, userID)
+}
+
+// CountUnreadNotifications returns the number of unread notifications of the user
+func (m *PostManagerImpl) CountUnreadNotifications(ctx context.Context, userID string) (int, error) {
+	store, ok := m.store.(NotificationStore)
+	if !ok {
+		return 0, ErrNotSupported
+	}
+
+	return store.CountUnreadNotifications(ctx, userID)
+}
+
+// GetNotificationPreferences returns the notification categories the user turned off
+func (m *PostManagerImpl) GetNotificationPreferences(ctx context.Context, userID string) (*NotificationPreferences, error) {
+	store, ok := m.store.(NotificationStore)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	return store.GetNotificationPreferences(ctx, userID)
+}
+
+// SetNotificationEnabled turns a notification category on or off for the user.
+// Turning a category off does not remove notifications the user already has.
+func (m *PostManagerImpl) SetNotificationEnabled(ctx context.Context, userID string, kind NotificationKind, enabled bool) error {
+	store, ok := m.store.(NotificationStore)
+	if !ok {
+		return ErrNotSupported
+	}
+	if kind.String() == "unknown" {
+		return fmt.Errorf("%w: unknown kind %d", ErrInvalidNotification, kind)
+	}
+
+	prefs, err := store.GetNotificationPreferences(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	disabled := []NotificationKind{}
+	for _, existing := range prefs.Disabled {
+		if existing != kind {
+			disabled = append(disabled, existing)
+		}
+	}
+	if !enabled {
+		disabled = append(disabled, kind)
+	}
+	prefs.Disabled = disabled
+
+	return store.SaveNotificationPreferences(ctx, prefs)
+}
+
+// notifyReaction is the after-hook that notifies the authors of a post of a new reaction.
+// Replaced reactions and reactions of shadow-banned users do not notify.
+func (m *PostManagerImpl) notifyReaction(ctx context.Context, event *Event) {
+	if banned, err := m.isShadowBanned(ctx, event.Actor.UserID); err != nil || banned {
+		return
+	}
+	_ = m.notifyAuthors(ctx, event.Post, NotificationReaction, event.Actor.UserID)
+}
+
+// notifyMentions is the after-hook that notifies users mentioned in a post who can read it once it is approved.
+// Mentions come from the author, even when the post is approved or edited by someone else. Edits of approved
+// posts only notify users who were not mentioned before, and posts of shadow-banned authors notify no one.
+func (m *PostManagerImpl) notifyMentions(ctx context.Context, event *Event) {
+	if banned, err := m.isShadowBanned(ctx, event.Post.UserID); err != nil || banned {
+		return
+	}
+
+	mentioned := mentionedUsers(event.Post.Content)
+	if event.Previous != nil && event.Previous.ModerationStatus == ModerationApproved {
+		before := make(map[string]bool)
+		for _, userID := range mentionedUsers(event.Previous.Content) {
+			before[userID] = true
+		}
+		fresh := mentioned[:0]
+		for _, userID := range mentioned {
+			if !before[userID] {
+				fresh = append(fresh, userID)
+			}
+		}
+		mentioned = fresh
+	}
+
+	for _, userID := range mentioned {
+		// Check read access as the mentioned user, not as the author
+		post, err := m.readablePost(WithActor(ctx, Actor{UserID: userID}), event.PostID)
+		if err != nil || post.ModerationStatus != ModerationApproved {
+			continue
+		}
+		_ = m.notify(ctx, userID, NotificationMention, event.PostID, event.Post.UserID)
+	}
+}
+
+// notifyAuthors notifies the author and co-authors of a post of activity by the actor
+func (m *PostManagerImpl) notifyAuthors(ctx context.Context, post *Post, kind NotificationKind, actorID string) error {
+	for _, authorID := range append([]string{post.UserID}, post.CoAuthors...) {
+		if err := m.notify(ctx, authorID, kind, post.ID, actorID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// notify records a notification unless the user caused the activity, turned the kind off,
+// or blocked or muted the actor
+func (m *PostManagerImpl) notify(ctx context.Context, userID string, kind NotificationKind, postID, actorID string) error {
+	store, ok := m.store.(NotificationStore)
+	if !ok {
+		return ErrNotSupported
+	}
+	if userID == "" || userID == actorID {
+		return nil
+	}
+
+	prefs, err := store.GetNotificationPreferences(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if !prefs.Enabled(kind) {
+		return nil
+	}
+
+	if relations, ok := m.store.(RelationStore); ok && actorID != "" {
+		for _, kind := range []RelationKind{RelationBlock, RelationMute} {
+			related, err := relations.HasRelation(ctx, userID, actorID, kind)
+			if err != nil {
+				return err
+			}
+			if related {
+				return nil
+			}
+		}
+	}
+
+	return store.RecordNotification(ctx, userID, kind, postID, actorID, time.Now())
+}
+
+// mentionedUsers returns the distinct user IDs mentioned as @userID in text, in order of appearance.
+// A mention starts after a space or punctuation other than @ and runs over letters, digits, _, . and -,
+// without a trailing dot or dash.
+func mentionedUsers(text string) []string {
+	var users []string
+	seen := make(map[string]bool)
+
+	isMentionRune := func(r rune) bool {
+		return isWordRune(r) || r == '_' || r == '.' || r == '-'
+	}
+
+	runes := []rune(text)
+	for i := 0; i < len(runes); i++ {
+		if runes[i] != '@' || (i > 0 && (isMentionRune(runes[i-1]) || runes[i-1] == '@')) {
+			continue
+		}
+
+		end := i + 1
+		for end < len(runes) && isMentionRune(runes[end]) {
+			end++
+		}
+		userID := strings.TrimRight(string(runes[i+1:end]), ".-")
+		if userID != "" && !seen[userID] {
+			seen[userID] = true
+			users = append(users, userID)
+		}
+		i = end - 1
+	}
+
+	return users
+}
+
+// prependActor returns the actors with the actor first, keeping at most NotificationActorLimit
+func prependActor(actors []string, actorID string) []string {
+	result := []string{actorID}
+	for _, existing := range actors {
+		if len(result) == NotificationActorLimit {
+			break
+		}
+		if existing != actorID {
+			result = append(result, existing)
+		}
+	}
+	return result
+}
+
+// RecordNotification adds the actor to the user's unread notification of the kind about the post, creating it if needed
+func (s *InMemoryPostStore) RecordNotification(ctx context.Context, userID string, kind NotificationKind, postID, actorID string, at time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	tenant := TenantFromContext(ctx)
+	for id, notification := range s.notifications {
+		if notification.TenantID != tenant || notification.UserID != userID || notification.Kind != kind ||
+			notification.PostID != postID || notification.Read {
+			continue
+		}
+		if s.notificationActors[id][actorID] {
+			return nil
+		}
+
+		// Replace rather than modify the stored notification, which may be shared with callers
+		updated := *notification
+		updated.Actors = prependActor(notification.Actors, actorID)
+		updated.ActorCount++
+		updated.UpdatedAt = at
+		s.notifications[id] = &updated
+		s.notificationActors[id][actorID] = true
+		return nil
+	}
+
+	notification := &Notification{
+		ID:         uuid.New().String(),
+		TenantID:   tenant,
+		UserID:     userID,
+		Kind:       kind,
+		PostID:     postID,
+		Actors:     []string{actorID},
+		ActorCount: 1,
+		CreatedAt:  at,
+		UpdatedAt:  at,
+	}
+	s.notifications[notification.ID] = notification
+	s.notificationActors[notification.ID] = map[string]bool{actorID: true}
+	return nil
+}
+
+// ListNotifications returns a user's notifications after the cursor, most recent activity first
+func (s *InMemoryPostStore) ListNotifications(ctx context.Context, userID string, cursor *NotificationCursor, limit int) ([]*Notification, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	notifications := []*Notification{}
+	tenant := TenantFromContext(ctx)
+	for _, notification := range s.notifications {
+		if notification.TenantID == tenant && notification.UserID == userID && cursor.before(notification) {
+			notificationCopy := *notification
+			notificationCopy.Actors = append([]string(nil), notification.Actors...)
+			notifications = append(notifications, &notificationCopy)
+		}
+	}
+
+	sort.Slice(notifications, func(i, j int) bool {
+		if !notifications[i].UpdatedAt.Equal(notifications[j].UpdatedAt) {
+			return notifications[i].UpdatedAt.After(notifications[j].UpdatedAt)
+		}
+		return notifications[i].ID > notifications[j].ID
+	})
+
+	if limit > 0 && len(notifications) > limit {
+		notifications = notifications[:limit]
+	}
+
+	return notifications, nil
+}
+
+// MarkNotificationRead marks a notification of the user as read
+func (s *InMemoryPostStore) MarkNotificationRead(ctx context.Context, userID, notificationID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	notification, exists := s.notifications[notificationID]
+	if !exists || notification.TenantID != TenantFromContext(ctx) || notification.UserID != userID {
+		return ErrNotificationNotFound
+	}
+
+	updated := *notification
+	updated.Read = true
+	s.notifications[notificationID] = &updated
+	return nil
+}
+
+// MarkAllNotificationsRead marks all notifications of the user as read
+func (s *InMemoryPostStore) MarkAllNotificationsRead(ctx context.Context, userID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	tenant := TenantFromContext(ctx)
+	for id, notification := range s.notifications {
+		if notification.TenantID == tenant && notification.UserID == userID && !notification.Read {
+			updated := *notification
+			updated.Read = true
+			s.notifications[id] = &updated
+		}
+	}
+	return nil
+}
+
+// CountUnreadNotifications returns the number of unread notifications of the user
+func (s *InMemoryPostStore) CountUnreadNotifications(ctx context.Context, userID string) (int, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	count := 0
+	tenant := TenantFromContext(ctx)
+	for _, notification := range s.notifications {
+		if notification.TenantID == tenant && notification.UserID == userID && !notification.Read {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// GetNotificationPreferences returns the preferences of the user
+func (s *InMemoryPostStore) GetNotificationPreferences(ctx context.Context, userID string) (*NotificationPreferences, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	prefs := &NotificationPreferences{UserID: userID}
+	if stored, exists := s.notificationPrefs[tenantScopedKey(ctx, userID)]; exists {
+		prefs.Disabled = append([]NotificationKind(nil), stored.Disabled...)
+	}
+	return prefs, nil
+}
+
+// SaveNotificationPreferences replaces the preferences of the user
+func (s *InMemoryPostStore) SaveNotificationPreferences(ctx context.Context, prefs *NotificationPreferences) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.notificationPrefs[tenantScopedKey(ctx, prefs.UserID)] = &NotificationPreferences{
+		UserID:   prefs.UserID,
+		Disabled: append([]NotificationKind(nil), prefs.Disabled...),
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package postflow
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPostManagerNotifications tests notifications with the in-memory store
+func TestPostManagerNotifications(t *testing.T) {
+	store := NewInMemoryPostStore()
+	pm := NewPostManager(store, WithNotifications())
+	ctx := context.Background()
+
+	list := func(userID string) []*Notification {
+		page, err := pm.ListNotifications(ctx, userID, "", 100)
+		require.NoError(t, err)
+		return page.Notifications
+	}
+	unread := func(userID string) int {
+		count, err := pm.CountUnreadNotifications(ctx, userID)
+		require.NoError(t, err)
+		return count
+	}
+
+	// Test: mentioned users are notified, but not the author or email addresses
+	post := createTestPostData("alice")
+	post.Content = "Thanks @bob and @carol! cc @alice, mail bob@example.com, again @bob."
+	postID, err := pm.CreatePost(ctx, post)
+	require.NoError(t, err)
+
+	for _, userID := range []string{"bob", "carol"} {
+		notifications := list(userID)
+		require.Len(t, notifications, 1, userID)
+		assert.Equal(t, NotificationMention, notifications[0].Kind)
+		assert.Equal(t, postID, notifications[0].PostID)
+		assert.Equal(t, []string{"alice"}, notifications[0].Actors)
+		assert.Equal(t, "alice mentioned you in a post", notifications[0].Summary())
+	}
+	assert.Empty(t, list("alice"))
+	assert.Empty(t, list("example.com"))
+
+	// Test: reactions by several users are aggregated into one notification
+	for _, userID := range []string{"bob", "carol", "dave", "erin"} {
+		require.NoError(t, pm.AddReaction(ctx, postID, userID, ReactionLike))
+	}
+	require.NoError(t, pm.AddReaction(ctx, postID, "bob", ReactionLove))
+	require.NoError(t, pm.AddReaction(ctx, postID, "alice", ReactionLike))
+
+	notifications := list("alice")
+	require.Len(t, notifications, 1)
+	assert.Equal(t, NotificationReaction, notifications[0].Kind)
+	assert.Equal(t, 4, notifications[0].ActorCount)
+	assert.Equal(t, []string{"erin", "dave", "carol"}, notifications[0].Actors)
+	assert.Equal(t, "erin and 3 others reacted to your post", notifications[0].Summary())
+	assert.False(t, notifications[0].Read)
+	assert.Equal(t, 1, unread("alice"))
+
+	// Test: activity after reading starts a new notification
+	assert.Equal(t, ErrNotificationNotFound, pm.MarkNotificationRead(ctx, "bob", notifications[0].ID))
+	require.NoError(t, pm.MarkNotificationRead(ctx, "alice", notifications[0].ID))
+	assert.Zero(t, unread("alice"))
+
+	require.NoError(t, pm.RemoveReaction(ctx, postID, "dave", ReactionLike))
+	require.NoError(t, pm.AddReaction(ctx, postID, "dave", ReactionWow))
+	notifications = list("alice")
+	require.Len(t, notifications, 2)
+	assert.Equal(t, 1, notifications[0].ActorCount)
+	assert.Equal(t, "dave reacted to your post", notifications[0].Summary())
+	assert.True(t, notifications[1].Read)
+
+	// Test: comments and shares are reported by the application
+	require.NoError(t, pm.NotifyPostActivity(ctx, postID, "bob", NotificationComment))
+	require.NoError(t, pm.NotifyPostActivity(ctx, postID, "carol", NotificationComment))
+	require.NoError(t, pm.NotifyPostActivity(ctx, postID, "erin", NotificationShare))
+	err = pm.NotifyPostActivity(ctx, postID, "bob", NotificationReaction)
+	assert.True(t, errors.Is(err, ErrInvalidNotification))
+	assert.Equal(t, ErrPostNotFound, pm.NotifyPostActivity(ctx, "missing", "bob", NotificationComment))
+
+	notifications = list("alice")
+	require.Len(t, notifications, 4)
+	assert.Equal(t, "erin shared your post", notifications[0].Summary())
+	assert.Equal(t, "carol and bob commented on your post", notifications[1].Summary())
+	assert.Equal(t, 3, unread("alice"))
+
+	// Test: disabled categories and muted users produce no notifications
+	require.NoError(t, pm.SetNotificationEnabled(ctx, "alice", NotificationShare, false))
+	prefs, err := pm.GetNotificationPreferences(ctx, "alice")
+	require.NoError(t, err)
+	assert.Equal(t, []NotificationKind{NotificationShare}, prefs.Disabled)
+	assert.False(t, prefs.Enabled(NotificationShare))
+	assert.True(t, prefs.Enabled(NotificationComment))
+
+	require.NoError(t, pm.NotifyPostActivity(ctx, postID, "frank", NotificationShare))
+	require.NoError(t, pm.Mute(ctx, "alice", "grace"))
+	require.NoError(t, pm.NotifyPostActivity(ctx, postID, "grace", NotificationComment))
+	assert.Len(t, list("alice"), 4)
+
+	require.NoError(t, pm.SetNotificationEnabled(ctx, "alice", NotificationShare, true))
+	prefs, err = pm.GetNotificationPreferences(ctx, "alice")
+	require.NoError(t, err)
+	assert.Empty(t, prefs.Disabled)
+	assert.True(t, errors.Is(pm.SetNotificationEnabled(ctx, "alice", 42, false), ErrInvalidNotification))
+
+	// Test: edits only notify newly mentioned users
+	require.NoError(t, pm.MarkAllNotificationsRead(ctx, "bob"))
+	assert.Zero(t, unread("bob"))
+	post, err = pm.GetPost(ctx, postID)
+	require.NoError(t, err)
+	post.Content = "Thanks @bob, @carol and @dave!"
+	require.NoError(t, pm.UpdatePost(ctx, post))
+	assert.Zero(t, unread("bob"))
+	assert.Equal(t, 1, unread("dave"))
+
+	// Test: users who cannot see the post are not told about it
+	private := createTestPostData("alice")
+	private.Visibility = VisibilityFollowers
+	private.Content = "For followers: @bob @zoe"
+	require.NoError(t, pm.Follow(ctx, "bob", "alice"))
+	_, err = pm.CreatePost(ctx, private)
+	require.NoError(t, err)
+	assert.Equal(t, 1, unread("bob"))
+	assert.Empty(t, list("zoe"))
+
+	// Test: notifications are listed page by page with a cursor
+	var seen []string
+	cursor := ""
+	for pages := 0; ; pages++ {
+		require.Less(t, pages, 10)
+		page, err := pm.ListNotifications(ctx, "alice", cursor, 2)
+		require.NoError(t, err)
+		for _, notification := range page.Notifications {
+			seen = append(seen, notification.ID)
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+	all := list("alice")
+	require.Len(t, seen, len(all))
+	for i := range all {
+		assert.Equal(t, all[i].ID, seen[i])
+		if i > 0 {
+			assert.False(t, all[i].UpdatedAt.After(all[i-1].UpdatedAt))
+		}
+	}
+
+	_, err = pm.ListNotifications(ctx, "alice", "not a cursor", 2)
+	assert.True(t, errors.Is(err, ErrInvalidNotification))
+
+	// Test: notifications are scoped to the tenant
+	tenantCtx := WithTenant(ctx, "other")
+	count, err := pm.CountUnreadNotifications(tenantCtx, "alice")
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
+
+// TestMentionedUsers tests finding mentions in post content
+func TestMentionedUsers(t *testing.T) {
+	assert.Equal(t, []string{"bob", "carol.smith", "dave_2"}, mentionedUsers("@bob, (@carol.smith). @dave_2 @bob"))
+	assert.Equal(t, []string{"josé"}, mentionedUsers("hola @josé!"))
+	assert.Empty(t, mentionedUsers("mail bob@example.com or @@bob or @ alone"))
+}
+
+// TestPostManagerNotificationsRespectReadAccess tests that notifications never reveal posts the user may not read
+func TestPostManagerNotificationsRespectReadAccess(t *testing.T) {
+	pm := NewPostManager(NewInMemoryPostStore(), WithNotifications(), WithModerator(keywordModerator))
+	ctx := context.Background()
+	modCtx := WithActor(ctx, Actor{UserID: "mod1", Role: RoleModerator})
+
+	unread := func(userID string) int {
+		count, err := pm.CountUnreadNotifications(ctx, userID)
+		require.NoError(t, err)
+		return count
+	}
+
+	// Test: private posts do not notify mentioned users
+	private := createTestPostData("alice")
+	private.Visibility = VisibilityPrivate
+	private.Content = "Note to self about @bob"
+	_, err := pm.CreatePost(ctx, private)
+	require.NoError(t, err)
+	assert.Zero(t, unread("bob"))
+
+	// Test: held posts do not notify mentioned users
+	held := createTestPostData("alice")
+	held.Content = "please review this, @dave"
+	heldID, err := pm.CreatePost(ctx, held)
+	require.NoError(t, err)
+	assert.Zero(t, unread("dave"))
+
+	// Test: mentioned users are notified once the post is approved, by the author rather than the moderator
+	require.NoError(t, pm.ApprovePost(modCtx, heldID))
+	assert.Equal(t, 1, unread("dave"))
+	page, err := pm.ListNotifications(ctx, "dave", "", 10)
+	require.NoError(t, err)
+	require.Len(t, page.Notifications, 1)
+	assert.Equal(t, []string{"alice"}, page.Notifications[0].Actors)
+
+	// Test: users who blocked the author are not notified when the post is approved
+	require.NoError(t, pm.Block(ctx, "erin", "alice"))
+	held = createTestPostData("alice")
+	held.Content = "please review this too, @erin"
+	heldID, err = pm.CreatePost(ctx, held)
+	require.NoError(t, err)
+	require.NoError(t, pm.ApprovePost(modCtx, heldID))
+	assert.Zero(t, unread("erin"))
+
+	// Test: shadow-banned users notify no one
+	require.NoError(t, pm.ShadowBanUser(modCtx, "mallory", "spam"))
+	banned := createTestPostData("mallory")
+	banned.Content = "Hey @carol"
+	_, err = pm.CreatePost(ctx, banned)
+	require.NoError(t, err)
+	assert.Zero(t, unread("carol"))
+
+	postID, err := pm.CreatePost(ctx, createTestPostData("carol"))
+	require.NoError(t, err)
+	require.NoError(t, pm.AddReaction(ctx, postID, "mallory", ReactionLike))
+	assert.Zero(t, unread("carol"))
+	require.NoError(t, pm.AddReaction(ctx, postID, "bob", ReactionLike))
+	assert.Equal(t, 1, unread("carol"))
+}
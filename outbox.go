@@ -0,0 +1,167 @@
+// Package postflow provides functionality for managing user posts and feeds.
+package postflow
+
+import (
+	"context"
+	"time"
+)
+
+// Defaults of an OutboxRelay
+const (
+	DefaultOutboxBatchSize   = 100 // Events published per pass
+	DefaultOutboxMaxAttempts = 10  // Failed deliveries before an event is set aside as a dead letter
+)
+
+// OutboxEvent is a domain event recorded by the store in the same transaction as the change it describes
+type OutboxEvent struct {
+	ID               int64        `json:"id"` // Increasing sequence number; events are relayed in this order
+	Type             EventType    `json:"type"`
+	TenantID         string       `json:"tenant_id,omitempty"`
+	PostID           string       `json:"post_id"`
+	UserID           string       `json:"user_id"`                     // The user who made the change
+	Post             *Post        `json:"post,omitempty"`              // The post as saved, for PostCreated and PostUpdated
+	Reaction         ReactionType `json:"reaction,omitempty"`          // The added, new or removed reaction
+	PreviousReaction ReactionType `json:"previous_reaction,omitempty"` // The replaced reaction, for ReactionChanged
+	CreatedAt        time.Time    `json:"created_at"`
+	Attempts         int          `json:"attempts"`             // Failed deliveries so far
+	LastError        string       `json:"last_error,omitempty"` // Error of the last failed delivery
+}
+
+// OutboxStore defines the interface for stores that keep a transactional outbox.
+// Events are read across all tenants.
+type OutboxStore interface {
+	// ListOutboxEvents returns undelivered events, oldest first
+	ListOutboxEvents(ctx context.Context, limit int) ([]*OutboxEvent, error)
+
+	// MarkOutboxEventDelivered records that an event was published
+	MarkOutboxEventDelivered(ctx context.Context, eventID int64) error
+
+	// MarkOutboxEventFailed records a failed attempt to publish an event
+	MarkOutboxEventFailed(ctx context.Context, eventID int64, cause error) error
+
+	// MarkOutboxEventDead records the last failed attempt to publish an event and sets it aside as a dead letter,
+	// so that it is no longer listed by ListOutboxEvents
+	MarkOutboxEventDead(ctx context.Context, eventID int64, cause error) error
+
+	// ListDeadOutboxEvents returns dead letters, oldest first
+	ListDeadOutboxEvents(ctx context.Context, limit int) ([]*OutboxEvent, error)
+
+	// RequeueOutboxEvent returns a dead letter to the outbox with no failed attempts
+	RequeueOutboxEvent(ctx context.Context, eventID int64) error
+
+	// PurgeOutboxEvents removes events delivered before the given time, returning how many were removed
+	PurgeOutboxEvents(ctx context.Context, deliveredBefore time.Time) (int64, error)
+}
+
+// OutboxPublisher hands outbox events to a message broker or another consumer.
+// Events may be published more than once, so consumers should deduplicate by OutboxEvent.ID.
+type OutboxPublisher interface {
+	Publish(ctx context.Context, event *OutboxEvent) error
+}
+
+// OutboxPublisherFunc adapts a function to the OutboxPublisher interface
+type OutboxPublisherFunc func(ctx context.Context, event *OutboxEvent) error
+
+// Publish calls f(ctx, event)
+func (f OutboxPublisherFunc) Publish(ctx context.Context, event *OutboxEvent) error {
+	return f(ctx, event)
+}
+
+// OutboxRelay publishes the events of an outbox in order with at-least-once semantics:
+// an event is marked delivered only after the publisher accepted it, so a crash in between
+// publishes it again. Run a single relay per outbox.
+type OutboxRelay struct {
+	store     OutboxStore
+	publisher OutboxPublisher
+
+	// BatchSize is the number of events read per pass
+	BatchSize int
+
+	// MaxAttempts is the number of failed deliveries after which an event is set aside as a dead letter,
+	// so that an event the publisher always rejects does not hold up the outbox; zero retries forever
+	MaxAttempts int
+
+	// OnError, when set, is called by Run with errors of failed passes
+	OnError func(err error)
+
+	// OnDeadLetter, when set, is called with events set aside as dead letters and the last delivery error
+	OnDeadLetter func(event *OutboxEvent, err error)
+}
+
+// NewOutboxRelay creates a relay from the store's outbox to the publisher
+func NewOutboxRelay(store OutboxStore, publisher OutboxPublisher) *OutboxRelay {
+	return &OutboxRelay{
+		store:       store,
+		publisher:   publisher,
+		BatchSize:   DefaultOutboxBatchSize,
+		MaxAttempts: DefaultOutboxMaxAttempts,
+	}
+}
+
+// RelayOnce publishes a batch of undelivered events and returns how many were delivered.
+// It stops at the first event the publisher rejects, so that later events are not delivered
+// ahead of it, records the failure and returns the publisher's error. An event rejected for the
+// MaxAttempts time is set aside as a dead letter instead, and the events after it are published.
+func (r *OutboxRelay) RelayOnce(ctx context.Context) (int, error) {
+	events, err := r.store.ListOutboxEvents(ctx, r.BatchSize)
+	if err != nil {
+		return 0, err
+	}
+
+	// Record outcomes even if ctx is cancelled meanwhile, so published events are not published again
+	markCtx := context.WithoutCancel(ctx)
+
+	delivered := 0
+	for _, event := range events {
+		if err := r.publisher.Publish(ctx, event); err != nil {
+			if r.MaxAttempts <= 0 || event.Attempts+1 < r.MaxAttempts || ctx.Err() != nil {
+				if markErr := r.store.MarkOutboxEventFailed(markCtx, event.ID, err); markErr != nil {
+					return delivered, markErr
+				}
+				return delivered, err
+			}
+
+			if markErr := r.store.MarkOutboxEventDead(markCtx, event.ID, err); markErr != nil {
+				return delivered, markErr
+			}
+			event.Attempts++
+			event.LastError = err.Error()
+			if r.OnDeadLetter != nil {
+				r.OnDeadLetter(event, err)
+			}
+			continue
+		}
+
+		if err := r.store.MarkOutboxEventDelivered(markCtx, event.ID); err != nil {
+			return delivered, err
+		}
+		delivered++
+	}
+
+	return delivered, nil
+}
+
+// Run relays events until the context is cancelled and returns the context's error.
+// Full batches are followed by the next pass right away; otherwise the relay waits for interval.
+// Failed passes are retried after interval.
+func (r *OutboxRelay) Run(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		delivered, err := r.RelayOnce(ctx)
+		if err != nil && ctx.Err() == nil && r.OnError != nil {
+			r.OnError(err)
+		}
+
+		if err != nil || delivered < r.BatchSize || r.BatchSize <= 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-ticker.C:
+			}
+		} else if ctx.Err() != nil {
+			return ctx.Err()
+		}
+	}
+}
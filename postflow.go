@@ -52,7 +52,9 @@ type Media struct {
 // Post represents a user post in the system.
 type Post struct {
 	ID         string               `json:"id"`
+	TenantID   string               `json:"tenant_id,omitempty"` // Set by the store from the context
 	UserID     string               `json:"user_id"`
+	GroupID    string               `json:"group_id,omitempty"` // Group the post was made in; fixed at creation
 	Content    string               `json:"content"`
 	Media      []Media              `json:"media,omitempty"`
 	Tags       []string             `json:"tags,omitempty"`
@@ -61,19 +63,40 @@ type Post struct {
 	Reactions  map[ReactionType]int `json:"reactions"`
 	Comments   int                  `json:"comments"`
 	Shares     int                  `json:"shares"`
-	Visibility string               `json:"visibility"` // public, private, friends
+	Visibility Visibility           `json:"visibility"`
+	Version    int64                `json:"version"` // Incremented by the store on every save
+
+	ModerationStatus ModerationStatus `json:"moderation_status"`
+	ModerationReason string           `json:"moderation_reason,omitempty"`
+	UpdatedBy        string           `json:"updated_by,omitempty"` // User who last changed the post
+	CoAuthors        []string         `json:"co_authors,omitempty"` // Accepted co-authors; managed through invitations
+
+	// Audience of a post with VisibilityCustom: members of these audience lists and these users
+	AudienceIDs     []string `json:"audience_ids,omitempty"`
+	AudienceUserIDs []string `json:"audience_user_ids,omitempty"`
+
+	ContentHash string `json:"content_hash,omitempty"` // SHA-256 of the normalized content
+	SimHash     uint64 `json:"simhash,omitempty"`      // SimHash of the normalized content for near-duplicate matching
+
+	// Set on feed reads when the post matches a collapsing muted term of the viewer; never stored
+	Collapsed      bool   `json:"collapsed,omitempty"`
+	CollapseReason string `json:"collapse_reason,omitempty"`
 }
 
 // PostFilter represents filtering options for retrieving posts.
 type PostFilter struct {
 	UserID     string
+	GroupID    string
 	Tags       []string
 	TimeRange  *TimeRange
-	Visibility string
+	Visibility Visibility
 	Limit      int
 	Offset     int
 	SortBy     string
 	SortOrder  string
+
+	// ModerationStatus restricts results to posts with this status; nil means approved posts only
+	ModerationStatus *ModerationStatus
 }
 
 // TimeRange represents a time range for filtering posts.
@@ -83,6 +106,11 @@ type TimeRange struct {
 }
 
 // PostManager defines the interface for managing posts in the system.
+//
+// Read access is checked for the actor in the context (see WithActor). A context without an actor is
+// trusted, as for internal jobs and services: GetPost, the reaction reads and the post IDs of Watch
+// are not checked against visibility, audience or the Authorizer, and ListPosts without an actor returns
+// private and friends-only posts of every user. Requests on behalf of end users should carry an actor.
 type PostManager interface {
 	// CreatePost creates a new post in the system.
 	CreatePost(ctx context.Context, post *Post) (string, error)
@@ -91,8 +119,12 @@ type PostManager interface {
 	GetPost(ctx context.Context, postID string) (*Post, error)
 
 	// UpdatePost updates an existing post.
+	// The post's Version must match the stored version, otherwise ErrVersionConflict is returned.
 	UpdatePost(ctx context.Context, post *Post) error
 
+	// PatchPost applies a partial update to a post and returns the updated post.
+	PatchPost(ctx context.Context, postID string, userID string, patch *PostPatch) (*Post, error)
+
 	// DeletePost removes a post from the system.
 	DeletePost(ctx context.Context, postID string, userID string) error
 
@@ -119,4 +151,7 @@ type PostManager interface {
 
 	// GetReactionCounts returns the count of each reaction type for a post.
 	GetReactionCounts(ctx context.Context, postID string) (map[ReactionType]int, error)
+
+	// Watch returns a channel of live post and reaction changes, closed when ctx is cancelled.
+	Watch(ctx context.Context, filter *WatchFilter) (<-chan *ChangeEvent, error)
 }
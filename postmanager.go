@@ -4,6 +4,7 @@ package postflow
 import (
 	"context"
 	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
@@ -11,14 +12,38 @@ import (
 
 // PostManagerImpl implements the PostManager interface using a PostStore for persistence
 type PostManagerImpl struct {
-	store PostStore
+	store            PostStore
+	idempotencyTTL   time.Duration
+	idempotencyLease time.Duration
+	moderator        Moderator
+	reportThreshold  int
+	blocklist        *Blocklist
+	authorizer       Authorizer
+	rateLimiter      RateLimiter
+	rateLimits       map[Action]RateLimit
+	duplicatePolicy  *DuplicatePolicy
+	hooks            hookRegistry
+	watches          watchHub
 }
 
+// PostManagerOption configures optional behavior of a PostManagerImpl
+type PostManagerOption func(*PostManagerImpl)
+
 // NewPostManager creates a new instance of PostManagerImpl
-func NewPostManager(store PostStore) *PostManagerImpl {
-	return &PostManagerImpl{
-		store: store,
+func NewPostManager(store PostStore, opts ...PostManagerOption) *PostManagerImpl {
+	m := &PostManagerImpl{
+		store:            store,
+		idempotencyTTL:   DefaultIdempotencyTTL,
+		idempotencyLease: DefaultIdempotencyLease,
+		reportThreshold:  DefaultReportThreshold,
+		authorizer:       DefaultAuthorizer,
+	}
+
+	for _, opt := range opts {
+		opt(m)
 	}
+
+	return m
 }
 
 // CreatePost creates a new post in the system
@@ -33,6 +58,44 @@ func (m *PostManagerImpl) CreatePost(ctx context.Context, post *Post) (string, e
 		post.ID = uuid.New().String()
 	}
 
+	actor, err := actingAs(ctx, post.UserID, ActionCreate)
+	if err != nil {
+		return "", err
+	}
+	if err := m.authorizer.Authorize(ctx, actor, ActionCreate, post); err != nil {
+		return "", err
+	}
+
+	key := IdempotencyKeyFromContext(ctx)
+	if key == "" {
+		return m.createPost(ctx, post)
+	}
+
+	// Return the original post if this request is a retry
+	claimKey := "create_post:" + post.UserID + ":" + key
+	fingerprint := createRequestFingerprint(post)
+	claimedID, claim, err := m.claimIdempotencyKey(ctx, claimKey, fingerprint, post.ID)
+	if err != nil {
+		return "", err
+	}
+	if claim == nil {
+		post.ID = claimedID
+		return claimedID, nil
+	}
+
+	postID, err := m.createPost(ctx, post)
+	if err != nil {
+		// Let the client retry with the same key
+		m.releaseIdempotencyKey(ctx, claimKey, claim)
+		return "", err
+	}
+	m.completeIdempotencyKey(ctx, claimKey, claim, postID)
+
+	return postID, nil
+}
+
+// createPost prepares a validated post and saves it
+func (m *PostManagerImpl) createPost(ctx context.Context, post *Post) (string, error) {
 	// Set creation time
 	now := time.Now()
 	post.CreatedAt = now
@@ -43,21 +106,116 @@ func (m *PostManagerImpl) CreatePost(ctx context.Context, post *Post) (string, e
 		post.Reactions = make(map[ReactionType]int)
 	}
 
+	// Co-authors join through invitations
+	post.CoAuthors = nil
+
+	if err := m.checkRateLimit(ctx, post.UserID, ActionCreate); err != nil {
+		return "", err
+	}
+
+	// Screen the post before it becomes visible
+	post.ModerationStatus = ModerationApproved
+	post.ModerationReason = ""
+	if err := m.screenPost(ctx, post); err != nil {
+		return "", err
+	}
+
+	if err := validateVisibility(post, VisibilityPublic); err != nil {
+		return "", err
+	}
+	if err := m.prepareAudience(ctx, post); err != nil {
+		return "", err
+	}
+	if err := m.checkGroupPost(ctx, post); err != nil {
+		return "", err
+	}
+
+	// Reject or flag repeated content
+	applyFingerprint(post)
+	if err := m.checkDuplicates(ctx, post); err != nil {
+		return "", err
+	}
+
+	event := newEvent(EventPostCreated, post, actorFor(ctx, post.UserID))
+	if err := m.runBeforeHooks(ctx, event); err != nil {
+		return "", err
+	}
+
 	// Save to store
 	err := m.store.SavePost(ctx, post)
 	if err != nil {
 		return "", err
 	}
 
+	m.runAfterHooks(ctx, event)
 	return post.ID, nil
 }
 
-// GetPost retrieves a post by its ID
+// GetPost retrieves a post by its ID.
+// When the context carries an actor, the post is only returned if the actor may read it;
+// posts that are not approved are only readable by their authors and privileged actors.
 func (m *PostManagerImpl) GetPost(ctx context.Context, postID string) (*Post, error) {
-	return m.store.GetPost(ctx, postID)
+	return m.readablePost(ctx, postID)
 }
 
-// UpdatePost updates an existing post
+// readablePost loads a post the actor in the context may read
+func (m *PostManagerImpl) readablePost(ctx context.Context, postID string) (*Post, error) {
+	post, err := m.store.GetPost(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+
+	if actor, ok := ActorFromContext(ctx); ok {
+		if err := m.authorizer.Authorize(ctx, actor, ActionRead, post); err != nil {
+			return nil, err
+		}
+		if err := checkModerationReadable(actor, post); err != nil {
+			return nil, err
+		}
+		// Blocks hide posts in both directions, as in lists
+		if err := m.checkBlocked(ctx, post.UserID, actor.UserID); err != nil {
+			return nil, err
+		}
+		if err := m.checkBlocked(ctx, actor.UserID, post.UserID); err != nil {
+			return nil, err
+		}
+		if err := m.checkVisibility(ctx, post, actor.UserID); err != nil {
+			return nil, err
+		}
+	}
+
+	return post, nil
+}
+
+// checkReactionsReadable checks that the reactions of a post may be read, which is the case when the post is.
+func (m *PostManagerImpl) checkReactionsReadable(ctx context.Context, postID string) error {
+	_, err := m.readablePost(ctx, postID)
+	return err
+}
+
+// authorizedPosts drops listed posts the Authorizer does not let the actor in the context read.
+// Stores already leave out posts the viewer may not see, so the default policy drops nothing.
+func (m *PostManagerImpl) authorizedPosts(ctx context.Context, posts []*Post) ([]*Post, error) {
+	actor, ok := ActorFromContext(ctx)
+	if !ok {
+		return posts, nil
+	}
+
+	allowed := posts[:0]
+	for _, post := range posts {
+		err := m.authorizer.Authorize(ctx, actor, ActionRead, post)
+		if err == nil {
+			allowed = append(allowed, post)
+		} else if !errors.Is(err, ErrPermissionDenied) {
+			return nil, err
+		}
+	}
+
+	return allowed, nil
+}
+
+// UpdatePost updates an existing post.
+// Moderators and administrators in the context may update any post.
 func (m *PostManagerImpl) UpdatePost(ctx context.Context, post *Post) error {
 	// Validate post
 	if post.ID == "" {
@@ -71,8 +229,23 @@ func (m *PostManagerImpl) UpdatePost(ctx context.Context, post *Post) error {
 	}
 
 	// Check if user is authorized to update the post
-	if existingPost.UserID != post.UserID {
-		return errors.New("unauthorized to update this post")
+	actor, err := actingAs(ctx, post.UserID, ActionUpdate)
+	if err != nil {
+		return err
+	}
+	if err := m.authorizer.Authorize(ctx, actor, ActionUpdate, existingPost); err != nil {
+		return fmt.Errorf("unauthorized to update this post: %w", err)
+	}
+
+	// Co-authors and moderators edit on behalf of the author, within the post's group
+	post.UserID = existingPost.UserID
+	post.GroupID = existingPost.GroupID
+	post.UpdatedBy = actor.UserID
+	if err := validateVisibility(post, existingPost.Visibility); err != nil {
+		return err
+	}
+	if err := m.prepareAudience(ctx, post); err != nil {
+		return err
 	}
 
 	// Update modification time
@@ -80,52 +253,254 @@ func (m *PostManagerImpl) UpdatePost(ctx context.Context, post *Post) error {
 
 	// Preserve creation time
 	post.CreatedAt = existingPost.CreatedAt
+	applyFingerprint(post)
+
+	// Screen the changes, keeping the current verdict if there is no moderator
+	post.ModerationStatus = existingPost.ModerationStatus
+	post.ModerationReason = existingPost.ModerationReason
+	if err := m.screenPost(ctx, post); err != nil {
+		return err
+	}
+
+	event := newEvent(EventPostUpdated, post, actor)
+	event.Previous = existingPost
+	if err := m.runBeforeHooks(ctx, event); err != nil {
+		return err
+	}
 
 	// Save to store
-	return m.store.SavePost(ctx, post)
+	if err := m.store.SavePost(ctx, post); err != nil {
+		return err
+	}
+	m.runAfterHooks(ctx, event)
+
+	if !existingPost.isAuthor(actor.UserID) {
+		m.recordAudit(ctx, existingPost, actor, AuditEdit, "")
+	}
+
+	return nil
 }
 
-// DeletePost removes a post from the system
+// DeletePost removes a post from the system.
+// Moderators and administrators in the context may delete any post.
 func (m *PostManagerImpl) DeletePost(ctx context.Context, postID string, userID string) error {
-	return m.store.DeletePost(ctx, postID, userID)
+	actor, err := actingAs(ctx, userID, ActionDelete)
+	if err != nil {
+		return err
+	}
+
+	post, err := m.store.GetPost(ctx, postID)
+	if err != nil {
+		return err
+	}
+	if err := m.authorizer.Authorize(ctx, actor, ActionDelete, post); err != nil {
+		return err
+	}
+
+	if err := m.deletePost(ctx, post, actor); err != nil {
+		return err
+	}
+
+	m.recordAudit(ctx, post, actor, AuditDelete, "")
+
+	return nil
 }
 
-// ListPosts retrieves a list of posts based on filter criteria
+// deletePost deletes a post on behalf of the actor, running the PostDeleted hooks
+func (m *PostManagerImpl) deletePost(ctx context.Context, post *Post, actor Actor) error {
+	event := newEvent(EventPostDeleted, post, actor)
+	if err := m.runBeforeHooks(ctx, event); err != nil {
+		return err
+	}
+
+	if err := m.store.DeletePost(ctx, post.ID, post.UserID); err != nil {
+		return err
+	}
+
+	m.runAfterHooks(ctx, event)
+	return nil
+}
+
+// ListPosts retrieves a list of posts based on filter criteria.
+// Tag and group feeds honor the muted words and tags of the actor in the context.
+// Only privileged actors may list posts that are not approved.
 func (m *PostManagerImpl) ListPosts(ctx context.Context, filter *PostFilter) ([]*Post, error) {
-	return m.store.ListPosts(ctx, filter)
+	if err := checkModerationFilter(ctx, filter); err != nil {
+		return nil, err
+	}
+
+	posts, err := m.store.ListPosts(ctx, filter)
+	if err == nil {
+		posts, err = m.authorizedPosts(ctx, posts)
+	}
+	if err != nil || !filter.honorsMutedTerms() {
+		return posts, err
+	}
+
+	return m.applyMutedTerms(ctx, viewerFromContext(ctx), posts)
 }
 
-// GetUserFeed returns posts for a user's feed
+// GetUserFeed returns posts for a user's feed, honoring the user's muted words and tags
 func (m *PostManagerImpl) GetUserFeed(ctx context.Context, userID string, limit, offset int) ([]*Post, error) {
-	return m.store.GetUserFeed(ctx, userID, limit, offset)
+	posts, err := m.store.GetUserFeed(ctx, userID, limit, offset)
+	if err == nil {
+		posts, err = m.authorizedPosts(ctx, posts)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return m.applyMutedTerms(ctx, userID, posts)
 }
 
-// GetTrendingPosts returns currently trending posts
+// GetTrendingPosts returns currently trending posts.
+// The muted words and tags of the actor in the context are honored.
 func (m *PostManagerImpl) GetTrendingPosts(ctx context.Context, limit int) ([]*Post, error) {
-	return m.store.GetTrendingPosts(ctx, limit)
+	posts, err := m.store.GetTrendingPosts(ctx, limit)
+	if err == nil {
+		posts, err = m.authorizedPosts(ctx, posts)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return m.applyMutedTerms(ctx, viewerFromContext(ctx), posts)
 }
 
 // AddReaction adds an emotional reaction to a post
 func (m *PostManagerImpl) AddReaction(ctx context.Context, postID string, userID string, reactionType ReactionType) error {
-	return m.store.SaveReaction(ctx, postID, userID, reactionType)
+	post, err := m.store.GetPost(ctx, postID)
+	if err != nil {
+		return err
+	}
+	actor, err := actingAs(ctx, userID, ActionReact)
+	if err != nil {
+		return err
+	}
+	if err := m.authorizer.Authorize(ctx, actor, ActionReact, post); err != nil {
+		return err
+	}
+	if err := m.checkVisibility(ctx, post, userID); err != nil {
+		return err
+	}
+
+	// Skip reactions that were already applied by an earlier attempt of this request
+	key := IdempotencyKeyFromContext(ctx)
+	claimKey := "add_reaction:" + userID + ":" + key
+	fingerprint := requestFingerprint(postID, reactionType)
+	var claim *idempotencyClaim
+	if key != "" {
+		_, claim, err = m.claimIdempotencyKey(ctx, claimKey, fingerprint, "")
+		if err != nil {
+			return err
+		}
+		if claim == nil {
+			return nil
+		}
+	}
+
+	err = m.checkRateLimit(ctx, userID, ActionReact)
+	if err == nil {
+		err = m.saveReaction(ctx, post, userID, reactionType)
+	}
+	if claim != nil {
+		if err != nil {
+			m.releaseIdempotencyKey(ctx, claimKey, claim)
+		} else {
+			m.completeIdempotencyKey(ctx, claimKey, claim, "")
+		}
+	}
+
+	return err
+}
+
+// saveReaction stores a reaction, running the ReactionAdded or ReactionChanged hooks
+func (m *PostManagerImpl) saveReaction(ctx context.Context, post *Post, userID string, reactionType ReactionType) error {
+	event, err := m.reactionEvent(ctx, post, userID, reactionType)
+	if err != nil {
+		return err
+	}
+	if event != nil {
+		if err := m.runBeforeHooks(ctx, event); err != nil {
+			return err
+		}
+	}
+
+	if err := m.store.SaveReaction(ctx, post.ID, userID, reactionType); err != nil {
+		return err
+	}
+
+	if event != nil {
+		m.runAfterHooks(ctx, event)
+	}
+	return nil
 }
 
 // RemoveReaction removes an emotional reaction from a post
 func (m *PostManagerImpl) RemoveReaction(ctx context.Context, postID string, userID string, reactionType ReactionType) error {
-	return m.store.DeleteReaction(ctx, postID, userID, reactionType)
+	post, err := m.store.GetPost(ctx, postID)
+	if err != nil {
+		return err
+	}
+	actor, err := actingAs(ctx, userID, ActionReact)
+	if err != nil {
+		return err
+	}
+	if err := m.authorizer.Authorize(ctx, actor, ActionReact, post); err != nil {
+		return err
+	}
+
+	// Removals share the reaction budget so reactions cannot be toggled endlessly
+	if err := m.checkRateLimit(ctx, userID, ActionReact); err != nil {
+		return err
+	}
+
+	event, err := m.reactionRemovedEvent(ctx, postID, userID, reactionType)
+	if err != nil {
+		return err
+	}
+	if event != nil {
+		if err := m.runBeforeHooks(ctx, event); err != nil {
+			return err
+		}
+	}
+
+	if err := m.store.DeleteReaction(ctx, postID, userID, reactionType); err != nil {
+		return err
+	}
+
+	if event != nil {
+		m.runAfterHooks(ctx, event)
+	}
+	return nil
 }
 
-// GetUserReaction gets the current reaction of a user for a post
+// GetUserReaction gets the current reaction of a user for a post.
+// When the context carries an actor, the actor must be able to read the post.
 func (m *PostManagerImpl) GetUserReaction(ctx context.Context, postID string, userID string) (*ReactionType, error) {
+	if err := m.checkReactionsReadable(ctx, postID); err != nil {
+		return nil, err
+	}
+
 	return m.store.GetUserReaction(ctx, postID, userID)
 }
 
-// GetReactedUsers returns users who reacted to a specific post with optional reaction type filter
+// GetReactedUsers returns users who reacted to a specific post with optional reaction type filter.
+// When the context carries an actor, the actor must be able to read the post.
 func (m *PostManagerImpl) GetReactedUsers(ctx context.Context, postID string, reactionType *ReactionType, limit, offset int) ([]string, error) {
+	if err := m.checkReactionsReadable(ctx, postID); err != nil {
+		return nil, err
+	}
+
 	return m.store.GetReactedUsers(ctx, postID, reactionType, limit, offset)
 }
 
-// GetReactionCounts returns the count of each reaction type for a post
+// GetReactionCounts returns the count of each reaction type for a post.
+// When the context carries an actor, the actor must be able to read the post.
 func (m *PostManagerImpl) GetReactionCounts(ctx context.Context, postID string) (map[ReactionType]int, error) {
+	if err := m.checkReactionsReadable(ctx, postID); err != nil {
+		return nil, err
+	}
+
 	return m.store.GetReactionCounts(ctx, postID)
 }
@@ -235,7 +235,7 @@ func TestPostManagerListPosts(t *testing.T) {
 	posts, err = pm.ListPosts(ctx, filter)
 	assert.NoError(t, err)
 	assert.Equal(t, 1, len(posts))
-	assert.Equal(t, "private", posts[0].Visibility)
+	assert.Equal(t, VisibilityPrivate, posts[0].Visibility)
 }
 
 // TestPostManagerGetUserFeed tests the GetUserFeed method
@@ -263,7 +263,7 @@ func TestPostManagerGetUserFeed(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, 5, len(posts))
 	for _, post := range posts {
-		assert.Equal(t, "public", post.Visibility)
+		assert.Equal(t, VisibilityPublic, post.Visibility)
 	}
 
 	// Test: pagination
@@ -515,3 +515,30 @@ func TestPostManagerGetReactionCounts(t *testing.T) {
 	assert.Equal(t, ErrPostNotFound, err)
 	assert.Nil(t, counts)
 }
+
+// TestPostManagerUpdatePostVersionConflict tests that concurrent updates do not silently overwrite each other
+func TestPostManagerUpdatePostVersionConflict(t *testing.T) {
+	pm := setupTestPostManager()
+	ctx := context.Background()
+
+	post := createTestPostData("user1")
+	postID, err := pm.CreatePost(ctx, post)
+	assert.NoError(t, err)
+
+	first, err := pm.GetPost(ctx, postID)
+	assert.NoError(t, err)
+	second, err := pm.GetPost(ctx, postID)
+	assert.NoError(t, err)
+
+	first.Content = "First writer"
+	err = pm.UpdatePost(ctx, first)
+	assert.NoError(t, err)
+
+	second.Content = "Second writer"
+	err = pm.UpdatePost(ctx, second)
+	assert.ErrorIs(t, err, ErrVersionConflict)
+
+	updatedPost, err := pm.GetPost(ctx, postID)
+	assert.NoError(t, err)
+	assert.Equal(t, "First writer", updatedPost.Content)
+}
@@ -0,0 +1,227 @@
+// Package postflow provides functionality for managing user posts and feeds.
+package postflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidPatch is returned when a patch is malformed or references unknown media
+var ErrInvalidPatch = errors.New("invalid patch")
+
+// maxPatchAttempts bounds how often PatchPost re-applies a patch after losing a concurrent update
+const maxPatchAttempts = 3
+
+// PatchField is a bit mask naming the post fields a PostPatch modifies
+type PatchField uint8
+
+const (
+	PatchContent    PatchField = 1 << iota // Replace Content
+	PatchTags                              // Replace Tags
+	PatchMedia                             // Apply the MediaPatch
+	PatchVisibility                        // Replace Visibility and the audience
+)
+
+// PostPatch describes a partial update of a post.
+// Only the fields named in Fields are applied; all other fields keep their stored values.
+type PostPatch struct {
+	Fields     PatchField
+	Content    string
+	Tags       []string
+	Media      *MediaPatch
+	Visibility Visibility
+
+	// AudienceIDs and AudienceUserIDs replace the audience along with Visibility
+	AudienceIDs     []string
+	AudienceUserIDs []string
+
+	// Version, when non-zero, must match the stored version of the post.
+	// When zero, the patch is re-applied to the latest version on concurrent modification.
+	Version int64
+}
+
+// MediaPatch describes changes to the media of a post.
+// Removals are applied first, then additions, then the ordering.
+type MediaPatch struct {
+	Add    []Media  // Media to append; an ID is generated if missing
+	Remove []string // IDs of media to remove
+	Order  []string // Media IDs in their new order; unlisted media keep their relative order at the end
+}
+
+// Has reports whether the mask includes the given field
+func (f PatchField) Has(field PatchField) bool {
+	return f&field != 0
+}
+
+// PatchPost applies a partial update to a post owned by userID and returns the updated post.
+// Moderators and administrators in the context may patch any post.
+func (m *PostManagerImpl) PatchPost(ctx context.Context, postID string, userID string, patch *PostPatch) (*Post, error) {
+	if postID == "" {
+		return nil, errors.New("post ID is required")
+	}
+	if patch == nil || patch.Fields == 0 {
+		return nil, fmt.Errorf("%w: no fields selected", ErrInvalidPatch)
+	}
+	if patch.Fields.Has(PatchMedia) && patch.Media == nil {
+		return nil, fmt.Errorf("%w: media patch is missing", ErrInvalidPatch)
+	}
+
+	for attempt := 1; ; attempt++ {
+		post, err := m.store.GetPost(ctx, postID)
+		if err != nil {
+			return nil, err
+		}
+		previous := *post
+
+		// Check if user is authorized to update the post
+		actor, err := actingAs(ctx, userID, ActionUpdate)
+		if err != nil {
+			return nil, err
+		}
+		if err := m.authorizer.Authorize(ctx, actor, ActionUpdate, post); err != nil {
+			return nil, err
+		}
+		post.UpdatedBy = actor.UserID
+
+		if patch.Version != 0 && patch.Version != post.Version {
+			return nil, ErrVersionConflict
+		}
+
+		visibility := post.Visibility
+		if err := applyPatch(post, patch); err != nil {
+			return nil, err
+		}
+		if err := validateVisibility(post, visibility); err != nil {
+			return nil, err
+		}
+		if err := m.prepareAudience(ctx, post); err != nil {
+			return nil, err
+		}
+		post.UpdatedAt = time.Now()
+		applyFingerprint(post)
+
+		// Screen the changes, keeping the current verdict if there is no moderator
+		if err := m.screenPost(ctx, post); err != nil {
+			return nil, err
+		}
+
+		event := newEvent(EventPostUpdated, post, actor)
+		event.Previous = &previous
+		if err := m.runBeforeHooks(ctx, event); err != nil {
+			return nil, err
+		}
+
+		err = m.store.SavePost(ctx, post)
+		if errors.Is(err, ErrVersionConflict) && patch.Version == 0 && attempt < maxPatchAttempts {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		m.runAfterHooks(ctx, event)
+
+		if !post.isAuthor(actor.UserID) {
+			m.recordAudit(ctx, post, actor, AuditEdit, "")
+		}
+
+		return post, nil
+	}
+}
+
+// applyPatch applies the selected fields of a patch to a post.
+// Slices are rebuilt rather than modified in place because stores may share them with the caller.
+func applyPatch(post *Post, patch *PostPatch) error {
+	if patch.Fields.Has(PatchContent) {
+		post.Content = patch.Content
+	}
+
+	if patch.Fields.Has(PatchTags) {
+		post.Tags = append([]string(nil), patch.Tags...)
+	}
+
+	if patch.Fields.Has(PatchVisibility) {
+		post.Visibility = patch.Visibility
+		post.AudienceIDs = append([]string(nil), patch.AudienceIDs...)
+		post.AudienceUserIDs = append([]string(nil), patch.AudienceUserIDs...)
+	}
+
+	if patch.Fields.Has(PatchMedia) {
+		media, err := applyMediaPatch(post.Media, patch.Media)
+		if err != nil {
+			return err
+		}
+		post.Media = media
+	}
+
+	return nil
+}
+
+// applyMediaPatch returns a new media list with removals, additions and ordering applied
+func applyMediaPatch(current []Media, patch *MediaPatch) ([]Media, error) {
+	index := make(map[string]bool, len(current))
+	for _, media := range current {
+		index[media.ID] = true
+	}
+
+	// Remove media
+	removed := make(map[string]bool, len(patch.Remove))
+	for _, id := range patch.Remove {
+		if !index[id] {
+			return nil, fmt.Errorf("%w: media %s not found", ErrInvalidPatch, id)
+		}
+		removed[id] = true
+	}
+
+	result := make([]Media, 0, len(current)+len(patch.Add))
+	for _, media := range current {
+		if !removed[media.ID] {
+			result = append(result, media)
+		}
+	}
+
+	// Add media, keeping supplied IDs
+	for _, media := range patch.Add {
+		if media.ID == "" {
+			media.ID = uuid.New().String()
+		} else if index[media.ID] && !removed[media.ID] {
+			return nil, fmt.Errorf("%w: media %s already exists", ErrInvalidPatch, media.ID)
+		}
+		if media.CreatedAt.IsZero() {
+			media.CreatedAt = time.Now()
+		}
+		index[media.ID] = true
+		delete(removed, media.ID)
+		result = append(result, media)
+	}
+
+	if len(patch.Order) == 0 {
+		return result, nil
+	}
+
+	// Reorder media
+	position := make(map[string]int, len(patch.Order))
+	for i, id := range patch.Order {
+		if !index[id] || removed[id] {
+			return nil, fmt.Errorf("%w: media %s not found", ErrInvalidPatch, id)
+		}
+		if _, dup := position[id]; dup {
+			return nil, fmt.Errorf("%w: media %s listed twice", ErrInvalidPatch, id)
+		}
+		position[id] = i
+	}
+
+	ordered := make([]Media, len(patch.Order), len(result))
+	for _, media := range result {
+		if i, ok := position[media.ID]; ok {
+			ordered[i] = media
+		} else {
+			ordered = append(ordered, media)
+		}
+	}
+
+	return ordered, nil
+}
@@ -0,0 +1,162 @@
+package postflow
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createTestPatchPost creates a post with three media items for patch tests
+func createTestPatchPost(userID string) *Post {
+	post := createTestPostData(userID)
+	for _, id := range []string{"m1", "m2", "m3"} {
+		post.Media = append(post.Media, Media{
+			ID:        id,
+			Type:      MediaTypeImage,
+			URL:       "https://example.com/" + id + ".jpg",
+			CreatedAt: time.Now(),
+		})
+	}
+	return post
+}
+
+// mediaIDs returns the IDs of the given media in order
+func mediaIDs(media []Media) []string {
+	ids := make([]string, len(media))
+	for i, item := range media {
+		ids[i] = item.ID
+	}
+	return ids
+}
+
+// TestPostManagerPatchPost tests the PatchPost method
+func TestPostManagerPatchPost(t *testing.T) {
+	pm := setupTestPostManager()
+	ctx := context.Background()
+
+	post := createTestPatchPost("user1")
+	postID, err := pm.CreatePost(ctx, post)
+	require.NoError(t, err)
+	require.NoError(t, pm.AddReaction(ctx, postID, "user2", ReactionLike))
+
+	// Test: patch content only
+	patched, err := pm.PatchPost(ctx, postID, "user1", &PostPatch{
+		Fields:  PatchContent,
+		Content: "Patched content",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "Patched content", patched.Content)
+	assert.Equal(t, []string{"m1", "m2", "m3"}, mediaIDs(patched.Media))
+	assert.Equal(t, []string{"test", "golang", "postmanager"}, patched.Tags)
+	assert.Equal(t, 1, patched.Reactions[ReactionLike])
+
+	// Test: patch tags and visibility
+	patched, err = pm.PatchPost(ctx, postID, "user1", &PostPatch{
+		Fields:     PatchTags | PatchVisibility,
+		Tags:       []string{"patched"},
+		Visibility: "private",
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"patched"}, patched.Tags)
+	assert.Equal(t, VisibilityPrivate, patched.Visibility)
+	assert.Equal(t, "Patched content", patched.Content)
+
+	// Test: remove, add and reorder media
+	patched, err = pm.PatchPost(ctx, postID, "user1", &PostPatch{
+		Fields: PatchMedia,
+		Media: &MediaPatch{
+			Remove: []string{"m2"},
+			Add:    []Media{{ID: "m4", Type: MediaTypeVideo, URL: "https://example.com/m4.mp4"}},
+			Order:  []string{"m4", "m1"},
+		},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"m4", "m1", "m3"}, mediaIDs(patched.Media))
+
+	stored, err := pm.GetPost(ctx, postID)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"m4", "m1", "m3"}, mediaIDs(stored.Media))
+	assert.Equal(t, 1, stored.Reactions[ReactionLike])
+
+	// Test: unknown media is rejected
+	_, err = pm.PatchPost(ctx, postID, "user1", &PostPatch{
+		Fields: PatchMedia,
+		Media:  &MediaPatch{Remove: []string{"missing"}},
+	})
+	assert.ErrorIs(t, err, ErrInvalidPatch)
+
+	// Test: empty patch is rejected
+	_, err = pm.PatchPost(ctx, postID, "user1", &PostPatch{})
+	assert.ErrorIs(t, err, ErrInvalidPatch)
+
+	// Test: another user cannot patch the post
+	_, err = pm.PatchPost(ctx, postID, "user2", &PostPatch{Fields: PatchContent, Content: "Hijacked"})
+	assert.Equal(t, ErrPermissionDenied, err)
+
+	// Test: stale explicit version is rejected
+	_, err = pm.PatchPost(ctx, postID, "user1", &PostPatch{Fields: PatchContent, Content: "Stale", Version: 1})
+	assert.Equal(t, ErrVersionConflict, err)
+
+	// Test: nonexistent post
+	_, err = pm.PatchPost(ctx, "nonexistent-id", "user1", &PostPatch{Fields: PatchContent})
+	assert.Equal(t, ErrPostNotFound, err)
+}
+
+// TestPostManagerPatchPostGorm tests that patching through GormPostStore preserves media and their order
+func TestPostManagerPatchPostGorm(t *testing.T) {
+	store, db := setupTestGormStore(t)
+	defer cleanupTestDB(t, db)
+	pm := NewPostManager(store)
+	ctx := context.Background()
+
+	post := createTestPatchPost("user1")
+	postID, err := pm.CreatePost(ctx, post)
+	require.NoError(t, err)
+
+	// Test: patching content keeps all media
+	_, err = pm.PatchPost(ctx, postID, "user1", &PostPatch{Fields: PatchContent, Content: "Patched"})
+	assert.NoError(t, err)
+
+	stored, err := pm.GetPost(ctx, postID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Patched", stored.Content)
+	assert.Equal(t, []string{"m1", "m2", "m3"}, mediaIDs(stored.Media))
+
+	// Test: reordering is persisted
+	_, err = pm.PatchPost(ctx, postID, "user1", &PostPatch{
+		Fields: PatchMedia,
+		Media:  &MediaPatch{Order: []string{"m3", "m2", "m1"}},
+	})
+	assert.NoError(t, err)
+
+	stored, err = pm.GetPost(ctx, postID)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"m3", "m2", "m1"}, mediaIDs(stored.Media))
+}
+
+// TestPostManagerPatchPostReturnsCopy tests that the patched post does not share state with the store
+func TestPostManagerPatchPostReturnsCopy(t *testing.T) {
+	pm := setupTestPostManager()
+	ctx := context.Background()
+
+	postID, err := pm.CreatePost(ctx, createTestPatchPost("user1"))
+	require.NoError(t, err)
+	require.NoError(t, pm.AddReaction(ctx, postID, "user2", ReactionLike))
+
+	patched, err := pm.PatchPost(ctx, postID, "user1", &PostPatch{Fields: PatchContent, Content: "Patched"})
+	require.NoError(t, err)
+	assert.Equal(t, 1, patched.Reactions[ReactionLike])
+
+	// Test: later reactions do not change the returned post
+	require.NoError(t, pm.AddReaction(ctx, postID, "user3", ReactionLike))
+	assert.Equal(t, 1, patched.Reactions[ReactionLike])
+
+	// Test: changing the returned post does not change the stored post
+	patched.Reactions[ReactionLike] = 10
+	stored, err := pm.GetPost(ctx, postID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, stored.Reactions[ReactionLike])
+}
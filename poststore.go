@@ -18,11 +18,19 @@ var (
 
 	// ErrInvalidReaction is returned when an invalid reaction is provided
 	ErrInvalidReaction = errors.New("invalid reaction")
+
+	// ErrVersionConflict is returned when a post was modified concurrently since it was read
+	ErrVersionConflict = errors.New("version conflict")
+
+	// ErrNotSupported is returned when the configured store does not implement an optional capability
+	ErrNotSupported = errors.New("operation not supported by store")
 )
 
 // PostStore defines the interface for storing and retrieving posts
 type PostStore interface {
-	// SavePost saves a new post or updates an existing post
+	// SavePost saves a new post or updates an existing post.
+	// New posts start at version 1. Updates must carry the currently stored version,
+	// otherwise ErrVersionConflict is returned. On success post.Version holds the new version.
 	SavePost(ctx context.Context, post *Post) error
 
 	// GetPost retrieves a post by its ID
@@ -70,6 +78,26 @@ type InMemoryPostStore struct {
 	reactions map[string]map[string]*UserReaction // postID -> userID -> UserReaction
 	userPosts map[string][]string                 // userID -> []postID
 	tagPosts  map[string][]string                 // tag -> []postID
+
+	reports            map[string]map[string]*Report                        // postID -> reporterID -> Report
+	auditEntries       map[string][]*AuditEntry                             // tenant-scoped postID -> []AuditEntry
+	spamFingerprints   map[string]*SpamFingerprint                          // tenant-scoped hash -> SpamFingerprint
+	shadowBans         map[string]*ShadowBan                                // tenant-scoped userID -> ShadowBan
+	relations          map[RelationKind]map[string]map[string]*UserRelation // kind -> tenant-scoped userID -> targetID -> UserRelation
+	mutedTerms         map[string]map[string]*MutedTerm                     // tenant-scoped userID -> kind:value -> MutedTerm
+	feedback           map[string]map[string]*PostFeedback                  // postID -> userID -> PostFeedback
+	coAuthors          map[string]map[string]*CoAuthor                      // postID -> userID -> CoAuthor
+	audiences          map[string]*Audience                                 // audienceID -> Audience
+	audienceMembers    map[string]map[string]bool                           // audienceID -> userID -> member
+	groups             map[string]*Group                                    // groupID -> Group
+	groupMembers       map[string]map[string]*GroupMember                   // groupID -> userID -> GroupMember
+	webhooks           map[string]*WebhookEndpoint                          // endpointID -> WebhookEndpoint
+	webhookDeliveries  map[string][]*WebhookDelivery                        // endpointID -> []WebhookDelivery
+	notifications      map[string]*Notification                             // notificationID -> Notification
+	notificationActors map[string]map[string]bool                           // notificationID -> actorID -> counted
+	notificationPrefs  map[string]*NotificationPreferences                  // tenant-scoped userID -> NotificationPreferences
+	idempotencyKeys    map[string]idempotencyEntry                          // key -> claimed value
+	idempotencySweep   time.Time                                            // last purge of expired keys
 }
 
 // NewInMemoryPostStore creates a new instance of InMemoryPostStore
@@ -79,6 +107,25 @@ func NewInMemoryPostStore() *InMemoryPostStore {
 		reactions: make(map[string]map[string]*UserReaction),
 		userPosts: make(map[string][]string),
 		tagPosts:  make(map[string][]string),
+
+		reports:            make(map[string]map[string]*Report),
+		auditEntries:       make(map[string][]*AuditEntry),
+		spamFingerprints:   make(map[string]*SpamFingerprint),
+		shadowBans:         make(map[string]*ShadowBan),
+		relations:          make(map[RelationKind]map[string]map[string]*UserRelation),
+		mutedTerms:         make(map[string]map[string]*MutedTerm),
+		feedback:           make(map[string]map[string]*PostFeedback),
+		coAuthors:          make(map[string]map[string]*CoAuthor),
+		audiences:          make(map[string]*Audience),
+		audienceMembers:    make(map[string]map[string]bool),
+		groups:             make(map[string]*Group),
+		groupMembers:       make(map[string]map[string]*GroupMember),
+		webhooks:           make(map[string]*WebhookEndpoint),
+		webhookDeliveries:  make(map[string][]*WebhookDelivery),
+		notifications:      make(map[string]*Notification),
+		notificationActors: make(map[string]map[string]bool),
+		notificationPrefs:  make(map[string]*NotificationPreferences),
+		idempotencyKeys:    make(map[string]idempotencyEntry),
 	}
 }
 
@@ -87,27 +134,33 @@ func (s *InMemoryPostStore) SavePost(ctx context.Context, post *Post) error {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	if _, exists := s.posts[post.ID]; !exists {
-		// New post
-		s.posts[post.ID] = post
-
-		// Index by user
-		s.userPosts[post.UserID] = append(s.userPosts[post.UserID], post.ID)
-
-		// Index by tags
-		for _, tag := range post.Tags {
-			s.tagPosts[tag] = append(s.tagPosts[tag], post.ID)
-		}
+	post.TenantID = TenantFromContext(ctx)
+
+	if oldPost, exists := s.posts[post.ID]; !exists {
+		// New post, stored as a copy so that the caller's post never shares the counts updated by reactions
+		stored := *post
+		stored.Reactions = copyReactionCounts(post.Reactions)
+		s.insertPost(&stored)
+		post.Version = stored.Version
+		post.CoAuthors = stored.CoAuthors
+	} else if oldPost.TenantID != post.TenantID {
+		// The ID is taken by another tenant
+		return ErrPostExists
 	} else {
 		// Update existing post
-		oldPost := s.posts[post.ID]
+
+		// Reject stale writes
+		if post.Version != oldPost.Version {
+			return ErrVersionConflict
+		}
 
 		// Remove old tag references
 		for _, tag := range oldPost.Tags {
-			tagPosts := s.tagPosts[tag]
+			key := tenantTag(post.TenantID, tag)
+			tagPosts := s.tagPosts[key]
 			for i, pid := range tagPosts {
 				if pid == post.ID {
-					s.tagPosts[tag] = append(tagPosts[:i], tagPosts[i+1:]...)
+					s.tagPosts[key] = append(tagPosts[:i], tagPosts[i+1:]...)
 					break
 				}
 			}
@@ -115,38 +168,72 @@ func (s *InMemoryPostStore) SavePost(ctx context.Context, post *Post) error {
 
 		// Add new tag references
 		for _, tag := range post.Tags {
+			key := tenantTag(post.TenantID, tag)
 			found := false
-			for _, pid := range s.tagPosts[tag] {
+			for _, pid := range s.tagPosts[key] {
 				if pid == post.ID {
 					found = true
 					break
 				}
 			}
 			if !found {
-				s.tagPosts[tag] = append(s.tagPosts[tag], post.ID)
+				s.tagPosts[key] = append(s.tagPosts[key], post.ID)
 			}
 		}
 
-		// Update the post
-		s.posts[post.ID] = post
+		// Reactions and co-authors are managed separately
+		stored := *post
+		stored.Reactions = oldPost.Reactions
+		stored.CoAuthors = oldPost.CoAuthors
+		stored.Version = oldPost.Version + 1
+		s.posts[post.ID] = &stored
+
+		// The caller gets copies of what the store manages
+		post.Reactions = copyReactionCounts(oldPost.Reactions)
+		post.CoAuthors = oldPost.CoAuthors
+		post.Version = stored.Version
 	}
 
 	return nil
 }
 
+// copyReactionCounts returns a copy of reaction counts, never nil
+func copyReactionCounts(counts map[ReactionType]int) map[ReactionType]int {
+	countsCopy := make(map[ReactionType]int, len(counts))
+	for reactionType, count := range counts {
+		countsCopy[reactionType] = count
+	}
+	return countsCopy
+}
+
+// insertPost stores a new post and indexes it. The caller must hold the write lock.
+func (s *InMemoryPostStore) insertPost(post *Post) {
+	post.Version = 1
+	post.CoAuthors = nil // Co-authors join through invitations
+	s.posts[post.ID] = post
+
+	// Index by user
+	s.userPosts[post.UserID] = append(s.userPosts[post.UserID], post.ID)
+
+	// Index by tags within the tenant's namespace
+	for _, tag := range post.Tags {
+		key := tenantTag(post.TenantID, tag)
+		s.tagPosts[key] = append(s.tagPosts[key], post.ID)
+	}
+}
+
 // GetPost retrieves a post by its ID
 func (s *InMemoryPostStore) GetPost(ctx context.Context, postID string) (*Post, error) {
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
 
-	post, exists := s.posts[postID]
+	post, exists := s.lookupPost(ctx, postID)
 	if !exists {
 		return nil, ErrPostNotFound
 	}
 
 	// Return a copy to prevent modifications to the stored post
-	postCopy := *post
-	return &postCopy, nil
+	return s.copyPostFor(post, viewerFromContext(ctx)), nil
 }
 
 // DeletePost removes a post from the store
@@ -154,7 +241,7 @@ func (s *InMemoryPostStore) DeletePost(ctx context.Context, postID string, userI
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	post, exists := s.posts[postID]
+	post, exists := s.lookupPost(ctx, postID)
 	if !exists {
 		return ErrPostNotFound
 	}
@@ -175,17 +262,26 @@ func (s *InMemoryPostStore) DeletePost(ctx context.Context, postID string, userI
 
 	// Remove from tag posts
 	for _, tag := range post.Tags {
-		tagPosts := s.tagPosts[tag]
+		key := tenantTag(post.TenantID, tag)
+		tagPosts := s.tagPosts[key]
 		for i, pid := range tagPosts {
 			if pid == postID {
-				s.tagPosts[tag] = append(tagPosts[:i], tagPosts[i+1:]...)
+				s.tagPosts[key] = append(tagPosts[:i], tagPosts[i+1:]...)
 				break
 			}
 		}
 	}
 
-	// Remove reactions
+	// Remove from co-authors' posts
+	for _, coAuthorID := range post.CoAuthors {
+		s.removeUserPost(coAuthorID, postID)
+	}
+
+	// Remove reactions, reports, feedback and co-authors
 	delete(s.reactions, postID)
+	delete(s.reports, postID)
+	delete(s.feedback, postID)
+	delete(s.coAuthors, postID)
 
 	// Remove the post
 	delete(s.posts, postID)
@@ -200,6 +296,11 @@ func (s *InMemoryPostStore) ListPosts(ctx context.Context, filter *PostFilter) (
 
 	var result []*Post
 	var candidateIDs map[string]bool
+	viewer := viewerFromContext(ctx)
+	allAudiences := seesAllAudiences(ctx)
+	_, hasActor := ActorFromContext(ctx)
+	muted := filter.honorsMutedTerms()
+	tenant := TenantFromContext(ctx)
 
 	// Start with user filter if present
 	if filter.UserID != "" {
@@ -214,7 +315,7 @@ func (s *InMemoryPostStore) ListPosts(ctx context.Context, filter *PostFilter) (
 		tagCandidates := make(map[string]bool)
 
 		for i, tag := range filter.Tags {
-			for _, pid := range s.tagPosts[tag] {
+			for _, pid := range s.tagPosts[tenantTag(tenant, tag)] {
 				if i == 0 || candidateIDs[pid] {
 					tagCandidates[pid] = true
 				}
@@ -229,43 +330,29 @@ func (s *InMemoryPostStore) ListPosts(ctx context.Context, filter *PostFilter) (
 		for id := range s.posts {
 			post := s.posts[id]
 
-			// Apply visibility filter
-			if filter.Visibility != "" && post.Visibility != filter.Visibility {
+			if post.TenantID != tenant || !matchesFilter(post, filter) || !s.visibleTo(post.TenantID, post.UserID, viewer) ||
+				!allAudiences && (!s.listedFor(post, filter, viewer) || hasActor && post.privateFrom(viewer)) ||
+				muted && s.mutedFor(post, viewer) {
 				continue
 			}
 
-			// Apply time range filter
-			if filter.TimeRange != nil {
-				if !post.CreatedAt.After(filter.TimeRange.Start) || !post.CreatedAt.Before(filter.TimeRange.End) {
-					continue
-				}
-			}
-
-			postCopy := *post
-			result = append(result, &postCopy)
+			result = append(result, s.copyPostFor(post, viewer))
 		}
 	} else {
 		// Apply additional filters to candidate posts
 		for id := range candidateIDs {
-			post, exists := s.posts[id]
+			post, exists := s.lookupPost(ctx, id)
 			if !exists {
 				continue
 			}
 
-			// Apply visibility filter
-			if filter.Visibility != "" && post.Visibility != filter.Visibility {
+			if !matchesFilter(post, filter) || !s.visibleTo(post.TenantID, post.UserID, viewer) ||
+				!allAudiences && (!s.listedFor(post, filter, viewer) || hasActor && post.privateFrom(viewer)) ||
+				muted && s.mutedFor(post, viewer) {
 				continue
 			}
 
-			// Apply time range filter
-			if filter.TimeRange != nil {
-				if !post.CreatedAt.After(filter.TimeRange.Start) || !post.CreatedAt.Before(filter.TimeRange.End) {
-					continue
-				}
-			}
-
-			postCopy := *post
-			result = append(result, &postCopy)
+			result = append(result, s.copyPostFor(post, viewer))
 		}
 	}
 
@@ -314,6 +401,44 @@ func (s *InMemoryPostStore) ListPosts(ctx context.Context, filter *PostFilter) (
 	return result, nil
 }
 
+// matchesFilter applies the group, visibility, time range and moderation filters to a post
+func matchesFilter(post *Post, filter *PostFilter) bool {
+	// Apply group filter
+	if filter.GroupID != "" && post.GroupID != filter.GroupID {
+		return false
+	}
+
+	// Apply visibility filter
+	if filter.Visibility != "" && post.Visibility != filter.Visibility {
+		return false
+	}
+
+	// Apply time range filter
+	if filter.TimeRange != nil {
+		if !post.CreatedAt.After(filter.TimeRange.Start) || !post.CreatedAt.Before(filter.TimeRange.End) {
+			return false
+		}
+	}
+
+	// Apply moderation filter, only approved posts unless asked otherwise
+	return post.ModerationStatus == filter.moderationStatus()
+}
+
+// inFeedPost reports whether the post may appear in the viewer's feed and trending posts:
+// it is approved, public, followers-only or custom and shared with the viewer, outside private groups
+// the viewer is not in, and not filtered by the viewer's relations, feedback or muted terms. The caller must hold the lock.
+func (s *InMemoryPostStore) inFeedPost(post *Post, viewer string) bool {
+	if post.ModerationStatus != ModerationApproved {
+		return false
+	}
+	switch post.Visibility {
+	case VisibilityPublic, VisibilityFollowers, VisibilityCustom:
+	default:
+		return false
+	}
+	return s.canView(post, viewer) && s.inFeedOf(post.TenantID, post.UserID, viewer) && !s.hasFeedback(viewer, post.ID) && !s.mutedFor(post, viewer)
+}
+
 // GetUserFeed retrieves posts for a user's feed
 // In a real implementation, this would consider followed users, algorithms, etc.
 // This simple version just returns recent public posts
@@ -323,11 +448,11 @@ func (s *InMemoryPostStore) GetUserFeed(ctx context.Context, userID string, limi
 
 	var result []*Post
 
-	// Get all public posts
+	// Get all approved posts that are public or shared with the user
+	tenant := TenantFromContext(ctx)
 	for _, post := range s.posts {
-		if post.Visibility == "public" {
-			postCopy := *post
-			result = append(result, &postCopy)
+		if post.TenantID == tenant && s.inFeedPost(post, userID) {
+			result = append(result, s.copyPostFor(post, userID))
 		}
 	}
 
@@ -359,12 +484,13 @@ func (s *InMemoryPostStore) GetTrendingPosts(ctx context.Context, limit int) ([]
 	defer s.mutex.RUnlock()
 
 	var result []*Post
+	viewer := viewerFromContext(ctx)
 
-	// Get all public posts
+	// Get all approved posts that are public or shared with the viewer
+	tenant := TenantFromContext(ctx)
 	for _, post := range s.posts {
-		if post.Visibility == "public" {
-			postCopy := *post
-			result = append(result, &postCopy)
+		if post.TenantID == tenant && s.inFeedPost(post, viewer) {
+			result = append(result, s.copyPostFor(post, viewer))
 		}
 	}
 
@@ -388,7 +514,7 @@ func (s *InMemoryPostStore) SaveReaction(ctx context.Context, postID string, use
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	post, exists := s.posts[postID]
+	post, exists := s.lookupPost(ctx, postID)
 	if !exists {
 		return ErrPostNotFound
 	}
@@ -398,6 +524,11 @@ func (s *InMemoryPostStore) SaveReaction(ctx context.Context, postID string, use
 		return ErrInvalidReaction
 	}
 
+	// Users blocked by the author cannot react
+	if s.hasRelation(post.TenantID, post.UserID, userID, RelationBlock) {
+		return ErrBlocked
+	}
+
 	// Initialize reactions map for this post if it doesn't exist
 	if _, exists := s.reactions[postID]; !exists {
 		s.reactions[postID] = make(map[string]*UserReaction)
@@ -448,7 +579,7 @@ func (s *InMemoryPostStore) DeleteReaction(ctx context.Context, postID string, u
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	post, exists := s.posts[postID]
+	post, exists := s.lookupPost(ctx, postID)
 	if !exists {
 		return ErrPostNotFound
 	}
@@ -480,7 +611,7 @@ func (s *InMemoryPostStore) GetUserReaction(ctx context.Context, postID string,
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
 
-	if _, exists := s.posts[postID]; !exists {
+	if _, exists := s.lookupPost(ctx, postID); !exists {
 		return nil, ErrPostNotFound
 	}
 
@@ -503,7 +634,7 @@ func (s *InMemoryPostStore) GetReactedUsers(ctx context.Context, postID string,
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
 
-	if _, exists := s.posts[postID]; !exists {
+	if _, exists := s.lookupPost(ctx, postID); !exists {
 		return nil, ErrPostNotFound
 	}
 
@@ -513,9 +644,13 @@ func (s *InMemoryPostStore) GetReactedUsers(ctx context.Context, postID string,
 	}
 
 	var userIDs []string
+	viewer := viewerFromContext(ctx)
 
 	// Filter by reaction type if specified
 	for userID, reaction := range postReactions {
+		if !s.visibleTo(TenantFromContext(ctx), userID, viewer) {
+			continue
+		}
 		if reactionType == nil || reaction.ReactionType == *reactionType {
 			userIDs = append(userIDs, userID)
 		}
@@ -547,22 +682,13 @@ func (s *InMemoryPostStore) GetReactionCounts(ctx context.Context, postID string
 	s.mutex.RLock()
 	defer s.mutex.RUnlock()
 
-	post, exists := s.posts[postID]
+	post, exists := s.lookupPost(ctx, postID)
 	if !exists {
 		return nil, ErrPostNotFound
 	}
 
-	// Create a copy of the reactions map
-	if post.Reactions == nil {
-		return make(map[ReactionType]int), nil
-	}
-
-	result := make(map[ReactionType]int)
-	for reactionType, count := range post.Reactions {
-		result[reactionType] = count
-	}
-
-	return result, nil
+	// Reactions of shadow-banned users are hidden from everyone else
+	return s.reactionCountsFor(post, viewerFromContext(ctx)), nil
 }
 
 // Helper function to sum all reactions
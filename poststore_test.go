@@ -217,7 +217,7 @@ func TestListPosts(t *testing.T) {
 	posts, err = store.ListPosts(ctx, filter)
 	assert.NoError(t, err)
 	assert.Equal(t, 1, len(posts))
-	assert.Equal(t, "private", posts[0].Visibility)
+	assert.Equal(t, VisibilityPrivate, posts[0].Visibility)
 
 	// Test: list posts with time range filter
 	now := time.Now()
@@ -257,7 +257,7 @@ func TestGetUserFeed(t *testing.T) {
 	assert.NoError(t, err)
 	assert.Equal(t, 5, len(posts))
 	for _, post := range posts {
-		assert.Equal(t, "public", post.Visibility)
+		assert.Equal(t, VisibilityPublic, post.Visibility)
 	}
 
 	// Test: pagination
@@ -506,3 +506,37 @@ func TestGetReactionCounts(t *testing.T) {
 	assert.Equal(t, ErrPostNotFound, err)
 	assert.Nil(t, counts)
 }
+
+// TestSavePostVersionConflict tests optimistic concurrency control in SavePost
+func TestSavePostVersionConflict(t *testing.T) {
+	store := setupTestStore()
+	ctx := context.Background()
+
+	post := createTestPost("user1")
+	err := store.SavePost(ctx, post)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), post.Version)
+
+	// Two readers get the same version
+	first, err := store.GetPost(ctx, post.ID)
+	assert.NoError(t, err)
+	second, err := store.GetPost(ctx, post.ID)
+	assert.NoError(t, err)
+
+	// The first writer wins and bumps the version
+	first.Comments = 5
+	err = store.SavePost(ctx, first)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), first.Version)
+
+	// The second writer holds a stale version and must fail
+	second.Shares = 3
+	err = store.SavePost(ctx, second)
+	assert.Equal(t, ErrVersionConflict, err)
+
+	saved, err := store.GetPost(ctx, post.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, saved.Comments)
+	assert.Equal(t, 0, saved.Shares)
+	assert.Equal(t, int64(2), saved.Version)
+}
@@ -0,0 +1,156 @@
+// Package postflow provides functionality for managing user posts and feeds.
+package postflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+	"time"
+)
+
+// ErrRateLimited is returned when a user exceeds the rate limit of an action
+var ErrRateLimited = errors.New("rate limited")
+
+// RateLimit allows Limit calls per Period, with bursts of up to Limit calls
+type RateLimit struct {
+	Limit  int
+	Period time.Duration
+}
+
+// RateLimitError is returned when a user exceeds the rate limit of an action
+type RateLimitError struct {
+	Action     Action
+	RetryAfter time.Duration // Time until the next call is allowed
+}
+
+// Error describes the exceeded limit
+func (e *RateLimitError) Error() string {
+	return fmt.Sprintf("rate limited: %s, retry after %s", e.Action, e.RetryAfter)
+}
+
+// Is makes errors.Is(err, ErrRateLimited) match
+func (e *RateLimitError) Is(target error) bool {
+	return target == ErrRateLimited
+}
+
+// RateLimiter takes tokens from token buckets identified by key
+type RateLimiter interface {
+	// Take takes a token from the bucket, returning zero if the call is allowed
+	// or how long to wait for the next token otherwise
+	Take(ctx context.Context, key string, limit RateLimit) (time.Duration, error)
+}
+
+// WithRateLimiter enables per-user rate limiting of the given actions.
+// Only ActionCreate and ActionReact are rate limited.
+func WithRateLimiter(limiter RateLimiter, limits map[Action]RateLimit) PostManagerOption {
+	return func(m *PostManagerImpl) {
+		m.rateLimiter = limiter
+		m.rateLimits = limits
+	}
+}
+
+// checkRateLimit takes a token for the user and action if the action is rate limited.
+// Each tenant has its own buckets.
+func (m *PostManagerImpl) checkRateLimit(ctx context.Context, userID string, action Action) error {
+	if m.rateLimiter == nil {
+		return nil
+	}
+
+	limit, ok := m.rateLimits[action]
+	if !ok || limit.Limit <= 0 || limit.Period <= 0 {
+		return nil
+	}
+
+	retryAfter, err := m.rateLimiter.Take(ctx, tenantScopedKey(ctx, action.String()+":"+userID), limit)
+	if err != nil {
+		return err
+	}
+	if retryAfter > 0 {
+		return &RateLimitError{Action: action, RetryAfter: retryAfter}
+	}
+
+	return nil
+}
+
+// tokenEpsilon is the rounding tolerance when comparing token counts
+const tokenEpsilon = 1e-9
+
+// takeToken refills a bucket for the time elapsed since last and takes a token.
+// It returns the remaining tokens and, if no token was available, the time until the next one.
+func takeToken(tokens float64, last, now time.Time, limit RateLimit) (float64, time.Duration) {
+	perToken := limit.Period / time.Duration(limit.Limit)
+
+	if elapsed := now.Sub(last); elapsed > 0 {
+		tokens = math.Min(float64(limit.Limit), tokens+float64(elapsed)/float64(perToken))
+	}
+
+	// Tolerate rounding errors from accumulating fractional refills
+	if tokens+tokenEpsilon >= 1 {
+		return math.Max(0, tokens-1), 0
+	}
+
+	return tokens, time.Duration(math.Round((1 - tokens) * float64(perToken)))
+}
+
+// fullAt returns when a bucket holding tokens at now is refilled completely
+func fullAt(tokens float64, now time.Time, limit RateLimit) time.Time {
+	perToken := limit.Period / time.Duration(limit.Limit)
+	return now.Add(time.Duration(math.Ceil((float64(limit.Limit) - tokens) * float64(perToken))))
+}
+
+// tokenBucket is the state of a single in-memory bucket
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+	full    time.Time // When the bucket is refilled and no different from a new one
+}
+
+// InMemoryRateLimiter keeps token buckets in memory; it suits single-instance deployments.
+// Buckets that have refilled completely are evicted, so idle users take no memory.
+type InMemoryRateLimiter struct {
+	buckets map[string]*tokenBucket
+	swept   time.Time // Last eviction of refilled buckets
+	mutex   sync.Mutex
+	now     func() time.Time
+}
+
+// NewInMemoryRateLimiter creates a new in-memory rate limiter
+func NewInMemoryRateLimiter() *InMemoryRateLimiter {
+	return &InMemoryRateLimiter{
+		buckets: make(map[string]*tokenBucket),
+		now:     time.Now,
+	}
+}
+
+// Take takes a token from the bucket identified by key
+func (l *InMemoryRateLimiter) Take(ctx context.Context, key string, limit RateLimit) (time.Duration, error) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+
+	// Evict refilled buckets at most once a minute
+	if now.Sub(l.swept) > time.Minute {
+		for k, bucket := range l.buckets {
+			if !now.Before(bucket.full) {
+				delete(l.buckets, k)
+			}
+		}
+		l.swept = now
+	}
+
+	bucket, exists := l.buckets[key]
+	if !exists {
+		bucket = &tokenBucket{tokens: float64(limit.Limit), updated: now}
+		l.buckets[key] = bucket
+	}
+
+	tokens, retryAfter := takeToken(bucket.tokens, bucket.updated, now, limit)
+	bucket.tokens = tokens
+	bucket.updated = now
+	bucket.full = fullAt(tokens, now, limit)
+
+	return retryAfter, nil
+}
@@ -0,0 +1,122 @@
+package postflow
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestInMemoryRateLimiter tests token bucket refills
+func TestInMemoryRateLimiter(t *testing.T) {
+	limiter := NewInMemoryRateLimiter()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter.now = func() time.Time { return now }
+	ctx := context.Background()
+	limit := RateLimit{Limit: 2, Period: time.Minute}
+
+	// Test: the bucket starts full
+	for i := 0; i < 2; i++ {
+		retryAfter, err := limiter.Take(ctx, "user1", limit)
+		assert.NoError(t, err)
+		assert.Equal(t, time.Duration(0), retryAfter)
+	}
+
+	// Test: an empty bucket reports when the next token arrives
+	retryAfter, err := limiter.Take(ctx, "user1", limit)
+	assert.NoError(t, err)
+	assert.Equal(t, 30*time.Second, retryAfter)
+
+	// Test: buckets are independent per key
+	retryAfter, err = limiter.Take(ctx, "user2", limit)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), retryAfter)
+
+	// Test: tokens refill over time
+	now = now.Add(20 * time.Second)
+	retryAfter, err = limiter.Take(ctx, "user1", limit)
+	assert.NoError(t, err)
+	assert.Equal(t, 10*time.Second, retryAfter)
+
+	now = now.Add(10 * time.Second)
+	retryAfter, err = limiter.Take(ctx, "user1", limit)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), retryAfter)
+}
+
+// TestInMemoryRateLimiterEviction tests that refilled buckets are evicted
+func TestInMemoryRateLimiterEviction(t *testing.T) {
+	limiter := NewInMemoryRateLimiter()
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	limiter.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	_, err := limiter.Take(ctx, "fast", RateLimit{Limit: 2, Period: time.Minute})
+	require.NoError(t, err)
+	_, err = limiter.Take(ctx, "slow", RateLimit{Limit: 2, Period: time.Hour})
+	require.NoError(t, err)
+
+	// Test: buckets that have refilled are evicted, others are kept
+	now = now.Add(2 * time.Minute)
+	_, err = limiter.Take(ctx, "other", RateLimit{Limit: 2, Period: time.Minute})
+	require.NoError(t, err)
+	assert.NotContains(t, limiter.buckets, "fast")
+	assert.Contains(t, limiter.buckets, "slow")
+
+	// Test: an evicted bucket starts full again
+	for i := 0; i < 2; i++ {
+		retryAfter, err := limiter.Take(ctx, "fast", RateLimit{Limit: 2, Period: time.Minute})
+		assert.NoError(t, err)
+		assert.Equal(t, time.Duration(0), retryAfter)
+	}
+
+	now = now.Add(time.Hour)
+	_, err = limiter.Take(ctx, "other", RateLimit{Limit: 2, Period: time.Minute})
+	require.NoError(t, err)
+	assert.Equal(t, 1, len(limiter.buckets))
+}
+
+// TestPostManagerRateLimit tests rate limiting of posting and reacting
+func TestPostManagerRateLimit(t *testing.T) {
+	pm := NewPostManager(NewInMemoryPostStore(), WithRateLimiter(NewInMemoryRateLimiter(), map[Action]RateLimit{
+		ActionCreate: {Limit: 2, Period: time.Hour},
+		ActionReact:  {Limit: 3, Period: time.Hour},
+	}))
+	ctx := context.Background()
+
+	// Test: posting is limited per user
+	postID, err := pm.CreatePost(ctx, createTestPostData("user1"))
+	require.NoError(t, err)
+	_, err = pm.CreatePost(ctx, createTestPostData("user1"))
+	assert.NoError(t, err)
+	_, err = pm.CreatePost(ctx, createTestPostData("user1"))
+	assert.True(t, errors.Is(err, ErrRateLimited))
+
+	var rateLimitErr *RateLimitError
+	require.True(t, errors.As(err, &rateLimitErr))
+	assert.Equal(t, ActionCreate, rateLimitErr.Action)
+	assert.Greater(t, rateLimitErr.RetryAfter, time.Duration(0))
+
+	_, err = pm.CreatePost(ctx, createTestPostData("user2"))
+	assert.NoError(t, err)
+
+	// Test: toggling reactions shares one budget
+	assert.NoError(t, pm.AddReaction(ctx, postID, "user2", ReactionLike))
+	assert.NoError(t, pm.RemoveReaction(ctx, postID, "user2", ReactionLike))
+	assert.NoError(t, pm.AddReaction(ctx, postID, "user2", ReactionLove))
+	err = pm.RemoveReaction(ctx, postID, "user2", ReactionLove)
+	assert.True(t, errors.Is(err, ErrRateLimited))
+	err = pm.AddReaction(ctx, postID, "user2", ReactionLike)
+	assert.True(t, errors.Is(err, ErrRateLimited))
+
+	// Test: a limited reaction does not consume its idempotency key
+	keyCtx := WithIdempotencyKey(ctx, "reaction-1")
+	err = pm.AddReaction(keyCtx, postID, "user2", ReactionHaha)
+	assert.True(t, errors.Is(err, ErrRateLimited))
+	_, claim, err := pm.claimIdempotencyKey(ctx, "add_reaction:user2:reaction-1", requestFingerprint(postID, ReactionHaha), "")
+	assert.NoError(t, err)
+	assert.NotNil(t, claim)
+}
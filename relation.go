@@ -0,0 +1,280 @@
+// Package postflow provides functionality for managing user posts and feeds.
+package postflow
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+)
+
+var (
+	// ErrBlocked is returned when a user acts on content of a user who blocked them
+	ErrBlocked = errors.New("blocked by the author")
+
+	// ErrInvalidRelation is returned when a user tries to block or mute themselves
+	ErrInvalidRelation = errors.New("invalid relation")
+)
+
+// RelationKind represents how a user relates to another user
+type RelationKind uint8
+
+const (
+	RelationBlock  RelationKind = 1 // The target can neither see nor react to the user's posts
+	RelationMute   RelationKind = 2 // The target's posts are left out of the user's feed and trending
+	RelationFollow RelationKind = 3 // The user sees the target's followers-only posts
+)
+
+// String returns the name of the relation kind
+func (k RelationKind) String() string {
+	switch k {
+	case RelationBlock:
+		return "block"
+	case RelationMute:
+		return "mute"
+	case RelationFollow:
+		return "follow"
+	default:
+		return "unknown"
+	}
+}
+
+// UserRelation is a directed relation from UserID to TargetID
+type UserRelation struct {
+	UserID    string       `json:"user_id"`
+	TargetID  string       `json:"target_id"`
+	Kind      RelationKind `json:"kind"`
+	CreatedAt time.Time    `json:"created_at"`
+}
+
+// RelationStore defines the interface for stores that keep relations between users.
+// Relations belong to the tenant in the context and have no effect in other tenants.
+// Stores hide posts across blocks in both directions, leave muted authors out of
+// GetUserFeed and GetTrendingPosts, and reject reactions from users blocked by the author with ErrBlocked.
+type RelationStore interface {
+	// SaveRelation creates a relation, keeping the existing one if present
+	SaveRelation(ctx context.Context, relation *UserRelation) error
+
+	// DeleteRelation removes a relation
+	DeleteRelation(ctx context.Context, userID, targetID string, kind RelationKind) error
+
+	// HasRelation reports whether the relation exists
+	HasRelation(ctx context.Context, userID, targetID string, kind RelationKind) (bool, error)
+
+	// ListRelations returns the relations of a kind from a user, most recent first
+	ListRelations(ctx context.Context, userID string, kind RelationKind, limit, offset int) ([]*UserRelation, error)
+}
+
+// Block prevents targetID from seeing and reacting to the posts of userID and hides
+// the target's posts from the user. Follows between the two users are removed.
+func (m *PostManagerImpl) Block(ctx context.Context, userID, targetID string) error {
+	if err := m.saveRelation(ctx, userID, targetID, RelationBlock); err != nil {
+		return err
+	}
+	if err := m.deleteRelation(ctx, userID, targetID, RelationFollow); err != nil {
+		return err
+	}
+
+	return m.deleteRelation(ctx, targetID, userID, RelationFollow)
+}
+
+// Unblock removes a block
+func (m *PostManagerImpl) Unblock(ctx context.Context, userID, targetID string) error {
+	return m.deleteRelation(ctx, userID, targetID, RelationBlock)
+}
+
+// Mute leaves the posts of targetID out of the feed and trending posts of userID
+func (m *PostManagerImpl) Mute(ctx context.Context, userID, targetID string) error {
+	return m.saveRelation(ctx, userID, targetID, RelationMute)
+}
+
+// Unmute removes a mute
+func (m *PostManagerImpl) Unmute(ctx context.Context, userID, targetID string) error {
+	return m.deleteRelation(ctx, userID, targetID, RelationMute)
+}
+
+// Follow lets userID see the followers-only posts of targetID.
+// Users blocked by the target cannot follow them.
+func (m *PostManagerImpl) Follow(ctx context.Context, userID, targetID string) error {
+	if err := m.checkBlocked(ctx, targetID, userID); err != nil {
+		return err
+	}
+
+	return m.saveRelation(ctx, userID, targetID, RelationFollow)
+}
+
+// Unfollow removes a follow
+func (m *PostManagerImpl) Unfollow(ctx context.Context, userID, targetID string) error {
+	return m.deleteRelation(ctx, userID, targetID, RelationFollow)
+}
+
+// ListFollowing returns the users followed by userID, most recent first
+func (m *PostManagerImpl) ListFollowing(ctx context.Context, userID string, limit, offset int) ([]*UserRelation, error) {
+	return m.listRelations(ctx, userID, RelationFollow, limit, offset)
+}
+
+// ListBlocked returns the users blocked by userID, most recent first
+func (m *PostManagerImpl) ListBlocked(ctx context.Context, userID string, limit, offset int) ([]*UserRelation, error) {
+	return m.listRelations(ctx, userID, RelationBlock, limit, offset)
+}
+
+// ListMuted returns the users muted by userID, most recent first
+func (m *PostManagerImpl) ListMuted(ctx context.Context, userID string, limit, offset int) ([]*UserRelation, error) {
+	return m.listRelations(ctx, userID, RelationMute, limit, offset)
+}
+
+// saveRelation validates and stores a relation
+func (m *PostManagerImpl) saveRelation(ctx context.Context, userID, targetID string, kind RelationKind) error {
+	store, ok := m.store.(RelationStore)
+	if !ok {
+		return ErrNotSupported
+	}
+	if userID == "" || targetID == "" || userID == targetID {
+		return ErrInvalidRelation
+	}
+
+	return store.SaveRelation(ctx, &UserRelation{
+		UserID:    userID,
+		TargetID:  targetID,
+		Kind:      kind,
+		CreatedAt: time.Now(),
+	})
+}
+
+// deleteRelation removes a relation
+func (m *PostManagerImpl) deleteRelation(ctx context.Context, userID, targetID string, kind RelationKind) error {
+	store, ok := m.store.(RelationStore)
+	if !ok {
+		return ErrNotSupported
+	}
+
+	return store.DeleteRelation(ctx, userID, targetID, kind)
+}
+
+// listRelations lists the relations of a kind from a user
+func (m *PostManagerImpl) listRelations(ctx context.Context, userID string, kind RelationKind, limit, offset int) ([]*UserRelation, error) {
+	store, ok := m.store.(RelationStore)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	return store.ListRelations(ctx, userID, kind, limit, offset)
+}
+
+// checkBlocked returns ErrBlocked if the author blocked the viewer
+func (m *PostManagerImpl) checkBlocked(ctx context.Context, authorID, viewer string) error {
+	store, ok := m.store.(RelationStore)
+	if !ok || viewer == "" || viewer == authorID {
+		return nil
+	}
+
+	blocked, err := store.HasRelation(ctx, authorID, viewer, RelationBlock)
+	if err != nil {
+		return err
+	}
+	if blocked {
+		return ErrBlocked
+	}
+
+	return nil
+}
+
+// hasRelation reports whether the relation exists in the tenant. The caller must hold the mutex.
+func (s *InMemoryPostStore) hasRelation(tenantID, userID, targetID string, kind RelationKind) bool {
+	_, exists := s.relations[kind][tenantTag(tenantID, userID)][targetID]
+	return exists
+}
+
+// visibleTo reports whether content by authorID in the tenant may be shown to the viewer:
+// the author is not shadow-banned in the tenant and neither user blocked the other.
+// The caller must hold the mutex.
+func (s *InMemoryPostStore) visibleTo(tenantID, authorID string, viewer string) bool {
+	if authorID == viewer {
+		return true
+	}
+
+	if _, banned := s.shadowBans[tenantTag(tenantID, authorID)]; banned {
+		return false
+	}
+
+	return !s.hasRelation(tenantID, authorID, viewer, RelationBlock) && !s.hasRelation(tenantID, viewer, authorID, RelationBlock)
+}
+
+// inFeedOf reports whether posts by authorID may appear in the viewer's feed and trending posts.
+// The caller must hold the mutex.
+func (s *InMemoryPostStore) inFeedOf(tenantID, authorID string, viewer string) bool {
+	return s.visibleTo(tenantID, authorID, viewer) && !s.hasRelation(tenantID, viewer, authorID, RelationMute)
+}
+
+// SaveRelation creates a relation, keeping the existing one if present
+func (s *InMemoryPostStore) SaveRelation(ctx context.Context, relation *UserRelation) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	userKey := tenantScopedKey(ctx, relation.UserID)
+	if s.relations[relation.Kind] == nil {
+		s.relations[relation.Kind] = make(map[string]map[string]*UserRelation)
+	}
+	if s.relations[relation.Kind][userKey] == nil {
+		s.relations[relation.Kind][userKey] = make(map[string]*UserRelation)
+	}
+	if _, exists := s.relations[relation.Kind][userKey][relation.TargetID]; !exists {
+		relationCopy := *relation
+		s.relations[relation.Kind][userKey][relation.TargetID] = &relationCopy
+	}
+
+	return nil
+}
+
+// DeleteRelation removes a relation
+func (s *InMemoryPostStore) DeleteRelation(ctx context.Context, userID, targetID string, kind RelationKind) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.relations[kind][tenantScopedKey(ctx, userID)], targetID)
+
+	return nil
+}
+
+// HasRelation reports whether the relation exists
+func (s *InMemoryPostStore) HasRelation(ctx context.Context, userID, targetID string, kind RelationKind) (bool, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	return s.hasRelation(TenantFromContext(ctx), userID, targetID, kind), nil
+}
+
+// ListRelations returns the relations of a kind from a user, most recent first
+func (s *InMemoryPostStore) ListRelations(ctx context.Context, userID string, kind RelationKind, limit, offset int) ([]*UserRelation, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	userRelations := s.relations[kind][tenantScopedKey(ctx, userID)]
+	relations := make([]*UserRelation, 0, len(userRelations))
+	for _, relation := range userRelations {
+		relationCopy := *relation
+		relations = append(relations, &relationCopy)
+	}
+
+	sort.Slice(relations, func(i, j int) bool {
+		if relations[i].CreatedAt.Equal(relations[j].CreatedAt) {
+			return relations[i].TargetID < relations[j].TargetID
+		}
+		return relations[i].CreatedAt.After(relations[j].CreatedAt)
+	})
+
+	// Apply pagination
+	if limit > 0 {
+		end := offset + limit
+		if end > len(relations) {
+			end = len(relations)
+		}
+		if offset < len(relations) {
+			relations = relations[offset:end]
+		} else {
+			relations = []*UserRelation{}
+		}
+	}
+
+	return relations, nil
+}
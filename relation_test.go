@@ -0,0 +1,99 @@
+package postflow
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// postIDs returns the IDs of posts in order
+func postIDs(posts []*Post) []string {
+	ids := make([]string, len(posts))
+	for i, post := range posts {
+		ids[i] = post.ID
+	}
+	return ids
+}
+
+// TestPostManagerBlockAndMute tests blocking and muting with the in-memory store
+func TestPostManagerBlockAndMute(t *testing.T) {
+	pm := setupTestPostManager()
+	ctx := context.Background()
+
+	aliceID, err := pm.CreatePost(ctx, createTestPostData("alice"))
+	require.NoError(t, err)
+	trollID, err := pm.CreatePost(ctx, createTestPostData("troll"))
+	require.NoError(t, err)
+	noisyID, err := pm.CreatePost(ctx, createTestPostData("noisy"))
+	require.NoError(t, err)
+
+	// Test: invalid relations
+	assert.Equal(t, ErrInvalidRelation, pm.Block(ctx, "alice", "alice"))
+	assert.Equal(t, ErrInvalidRelation, pm.Mute(ctx, "alice", ""))
+
+	require.NoError(t, pm.Block(ctx, "alice", "troll"))
+	require.NoError(t, pm.Block(ctx, "alice", "troll"))
+	require.NoError(t, pm.Mute(ctx, "alice", "noisy"))
+
+	blocked, err := pm.ListBlocked(ctx, "alice", 10, 0)
+	assert.NoError(t, err)
+	require.Equal(t, 1, len(blocked))
+	assert.Equal(t, "troll", blocked[0].TargetID)
+	assert.Equal(t, RelationBlock, blocked[0].Kind)
+	muted, err := pm.ListMuted(ctx, "alice", 10, 0)
+	assert.NoError(t, err)
+	require.Equal(t, 1, len(muted))
+	assert.Equal(t, "noisy", muted[0].TargetID)
+
+	// Test: blocked users cannot react to or view the blocker's posts
+	assert.Equal(t, ErrBlocked, pm.AddReaction(ctx, aliceID, "troll", ReactionAngry))
+	_, err = pm.GetPost(WithActor(ctx, Actor{UserID: "troll"}), aliceID)
+	assert.Equal(t, ErrBlocked, err)
+
+	// Test: the blocker cannot view the blocked user's posts either
+	_, err = pm.GetPost(WithActor(ctx, Actor{UserID: "alice"}), trollID)
+	assert.Equal(t, ErrBlocked, err)
+
+	trollFeed, err := pm.GetUserFeed(ctx, "troll", 10, 0)
+	assert.NoError(t, err)
+	assert.NotContains(t, postIDs(trollFeed), aliceID)
+	trollPosts, err := pm.ListPosts(WithActor(ctx, Actor{UserID: "troll"}), &PostFilter{UserID: "alice"})
+	assert.NoError(t, err)
+	assert.Empty(t, trollPosts)
+
+	// Test: blocked and muted authors are left out of the blocker's feed and trending
+	aliceCtx := WithActor(ctx, Actor{UserID: "alice"})
+	feed, err := pm.GetUserFeed(ctx, "alice", 10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{aliceID}, postIDs(feed))
+	trending, err := pm.GetTrendingPosts(aliceCtx, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{aliceID}, postIDs(trending))
+
+	// Test: muted authors stay reachable on their profile, blocked ones do not
+	profile, err := pm.ListPosts(aliceCtx, &PostFilter{UserID: "noisy"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{noisyID}, postIDs(profile))
+	profile, err = pm.ListPosts(aliceCtx, &PostFilter{UserID: "troll"})
+	assert.NoError(t, err)
+	assert.Empty(t, profile)
+
+	// Test: other users are unaffected
+	feed, err = pm.GetUserFeed(ctx, "bob", 10, 0)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{aliceID, trollID, noisyID}, postIDs(feed))
+	assert.NoError(t, pm.AddReaction(ctx, aliceID, "bob", ReactionLike))
+
+	// Test: unblocking and unmuting restore everything
+	require.NoError(t, pm.Unblock(ctx, "alice", "troll"))
+	require.NoError(t, pm.Unmute(ctx, "alice", "noisy"))
+	assert.NoError(t, pm.AddReaction(ctx, aliceID, "troll", ReactionLike))
+	feed, err = pm.GetUserFeed(ctx, "alice", 10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, len(feed))
+	blocked, err = pm.ListBlocked(ctx, "alice", 10, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, blocked)
+}
@@ -0,0 +1,330 @@
+// Package postflow provides functionality for managing user posts and feeds.
+package postflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+var (
+	// ErrAlreadyReported is returned when a user reports the same post twice
+	ErrAlreadyReported = errors.New("post already reported by this user")
+
+	// ErrInvalidReportReason is returned when an unknown report reason is provided
+	ErrInvalidReportReason = errors.New("invalid report reason")
+)
+
+// DefaultReportThreshold is the number of reports after which a post is hidden for review
+const DefaultReportThreshold = 5
+
+// ReportReason represents why a user reported a post
+type ReportReason uint8
+
+const (
+	ReportSpam           ReportReason = 1
+	ReportHarassment     ReportReason = 2
+	ReportHateSpeech     ReportReason = 3
+	ReportViolence       ReportReason = 4
+	ReportNudity         ReportReason = 5
+	ReportMisinformation ReportReason = 6
+	ReportOther          ReportReason = 7
+)
+
+// Severity returns the weight of the reason when ranking reported posts
+func (r ReportReason) Severity() int {
+	switch r {
+	case ReportHateSpeech, ReportViolence:
+		return 5
+	case ReportHarassment:
+		return 4
+	case ReportNudity:
+		return 3
+	case ReportMisinformation:
+		return 2
+	case ReportSpam, ReportOther:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// Report represents a user's report of a post
+type Report struct {
+	PostID     string       `json:"post_id"`
+	ReporterID string       `json:"reporter_id"`
+	Reason     ReportReason `json:"reason"`
+	Note       string       `json:"note,omitempty"`
+	Reviewed   bool         `json:"reviewed"` // A moderator decided on the post after this report
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+// ReportSummary aggregates the reports of a post
+type ReportSummary struct {
+	PostID         string               `json:"post_id"`
+	Count          int                  `json:"count"`
+	Reviewed       int                  `json:"reviewed"` // Reports made before the last moderator decision
+	Severity       int                  `json:"severity"` // Sum of the severities of all reports
+	Reasons        map[ReportReason]int `json:"reasons"`
+	LastReportedAt time.Time            `json:"last_reported_at"`
+}
+
+// ReportStore defines the interface for stores that keep user reports
+type ReportStore interface {
+	// SaveReport records a report and returns the updated summary of the post.
+	// It returns ErrAlreadyReported if the reporter already reported the post.
+	SaveReport(ctx context.Context, report *Report) (*ReportSummary, error)
+
+	// GetReports returns the reports of a post, most recent first
+	GetReports(ctx context.Context, postID string) ([]*Report, error)
+
+	// ListReportedPosts returns report summaries ordered by severity, then count, then recency, most severe first
+	ListReportedPosts(ctx context.Context, limit, offset int) ([]*ReportSummary, error)
+
+	// MarkReportsReviewed records that a moderator decided on a post, covering its reports so far
+	MarkReportsReviewed(ctx context.Context, postID string) error
+}
+
+// WithReportThreshold sets the number of reports after which a post is hidden for review.
+// A threshold of zero disables automatic hiding.
+func WithReportThreshold(threshold int) PostManagerOption {
+	return func(m *PostManagerImpl) {
+		m.reportThreshold = threshold
+	}
+}
+
+// ReportPost records a user's report of a post the user can read. Once the number of reports reaches the
+// configured threshold the post is moved back to the moderation queue by SystemActor. A post a moderator
+// decided on after it reached the threshold is not hidden again by further reports.
+func (m *PostManagerImpl) ReportPost(ctx context.Context, postID string, reporterID string, reason ReportReason, note string) error {
+	if reporterID == "" {
+		return errors.New("reporter ID is required")
+	}
+	if reason.Severity() == 0 {
+		return ErrInvalidReportReason
+	}
+
+	store, ok := m.store.(ReportStore)
+	if !ok {
+		return ErrNotSupported
+	}
+	actor, err := actingAs(ctx, reporterID, ActionRead)
+	if err != nil {
+		return err
+	}
+	if _, err := m.readablePost(WithActor(ctx, actor), postID); err != nil {
+		return err
+	}
+
+	summary, err := store.SaveReport(ctx, &Report{
+		PostID:     postID,
+		ReporterID: reporterID,
+		Reason:     reason,
+		Note:       note,
+		CreatedAt:  time.Now(),
+	})
+	if err != nil {
+		return err
+	}
+
+	// Hide the post until a moderator reviewed it at or beyond the threshold, so a post re-approved after
+	// review stays visible. A hide that failed is retried by the next report.
+	if m.reportThreshold > 0 && summary.Count >= m.reportThreshold && summary.Reviewed < m.reportThreshold {
+		post, err := m.store.GetPost(ctx, postID)
+		if err != nil {
+			return err
+		}
+		if post.ModerationStatus == ModerationApproved {
+			reason := fmt.Sprintf("hidden after %d reports", summary.Count)
+			return m.setModerationStatus(ctx, post, SystemActor, ModerationPending, reason)
+		}
+	}
+
+	return nil
+}
+
+// markReportsReviewed records a moderator decision on a post, which stops its reports so far from hiding it again.
+// Errors are ignored because the decision is already stored; at worst further reports hide the post for review again.
+func (m *PostManagerImpl) markReportsReviewed(ctx context.Context, postID string) {
+	if store, ok := m.store.(ReportStore); ok {
+		_ = store.MarkReportsReviewed(ctx, postID)
+	}
+}
+
+// GetPostReports returns the reports of a post, most recent first.
+// Only actors allowed to moderate the post may read them; the actor is taken from the context.
+func (m *PostManagerImpl) GetPostReports(ctx context.Context, postID string) ([]*Report, error) {
+	store, ok := m.store.(ReportStore)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	if _, ok := ActorFromContext(ctx); !ok {
+		return nil, ErrPermissionDenied
+	}
+	post, err := m.store.GetPost(ctx, postID)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := m.authorizeModerator(ctx, post); err != nil {
+		return nil, err
+	}
+
+	return store.GetReports(ctx, postID)
+}
+
+// ListReportedPosts returns reported posts for administrators, most severe first.
+// Only actors allowed to moderate may list them; the actor is taken from the context.
+func (m *PostManagerImpl) ListReportedPosts(ctx context.Context, limit, offset int) ([]*ReportSummary, error) {
+	store, ok := m.store.(ReportStore)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+	if _, err := m.authorizeModerator(ctx, nil); err != nil {
+		return nil, err
+	}
+
+	return store.ListReportedPosts(ctx, limit, offset)
+}
+
+// summarizeReports aggregates the reports of a single post
+func summarizeReports(postID string, reports []*Report) *ReportSummary {
+	summary := &ReportSummary{
+		PostID:  postID,
+		Reasons: make(map[ReportReason]int),
+	}
+	for _, report := range reports {
+		summary.Count++
+		if report.Reviewed {
+			summary.Reviewed++
+		}
+		summary.Severity += report.Reason.Severity()
+		summary.Reasons[report.Reason]++
+		if report.CreatedAt.After(summary.LastReportedAt) {
+			summary.LastReportedAt = report.CreatedAt
+		}
+	}
+	return summary
+}
+
+// sortReportSummaries orders summaries by severity, then count, then recency, then post ID
+func sortReportSummaries(summaries []*ReportSummary) {
+	sort.Slice(summaries, func(i, j int) bool {
+		if summaries[i].Severity != summaries[j].Severity {
+			return summaries[i].Severity > summaries[j].Severity
+		}
+		if summaries[i].Count != summaries[j].Count {
+			return summaries[i].Count > summaries[j].Count
+		}
+		if !summaries[i].LastReportedAt.Equal(summaries[j].LastReportedAt) {
+			return summaries[i].LastReportedAt.After(summaries[j].LastReportedAt)
+		}
+		return summaries[i].PostID < summaries[j].PostID
+	})
+}
+
+// SaveReport records a report
+func (s *InMemoryPostStore) SaveReport(ctx context.Context, report *Report) (*ReportSummary, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.lookupPost(ctx, report.PostID); !exists {
+		return nil, ErrPostNotFound
+	}
+
+	if _, exists := s.reports[report.PostID]; !exists {
+		s.reports[report.PostID] = make(map[string]*Report)
+	}
+	if _, exists := s.reports[report.PostID][report.ReporterID]; exists {
+		return nil, ErrAlreadyReported
+	}
+
+	reportCopy := *report
+	s.reports[report.PostID][report.ReporterID] = &reportCopy
+
+	return summarizeReports(report.PostID, s.postReports(report.PostID)), nil
+}
+
+// GetReports returns the reports of a post, most recent first
+func (s *InMemoryPostStore) GetReports(ctx context.Context, postID string) ([]*Report, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if _, exists := s.lookupPost(ctx, postID); !exists {
+		return nil, ErrPostNotFound
+	}
+
+	reports := s.postReports(postID)
+	result := make([]*Report, len(reports))
+	for i, report := range reports {
+		reportCopy := *report
+		result[i] = &reportCopy
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.After(result[j].CreatedAt)
+	})
+
+	return result, nil
+}
+
+// ListReportedPosts returns report summaries, most severe first
+func (s *InMemoryPostStore) ListReportedPosts(ctx context.Context, limit, offset int) ([]*ReportSummary, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	summaries := make([]*ReportSummary, 0, len(s.reports))
+	for postID, reports := range s.reports {
+		if _, inTenant := s.lookupPost(ctx, postID); len(reports) == 0 || !inTenant {
+			continue
+		}
+		summaries = append(summaries, summarizeReports(postID, s.postReports(postID)))
+	}
+
+	sortReportSummaries(summaries)
+
+	// Apply pagination
+	if limit > 0 {
+		end := offset + limit
+		if end > len(summaries) {
+			end = len(summaries)
+		}
+		if offset < len(summaries) {
+			summaries = summaries[offset:end]
+		} else {
+			summaries = []*ReportSummary{}
+		}
+	}
+
+	return summaries, nil
+}
+
+// MarkReportsReviewed marks the reports of a post as reviewed
+func (s *InMemoryPostStore) MarkReportsReviewed(ctx context.Context, postID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.lookupPost(ctx, postID); !exists {
+		return ErrPostNotFound
+	}
+
+	for reporterID, report := range s.reports[postID] {
+		// Replace rather than modify stored reports, which may be shared with callers
+		reportCopy := *report
+		reportCopy.Reviewed = true
+		s.reports[postID][reporterID] = &reportCopy
+	}
+
+	return nil
+}
+
+// postReports returns the reports of a post. The caller must hold the lock.
+func (s *InMemoryPostStore) postReports(postID string) []*Report {
+	reports := make([]*Report, 0, len(s.reports[postID]))
+	for _, report := range s.reports[postID] {
+		reports = append(reports, report)
+	}
+	return reports
+}
@@ -0,0 +1,185 @@
+package postflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPostManagerReportPost tests reporting posts and automatic hiding
+func TestPostManagerReportPost(t *testing.T) {
+	pm := NewPostManager(NewInMemoryPostStore(), WithReportThreshold(3))
+	ctx := context.Background()
+	modCtx := WithActor(ctx, Actor{UserID: "mod1", Role: RoleModerator})
+
+	postID, err := pm.CreatePost(ctx, createTestPostData("user1"))
+	require.NoError(t, err)
+
+	// Test: report a post
+	err = pm.ReportPost(ctx, postID, "reporter1", ReportSpam, "buy now links")
+	assert.NoError(t, err)
+
+	// Test: the same reporter cannot report twice
+	err = pm.ReportPost(ctx, postID, "reporter1", ReportHarassment, "")
+	assert.Equal(t, ErrAlreadyReported, err)
+
+	// Test: invalid input
+	err = pm.ReportPost(ctx, postID, "reporter2", ReportReason(100), "")
+	assert.Equal(t, ErrInvalidReportReason, err)
+	err = pm.ReportPost(ctx, "nonexistent-id", "reporter2", ReportSpam, "")
+	assert.Equal(t, ErrPostNotFound, err)
+
+	// Test: the post stays visible below the threshold
+	err = pm.ReportPost(ctx, postID, "reporter2", ReportSpam, "")
+	assert.NoError(t, err)
+	feed, err := pm.GetUserFeed(ctx, "user2", 10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(feed))
+
+	// Test: the post is hidden for review once the threshold is crossed
+	err = pm.ReportPost(ctx, postID, "reporter3", ReportHarassment, "")
+	assert.NoError(t, err)
+	feed, err = pm.GetUserFeed(ctx, "user2", 10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(feed))
+
+	pending, err := pm.ListPendingPosts(modCtx, 10, 0)
+	assert.NoError(t, err)
+	require.Equal(t, 1, len(pending))
+	assert.Equal(t, "hidden after 3 reports", pending[0].ModerationReason)
+
+	// Test: a post approved after review is not hidden again by further reports
+	require.NoError(t, pm.ApprovePost(modCtx, postID))
+	err = pm.ReportPost(ctx, postID, "reporter4", ReportSpam, "")
+	assert.NoError(t, err)
+	feed, err = pm.GetUserFeed(ctx, "user2", 10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(feed))
+
+	// Test: reports of a post are only shown to moderators
+	reports, err := pm.GetPostReports(modCtx, postID)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, len(reports))
+	_, err = pm.GetPostReports(WithActor(ctx, Actor{UserID: "user1"}), postID)
+	assert.Equal(t, ErrPermissionDenied, err)
+	_, err = pm.GetPostReports(ctx, postID)
+	assert.Equal(t, ErrPermissionDenied, err)
+
+	// Test: users can only report posts they can read
+	private := createTestPostData("user1")
+	private.Visibility = VisibilityPrivate
+	privateID, err := pm.CreatePost(ctx, private)
+	require.NoError(t, err)
+	err = pm.ReportPost(ctx, privateID, "reporter1", ReportSpam, "")
+	assert.Equal(t, ErrPermissionDenied, err)
+	err = pm.ReportPost(WithActor(ctx, Actor{UserID: "reporter1"}), privateID, "reporter1", ReportSpam, "")
+	assert.Equal(t, ErrPermissionDenied, err)
+}
+
+// TestPostManagerReportPostRetriesHide tests that a hide that failed at the threshold is retried by later reports
+func TestPostManagerReportPostRetriesHide(t *testing.T) {
+	pm := NewPostManager(NewInMemoryPostStore(), WithReportThreshold(2))
+	ctx := context.Background()
+	modCtx := WithActor(ctx, Actor{UserID: "mod1", Role: RoleModerator})
+
+	postID, err := pm.CreatePost(ctx, createTestPostData("user1"))
+	require.NoError(t, err)
+
+	// The hide at the threshold is vetoed once
+	errVetoed := errors.New("vetoed")
+	vetoes := 1
+	var hiddenBy []Actor
+	pm.OnBefore(EventPostUpdated, func(ctx context.Context, event *Event) error {
+		if vetoes > 0 {
+			vetoes--
+			return errVetoed
+		}
+		return nil
+	})
+	pm.OnAfter(EventPostUpdated, func(ctx context.Context, event *Event) {
+		hiddenBy = append(hiddenBy, event.Actor)
+	})
+
+	require.NoError(t, pm.ReportPost(ctx, postID, "reporter1", ReportSpam, ""))
+	assert.Equal(t, errVetoed, pm.ReportPost(ctx, postID, "reporter2", ReportSpam, ""))
+	post, err := pm.GetPost(ctx, postID)
+	require.NoError(t, err)
+	assert.Equal(t, ModerationApproved, post.ModerationStatus)
+
+	// Test: the next report hides the post, as the system
+	require.NoError(t, pm.ReportPost(ctx, postID, "reporter3", ReportSpam, ""))
+	post, err = pm.GetPost(modCtx, postID)
+	require.NoError(t, err)
+	assert.Equal(t, ModerationPending, post.ModerationStatus)
+	assert.Equal(t, "hidden after 3 reports", post.ModerationReason)
+	assert.Equal(t, []Actor{SystemActor}, hiddenBy)
+
+	// Test: the moderator decision marks the reports so far as reviewed
+	require.NoError(t, pm.ApprovePost(modCtx, postID))
+	reports, err := pm.GetPostReports(modCtx, postID)
+	require.NoError(t, err)
+	for _, report := range reports {
+		assert.True(t, report.Reviewed, report.ReporterID)
+	}
+
+	require.NoError(t, pm.ReportPost(ctx, postID, "reporter4", ReportSpam, ""))
+	post, err = pm.GetPost(ctx, postID)
+	require.NoError(t, err)
+	assert.Equal(t, ModerationApproved, post.ModerationStatus)
+
+	summaries, err := pm.ListReportedPosts(modCtx, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, summaries, 1)
+	assert.Equal(t, 4, summaries[0].Count)
+	assert.Equal(t, 3, summaries[0].Reviewed)
+}
+
+// TestPostManagerListReportedPosts tests the admin listing of reported posts
+func TestPostManagerListReportedPosts(t *testing.T) {
+	pm := setupTestPostManager()
+	ctx := context.Background()
+	modCtx := WithActor(ctx, Actor{UserID: "mod1", Role: RoleModerator})
+
+	spamID, err := pm.CreatePost(ctx, createTestPostData("user1"))
+	require.NoError(t, err)
+	hateID, err := pm.CreatePost(ctx, createTestPostData("user1"))
+	require.NoError(t, err)
+	_, err = pm.CreatePost(ctx, createTestPostData("user1"))
+	require.NoError(t, err)
+
+	// Three spam reports weigh less than one hate speech report
+	for i := 0; i < 3; i++ {
+		require.NoError(t, pm.ReportPost(ctx, spamID, fmt.Sprintf("reporter%d", i), ReportSpam, ""))
+	}
+	require.NoError(t, pm.ReportPost(ctx, hateID, "reporter1", ReportHateSpeech, "slurs"))
+
+	_, err = pm.ListReportedPosts(WithActor(ctx, Actor{UserID: "user1"}), 10, 0)
+	assert.Equal(t, ErrPermissionDenied, err)
+
+	summaries, err := pm.ListReportedPosts(modCtx, 10, 0)
+	assert.NoError(t, err)
+	require.Equal(t, 2, len(summaries))
+	assert.Equal(t, hateID, summaries[0].PostID)
+	assert.Equal(t, 5, summaries[0].Severity)
+	assert.Equal(t, 1, summaries[0].Count)
+	assert.Equal(t, spamID, summaries[1].PostID)
+	assert.Equal(t, 3, summaries[1].Count)
+	assert.Equal(t, 3, summaries[1].Reasons[ReportSpam])
+	assert.False(t, summaries[1].LastReportedAt.IsZero())
+
+	// Test: pagination
+	summaries, err = pm.ListReportedPosts(modCtx, 1, 1)
+	assert.NoError(t, err)
+	require.Equal(t, 1, len(summaries))
+	assert.Equal(t, spamID, summaries[0].PostID)
+
+	// Test: reports are removed with the post
+	require.NoError(t, pm.DeletePost(ctx, hateID, "user1"))
+	summaries, err = pm.ListReportedPosts(modCtx, 10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(summaries))
+}
@@ -0,0 +1,221 @@
+// Package postflow provides functionality for managing user posts and feeds.
+package postflow
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"time"
+)
+
+// ShadowBan records that a user's posts and reactions are hidden from everyone else
+type ShadowBan struct {
+	UserID    string    `json:"user_id"`
+	TenantID  string    `json:"tenant_id,omitempty"` // Set by the store from the context
+	Reason    string    `json:"reason,omitempty"`
+	BannedBy  string    `json:"banned_by,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ShadowBanStore defines the interface for stores that support shadow banning.
+// Bans belong to the tenant in the context. Stores hide the posts and reactions of users banned in a tenant
+// from every viewer but themselves in that tenant's ListPosts, GetUserFeed, GetTrendingPosts and GetReactedUsers.
+type ShadowBanStore interface {
+	// SaveShadowBan shadow-bans a user, replacing any existing ban
+	SaveShadowBan(ctx context.Context, ban *ShadowBan) error
+
+	// DeleteShadowBan lifts the shadow ban of a user
+	DeleteShadowBan(ctx context.Context, userID string) error
+
+	// GetShadowBan returns the shadow ban of a user, or nil if the user is not banned
+	GetShadowBan(ctx context.Context, userID string) (*ShadowBan, error)
+
+	// ListShadowBans returns shadow bans, most recent first
+	ListShadowBans(ctx context.Context, limit, offset int) ([]*ShadowBan, error)
+}
+
+// viewerFromContext returns the user ID of the actor in the context, or an empty string for anonymous reads
+func viewerFromContext(ctx context.Context) string {
+	if actor, ok := ActorFromContext(ctx); ok {
+		return actor.UserID
+	}
+	return ""
+}
+
+// ShadowBanUser hides a user's posts and reactions in the tenant in the context from everyone but the user.
+// Only moderators and administrators may shadow-ban users; the actor is taken from the context.
+func (m *PostManagerImpl) ShadowBanUser(ctx context.Context, userID string, reason string) error {
+	store, actor, err := m.shadowBanStoreAs(ctx, userID)
+	if err != nil {
+		return err
+	}
+	if userID == "" {
+		return errors.New("user ID is required")
+	}
+
+	return store.SaveShadowBan(ctx, &ShadowBan{
+		UserID:    userID,
+		Reason:    reason,
+		BannedBy:  actor.UserID,
+		CreatedAt: time.Now(),
+	})
+}
+
+// LiftShadowBan makes a shadow-banned user's content visible again.
+// Only moderators and administrators may lift shadow bans; the actor is taken from the context.
+func (m *PostManagerImpl) LiftShadowBan(ctx context.Context, userID string) error {
+	store, _, err := m.shadowBanStoreAs(ctx, userID)
+	if err != nil {
+		return err
+	}
+
+	return store.DeleteShadowBan(ctx, userID)
+}
+
+// GetShadowBan returns the shadow ban of a user, or nil if the user is not banned.
+// Only moderators and administrators may see shadow bans; the actor is taken from the context.
+func (m *PostManagerImpl) GetShadowBan(ctx context.Context, userID string) (*ShadowBan, error) {
+	store, _, err := m.shadowBanStoreAs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return store.GetShadowBan(ctx, userID)
+}
+
+// ListShadowBans returns shadow-banned users, most recent first.
+// Only moderators and administrators may list shadow bans; the actor is taken from the context.
+func (m *PostManagerImpl) ListShadowBans(ctx context.Context, limit, offset int) ([]*ShadowBan, error) {
+	store, _, err := m.shadowBanStoreAs(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return store.ListShadowBans(ctx, limit, offset)
+}
+
+// shadowBanStoreAs returns the shadow ban store if the actor in the context may moderate the user's content.
+// Shadow bans are authorized as ActionModerate on a post of the user; listings pass no user.
+func (m *PostManagerImpl) shadowBanStoreAs(ctx context.Context, userID string) (ShadowBanStore, Actor, error) {
+	actor, err := m.authorizeModerator(ctx, &Post{TenantID: TenantFromContext(ctx), UserID: userID})
+	if err != nil {
+		return nil, actor, err
+	}
+
+	store, ok := m.store.(ShadowBanStore)
+	if !ok {
+		return nil, actor, ErrNotSupported
+	}
+
+	return store, actor, nil
+}
+
+// isShadowBanned reports whether a user is shadow-banned. Stores without shadow bans ban no one.
+func (m *PostManagerImpl) isShadowBanned(ctx context.Context, userID string) (bool, error) {
+	store, ok := m.store.(ShadowBanStore)
+	if !ok {
+		return false, nil
+	}
+
+	ban, err := store.GetShadowBan(ctx, userID)
+	return ban != nil, err
+}
+
+// reactionCountsFor returns the reaction counts of a post without the reactions of shadow-banned users,
+// except the viewer's own. The caller must hold the mutex.
+func (s *InMemoryPostStore) reactionCountsFor(post *Post, viewer string) map[ReactionType]int {
+	counts := make(map[ReactionType]int, len(post.Reactions))
+	for reactionType, count := range post.Reactions {
+		counts[reactionType] = count
+	}
+
+	for _, ban := range s.shadowBans {
+		if ban.TenantID != post.TenantID || ban.UserID == viewer {
+			continue
+		}
+		if reaction, exists := s.reactions[post.ID][ban.UserID]; exists && counts[reaction.ReactionType] > 0 {
+			counts[reaction.ReactionType]--
+		}
+	}
+
+	return counts
+}
+
+// copyPostFor returns a copy of a stored post with the reaction counts shown to the viewer.
+// The caller must hold the mutex.
+func (s *InMemoryPostStore) copyPostFor(post *Post, viewer string) *Post {
+	postCopy := *post
+	postCopy.Reactions = s.reactionCountsFor(post, viewer)
+	return &postCopy
+}
+
+// SaveShadowBan shadow-bans a user, replacing any existing ban
+func (s *InMemoryPostStore) SaveShadowBan(ctx context.Context, ban *ShadowBan) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	ban.TenantID = TenantFromContext(ctx)
+	banCopy := *ban
+	s.shadowBans[tenantScopedKey(ctx, ban.UserID)] = &banCopy
+
+	return nil
+}
+
+// DeleteShadowBan lifts the shadow ban of a user
+func (s *InMemoryPostStore) DeleteShadowBan(ctx context.Context, userID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.shadowBans, tenantScopedKey(ctx, userID))
+
+	return nil
+}
+
+// GetShadowBan returns the shadow ban of a user, or nil if the user is not banned
+func (s *InMemoryPostStore) GetShadowBan(ctx context.Context, userID string) (*ShadowBan, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	ban, exists := s.shadowBans[tenantScopedKey(ctx, userID)]
+	if !exists {
+		return nil, nil
+	}
+
+	banCopy := *ban
+	return &banCopy, nil
+}
+
+// ListShadowBans returns shadow bans, most recent first
+func (s *InMemoryPostStore) ListShadowBans(ctx context.Context, limit, offset int) ([]*ShadowBan, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	tenant := TenantFromContext(ctx)
+	bans := make([]*ShadowBan, 0, len(s.shadowBans))
+	for _, ban := range s.shadowBans {
+		if ban.TenantID != tenant {
+			continue
+		}
+		banCopy := *ban
+		bans = append(bans, &banCopy)
+	}
+
+	sort.Slice(bans, func(i, j int) bool {
+		return bans[i].CreatedAt.After(bans[j].CreatedAt)
+	})
+
+	// Apply pagination
+	if limit > 0 {
+		end := offset + limit
+		if end > len(bans) {
+			end = len(bans)
+		}
+		if offset < len(bans) {
+			bans = bans[offset:end]
+		} else {
+			bans = []*ShadowBan{}
+		}
+	}
+
+	return bans, nil
+}
@@ -0,0 +1,170 @@
+package postflow
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPostManagerShadowBan tests that shadow-banned content is only visible to its author
+func TestPostManagerShadowBan(t *testing.T) {
+	pm := setupTestPostManager()
+	ctx := context.Background()
+	modCtx := WithActor(ctx, Actor{UserID: "mod1", Role: RoleModerator})
+	spammerCtx := WithActor(ctx, Actor{UserID: "spammer"})
+	viewerCtx := WithActor(ctx, Actor{UserID: "user2"})
+
+	normalID, err := pm.CreatePost(ctx, createTestPostData("user1"))
+	require.NoError(t, err)
+	spamID, err := pm.CreatePost(ctx, createTestPostData("spammer"))
+	require.NoError(t, err)
+	require.NoError(t, pm.AddReaction(ctx, normalID, "spammer", ReactionLike))
+	require.NoError(t, pm.AddReaction(ctx, normalID, "user2", ReactionLove))
+
+	// Test: only privileged actors may shadow-ban
+	err = pm.ShadowBanUser(viewerCtx, "spammer", "")
+	assert.Equal(t, ErrPermissionDenied, err)
+	err = pm.ShadowBanUser(modCtx, "spammer", "link spam")
+	require.NoError(t, err)
+
+	ban, err := pm.GetShadowBan(modCtx, "spammer")
+	assert.NoError(t, err)
+	require.NotNil(t, ban)
+	assert.Equal(t, "mod1", ban.BannedBy)
+	assert.Equal(t, "link spam", ban.Reason)
+
+	bans, err := pm.ListShadowBans(modCtx, 10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(bans))
+
+	// Test: other viewers no longer see the spammer's posts and reactions
+	feed, err := pm.GetUserFeed(ctx, "user2", 10, 0)
+	assert.NoError(t, err)
+	assert.NotContains(t, postIDs(feed), spamID)
+	assert.Contains(t, postIDs(feed), normalID)
+
+	trending, err := pm.GetTrendingPosts(viewerCtx, 10)
+	assert.NoError(t, err)
+	assert.NotContains(t, postIDs(trending), spamID)
+
+	listed, err := pm.ListPosts(viewerCtx, &PostFilter{UserID: "spammer"})
+	assert.NoError(t, err)
+	assert.Empty(t, listed)
+
+	users, err := pm.GetReactedUsers(viewerCtx, normalID, nil, 10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"user2"}, users)
+
+	// Test: the spammer still sees their own content
+	feed, err = pm.GetUserFeed(ctx, "spammer", 10, 0)
+	assert.NoError(t, err)
+	assert.Contains(t, postIDs(feed), spamID)
+
+	trending, err = pm.GetTrendingPosts(spammerCtx, 10)
+	assert.NoError(t, err)
+	assert.Contains(t, postIDs(trending), spamID)
+
+	listed, err = pm.ListPosts(spammerCtx, &PostFilter{UserID: "spammer"})
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(listed))
+
+	users, err = pm.GetReactedUsers(spammerCtx, normalID, nil, 10, 0)
+	assert.NoError(t, err)
+	assert.ElementsMatch(t, []string{"spammer", "user2"}, users)
+
+	// Test: lifting the ban restores visibility
+	err = pm.LiftShadowBan(modCtx, "spammer")
+	assert.NoError(t, err)
+	ban, err = pm.GetShadowBan(modCtx, "spammer")
+	assert.NoError(t, err)
+	assert.Nil(t, ban)
+
+	feed, err = pm.GetUserFeed(ctx, "user2", 10, 0)
+	assert.NoError(t, err)
+	assert.Contains(t, postIDs(feed), spamID)
+}
+
+// TestPostManagerShadowBannedReactions tests that reactions of shadow-banned users are not counted
+func TestPostManagerShadowBannedReactions(t *testing.T) {
+	pm := setupTestPostManager()
+	ctx := context.Background()
+	modCtx := WithActor(ctx, Actor{UserID: "mod1", Role: RoleModerator})
+	spammerCtx := WithActor(ctx, Actor{UserID: "spammer1"})
+	viewerCtx := WithActor(ctx, Actor{UserID: "user2"})
+
+	boostedID, err := pm.CreatePost(ctx, createTestPostData("user1"))
+	require.NoError(t, err)
+	likedID, err := pm.CreatePost(ctx, createTestPostData("user1"))
+	require.NoError(t, err)
+
+	require.NoError(t, pm.AddReaction(ctx, boostedID, "spammer1", ReactionLike))
+	require.NoError(t, pm.AddReaction(ctx, boostedID, "spammer2", ReactionLove))
+	require.NoError(t, pm.AddReaction(ctx, likedID, "user3", ReactionLike))
+	require.NoError(t, pm.ShadowBanUser(modCtx, "spammer1", "vote ring"))
+	require.NoError(t, pm.ShadowBanUser(modCtx, "spammer2", "vote ring"))
+
+	// Test: reactions of shadow-banned users are not counted for other viewers
+	counts, err := pm.GetReactionCounts(viewerCtx, boostedID)
+	assert.NoError(t, err)
+	assert.Zero(t, counts[ReactionLike])
+	assert.Zero(t, counts[ReactionLove])
+
+	post, err := pm.GetPost(viewerCtx, boostedID)
+	assert.NoError(t, err)
+	assert.Zero(t, sumReactions(post.Reactions))
+
+	// Test: shadow-banned reactions do not boost trending posts
+	trending, err := pm.GetTrendingPosts(viewerCtx, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{likedID}, postIDs(trending))
+
+	// Test: the shadow-banned user still sees their own reaction
+	counts, err = pm.GetReactionCounts(spammerCtx, boostedID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, counts[ReactionLike])
+	assert.Zero(t, counts[ReactionLove])
+}
+
+// TestPostManagerShadowBanTenants tests that shadow bans only apply in the tenant they were issued in
+func TestPostManagerShadowBanTenants(t *testing.T) {
+	pm := setupTestPostManager()
+	ctx := context.Background()
+	tenantA := WithTenant(ctx, "tenant-a")
+	tenantB := WithTenant(ctx, "tenant-b")
+	modA := WithActor(tenantA, Actor{UserID: "mod1", Role: RoleModerator})
+	modB := WithActor(tenantB, Actor{UserID: "mod1", Role: RoleModerator})
+
+	postA, err := pm.CreatePost(tenantA, createTestPostData("spammer"))
+	require.NoError(t, err)
+	postB, err := pm.CreatePost(tenantB, createTestPostData("spammer"))
+	require.NoError(t, err)
+	require.NoError(t, pm.ShadowBanUser(modA, "spammer", "link spam"))
+
+	// Test: the ban is only visible to moderators of its tenant
+	ban, err := pm.GetShadowBan(modA, "spammer")
+	require.NoError(t, err)
+	require.NotNil(t, ban)
+	assert.Equal(t, "tenant-a", ban.TenantID)
+	ban, err = pm.GetShadowBan(modB, "spammer")
+	require.NoError(t, err)
+	assert.Nil(t, ban)
+	bans, err := pm.ListShadowBans(modB, 10, 0)
+	require.NoError(t, err)
+	assert.Empty(t, bans)
+
+	// Test: the user's posts stay visible in other tenants
+	posts, err := pm.ListPosts(WithActor(tenantA, Actor{UserID: "viewer"}), &PostFilter{UserID: "spammer"})
+	require.NoError(t, err)
+	assert.NotContains(t, postIDs(posts), postA)
+	posts, err = pm.ListPosts(WithActor(tenantB, Actor{UserID: "viewer"}), &PostFilter{UserID: "spammer"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{postB}, postIDs(posts))
+
+	// Test: lifting a ban in another tenant leaves it in place
+	require.NoError(t, pm.LiftShadowBan(modB, "spammer"))
+	ban, err = pm.GetShadowBan(modA, "spammer")
+	require.NoError(t, err)
+	assert.NotNil(t, ban)
+}
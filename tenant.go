@@ -0,0 +1,59 @@
+// Package postflow provides functionality for managing user posts and feeds.
+package postflow
+
+import (
+	"context"
+	"strings"
+)
+
+// tenantSeparator joins a tenant ID to tags and keys; tenant IDs must not contain it
+const tenantSeparator = "\x1f"
+
+// tenantKey is the context key for the tenant
+type tenantKey struct{}
+
+// WithTenant returns a context whose store reads and writes are scoped to the tenant.
+// Posts, tags, reactions, feeds and trending posts of one tenant are invisible to the others.
+// Contexts without a tenant use the default tenant, which holds posts saved before tenants were introduced.
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// TenantFromContext returns the tenant of the context, or an empty string for the default tenant
+func TenantFromContext(ctx context.Context) string {
+	tenantID, _ := ctx.Value(tenantKey{}).(string)
+	return tenantID
+}
+
+// tenantScopedKey prefixes a key with the tenant of the context, leaving keys of the default tenant unchanged
+func tenantScopedKey(ctx context.Context, key string) string {
+	if tenantID := TenantFromContext(ctx); tenantID != "" {
+		return tenantID + tenantSeparator + key
+	}
+	return key
+}
+
+// tenantTag returns the stored name of a tag in a tenant's namespace
+func tenantTag(tenantID, tag string) string {
+	if tenantID == "" {
+		return tag
+	}
+	return tenantID + tenantSeparator + tag
+}
+
+// untenantTag returns the tag of a stored tag name
+func untenantTag(name string) string {
+	if i := strings.Index(name, tenantSeparator); i >= 0 {
+		return name[i+len(tenantSeparator):]
+	}
+	return name
+}
+
+// lookupPost returns a stored post of the tenant in the context. The caller must hold the lock.
+func (s *InMemoryPostStore) lookupPost(ctx context.Context, postID string) (*Post, bool) {
+	post, exists := s.posts[postID]
+	if !exists || post.TenantID != TenantFromContext(ctx) {
+		return nil, false
+	}
+	return post, true
+}
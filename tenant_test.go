@@ -0,0 +1,209 @@
+package postflow
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPostManagerTenants tests tenant isolation with the in-memory store
+func TestPostManagerTenants(t *testing.T) {
+	pm := setupTestPostManager()
+	ctxA := WithTenant(context.Background(), "tenant-a")
+	ctxB := WithTenant(context.Background(), "tenant-b")
+	defaultCtx := context.Background()
+
+	// The same user and tag exist in both tenants
+	postA := createTestPostData("alice")
+	postA.Tags = []string{"news"}
+	postAID, err := pm.CreatePost(ctxA, postA)
+	require.NoError(t, err)
+	assert.Equal(t, "tenant-a", postA.TenantID)
+
+	postB := createTestPostData("alice")
+	postB.Tags = []string{"news"}
+	postBID, err := pm.CreatePost(ctxB, postB)
+	require.NoError(t, err)
+
+	// Test: posts are only found in their tenant
+	stored, err := pm.GetPost(ctxA, postAID)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"news"}, stored.Tags)
+	assert.Equal(t, "tenant-a", stored.TenantID)
+
+	_, err = pm.GetPost(ctxB, postAID)
+	assert.Equal(t, ErrPostNotFound, err)
+	_, err = pm.GetPost(defaultCtx, postAID)
+	assert.Equal(t, ErrPostNotFound, err)
+
+	// Test: tags, profiles, feeds and trending are scoped
+	for _, tc := range []struct {
+		ctx      context.Context
+		expected []string
+	}{
+		{ctxA, []string{postAID}},
+		{ctxB, []string{postBID}},
+		{defaultCtx, []string{}},
+	} {
+		tagged, err := pm.ListPosts(tc.ctx, &PostFilter{Tags: []string{"news"}})
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expected, postIDs(tagged))
+
+		profile, err := pm.ListPosts(tc.ctx, &PostFilter{UserID: "alice"})
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expected, postIDs(profile))
+
+		all, err := pm.ListPosts(tc.ctx, &PostFilter{})
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expected, postIDs(all))
+
+		feed, err := pm.GetUserFeed(tc.ctx, "bob", 10, 0)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expected, postIDs(feed))
+
+		trending, err := pm.GetTrendingPosts(tc.ctx, 10)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.expected, postIDs(trending))
+	}
+
+	// Test: reactions are scoped
+	assert.Equal(t, ErrPostNotFound, pm.AddReaction(ctxB, postAID, "bob", ReactionLike))
+	require.NoError(t, pm.AddReaction(ctxA, postAID, "bob", ReactionLike))
+
+	counts, err := pm.GetReactionCounts(ctxA, postAID)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, counts[ReactionLike])
+	_, err = pm.GetReactionCounts(ctxB, postAID)
+	assert.Equal(t, ErrPostNotFound, err)
+	_, err = pm.GetReactedUsers(ctxB, postAID, nil, 10, 0)
+	assert.Equal(t, ErrPostNotFound, err)
+	_, err = pm.GetUserReaction(ctxB, postAID, "bob")
+	assert.Equal(t, ErrPostNotFound, err)
+	assert.Equal(t, ErrPostNotFound, pm.RemoveReaction(ctxB, postAID, "bob", ReactionLike))
+
+	counts, err = pm.GetReactionCounts(ctxB, postBID)
+	assert.NoError(t, err)
+	assert.Equal(t, 0, counts[ReactionLike])
+
+	// Test: posts cannot be changed or taken over from another tenant
+	_, err = pm.PatchPost(ctxB, postAID, "alice", &PostPatch{Fields: PatchContent, Content: "Hijacked"})
+	assert.Equal(t, ErrPostNotFound, err)
+	assert.Equal(t, ErrPostNotFound, pm.DeletePost(ctxB, postAID, "alice"))
+
+	clash := createTestPostData("alice")
+	clash.ID = postAID
+	_, err = pm.CreatePost(ctxB, clash)
+	assert.Equal(t, ErrPostExists, err)
+
+	stored, err = pm.GetPost(ctxA, postAID)
+	require.NoError(t, err)
+	assert.Equal(t, postA.Content, stored.Content)
+
+	// Test: idempotency keys are per tenant
+	keyA := WithIdempotencyKey(ctxA, "retry-1")
+	keyB := WithIdempotencyKey(ctxB, "retry-1")
+	firstID, err := pm.CreatePost(keyA, createTestPostData("alice"))
+	require.NoError(t, err)
+	secondID, err := pm.CreatePost(keyB, createTestPostData("alice"))
+	require.NoError(t, err)
+	assert.NotEqual(t, firstID, secondID)
+	_, err = pm.GetPost(ctxB, secondID)
+	assert.NoError(t, err)
+
+	// Test: deleting in one tenant leaves the other intact
+	require.NoError(t, pm.DeletePost(ctxA, postAID, "alice"))
+	tagged, err := pm.ListPosts(ctxB, &PostFilter{Tags: []string{"news"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{postBID}, postIDs(tagged))
+}
+
+// TestPostManagerTenantRelations tests that relations, muted terms and audience lists stay in their tenant with the in-memory store
+func TestPostManagerTenantRelations(t *testing.T) {
+	pm := setupTestPostManager()
+	ctxA := WithTenant(context.Background(), "tenant-a")
+	ctxB := WithTenant(context.Background(), "tenant-b")
+	as := func(ctx context.Context, userID string) context.Context {
+		return WithActor(ctx, Actor{UserID: userID})
+	}
+
+	// Relations, muted terms and an audience list are made in tenant A
+	require.NoError(t, pm.Follow(ctxA, "bob", "alice"))
+	require.NoError(t, pm.Block(ctxA, "alice", "carol"))
+	require.NoError(t, pm.Mute(ctxA, "dave", "alice"))
+	_, err := pm.MuteWord(ctxA, "dave", "news", MuteOptions{})
+	require.NoError(t, err)
+	audience, err := pm.CreateAudience(ctxA, "alice", "close friends")
+	require.NoError(t, err)
+	require.NoError(t, pm.AddAudienceMembers(ctxA, "alice", audience.ID, "erin"))
+
+	// Test: they apply in tenant A
+	followersA := createTestPostData("alice")
+	followersA.Visibility = VisibilityFollowers
+	followersAID, err := pm.CreatePost(ctxA, followersA)
+	require.NoError(t, err)
+	_, err = pm.GetPost(as(ctxA, "bob"), followersAID)
+	assert.NoError(t, err)
+
+	publicA := createTestPostData("alice")
+	publicAID, err := pm.CreatePost(ctxA, publicA)
+	require.NoError(t, err)
+	assert.Equal(t, ErrBlocked, pm.AddReaction(ctxA, publicAID, "carol", ReactionLike))
+
+	// Test: following in tenant A does not unlock followers-only posts in tenant B
+	followersB := createTestPostData("alice")
+	followersB.Visibility = VisibilityFollowers
+	followersBID, err := pm.CreatePost(ctxB, followersB)
+	require.NoError(t, err)
+
+	_, err = pm.GetPost(as(ctxB, "bob"), followersBID)
+	assert.Equal(t, ErrPermissionDenied, err)
+	feed, err := pm.GetUserFeed(ctxB, "bob", 10, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, feed)
+
+	following, err := pm.ListFollowing(ctxB, "bob", 10, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, following)
+
+	// Test: blocks and mutes in tenant A do not hide content in tenant B
+	publicB := createTestPostData("alice")
+	publicB.Tags = []string{"news"}
+	publicBID, err := pm.CreatePost(ctxB, publicB)
+	require.NoError(t, err)
+
+	listed, err := pm.ListPosts(as(ctxB, "carol"), &PostFilter{UserID: "alice"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{publicBID}, postIDs(listed))
+	assert.NoError(t, pm.AddReaction(ctxB, publicBID, "carol", ReactionLike))
+
+	blocked, err := pm.ListBlocked(ctxB, "alice", 10, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, blocked)
+
+	feed, err = pm.GetUserFeed(ctxB, "dave", 10, 0)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{publicBID}, postIDs(feed))
+
+	tagged, err := pm.ListPosts(as(ctxB, "dave"), &PostFilter{Tags: []string{"news"}})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{publicBID}, postIDs(tagged))
+
+	terms, err := pm.ListMutedTerms(ctxB, "dave")
+	assert.NoError(t, err)
+	assert.Empty(t, terms)
+
+	// Test: audience lists of tenant A cannot be listed, changed or used in tenant B
+	audiences, err := pm.ListAudiences(ctxB, "alice")
+	assert.NoError(t, err)
+	assert.Empty(t, audiences)
+	assert.Equal(t, ErrAudienceNotFound, pm.AddAudienceMembers(ctxB, "alice", audience.ID, "frank"))
+
+	custom := createTestPostData("alice")
+	custom.Visibility = VisibilityCustom
+	custom.AudienceIDs = []string{audience.ID}
+	_, err = pm.CreatePost(ctxB, custom)
+	assert.True(t, errors.Is(err, ErrInvalidAudience))
+}
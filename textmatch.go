@@ -0,0 +1,71 @@
+// Package postflow provides functionality for managing user posts and feeds.
+package postflow
+
+import (
+	"strings"
+	"unicode"
+)
+
+// isWordRune reports whether a rune is part of a word
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r)
+}
+
+// isUnspacedRune reports whether a rune belongs to a script that does not separate
+// words with spaces. Each such rune is treated as a word of its own.
+func isUnspacedRune(r rune) bool {
+	return unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Thai, unicode.Lao, unicode.Khmer, unicode.Myanmar)
+}
+
+// tokenizeWords splits text into lower-cased words using Unicode word boundaries
+func tokenizeWords(text string) []string {
+	var words []string
+	start := -1
+
+	for i, r := range text {
+		switch {
+		case isUnspacedRune(r):
+			if start >= 0 {
+				words = append(words, strings.ToLower(text[start:i]))
+				start = -1
+			}
+			words = append(words, string(r))
+		case isWordRune(r):
+			if start < 0 {
+				start = i
+			}
+		default:
+			if start >= 0 {
+				words = append(words, strings.ToLower(text[start:i]))
+				start = -1
+			}
+		}
+	}
+	if start >= 0 {
+		words = append(words, strings.ToLower(text[start:]))
+	}
+
+	return words
+}
+
+// containsPhrase reports whether the phrase occurs in words as a run of whole words
+func containsPhrase(words []string, phrase []string) bool {
+	if len(phrase) == 0 {
+		return false
+	}
+
+	for i := 0; i+len(phrase) <= len(words); i++ {
+		match := true
+		for j := range phrase {
+			if words[i+j] != phrase[j] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+
+	return false
+}
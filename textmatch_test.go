@@ -0,0 +1,26 @@
+package postflow
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestTokenizeWords tests Unicode-aware word splitting
+func TestTokenizeWords(t *testing.T) {
+	assert.Equal(t, []string{"hello", "world"}, tokenizeWords("Hello, World!"))
+	assert.Equal(t, []string{"crème", "brûlée"}, tokenizeWords("Crème-Brûlée"))
+	assert.Equal(t, []string{"привет", "мир"}, tokenizeWords("ПРИВЕТ мир"))
+	assert.Equal(t, []string{"我", "爱", "go"}, tokenizeWords("我爱Go"))
+	assert.Empty(t, tokenizeWords(" -- "))
+}
+
+// TestContainsPhrase tests whole-word phrase matching
+func TestContainsPhrase(t *testing.T) {
+	words := tokenizeWords("Spoiler: the butler did it")
+	assert.True(t, containsPhrase(words, []string{"butler"}))
+	assert.True(t, containsPhrase(words, []string{"butler", "did"}))
+	assert.False(t, containsPhrase(words, []string{"butle"}))
+	assert.False(t, containsPhrase(words, []string{"did", "butler"}))
+	assert.False(t, containsPhrase(words, nil))
+}
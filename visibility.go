@@ -0,0 +1,148 @@
+// Package postflow provides functionality for managing user posts and feeds.
+package postflow
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidVisibility is returned when a post has an unknown visibility
+var ErrInvalidVisibility = errors.New("invalid visibility")
+
+// Visibility controls who can see a post
+type Visibility string
+
+const (
+	VisibilityPublic    Visibility = "public"    // Everyone, in feeds and trending
+	VisibilityPrivate   Visibility = "private"   // Only the author and co-authors
+	VisibilityFriends   Visibility = "friends"   // Only the author and co-authors until friendships exist
+	VisibilityUnlisted  Visibility = "unlisted"  // Everyone with the ID or on the author's profile, never in feeds or trending
+	VisibilityFollowers Visibility = "followers" // Followers of the author or a co-author
+	VisibilityCustom    Visibility = "custom"    // The audience named by Post.AudienceIDs and Post.AudienceUserIDs
+)
+
+// Valid reports whether v is a known visibility
+func (v Visibility) Valid() bool {
+	switch v {
+	case VisibilityPublic, VisibilityPrivate, VisibilityFriends, VisibilityUnlisted, VisibilityFollowers, VisibilityCustom:
+		return true
+	default:
+		return false
+	}
+}
+
+// String returns the stored form of the visibility
+func (v Visibility) String() string {
+	return string(v)
+}
+
+// ParseVisibility converts a stored or user-supplied value to a Visibility,
+// ignoring case and surrounding whitespace. Empty values are public, as posts were before
+// visibility was validated.
+func ParseVisibility(value string) (Visibility, error) {
+	v := Visibility(strings.ToLower(strings.TrimSpace(value)))
+	if v == "" {
+		return VisibilityPublic, nil
+	}
+	if !v.Valid() {
+		return "", fmt.Errorf("%w: %q", ErrInvalidVisibility, value)
+	}
+	return v, nil
+}
+
+// validateVisibility checks the visibility of a post, defaulting an empty one to fallback
+func validateVisibility(post *Post, fallback Visibility) error {
+	if post.Visibility == "" {
+		post.Visibility = fallback
+	}
+	if !post.Visibility.Valid() {
+		return fmt.Errorf("%w: %q", ErrInvalidVisibility, string(post.Visibility))
+	}
+	return nil
+}
+
+// checkVisibility returns ErrPermissionDenied if a followers-only, custom or private group post may not be seen
+// by the user. Other visibilities are left to the authorizer.
+func (m *PostManagerImpl) checkVisibility(ctx context.Context, post *Post, userID string) error {
+	if err := m.checkGroup(ctx, post, userID); err != nil {
+		return err
+	}
+
+	switch post.Visibility {
+	case VisibilityFollowers:
+		return m.checkFollower(ctx, post, userID)
+	case VisibilityCustom:
+		return m.checkAudience(ctx, post, userID)
+	default:
+		return nil
+	}
+}
+
+// checkFollower returns ErrPermissionDenied unless the user authors the post or follows one of its authors
+func (m *PostManagerImpl) checkFollower(ctx context.Context, post *Post, userID string) error {
+	if post.isAuthor(userID) || seesAllAudiences(ctx) {
+		return nil
+	}
+	if userID == "" {
+		return ErrPermissionDenied
+	}
+
+	store, ok := m.store.(RelationStore)
+	if !ok {
+		return ErrNotSupported
+	}
+
+	for _, authorID := range append([]string{post.UserID}, post.CoAuthors...) {
+		follows, err := store.HasRelation(ctx, userID, authorID, RelationFollow)
+		if err != nil {
+			return err
+		}
+		if follows {
+			return nil
+		}
+	}
+
+	return ErrPermissionDenied
+}
+
+// canView reports whether a followers-only, custom or private group post may be shown to the viewer.
+// The caller must hold the lock.
+func (s *InMemoryPostStore) canView(post *Post, viewer string) bool {
+	if !s.inGroupOf(post, viewer) {
+		return false
+	}
+
+	switch post.Visibility {
+	case VisibilityFollowers:
+		if post.isAuthor(viewer) {
+			return true
+		}
+		for _, authorID := range append([]string{post.UserID}, post.CoAuthors...) {
+			if s.hasRelation(post.TenantID, viewer, authorID, RelationFollow) {
+				return true
+			}
+		}
+		return false
+	case VisibilityCustom:
+		return s.inAudienceOf(post, viewer)
+	default:
+		return true
+	}
+}
+
+// privateFrom reports whether the post is private or friends-only and the viewer does not author it
+func (p *Post) privateFrom(viewer string) bool {
+	return (p.Visibility == VisibilityPrivate || p.Visibility == VisibilityFriends) && !p.isAuthor(viewer)
+}
+
+// listedFor reports whether ListPosts may return the post to the viewer: followers-only and
+// custom posts must be shared with the viewer, and unlisted posts only appear on profiles.
+// The caller must hold the lock.
+func (s *InMemoryPostStore) listedFor(post *Post, filter *PostFilter, viewer string) bool {
+	if post.Visibility == VisibilityUnlisted && filter.UserID == "" {
+		return false
+	}
+	return s.canView(post, viewer)
+}
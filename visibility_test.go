@@ -0,0 +1,157 @@
+package postflow
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseVisibility tests normalization of stored visibility values
+func TestParseVisibility(t *testing.T) {
+	for value, expected := range map[string]Visibility{
+		"":          VisibilityPublic,
+		"public":    VisibilityPublic,
+		" Private ": VisibilityPrivate,
+		"UNLISTED":  VisibilityUnlisted,
+		"followers": VisibilityFollowers,
+		"friends":   VisibilityFriends,
+		"custom":    VisibilityCustom,
+	} {
+		visibility, err := ParseVisibility(value)
+		assert.NoError(t, err, value)
+		assert.Equal(t, expected, visibility, value)
+	}
+
+	_, err := ParseVisibility("pubic")
+	assert.True(t, errors.Is(err, ErrInvalidVisibility))
+	assert.False(t, Visibility("pubic").Valid())
+}
+
+// TestPostManagerVisibility tests visibility modes with the in-memory store
+func TestPostManagerVisibility(t *testing.T) {
+	pm := setupTestPostManager()
+	ctx := context.Background()
+	as := func(userID string) context.Context {
+		return WithActor(ctx, Actor{UserID: userID})
+	}
+
+	// Test: unknown visibilities are rejected, empty ones default to public
+	typo := createTestPostData("alice")
+	typo.Visibility = "pubic"
+	_, err := pm.CreatePost(ctx, typo)
+	assert.True(t, errors.Is(err, ErrInvalidVisibility))
+
+	defaulted := createTestPostData("alice")
+	defaulted.Visibility = ""
+	defaultedID, err := pm.CreatePost(ctx, defaulted)
+	require.NoError(t, err)
+	assert.Equal(t, VisibilityPublic, defaulted.Visibility)
+	require.NoError(t, pm.DeletePost(ctx, defaultedID, "alice"))
+
+	// Test: unlisted posts are reachable by ID and profile only
+	unlisted := createTestPostData("alice")
+	unlisted.Visibility = VisibilityUnlisted
+	unlistedID, err := pm.CreatePost(ctx, unlisted)
+	require.NoError(t, err)
+
+	_, err = pm.GetPost(as("carol"), unlistedID)
+	assert.NoError(t, err)
+	require.NoError(t, pm.AddReaction(ctx, unlistedID, "carol", ReactionLike))
+
+	profile, err := pm.ListPosts(as("carol"), &PostFilter{UserID: "alice"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{unlistedID}, postIDs(profile))
+
+	all, err := pm.ListPosts(as("carol"), &PostFilter{})
+	assert.NoError(t, err)
+	assert.Empty(t, all)
+	all, err = pm.ListPosts(as("carol"), &PostFilter{Visibility: VisibilityUnlisted})
+	assert.NoError(t, err)
+	assert.Empty(t, all)
+
+	feed, err := pm.GetUserFeed(ctx, "carol", 10, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, feed)
+	trending, err := pm.GetTrendingPosts(as("carol"), 10)
+	assert.NoError(t, err)
+	assert.Empty(t, trending)
+	require.NoError(t, pm.DeletePost(ctx, unlistedID, "alice"))
+
+	// Test: followers-only posts reach followers of the author and co-authors
+	followersOnly := createTestPostData("alice")
+	followersOnly.Visibility = VisibilityFollowers
+	postID, err := pm.CreatePost(ctx, followersOnly)
+	require.NoError(t, err)
+	require.NoError(t, pm.InviteCoAuthor(ctx, postID, "alice", "partner"))
+	require.NoError(t, pm.AcceptCoAuthorInvite(ctx, postID, "partner"))
+
+	require.NoError(t, pm.Follow(ctx, "bob", "alice"))
+	require.NoError(t, pm.Follow(ctx, "dave", "partner"))
+	assert.Equal(t, ErrInvalidRelation, pm.Follow(ctx, "bob", "bob"))
+
+	following, err := pm.ListFollowing(ctx, "bob", 10, 0)
+	assert.NoError(t, err)
+	require.Equal(t, 1, len(following))
+	assert.Equal(t, "alice", following[0].TargetID)
+
+	for viewer, allowed := range map[string]bool{"alice": true, "partner": true, "bob": true, "dave": true, "carol": false, "": false} {
+		readCtx := ctx
+		if viewer != "" {
+			readCtx = as(viewer)
+		}
+
+		listed, err := pm.ListPosts(readCtx, &PostFilter{UserID: "alice"})
+		assert.NoError(t, err)
+		feed, err := pm.GetUserFeed(ctx, viewer, 10, 0)
+		assert.NoError(t, err)
+		trending, err := pm.GetTrendingPosts(readCtx, 10)
+		assert.NoError(t, err)
+
+		if allowed {
+			assert.Equal(t, []string{postID}, postIDs(listed), viewer)
+			assert.Equal(t, []string{postID}, postIDs(feed), viewer)
+			assert.Equal(t, []string{postID}, postIDs(trending), viewer)
+		} else {
+			assert.Empty(t, listed, viewer)
+			assert.Empty(t, feed, viewer)
+			assert.Empty(t, trending, viewer)
+		}
+	}
+
+	_, err = pm.GetPost(as("carol"), postID)
+	assert.Equal(t, ErrPermissionDenied, err)
+	assert.Equal(t, ErrPermissionDenied, pm.AddReaction(ctx, postID, "carol", ReactionLike))
+	require.NoError(t, pm.AddReaction(ctx, postID, "dave", ReactionLike))
+
+	// Test: unfollowing and blocking revoke access
+	require.NoError(t, pm.Unfollow(ctx, "dave", "partner"))
+	_, err = pm.GetPost(as("dave"), postID)
+	assert.Equal(t, ErrPermissionDenied, err)
+
+	require.NoError(t, pm.Block(ctx, "alice", "bob"))
+	following, err = pm.ListFollowing(ctx, "bob", 10, 0)
+	assert.NoError(t, err)
+	assert.Empty(t, following)
+	assert.Equal(t, ErrBlocked, pm.Follow(ctx, "bob", "alice"))
+
+	// Test: updates and patches are validated, keeping the visibility when none is given
+	post, err := pm.GetPost(ctx, postID)
+	require.NoError(t, err)
+	post.Visibility = "everyone"
+	assert.True(t, errors.Is(pm.UpdatePost(ctx, post), ErrInvalidVisibility))
+
+	post.Visibility = ""
+	require.NoError(t, pm.UpdatePost(ctx, post))
+	assert.Equal(t, VisibilityFollowers, post.Visibility)
+
+	_, err = pm.PatchPost(ctx, postID, "alice", &PostPatch{Fields: PatchVisibility, Visibility: "pubic"})
+	assert.True(t, errors.Is(err, ErrInvalidVisibility))
+	patched, err := pm.PatchPost(ctx, postID, "alice", &PostPatch{Fields: PatchVisibility, Visibility: VisibilityPublic})
+	require.NoError(t, err)
+	assert.Equal(t, VisibilityPublic, patched.Visibility)
+	_, err = pm.GetPost(as("carol"), postID)
+	assert.NoError(t, err)
+}
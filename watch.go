@@ -0,0 +1,432 @@
+// Package postflow provides functionality for managing user posts and feeds.
+package postflow
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrInvalidWatch is returned when a watch filter watches nothing
+var ErrInvalidWatch = errors.New("invalid watch")
+
+// Limits of watches
+const (
+	DefaultWatchBufferSize = 64   // Events buffered per watch when no size is given
+	MaxWatchBufferSize     = 4096 // Upper bound of the buffer of a watch
+
+	watchQueueSize = 1024 // Changes waiting to be matched against the watches of a manager
+)
+
+// ChangeType identifies a change delivered to a watch
+type ChangeType uint8
+
+const (
+	ChangePostCreated    ChangeType = 1 // A post newly matches the watched filter: it was created, or moderated into the watched status
+	ChangeReactionCounts ChangeType = 2 // The reaction counts of a watched post changed
+	ChangeDropped        ChangeType = 3 // Events were dropped because the consumer fell behind
+)
+
+// String returns the name of the change type
+func (t ChangeType) String() string {
+	switch t {
+	case ChangePostCreated:
+		return "post.created"
+	case ChangeReactionCounts:
+		return "reaction.counts"
+	case ChangeDropped:
+		return "dropped"
+	default:
+		return "unknown"
+	}
+}
+
+// ChangeEvent is a live change delivered by Watch
+type ChangeEvent struct {
+	Type           ChangeType           `json:"type"`
+	PostID         string               `json:"post_id,omitempty"`
+	Post           *Post                `json:"post,omitempty"`            // The post, for ChangePostCreated
+	ReactionCounts map[ReactionType]int `json:"reaction_counts,omitempty"` // Counts when the change was delivered, for ChangeReactionCounts
+	Dropped        int                  `json:"dropped,omitempty"`         // Events lost, for ChangeDropped
+	OccurredAt     time.Time            `json:"occurred_at"`
+}
+
+// WatchFilter selects the changes delivered by Watch
+type WatchFilter struct {
+	// Posts selects new posts by author, group, tags (all of them), visibility and moderation status,
+	// as ListPosts does; nil watches no new posts. Paging and sorting fields are ignored.
+	Posts *PostFilter
+
+	// PostIDs are the posts whose reaction counts are watched
+	PostIDs []string
+
+	// BufferSize is the number of undelivered events kept for the consumer; DefaultWatchBufferSize if zero
+	BufferSize int
+}
+
+// watcher is a subscription created by Watch
+type watcher struct {
+	ctx     context.Context // Context of the Watch call, carrying its tenant and viewer
+	tenant  string
+	viewer  string
+	posts   *PostFilter
+	postIDs map[string]bool
+
+	mutex   sync.Mutex
+	events  chan *ChangeEvent
+	dropped int
+	closed  bool
+}
+
+// watchHub holds the watches of a manager. Stored changes are only queued; a single goroutine matches them
+// against the watches and reads what they need from the store. The goroutine runs while there are watches:
+// it is started with the first watch and stopped when the last one ends.
+type watchHub struct {
+	mutex    sync.RWMutex
+	watchers map[*watcher]struct{}
+	queue    chan *watchChange // Nil while there are no watches
+	pending  sync.WaitGroup    // Queued changes not yet delivered
+}
+
+// watchedEvents are the event types delivered to watches
+var watchedEvents = map[EventType]bool{
+	EventPostCreated:     true,
+	EventPostUpdated:     true,
+	EventReactionAdded:   true,
+	EventReactionChanged: true,
+	EventReactionRemoved: true,
+}
+
+// watching reports whether the hub is running and interested in one of the event types
+func (h *watchHub) watching(eventTypes ...EventType) bool {
+	h.mutex.RLock()
+	defer h.mutex.RUnlock()
+
+	if h.queue == nil {
+		return false
+	}
+	for _, eventType := range eventTypes {
+		if watchedEvents[eventType] {
+			return true
+		}
+	}
+	return false
+}
+
+// watchChange is a change waiting in the queue of the hub
+type watchChange struct {
+	ctx   context.Context // Context of the change, carrying its tenant
+	event *Event
+}
+
+// Watch returns a channel of live changes: new posts matching filter.Posts that the actor in the context
+// may see, posts that come to match it when moderated (such as pending posts once approved), and reaction
+// count changes of filter.PostIDs. The channel is closed when ctx is cancelled.
+// Each post in filter.PostIDs must exist and its reactions be readable as with GetReactionCounts,
+// otherwise the error is returned and no watch is created.
+//
+// Events are never allowed to hold up writers: changes are matched against the watches in the background,
+// shortly after the call that made them returns. When the buffer of a slow consumer is full, further events
+// are dropped until it catches up, after which a ChangeDropped event reports how many were lost so that
+// the consumer can reload what it shows.
+func (m *PostManagerImpl) Watch(ctx context.Context, filter *WatchFilter) (<-chan *ChangeEvent, error) {
+	if filter == nil || (filter.Posts == nil && len(filter.PostIDs) == 0) {
+		return nil, ErrInvalidWatch
+	}
+	if filter.BufferSize < 0 {
+		return nil, ErrInvalidWatch
+	}
+	if err := checkModerationFilter(ctx, filter.Posts); err != nil {
+		return nil, err
+	}
+
+	// Reaction counts of a watched post are only sent to those who may read them
+	for _, postID := range filter.PostIDs {
+		if err := m.checkReactionsReadable(ctx, postID); err != nil {
+			return nil, err
+		}
+	}
+
+	size := filter.BufferSize
+	if size == 0 {
+		size = DefaultWatchBufferSize
+	}
+	if size > MaxWatchBufferSize {
+		size = MaxWatchBufferSize
+	}
+
+	w := &watcher{
+		ctx:     ctx,
+		tenant:  TenantFromContext(ctx),
+		viewer:  viewerFromContext(ctx),
+		postIDs: make(map[string]bool, len(filter.PostIDs)),
+		events:  make(chan *ChangeEvent, size),
+	}
+	if filter.Posts != nil {
+		posts := *filter.Posts
+		w.posts = &posts
+	}
+	for _, postID := range filter.PostIDs {
+		w.postIDs[postID] = true
+	}
+
+	// The hub goroutine starts with the first watch
+	m.watches.mutex.Lock()
+	if len(m.watches.watchers) == 0 {
+		m.watches.watchers = make(map[*watcher]struct{})
+		m.watches.queue = make(chan *watchChange, watchQueueSize)
+		go m.runWatchHub(m.watches.queue)
+	}
+	m.watches.watchers[w] = struct{}{}
+	m.watches.mutex.Unlock()
+
+	go func() {
+		<-ctx.Done()
+
+		// The hub goroutine stops with the last watch, once it delivered the changes already queued
+		m.watches.mutex.Lock()
+		delete(m.watches.watchers, w)
+		if len(m.watches.watchers) == 0 {
+			close(m.watches.queue)
+			m.watches.queue = nil
+		}
+		m.watches.mutex.Unlock()
+
+		w.close()
+	}()
+
+	return w.events, nil
+}
+
+// watchersOf returns the watches of the tenant in the context
+func (m *PostManagerImpl) watchersOf(ctx context.Context) []*watcher {
+	m.watches.mutex.RLock()
+	defer m.watches.mutex.RUnlock()
+
+	return m.watches.tenantWatchers(TenantFromContext(ctx))
+}
+
+// tenantWatchers returns the watches of a tenant. The caller must hold the lock.
+func (h *watchHub) tenantWatchers(tenant string) []*watcher {
+	var watchers []*watcher
+	for w := range h.watchers {
+		if w.tenant == tenant {
+			watchers = append(watchers, w)
+		}
+	}
+	return watchers
+}
+
+// queueWatchChange hands a stored change to the hub goroutine without blocking the writer.
+// When the queue is full the change is dropped and counted against every watch of the tenant.
+func (m *PostManagerImpl) queueWatchChange(ctx context.Context, event *Event) {
+	if !watchedEvents[event.Type] {
+		return
+	}
+	// Updates only matter to watches when they moderate a post into or out of a watched status
+	if event.Type == EventPostUpdated && (event.Previous == nil || event.Previous.ModerationStatus == event.Post.ModerationStatus) {
+		return
+	}
+
+	// The queue is only sent to under the lock, as it is closed when the last watch ends
+	m.watches.mutex.RLock()
+	defer m.watches.mutex.RUnlock()
+
+	watchers := m.watches.tenantWatchers(TenantFromContext(ctx))
+	if len(watchers) == 0 {
+		return
+	}
+
+	// The caller may reuse its post once the call returns
+	queued := *event
+	if event.Post != nil {
+		post := *event.Post
+		queued.Post = &post
+	}
+
+	m.watches.pending.Add(1)
+	select {
+	case m.watches.queue <- &watchChange{ctx: context.WithoutCancel(ctx), event: &queued}:
+	default:
+		m.watches.pending.Done()
+		for _, w := range watchers {
+			w.drop()
+		}
+	}
+}
+
+// runWatchHub delivers queued changes to the watches, in the order they were made, until the queue is closed
+func (m *PostManagerImpl) runWatchHub(queue <-chan *watchChange) {
+	for change := range queue {
+		switch change.event.Type {
+		case EventPostCreated, EventPostUpdated:
+			m.publishPostCreated(change.ctx, change.event)
+		default:
+			m.publishReactionCounts(change.ctx, change.event)
+		}
+		m.watches.pending.Done()
+	}
+}
+
+// publishPostCreated delivers a new or newly moderated post to the watches it matches
+func (m *PostManagerImpl) publishPostCreated(ctx context.Context, event *Event) {
+	for _, w := range m.watchersOf(ctx) {
+		if w.posts == nil || !watchMatches(event.Post, w.posts) || !m.watchable(w, event.Post) {
+			continue
+		}
+
+		post := *event.Post
+		w.send(&ChangeEvent{
+			Type:       ChangePostCreated,
+			PostID:     event.PostID,
+			Post:       &post,
+			OccurredAt: event.OccurredAt,
+		})
+	}
+}
+
+// publishReactionCounts delivers the current reaction counts of a post to its watches.
+// Counts are read as each viewer sees them, so reactions of shadow-banned users only reach themselves.
+func (m *PostManagerImpl) publishReactionCounts(ctx context.Context, event *Event) {
+	countsByViewer := make(map[string]map[ReactionType]int)
+	for _, w := range m.watchersOf(ctx) {
+		if !w.postIDs[event.PostID] {
+			continue
+		}
+
+		counts, read := countsByViewer[w.viewer]
+		if !read {
+			var err error
+			if counts, err = m.store.GetReactionCounts(w.ctx, event.PostID); err != nil {
+				continue
+			}
+			countsByViewer[w.viewer] = counts
+		}
+
+		w.send(&ChangeEvent{
+			Type:           ChangeReactionCounts,
+			PostID:         event.PostID,
+			ReactionCounts: copyReactionCounts(counts),
+			OccurredAt:     event.OccurredAt,
+		})
+	}
+}
+
+// watchMatches applies a post filter of a watch to a post. As on profiles, a user's posts include
+// the posts the user co-authors.
+func watchMatches(post *Post, filter *PostFilter) bool {
+	if filter.UserID != "" && !post.isAuthor(filter.UserID) {
+		return false
+	}
+	for _, tag := range filter.Tags {
+		if !post.hasTag(tag) {
+			return false
+		}
+	}
+	return matchesFilter(post, filter)
+}
+
+// hasTag reports whether the post is tagged with the tag
+func (p *Post) hasTag(tag string) bool {
+	for _, postTag := range p.Tags {
+		if postTag == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// watchable reports whether the viewer of a watch may see a new post, as ListPosts would decide:
+// the authorizer allows the actor of the watch to read it, the author is not shadow-banned and neither user
+// blocked the other, the post is shared with the viewer, and unlisted posts only appear when watching
+// an author. Private and friends-only posts only reach their authors and co-authors, as in listings.
+func (m *PostManagerImpl) watchable(w *watcher, post *Post) bool {
+	allowed, err := m.authorizedPosts(w.ctx, []*Post{post})
+	if err != nil || len(allowed) == 0 {
+		return false
+	}
+	if post.isAuthor(w.viewer) || seesAllAudiences(w.ctx) {
+		return true
+	}
+
+	switch post.Visibility {
+	case VisibilityPrivate, VisibilityFriends:
+		return false
+	case VisibilityUnlisted:
+		if w.posts.UserID == "" {
+			return false
+		}
+	}
+
+	if store, ok := m.store.(ShadowBanStore); ok {
+		ban, err := store.GetShadowBan(w.ctx, post.UserID)
+		if err != nil || ban != nil {
+			return false
+		}
+	}
+	if m.checkBlocked(w.ctx, post.UserID, w.viewer) != nil || m.checkBlocked(w.ctx, w.viewer, post.UserID) != nil {
+		return false
+	}
+	if m.checkVisibility(w.ctx, post, w.viewer) != nil {
+		return false
+	}
+
+	// Tag watches honor muted words and tags, as tag feeds do
+	if len(w.posts.Tags) > 0 {
+		posts, err := m.applyMutedTerms(w.ctx, w.viewer, []*Post{post})
+		if err != nil || len(posts) == 0 {
+			return false
+		}
+	}
+
+	return true
+}
+
+// send delivers an event without blocking. When the buffer is full the event is dropped and counted;
+// the count is delivered as a ChangeDropped event ahead of the next event that fits.
+func (w *watcher) send(event *ChangeEvent) {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.closed {
+		return
+	}
+
+	if w.dropped > 0 {
+		select {
+		case w.events <- &ChangeEvent{Type: ChangeDropped, Dropped: w.dropped, OccurredAt: time.Now()}:
+			w.dropped = 0
+		default:
+			w.dropped++
+			return
+		}
+	}
+
+	select {
+	case w.events <- event:
+	default:
+		w.dropped++
+	}
+}
+
+// drop counts an event lost before it reached the watch
+func (w *watcher) drop() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if !w.closed {
+		w.dropped++
+	}
+}
+
+// close ends the watch; buffered events remain readable before the channel reports closure
+func (w *watcher) close() {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if !w.closed {
+		w.closed = true
+		close(w.events)
+	}
+}
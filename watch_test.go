@@ -0,0 +1,381 @@
+package postflow
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// nextChange returns the next buffered change once the manager delivered the queued ones, or nil if there is none
+func nextChange(pm *PostManagerImpl, ch <-chan *ChangeEvent) *ChangeEvent {
+	pm.watches.pending.Wait()
+	select {
+	case event := <-ch:
+		return event
+	default:
+		return nil
+	}
+}
+
+// TestPostManagerWatch tests watching live changes with the in-memory store
+func TestPostManagerWatch(t *testing.T) {
+	pm := setupTestPostManager()
+	var _ PostManager = pm
+	ctx := context.Background()
+
+	create := func(ctx context.Context, userID string, visibility Visibility, tags ...string) string {
+		post := createTestPostData(userID)
+		post.Visibility = visibility
+		post.Tags = tags
+		postID, err := pm.CreatePost(ctx, post)
+		require.NoError(t, err)
+		return postID
+	}
+
+	// Test: a watch must watch something
+	_, err := pm.Watch(ctx, nil)
+	assert.Equal(t, ErrInvalidWatch, err)
+	_, err = pm.Watch(ctx, &WatchFilter{})
+	assert.Equal(t, ErrInvalidWatch, err)
+	_, err = pm.Watch(ctx, &WatchFilter{PostIDs: []string{"x"}, BufferSize: -1})
+	assert.Equal(t, ErrInvalidWatch, err)
+
+	watchedID := create(ctx, "alice", VisibilityPublic, "news")
+	otherID := create(ctx, "alice", VisibilityPublic, "news")
+
+	watchCtx, cancel := context.WithCancel(WithActor(ctx, Actor{UserID: "bob"}))
+	defer cancel()
+	changes, err := pm.Watch(watchCtx, &WatchFilter{
+		Posts:   &PostFilter{Tags: []string{"golang", "watch"}},
+		PostIDs: []string{watchedID},
+	})
+	require.NoError(t, err)
+	assert.Nil(t, nextChange(pm, changes))
+
+	// Test: new posts matching the filter are delivered
+	postID := create(ctx, "alice", VisibilityPublic, "golang", "watch", "extra")
+	change := nextChange(pm, changes)
+	require.NotNil(t, change)
+	assert.Equal(t, ChangePostCreated, change.Type)
+	assert.Equal(t, postID, change.PostID)
+	require.NotNil(t, change.Post)
+	assert.Equal(t, "alice", change.Post.UserID)
+	assert.Nil(t, nextChange(pm, changes))
+
+	create(ctx, "alice", VisibilityPublic, "golang")
+	assert.Nil(t, nextChange(pm, changes))
+
+	// Test: posts the viewer may not see are not delivered
+	create(ctx, "carol", VisibilityFollowers, "golang", "watch")
+	create(ctx, "carol", VisibilityPrivate, "golang", "watch")
+	create(ctx, "carol", VisibilityUnlisted, "golang", "watch")
+	create(WithTenant(ctx, "other"), "alice", VisibilityPublic, "golang", "watch")
+	assert.Nil(t, nextChange(pm, changes))
+
+	require.NoError(t, pm.Follow(ctx, "bob", "carol"))
+	followersID := create(ctx, "carol", VisibilityFollowers, "golang", "watch")
+	change = nextChange(pm, changes)
+	require.NotNil(t, change)
+	assert.Equal(t, followersID, change.PostID)
+
+	// Test: reactions of a post can only be watched by those who may read them
+	privateID := create(ctx, "carol", VisibilityPrivate)
+	_, err = pm.Watch(watchCtx, &WatchFilter{PostIDs: []string{watchedID, privateID}})
+	assert.Error(t, err)
+	_, err = pm.Watch(ctx, &WatchFilter{PostIDs: []string{followersID}})
+	assert.NoError(t, err)
+	_, err = pm.Watch(watchCtx, &WatchFilter{PostIDs: []string{"missing"}})
+	assert.Equal(t, ErrPostNotFound, err)
+	_, err = pm.Watch(watchCtx, &WatchFilter{PostIDs: []string{followersID}})
+	assert.NoError(t, err)
+	assert.Nil(t, nextChange(pm, changes))
+
+	// Test: reaction count changes of watched posts are delivered
+	require.NoError(t, pm.AddReaction(ctx, watchedID, "dave", ReactionLike))
+	change = nextChange(pm, changes)
+	require.NotNil(t, change)
+	assert.Equal(t, ChangeReactionCounts, change.Type)
+	assert.Equal(t, watchedID, change.PostID)
+	assert.Equal(t, 1, change.ReactionCounts[ReactionLike])
+
+	require.NoError(t, pm.AddReaction(ctx, watchedID, "dave", ReactionLike))
+	require.NoError(t, pm.AddReaction(ctx, otherID, "dave", ReactionLike))
+	assert.Nil(t, nextChange(pm, changes))
+
+	require.NoError(t, pm.AddReaction(ctx, watchedID, "dave", ReactionLove))
+	change = nextChange(pm, changes)
+	require.NotNil(t, change)
+	assert.Zero(t, change.ReactionCounts[ReactionLike])
+	assert.Equal(t, 1, change.ReactionCounts[ReactionLove])
+
+	require.NoError(t, pm.RemoveReaction(ctx, watchedID, "dave", ReactionLove))
+	change = nextChange(pm, changes)
+	require.NotNil(t, change)
+	assert.Zero(t, change.ReactionCounts[ReactionLove])
+
+	// Test: slow consumers lose events and are told how many
+	slowCtx, cancelSlow := context.WithCancel(ctx)
+	defer cancelSlow()
+	slow, err := pm.Watch(slowCtx, &WatchFilter{PostIDs: []string{watchedID}, BufferSize: 2})
+	require.NoError(t, err)
+
+	for _, userID := range []string{"u1", "u2", "u3", "u4", "u5"} {
+		require.NoError(t, pm.AddReaction(ctx, watchedID, userID, ReactionWow))
+		pm.watches.pending.Wait() // Counts are read when delivered
+	}
+	assert.Equal(t, 1, nextChange(pm, slow).ReactionCounts[ReactionWow])
+	assert.Equal(t, 2, nextChange(pm, slow).ReactionCounts[ReactionWow])
+	assert.Nil(t, nextChange(pm, slow))
+
+	require.NoError(t, pm.AddReaction(ctx, watchedID, "u6", ReactionWow))
+	change = nextChange(pm, slow)
+	require.NotNil(t, change)
+	assert.Equal(t, ChangeDropped, change.Type)
+	assert.Equal(t, 3, change.Dropped)
+	assert.Equal(t, 6, nextChange(pm, slow).ReactionCounts[ReactionWow])
+
+	// Other watches keep up
+	for i := 0; i < 6; i++ {
+		change = nextChange(pm, changes)
+		require.NotNil(t, change)
+		assert.Equal(t, ChangeReactionCounts, change.Type)
+	}
+
+	// Test: cancelling the context closes the channel
+	cancel()
+	deadline := time.After(time.Second)
+	for closed := false; !closed; {
+		select {
+		case _, ok := <-changes:
+			closed = !ok
+		case <-deadline:
+			t.Fatal("watch channel not closed")
+		}
+	}
+
+	require.NoError(t, pm.AddReaction(ctx, watchedID, "u7", ReactionWow))
+	assert.NotNil(t, nextChange(pm, slow))
+}
+
+// TestPostManagerWatchAuthorizer tests that watches only deliver posts the authorizer lets the viewer read
+func TestPostManagerWatchAuthorizer(t *testing.T) {
+	authorizer := AuthorizerFunc(func(ctx context.Context, actor Actor, action Action, post *Post) error {
+		if action == ActionRead && post.Content == "embargoed" && actor.UserID != post.UserID {
+			return ErrPermissionDenied
+		}
+		return DefaultAuthorizer.Authorize(ctx, actor, action, post)
+	})
+	pm := NewPostManager(NewInMemoryPostStore(), WithAuthorizer(authorizer))
+	ctx := context.Background()
+
+	watchCtx, cancel := context.WithCancel(WithActor(ctx, Actor{UserID: "bob"}))
+	defer cancel()
+	changes, err := pm.Watch(watchCtx, &WatchFilter{Posts: &PostFilter{UserID: "alice"}})
+	require.NoError(t, err)
+
+	embargoed := createTestPostData("alice")
+	embargoed.Content = "embargoed"
+	_, err = pm.CreatePost(ctx, embargoed)
+	require.NoError(t, err)
+	assert.Nil(t, nextChange(pm, changes))
+
+	postID, err := pm.CreatePost(ctx, createTestPostData("alice"))
+	require.NoError(t, err)
+	change := nextChange(pm, changes)
+	require.NotNil(t, change)
+	assert.Equal(t, postID, change.PostID)
+}
+
+// TestPostManagerWatchShadowBan tests that watched reaction counts hide the reactions of shadow-banned users
+func TestPostManagerWatchShadowBan(t *testing.T) {
+	pm := setupTestPostManager()
+	ctx := context.Background()
+	moderator := WithActor(ctx, Actor{UserID: "mod", Role: RoleModerator})
+
+	post := createTestPostData("alice")
+	postID, err := pm.CreatePost(ctx, post)
+	require.NoError(t, err)
+	require.NoError(t, pm.ShadowBanUser(moderator, "spammer", "spam"))
+
+	watch := func(userID string) (<-chan *ChangeEvent, context.CancelFunc) {
+		watchCtx, cancel := context.WithCancel(WithActor(ctx, Actor{UserID: userID}))
+		changes, err := pm.Watch(watchCtx, &WatchFilter{PostIDs: []string{postID}})
+		require.NoError(t, err)
+		return changes, cancel
+	}
+	bobChanges, cancelBob := watch("bob")
+	defer cancelBob()
+	spammerChanges, cancelSpammer := watch("spammer")
+	defer cancelSpammer()
+
+	// Test: other watchers do not see the reaction of a shadow-banned user, the user does
+	require.NoError(t, pm.AddReaction(ctx, postID, "spammer", ReactionLike))
+
+	change := nextChange(pm, bobChanges)
+	require.NotNil(t, change)
+	assert.Equal(t, ChangeReactionCounts, change.Type)
+	assert.Equal(t, 0, change.ReactionCounts[ReactionLike])
+
+	change = nextChange(pm, spammerChanges)
+	require.NotNil(t, change)
+	assert.Equal(t, 1, change.ReactionCounts[ReactionLike])
+
+	// Test: reactions of other users reach every watcher
+	require.NoError(t, pm.AddReaction(ctx, postID, "carol", ReactionLike))
+
+	change = nextChange(pm, bobChanges)
+	require.NotNil(t, change)
+	assert.Equal(t, 1, change.ReactionCounts[ReactionLike])
+
+	change = nextChange(pm, spammerChanges)
+	require.NotNil(t, change)
+	assert.Equal(t, 2, change.ReactionCounts[ReactionLike])
+}
+
+// TestPostManagerWatchModeration tests that posts are delivered to watches when moderated into the watched status
+func TestPostManagerWatchModeration(t *testing.T) {
+	pm := NewPostManager(NewInMemoryPostStore(), WithModerator(keywordModerator))
+	ctx := context.Background()
+	modCtx := WithActor(ctx, Actor{UserID: "mod1", Role: RoleModerator})
+
+	watchCtx, cancel := context.WithCancel(WithActor(ctx, Actor{UserID: "bob"}))
+	defer cancel()
+	changes, err := pm.Watch(watchCtx, &WatchFilter{Posts: &PostFilter{UserID: "alice"}})
+	require.NoError(t, err)
+	pending := ModerationPending
+	queueCtx, cancelQueue := context.WithCancel(modCtx)
+	defer cancelQueue()
+	queue, err := pm.Watch(queueCtx, &WatchFilter{Posts: &PostFilter{ModerationStatus: &pending}})
+	require.NoError(t, err)
+
+	// Test: held posts only reach watches of the moderation queue
+	post := createTestPostData("alice")
+	post.Content = "please review"
+	postID, err := pm.CreatePost(ctx, post)
+	require.NoError(t, err)
+	assert.Nil(t, nextChange(pm, changes))
+	change := nextChange(pm, queue)
+	require.NotNil(t, change)
+	assert.Equal(t, postID, change.PostID)
+
+	// Test: approved posts are delivered
+	require.NoError(t, pm.ApprovePost(modCtx, postID))
+	change = nextChange(pm, changes)
+	require.NotNil(t, change)
+	assert.Equal(t, ChangePostCreated, change.Type)
+	assert.Equal(t, postID, change.PostID)
+	require.NotNil(t, change.Post)
+	assert.Equal(t, ModerationApproved, change.Post.ModerationStatus)
+	assert.Nil(t, nextChange(pm, queue))
+
+	// Test: edits that keep the status are not delivered again
+	_, err = pm.PatchPost(ctx, postID, "alice", &PostPatch{Fields: PatchContent, Content: "edited"})
+	require.NoError(t, err)
+	assert.Nil(t, nextChange(pm, changes))
+
+	// Test: hidden posts leave the watch and unhidden posts are delivered again
+	require.NoError(t, pm.HidePost(modCtx, postID, "off-topic"))
+	assert.Nil(t, nextChange(pm, changes))
+	require.NoError(t, pm.UnhidePost(modCtx, postID))
+	change = nextChange(pm, changes)
+	require.NotNil(t, change)
+	assert.Equal(t, postID, change.PostID)
+}
+
+// TestPostManagerWatchLifecycle tests that the hub of a manager only runs while there are watches
+func TestPostManagerWatchLifecycle(t *testing.T) {
+	pm := setupTestPostManager()
+	ctx := context.Background()
+	assert.False(t, pm.watches.watching(EventPostCreated))
+	assert.False(t, pm.hasHooks(EventReactionAdded))
+
+	watch := func() (<-chan *ChangeEvent, context.CancelFunc) {
+		watchCtx, cancel := context.WithCancel(ctx)
+		changes, err := pm.Watch(watchCtx, &WatchFilter{Posts: &PostFilter{UserID: "alice"}})
+		require.NoError(t, err)
+		return changes, cancel
+	}
+	first, cancelFirst := watch()
+	_, cancelSecond := watch()
+	assert.True(t, pm.watches.watching(EventPostCreated))
+	assert.True(t, pm.hasHooks(EventReactionAdded))
+
+	// Test: the hub keeps running while a watch remains
+	cancelFirst()
+	assert.Eventually(t, func() bool {
+		_, ok := <-first
+		return !ok
+	}, time.Second, time.Millisecond)
+	assert.True(t, pm.watches.watching(EventPostCreated))
+
+	// Test: the hub stops with the last watch
+	cancelSecond()
+	assert.Eventually(t, func() bool { return !pm.watches.watching(EventPostCreated) }, time.Second, time.Millisecond)
+	assert.False(t, pm.hasHooks(EventReactionAdded))
+	_, err := pm.CreatePost(ctx, createTestPostData("alice"))
+	require.NoError(t, err)
+
+	// Test: a new watch starts it again
+	changes, cancel := watch()
+	defer cancel()
+	postID, err := pm.CreatePost(ctx, createTestPostData("alice"))
+	require.NoError(t, err)
+	change := nextChange(pm, changes)
+	require.NotNil(t, change)
+	assert.Equal(t, postID, change.PostID)
+	assert.Nil(t, nextChange(pm, changes))
+}
+
+// TestPostManagerWatchMatchesListPosts tests that watches deliver the posts ListPosts would list for the viewer
+func TestPostManagerWatchMatchesListPosts(t *testing.T) {
+	pm := NewPostManager(NewInMemoryPostStore(), WithModerator(keywordModerator))
+	ctx := context.Background()
+	bobCtx := WithActor(ctx, Actor{UserID: "bob"})
+	modCtx := WithActor(ctx, Actor{UserID: "mod1", Role: RoleModerator})
+
+	watch := func(userID string) (<-chan *ChangeEvent, context.CancelFunc) {
+		watchCtx, cancel := context.WithCancel(bobCtx)
+		changes, err := pm.Watch(watchCtx, &WatchFilter{Posts: &PostFilter{UserID: userID}})
+		require.NoError(t, err)
+		return changes, cancel
+	}
+	listed := func(userID string) []string {
+		posts, err := pm.ListPosts(bobCtx, &PostFilter{UserID: userID})
+		require.NoError(t, err)
+		return postIDs(posts)
+	}
+
+	// Test: posts co-authored by the watched user are delivered, as they are listed on the profile
+	carolChanges, cancelCarol := watch("carol")
+	defer cancelCarol()
+
+	held := createTestPostData("alice")
+	held.Content = "please review our joint post"
+	heldID, err := pm.CreatePost(ctx, held)
+	require.NoError(t, err)
+	require.NoError(t, pm.InviteCoAuthor(ctx, heldID, "alice", "carol"))
+	require.NoError(t, pm.AcceptCoAuthorInvite(ctx, heldID, "carol"))
+	assert.Nil(t, nextChange(pm, carolChanges))
+
+	require.NoError(t, pm.ApprovePost(modCtx, heldID))
+	change := nextChange(pm, carolChanges)
+	require.NotNil(t, change)
+	assert.Equal(t, heldID, change.PostID)
+	assert.Equal(t, []string{heldID}, listed("carol"))
+
+	// Test: friends-only posts reach neither the watch nor the listing of a follower
+	require.NoError(t, pm.Follow(ctx, "bob", "dave"))
+	require.NoError(t, pm.Follow(ctx, "dave", "bob"))
+	daveChanges, cancelDave := watch("dave")
+	defer cancelDave()
+
+	friends := createTestPostData("dave")
+	friends.Visibility = VisibilityFriends
+	_, err = pm.CreatePost(ctx, friends)
+	require.NoError(t, err)
+	assert.Nil(t, nextChange(pm, daveChanges))
+	assert.Empty(t, listed("dave"))
+}
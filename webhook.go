@@ -0,0 +1,883 @@
+// Package postflow provides functionality for managing user posts and feeds.
+package postflow
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrWebhookNotFound is returned when a webhook endpoint does not exist
+	ErrWebhookNotFound = errors.New("webhook not found")
+
+	// ErrInvalidWebhook is returned when a webhook endpoint has an invalid URL or event filter
+	ErrInvalidWebhook = errors.New("invalid webhook")
+
+	// ErrInvalidSignature is returned when a webhook request is not signed with the expected secret
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+
+	// ErrWebhookQueueFull is reported through OnError when an event is dropped because the delivery queue is full
+	ErrWebhookQueueFull = errors.New("webhook queue full")
+
+	// ErrWebhookDispatcherClosed is reported through OnError when an event arrives after Close
+	ErrWebhookDispatcherClosed = errors.New("webhook dispatcher closed")
+)
+
+// Headers of webhook requests
+const (
+	WebhookEventHeader     = "X-Postflow-Event"     // Event type, e.g. post.created
+	WebhookDeliveryHeader  = "X-Postflow-Delivery"  // Event ID, the same for every attempt and endpoint
+	WebhookTimestampHeader = "X-Postflow-Timestamp" // Unix time of the attempt in seconds
+	WebhookSignatureHeader = "X-Postflow-Signature" // sha256=<hex HMAC-SHA256 of "<timestamp>.<body>">
+)
+
+// Defaults of a WebhookDispatcher
+const (
+	DefaultWebhookMaxAttempts    = 5
+	DefaultWebhookInitialBackoff = time.Second
+	DefaultWebhookMaxBackoff     = time.Minute
+	DefaultWebhookDisableAfter   = 10
+	DefaultWebhookTimeout        = 10 * time.Second
+	DefaultWebhookQueueSize      = 1000
+	DefaultWebhookWorkers        = 4
+)
+
+// webhookEventTypes are the event types that can be delivered to webhooks
+var webhookEventTypes = []EventType{
+	EventPostCreated, EventPostUpdated, EventPostDeleted,
+	EventReactionAdded, EventReactionChanged, EventReactionRemoved,
+}
+
+// WebhookEndpoint is a URL that receives signed HTTP callbacks for post events
+type WebhookEndpoint struct {
+	ID           string      `json:"id"`
+	TenantID     string      `json:"tenant_id,omitempty"` // Set by the store from the context
+	URL          string      `json:"url"`
+	Events       []EventType `json:"events,omitempty"` // Event types to deliver; empty means all
+	Secret       string      `json:"-"`                // Key of the request signatures; generated if empty
+	Disabled     bool        `json:"disabled"`
+	FailureCount int         `json:"failure_count"` // Consecutive events that could not be delivered
+	CreatedAt    time.Time   `json:"created_at"`
+	DisabledAt   time.Time   `json:"disabled_at,omitempty"`
+}
+
+// wants reports whether the endpoint receives events of the type
+func (e *WebhookEndpoint) wants(eventType EventType) bool {
+	if len(e.Events) == 0 {
+		return true
+	}
+	for _, wanted := range e.Events {
+		if wanted == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery records one attempt to deliver an event to an endpoint
+type WebhookDelivery struct {
+	ID         string        `json:"id"`
+	EndpointID string        `json:"endpoint_id"`
+	EventID    string        `json:"event_id"`
+	EventType  EventType     `json:"event_type"`
+	Attempt    int           `json:"attempt"`
+	StatusCode int           `json:"status_code,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	Succeeded  bool          `json:"succeeded"`
+	Duration   time.Duration `json:"duration"`
+	CreatedAt  time.Time     `json:"created_at"`
+}
+
+// WebhookPayload is the JSON body of a webhook request
+type WebhookPayload struct {
+	ID               string       `json:"id"`   // Event ID; receivers should deduplicate by it
+	Type             string       `json:"type"` // Event type, e.g. post.created
+	TenantID         string       `json:"tenant_id,omitempty"`
+	PostID           string       `json:"post_id"`
+	UserID           string       `json:"user_id"`        // The user who made the change
+	Post             *Post        `json:"post,omitempty"` // The post as saved, or before deletion; only approved public posts outside groups
+	Reaction         ReactionType `json:"reaction,omitempty"`
+	PreviousReaction ReactionType `json:"previous_reaction,omitempty"`
+	OccurredAt       time.Time    `json:"occurred_at"`
+}
+
+// WebhookStore defines the interface for stores that keep webhook endpoints and delivery attempts.
+// Endpoints belong to the tenant in the context.
+type WebhookStore interface {
+	// SaveWebhook creates or updates a webhook endpoint
+	SaveWebhook(ctx context.Context, endpoint *WebhookEndpoint) error
+
+	// GetWebhook returns a webhook endpoint
+	GetWebhook(ctx context.Context, endpointID string) (*WebhookEndpoint, error)
+
+	// DeleteWebhook removes a webhook endpoint and its delivery attempts
+	DeleteWebhook(ctx context.Context, endpointID string) error
+
+	// ListWebhooks returns the webhook endpoints, oldest first
+	ListWebhooks(ctx context.Context) ([]*WebhookEndpoint, error)
+
+	// RecordWebhookOutcome resets the failure count of an endpoint after a delivered event, or increments it
+	// after an event that could not be delivered, disabling the endpoint once it reaches disableAfter
+	RecordWebhookOutcome(ctx context.Context, endpointID string, delivered bool, disableAfter int) error
+
+	// SaveWebhookDelivery records a delivery attempt
+	SaveWebhookDelivery(ctx context.Context, delivery *WebhookDelivery) error
+
+	// ListWebhookDeliveries returns the delivery attempts of an endpoint, most recent first
+	ListWebhookDeliveries(ctx context.Context, endpointID string, limit, offset int) ([]*WebhookDelivery, error)
+}
+
+// RegisterWebhook registers an endpoint for the events in its filter and returns its ID.
+// A secret is generated if none is given; it is returned in endpoint.Secret.
+// Endpoints receive every event of the tenant, so only actors allowed to moderate may manage them.
+func (m *PostManagerImpl) RegisterWebhook(ctx context.Context, endpoint *WebhookEndpoint) (string, error) {
+	store, err := m.webhookStoreAs(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	target, err := url.Parse(endpoint.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return "", fmt.Errorf("%w: %q is not an absolute HTTP URL", ErrInvalidWebhook, endpoint.URL)
+	}
+	for _, eventType := range endpoint.Events {
+		if eventType.String() == "unknown" {
+			return "", fmt.Errorf("%w: unknown event type %d", ErrInvalidWebhook, eventType)
+		}
+	}
+
+	if endpoint.Secret == "" {
+		secret := make([]byte, 32)
+		if _, err := rand.Read(secret); err != nil {
+			return "", err
+		}
+		endpoint.Secret = hex.EncodeToString(secret)
+	}
+	if endpoint.ID == "" {
+		endpoint.ID = uuid.New().String()
+	}
+	endpoint.Disabled = false
+	endpoint.FailureCount = 0
+	endpoint.CreatedAt = time.Now()
+	endpoint.DisabledAt = time.Time{}
+
+	if err := store.SaveWebhook(ctx, endpoint); err != nil {
+		return "", err
+	}
+
+	return endpoint.ID, nil
+}
+
+// webhookStoreAs returns the webhook store if the actor in the context may manage webhook endpoints.
+// Endpoints are authorized as ActionModerate on the tenant.
+func (m *PostManagerImpl) webhookStoreAs(ctx context.Context) (WebhookStore, error) {
+	if _, err := m.authorizeModerator(ctx, nil); err != nil {
+		return nil, err
+	}
+
+	store, ok := m.store.(WebhookStore)
+	if !ok {
+		return nil, ErrNotSupported
+	}
+
+	return store, nil
+}
+
+// GetWebhook returns a webhook endpoint
+func (m *PostManagerImpl) GetWebhook(ctx context.Context, endpointID string) (*WebhookEndpoint, error) {
+	store, err := m.webhookStoreAs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return store.GetWebhook(ctx, endpointID)
+}
+
+// ListWebhooks returns the webhook endpoints, oldest first
+func (m *PostManagerImpl) ListWebhooks(ctx context.Context) ([]*WebhookEndpoint, error) {
+	store, err := m.webhookStoreAs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return store.ListWebhooks(ctx)
+}
+
+// DeleteWebhook removes a webhook endpoint and its delivery attempts
+func (m *PostManagerImpl) DeleteWebhook(ctx context.Context, endpointID string) error {
+	store, err := m.webhookStoreAs(ctx)
+	if err != nil {
+		return err
+	}
+
+	return store.DeleteWebhook(ctx, endpointID)
+}
+
+// EnableWebhook re-enables an endpoint that was disabled after repeated failures
+func (m *PostManagerImpl) EnableWebhook(ctx context.Context, endpointID string) error {
+	store, err := m.webhookStoreAs(ctx)
+	if err != nil {
+		return err
+	}
+
+	endpoint, err := store.GetWebhook(ctx, endpointID)
+	if err != nil {
+		return err
+	}
+
+	endpoint.Disabled = false
+	endpoint.FailureCount = 0
+	endpoint.DisabledAt = time.Time{}
+	return store.SaveWebhook(ctx, endpoint)
+}
+
+// ListWebhookDeliveries returns the delivery attempts of an endpoint, most recent first
+func (m *PostManagerImpl) ListWebhookDeliveries(ctx context.Context, endpointID string, limit, offset int) ([]*WebhookDelivery, error) {
+	store, err := m.webhookStoreAs(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return store.ListWebhookDeliveries(ctx, endpointID, limit, offset)
+}
+
+// WithWebhooks delivers the manager's events to webhooks in the background through the dispatcher.
+// Use WebhookDispatcher.Close on shutdown to finish queued deliveries and stop its workers.
+func WithWebhooks(dispatcher *WebhookDispatcher) PostManagerOption {
+	return func(m *PostManagerImpl) {
+		for _, eventType := range webhookEventTypes {
+			m.OnAfter(eventType, dispatcher.enqueue)
+		}
+	}
+}
+
+// SignWebhook returns the signature header value of a webhook body sent at the given Unix time
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyWebhook checks the signature of a received webhook request and that its timestamp
+// is within tolerance of the current time, to reject replayed requests. It is meant for receivers.
+func VerifyWebhook(secret string, header http.Header, body []byte, tolerance time.Duration) error {
+	timestamp, err := strconv.ParseInt(header.Get(WebhookTimestampHeader), 10, 64)
+	if err != nil {
+		return fmt.Errorf("%w: missing timestamp", ErrInvalidSignature)
+	}
+
+	age := time.Since(time.Unix(timestamp, 0))
+	if age > tolerance || age < -tolerance {
+		return fmt.Errorf("%w: timestamp outside tolerance", ErrInvalidSignature)
+	}
+
+	expected := SignWebhook(secret, timestamp, body)
+	if !hmac.Equal([]byte(expected), []byte(header.Get(WebhookSignatureHeader))) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// WebhookDispatcher delivers events to the matching enabled webhook endpoints.
+// Failed attempts are retried with exponential backoff; network errors, 429 and 5xx responses are retried,
+// other responses are not. Endpoints are disabled after DisableAfter consecutive undeliverable events.
+// Each endpoint is delivered to on its own, so a slow or failing endpoint does not delay the others.
+//
+// Events of the manager's hooks are queued and delivered by a fixed number of workers, started with the
+// first event. When the queue is full, further events are dropped rather than holding up writers.
+// A worker makes one attempt at a time; retries wait outside the workers and are queued again
+// once their backoff has elapsed.
+type WebhookDispatcher struct {
+	store  WebhookStore
+	client *http.Client
+
+	once    sync.Once
+	mutex   sync.RWMutex
+	queue   chan *webhookJob
+	closed  bool
+	ctx     context.Context // Context of background deliveries, cancelled by Close
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup // Queued events and retries not yet delivered
+	workers sync.WaitGroup
+	stopped bool // The queue was closed after Close
+
+	MaxAttempts    int           // Attempts per event and endpoint
+	InitialBackoff time.Duration // Wait before the second attempt, doubled for each further attempt
+	MaxBackoff     time.Duration // Upper bound of the wait between attempts
+	DisableAfter   int           // Consecutive undeliverable events before an endpoint is disabled
+	QueueSize      int           // Events waiting for a worker; read when the first event is queued
+	Workers        int           // Background deliveries at a time; read when the first event is queued
+
+	// OnError, when set, is called with store errors of background deliveries
+	// and with ErrWebhookQueueFull or ErrWebhookDispatcherClosed for dropped events
+	OnError func(err error)
+}
+
+// webhookJob is an event of the manager's hooks waiting for a worker, marshalled when it was queued.
+// A job without an endpoint is fanned out to the endpoints of the tenant; a job with an endpoint
+// is one attempt at that endpoint.
+type webhookJob struct {
+	tenant    string
+	eventID   string
+	eventType EventType
+	body      []byte
+
+	endpoint *WebhookEndpoint
+	attempt  int
+	backoff  time.Duration // Wait before the attempt after this one
+}
+
+// NewWebhookDispatcher creates a dispatcher for the endpoints of the store.
+// A client with DefaultWebhookTimeout is used if client is nil.
+func NewWebhookDispatcher(store WebhookStore, client *http.Client) *WebhookDispatcher {
+	if client == nil {
+		client = &http.Client{Timeout: DefaultWebhookTimeout}
+	}
+
+	return &WebhookDispatcher{
+		store:          store,
+		client:         client,
+		MaxAttempts:    DefaultWebhookMaxAttempts,
+		InitialBackoff: DefaultWebhookInitialBackoff,
+		MaxBackoff:     DefaultWebhookMaxBackoff,
+		DisableAfter:   DefaultWebhookDisableAfter,
+		QueueSize:      DefaultWebhookQueueSize,
+		Workers:        DefaultWebhookWorkers,
+	}
+}
+
+// Deliver sends an event of the manager's hooks to the webhooks of the tenant in the context.
+// Undeliverable events are recorded against their endpoints rather than returned; the error
+// only reports store failures.
+func (d *WebhookDispatcher) Deliver(ctx context.Context, event *Event) error {
+	return d.dispatch(ctx, hookPayload(ctx, event), event.Type)
+}
+
+// hookPayload returns the webhook payload of an event of the manager's hooks
+func hookPayload(ctx context.Context, event *Event) *WebhookPayload {
+	return &WebhookPayload{
+		ID:               uuid.New().String(),
+		Type:             event.Type.String(),
+		TenantID:         TenantFromContext(ctx),
+		PostID:           event.PostID,
+		UserID:           event.Actor.UserID,
+		Post:             webhookPost(event.Post),
+		Reaction:         event.Reaction,
+		PreviousReaction: event.PreviousReaction,
+		OccurredAt:       event.OccurredAt,
+	}
+}
+
+// webhookPost returns the post to embed in a payload. Endpoints receive every event of the tenant,
+// so only approved public posts outside groups are embedded; receivers look up others by PostID.
+func webhookPost(post *Post) *Post {
+	if post == nil || post.Visibility != VisibilityPublic || post.ModerationStatus != ModerationApproved || post.GroupID != "" {
+		return nil
+	}
+	return post
+}
+
+// Publish implements OutboxPublisher, so that an OutboxRelay can drive webhook delivery.
+// The event ID is derived from the outbox sequence number, so redelivered events keep their ID.
+func (d *WebhookDispatcher) Publish(ctx context.Context, event *OutboxEvent) error {
+	return d.dispatch(WithTenant(ctx, event.TenantID), &WebhookPayload{
+		ID:               "outbox-" + strconv.FormatInt(event.ID, 10),
+		Type:             event.Type.String(),
+		TenantID:         event.TenantID,
+		PostID:           event.PostID,
+		UserID:           event.UserID,
+		Post:             webhookPost(event.Post),
+		Reaction:         event.Reaction,
+		PreviousReaction: event.PreviousReaction,
+		OccurredAt:       event.CreatedAt,
+	}, event.Type)
+}
+
+// Wait blocks until the events queued by the manager's hooks so far have been delivered
+func (d *WebhookDispatcher) Wait() {
+	d.wg.Wait()
+}
+
+// Close stops accepting events and waits for queued events, including their retries, to be delivered.
+// When ctx is done first, deliveries in progress are cancelled, the remaining queued events and retries
+// are dropped and ctx's error is returned.
+func (d *WebhookDispatcher) Close(ctx context.Context) error {
+	d.start()
+
+	d.mutex.Lock()
+	d.closed = true
+	d.mutex.Unlock()
+
+	// Retries are queued until every event is done, so the queue is closed after that
+	delivered := make(chan struct{})
+	go func() {
+		d.wg.Wait()
+		close(delivered)
+	}()
+
+	var err error
+	select {
+	case <-delivered:
+	case <-ctx.Done():
+		d.cancel()
+		<-delivered
+		err = ctx.Err()
+	}
+
+	d.mutex.Lock()
+	if !d.stopped {
+		d.stopped = true
+		close(d.queue)
+	}
+	d.mutex.Unlock()
+
+	d.workers.Wait()
+	d.cancel()
+	return err
+}
+
+// start creates the queue and its workers, once
+func (d *WebhookDispatcher) start() {
+	d.once.Do(func() {
+		size := d.QueueSize
+		if size <= 0 {
+			size = DefaultWebhookQueueSize
+		}
+		workers := d.Workers
+		if workers <= 0 {
+			workers = DefaultWebhookWorkers
+		}
+
+		d.queue = make(chan *webhookJob, size)
+		d.ctx, d.cancel = context.WithCancel(context.Background())
+		d.workers.Add(workers)
+		for i := 0; i < workers; i++ {
+			go d.work()
+		}
+	})
+}
+
+// work delivers queued events until the queue is closed; events left after Close gave up are dropped
+func (d *WebhookDispatcher) work() {
+	defer d.workers.Done()
+
+	for job := range d.queue {
+		if d.ctx.Err() == nil {
+			ctx := WithTenant(d.ctx, job.tenant)
+			if job.endpoint == nil {
+				d.reportError(d.fanOut(ctx, job))
+			} else {
+				d.reportError(d.attemptJob(ctx, job))
+			}
+		}
+		d.wg.Done()
+	}
+}
+
+// fanOut queues one job per enabled endpoint that wants the event. When the queue is full,
+// the first attempt is made right away instead.
+func (d *WebhookDispatcher) fanOut(ctx context.Context, job *webhookJob) error {
+	endpoints, err := d.store.ListWebhooks(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, endpoint := range endpoints {
+		if endpoint.Disabled || !endpoint.wants(job.eventType) {
+			continue
+		}
+
+		endpointJob := *job
+		endpointJob.endpoint = endpoint
+		endpointJob.attempt = 1
+		endpointJob.backoff = d.InitialBackoff
+
+		d.wg.Add(1)
+		select {
+		case d.queue <- &endpointJob:
+		default:
+			d.reportError(d.attemptJob(ctx, &endpointJob))
+			d.wg.Done()
+		}
+	}
+
+	return nil
+}
+
+// attemptJob makes the attempt of a job and schedules the next one if it failed and may be retried
+func (d *WebhookDispatcher) attemptJob(ctx context.Context, job *webhookJob) error {
+	retry, err := d.attempt(ctx, job.endpoint, job.eventID, job.eventType, job.body, job.attempt)
+	if retry {
+		d.retryLater(job)
+	}
+	return err
+}
+
+// retryLater queues the next attempt of a job once its backoff has elapsed.
+// When Close gives up first, the event is recorded as undeliverable.
+func (d *WebhookDispatcher) retryLater(job *webhookJob) {
+	next := *job
+	next.attempt++
+	next.backoff = d.nextBackoff(job.backoff)
+
+	d.wg.Add(1)
+	go func() {
+		timer := time.NewTimer(job.backoff)
+		defer timer.Stop()
+
+		select {
+		case <-timer.C:
+			select {
+			case d.queue <- &next:
+				return
+			case <-d.ctx.Done():
+			}
+		case <-d.ctx.Done():
+		}
+
+		ctx := WithTenant(context.Background(), job.tenant)
+		d.reportError(d.store.RecordWebhookOutcome(ctx, job.endpoint.ID, false, d.DisableAfter))
+		d.wg.Done()
+	}()
+}
+
+// enqueue queues an event for the workers; it is registered as an after-hook.
+// The payload is marshalled right away, as the post belongs to the caller once the hook returns.
+func (d *WebhookDispatcher) enqueue(ctx context.Context, event *Event) {
+	payload := hookPayload(ctx, event)
+	body, err := json.Marshal(payload)
+	if err != nil {
+		d.reportError(err)
+		return
+	}
+	job := &webhookJob{tenant: payload.TenantID, eventID: payload.ID, eventType: event.Type, body: body}
+
+	d.start()
+
+	d.mutex.RLock()
+	defer d.mutex.RUnlock()
+
+	if d.closed {
+		d.reportError(ErrWebhookDispatcherClosed)
+		return
+	}
+
+	d.wg.Add(1)
+	select {
+	case d.queue <- job:
+	default:
+		d.wg.Done()
+		d.reportError(ErrWebhookQueueFull)
+	}
+}
+
+// reportError passes an error of a background delivery to OnError, if set
+func (d *WebhookDispatcher) reportError(err error) {
+	if err != nil && d.OnError != nil {
+		d.OnError(err)
+	}
+}
+
+// dispatch sends a payload to every enabled endpoint that wants the event type
+func (d *WebhookDispatcher) dispatch(ctx context.Context, payload *WebhookPayload, eventType EventType) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	return d.dispatchBody(ctx, payload.ID, eventType, body)
+}
+
+// dispatchBody sends a marshalled payload to every enabled endpoint that wants the event type
+func (d *WebhookDispatcher) dispatchBody(ctx context.Context, eventID string, eventType EventType, body []byte) error {
+	endpoints, err := d.store.ListWebhooks(ctx)
+	if err != nil {
+		return err
+	}
+
+	// Deliver to the endpoints concurrently, so that one endpoint's retries do not delay the others
+	var wg sync.WaitGroup
+	errs := make([]error, len(endpoints))
+	for i, endpoint := range endpoints {
+		if endpoint.Disabled || !endpoint.wants(eventType) {
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int, endpoint *WebhookEndpoint) {
+			defer wg.Done()
+			errs[i] = d.deliverTo(ctx, endpoint, eventID, eventType, body)
+		}(i, endpoint)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// deliverTo sends a payload to one endpoint with retries, recording each attempt and the outcome
+func (d *WebhookDispatcher) deliverTo(ctx context.Context, endpoint *WebhookEndpoint, eventID string, eventType EventType, body []byte) error {
+	backoff := d.InitialBackoff
+
+	for attempt := 1; ; attempt++ {
+		retry, err := d.attempt(ctx, endpoint, eventID, eventType, body, attempt)
+		if !retry {
+			return err
+		}
+
+		// Wait before the next attempt
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return d.store.RecordWebhookOutcome(context.WithoutCancel(ctx), endpoint.ID, false, d.DisableAfter)
+		case <-timer.C:
+		}
+		backoff = d.nextBackoff(backoff)
+	}
+}
+
+// attempt makes one attempt to deliver a payload to an endpoint and records it. It reports whether
+// the event should be retried; otherwise the outcome of the event has been recorded against the endpoint.
+func (d *WebhookDispatcher) attempt(ctx context.Context, endpoint *WebhookEndpoint, eventID string, eventType EventType, body []byte, attempt int) (bool, error) {
+	started := time.Now()
+	status, err := d.send(ctx, endpoint, eventID, eventType, body)
+
+	delivery := &WebhookDelivery{
+		ID:         uuid.New().String(),
+		EndpointID: endpoint.ID,
+		EventID:    eventID,
+		EventType:  eventType,
+		Attempt:    attempt,
+		StatusCode: status,
+		Succeeded:  err == nil,
+		Duration:   time.Since(started),
+		CreatedAt:  started,
+	}
+	if err != nil {
+		delivery.Error = err.Error()
+	}
+	if saveErr := d.store.SaveWebhookDelivery(ctx, delivery); saveErr != nil {
+		return false, saveErr
+	}
+
+	if err != nil && attempt < d.MaxAttempts && retryableStatus(status) && ctx.Err() == nil {
+		return true, nil
+	}
+
+	return false, d.store.RecordWebhookOutcome(context.WithoutCancel(ctx), endpoint.ID, err == nil, d.DisableAfter)
+}
+
+// nextBackoff doubles the wait between attempts, up to MaxBackoff
+func (d *WebhookDispatcher) nextBackoff(backoff time.Duration) time.Duration {
+	backoff *= 2
+	if d.MaxBackoff > 0 && backoff > d.MaxBackoff {
+		backoff = d.MaxBackoff
+	}
+	return backoff
+}
+
+// send makes one signed request and returns the response status
+func (d *WebhookDispatcher) send(ctx context.Context, endpoint *WebhookEndpoint, eventID string, eventType EventType, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "postflow-webhooks")
+	req.Header.Set(WebhookEventHeader, eventType.String())
+	req.Header.Set(WebhookDeliveryHeader, eventID)
+	req.Header.Set(WebhookTimestampHeader, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(WebhookSignatureHeader, SignWebhook(endpoint.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	// Drain a bounded part of the body so the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+
+	return resp.StatusCode, nil
+}
+
+// retryableStatus reports whether an attempt with the status may succeed when retried.
+// Status 0 means the request did not get a response.
+func retryableStatus(status int) bool {
+	return status == 0 || status == http.StatusTooManyRequests || status >= 500
+}
+
+// SaveWebhook creates or updates a webhook endpoint
+func (s *InMemoryPostStore) SaveWebhook(ctx context.Context, endpoint *WebhookEndpoint) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	endpoint.TenantID = TenantFromContext(ctx)
+	if existing, exists := s.webhooks[endpoint.ID]; exists && existing.TenantID != endpoint.TenantID {
+		return fmt.Errorf("%w: ID %s is taken", ErrInvalidWebhook, endpoint.ID)
+	}
+
+	endpointCopy := *endpoint
+	endpointCopy.Events = append([]EventType(nil), endpoint.Events...)
+	s.webhooks[endpoint.ID] = &endpointCopy
+	return nil
+}
+
+// lookupWebhook returns a stored endpoint of the tenant in the context. The caller must hold the lock.
+func (s *InMemoryPostStore) lookupWebhook(ctx context.Context, endpointID string) (*WebhookEndpoint, bool) {
+	endpoint, exists := s.webhooks[endpointID]
+	if !exists || endpoint.TenantID != TenantFromContext(ctx) {
+		return nil, false
+	}
+	return endpoint, true
+}
+
+// GetWebhook returns a webhook endpoint
+func (s *InMemoryPostStore) GetWebhook(ctx context.Context, endpointID string) (*WebhookEndpoint, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	endpoint, exists := s.lookupWebhook(ctx, endpointID)
+	if !exists {
+		return nil, ErrWebhookNotFound
+	}
+
+	endpointCopy := *endpoint
+	return &endpointCopy, nil
+}
+
+// DeleteWebhook removes a webhook endpoint and its delivery attempts
+func (s *InMemoryPostStore) DeleteWebhook(ctx context.Context, endpointID string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.lookupWebhook(ctx, endpointID); !exists {
+		return ErrWebhookNotFound
+	}
+
+	delete(s.webhooks, endpointID)
+	delete(s.webhookDeliveries, endpointID)
+	return nil
+}
+
+// ListWebhooks returns the webhook endpoints, oldest first
+func (s *InMemoryPostStore) ListWebhooks(ctx context.Context) ([]*WebhookEndpoint, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	endpoints := []*WebhookEndpoint{}
+	tenant := TenantFromContext(ctx)
+	for _, endpoint := range s.webhooks {
+		if endpoint.TenantID == tenant {
+			endpointCopy := *endpoint
+			endpoints = append(endpoints, &endpointCopy)
+		}
+	}
+
+	sort.Slice(endpoints, func(i, j int) bool {
+		if !endpoints[i].CreatedAt.Equal(endpoints[j].CreatedAt) {
+			return endpoints[i].CreatedAt.Before(endpoints[j].CreatedAt)
+		}
+		return endpoints[i].ID < endpoints[j].ID
+	})
+
+	return endpoints, nil
+}
+
+// RecordWebhookOutcome resets or increments the failure count of an endpoint, disabling it at disableAfter failures
+func (s *InMemoryPostStore) RecordWebhookOutcome(ctx context.Context, endpointID string, delivered bool, disableAfter int) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	endpoint, exists := s.lookupWebhook(ctx, endpointID)
+	if !exists {
+		return ErrWebhookNotFound
+	}
+
+	// Replace the stored endpoint so that copies handed out earlier keep their state
+	endpointCopy := *endpoint
+	if delivered {
+		endpointCopy.FailureCount = 0
+	} else {
+		endpointCopy.FailureCount++
+		if disableAfter > 0 && endpointCopy.FailureCount >= disableAfter && !endpointCopy.Disabled {
+			endpointCopy.Disabled = true
+			endpointCopy.DisabledAt = time.Now()
+		}
+	}
+	s.webhooks[endpointID] = &endpointCopy
+
+	return nil
+}
+
+// SaveWebhookDelivery records a delivery attempt
+func (s *InMemoryPostStore) SaveWebhookDelivery(ctx context.Context, delivery *WebhookDelivery) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if _, exists := s.lookupWebhook(ctx, delivery.EndpointID); !exists {
+		return ErrWebhookNotFound
+	}
+
+	deliveryCopy := *delivery
+	s.webhookDeliveries[delivery.EndpointID] = append(s.webhookDeliveries[delivery.EndpointID], &deliveryCopy)
+	return nil
+}
+
+// ListWebhookDeliveries returns the delivery attempts of an endpoint, most recent first
+func (s *InMemoryPostStore) ListWebhookDeliveries(ctx context.Context, endpointID string, limit, offset int) ([]*WebhookDelivery, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	if _, exists := s.lookupWebhook(ctx, endpointID); !exists {
+		return nil, ErrWebhookNotFound
+	}
+
+	// Attempts are appended in order, so reverse them
+	stored := s.webhookDeliveries[endpointID]
+	deliveries := make([]*WebhookDelivery, len(stored))
+	for i, delivery := range stored {
+		deliveryCopy := *delivery
+		deliveries[len(stored)-1-i] = &deliveryCopy
+	}
+
+	// Apply pagination
+	if limit > 0 {
+		end := offset + limit
+		if end > len(deliveries) {
+			end = len(deliveries)
+		}
+		if offset < len(deliveries) {
+			deliveries = deliveries[offset:end]
+		} else {
+			deliveries = []*WebhookDelivery{}
+		}
+	}
+
+	return deliveries, nil
+}
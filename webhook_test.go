@@ -0,0 +1,451 @@
+package postflow
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// webhookReceiver is a test server that records verified webhook payloads
+type webhookReceiver struct {
+	*httptest.Server
+	mutex    sync.Mutex
+	secret   string
+	status   []int // Responses to the next requests; 200 once exhausted
+	payloads []WebhookPayload
+	invalid  int
+}
+
+// newWebhookReceiver starts a receiver that verifies signatures with the secret
+func newWebhookReceiver(t *testing.T, secret string) *webhookReceiver {
+	r := &webhookReceiver{secret: secret}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		body, err := io.ReadAll(req.Body)
+		require.NoError(t, err)
+
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+
+		if err := VerifyWebhook(r.secret, req.Header, body, time.Minute); err != nil {
+			r.invalid++
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		var payload WebhookPayload
+		require.NoError(t, json.Unmarshal(body, &payload))
+		assert.Equal(t, payload.Type, req.Header.Get(WebhookEventHeader))
+		assert.Equal(t, payload.ID, req.Header.Get(WebhookDeliveryHeader))
+		r.payloads = append(r.payloads, payload)
+
+		status := http.StatusOK
+		if len(r.status) > 0 {
+			status, r.status = r.status[0], r.status[1:]
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+// respond queues the status codes of the next responses
+func (r *webhookReceiver) respond(status ...int) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.status = append(r.status, status...)
+}
+
+// types returns the event types received so far and forgets them
+func (r *webhookReceiver) types() []string {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	types := []string{}
+	for _, payload := range r.payloads {
+		types = append(types, payload.Type)
+	}
+	r.payloads = nil
+	return types
+}
+
+// TestPostManagerWebhooks tests webhook registration and signed delivery with the in-memory store
+func TestPostManagerWebhooks(t *testing.T) {
+	ctx := WithTenant(context.Background(), "partner")
+	admin := Actor{UserID: "admin", Role: RoleAdmin}
+	adminCtx := WithActor(ctx, admin)
+	store := NewInMemoryPostStore()
+
+	dispatcher := NewWebhookDispatcher(store, nil)
+	dispatcher.MaxAttempts = 3
+	dispatcher.InitialBackoff = time.Millisecond
+	dispatcher.DisableAfter = 2
+	defer dispatcher.Close(context.Background())
+	pm := NewPostManager(store, WithWebhooks(dispatcher))
+
+	// Test: endpoints need an absolute HTTP URL and known event types
+	_, err := pm.RegisterWebhook(adminCtx, &WebhookEndpoint{URL: "ftp://example.com"})
+	assert.True(t, errors.Is(err, ErrInvalidWebhook))
+	_, err = pm.RegisterWebhook(adminCtx, &WebhookEndpoint{URL: "/hooks"})
+	assert.True(t, errors.Is(err, ErrInvalidWebhook))
+	_, err = pm.RegisterWebhook(adminCtx, &WebhookEndpoint{URL: "https://example.com", Events: []EventType{42}})
+	assert.True(t, errors.Is(err, ErrInvalidWebhook))
+
+	// Test: only moderators and administrators manage endpoints
+	_, err = pm.RegisterWebhook(ctx, &WebhookEndpoint{URL: "https://example.com"})
+	assert.Equal(t, ErrPermissionDenied, err)
+	_, err = pm.RegisterWebhook(WithActor(ctx, Actor{UserID: "alice"}), &WebhookEndpoint{URL: "https://example.com"})
+	assert.Equal(t, ErrPermissionDenied, err)
+	_, err = pm.ListWebhooks(WithActor(ctx, Actor{UserID: "alice"}))
+	assert.Equal(t, ErrPermissionDenied, err)
+
+	// Test: secrets are generated when missing
+	generated := &WebhookEndpoint{URL: "https://example.com/hooks", Events: []EventType{EventPostDeleted}}
+	generatedID, err := pm.RegisterWebhook(adminCtx, generated)
+	require.NoError(t, err)
+	assert.Len(t, generated.Secret, 64)
+	require.NoError(t, pm.DeleteWebhook(adminCtx, generatedID))
+	assert.Equal(t, ErrWebhookNotFound, pm.DeleteWebhook(adminCtx, generatedID))
+
+	// Test: signed payloads are delivered for the events in the filter
+	receiver := newWebhookReceiver(t, "s3cret")
+	endpointID, err := pm.RegisterWebhook(adminCtx, &WebhookEndpoint{
+		URL:    receiver.URL,
+		Secret: "s3cret",
+		Events: []EventType{EventPostCreated, EventReactionAdded},
+	})
+	require.NoError(t, err)
+
+	post := createTestPostData("alice")
+	postID, err := pm.CreatePost(ctx, post)
+	require.NoError(t, err)
+	dispatcher.Wait()
+
+	post.Content = "Edited"
+	require.NoError(t, pm.UpdatePost(ctx, post))
+	require.NoError(t, pm.AddReaction(ctx, postID, "bob", ReactionLike))
+	dispatcher.Wait()
+
+	receiver.mutex.Lock()
+	require.Len(t, receiver.payloads, 2)
+	created, reacted := receiver.payloads[0], receiver.payloads[1]
+	receiver.mutex.Unlock()
+	assert.Equal(t, "post.created", created.Type)
+	assert.Equal(t, "partner", created.TenantID)
+	assert.Equal(t, postID, created.PostID)
+	assert.Equal(t, "alice", created.UserID)
+	require.NotNil(t, created.Post)
+	assert.Equal(t, postID, created.Post.ID)
+	assert.Equal(t, "reaction.added", reacted.Type)
+	assert.Equal(t, "bob", reacted.UserID)
+	assert.Equal(t, ReactionLike, reacted.Reaction)
+	assert.NotEqual(t, created.ID, reacted.ID)
+	receiver.types()
+
+	deliveries, err := pm.ListWebhookDeliveries(adminCtx, endpointID, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 2)
+	assert.Equal(t, EventReactionAdded, deliveries[0].EventType)
+	assert.True(t, deliveries[0].Succeeded)
+	assert.Equal(t, http.StatusOK, deliveries[0].StatusCode)
+	assert.Equal(t, 1, deliveries[0].Attempt)
+
+	// Test: payloads leave out posts that are not approved and public
+	private := createTestPostData("alice")
+	private.Visibility = VisibilityPrivate
+	privateID, err := pm.CreatePost(ctx, private)
+	require.NoError(t, err)
+	dispatcher.Wait()
+
+	receiver.mutex.Lock()
+	require.Len(t, receiver.payloads, 1)
+	assert.Equal(t, privateID, receiver.payloads[0].PostID)
+	assert.Nil(t, receiver.payloads[0].Post)
+	receiver.mutex.Unlock()
+	receiver.types()
+
+	// Test: endpoints of other tenants receive nothing
+	_, err = pm.CreatePost(context.Background(), createTestPostData("alice"))
+	require.NoError(t, err)
+	dispatcher.Wait()
+	assert.Empty(t, receiver.types())
+
+	endpoints, err := pm.ListWebhooks(WithActor(context.Background(), admin))
+	require.NoError(t, err)
+	assert.Empty(t, endpoints)
+	_, err = pm.GetWebhook(WithActor(context.Background(), admin), endpointID)
+	assert.Equal(t, ErrWebhookNotFound, err)
+
+	// Test: server errors are retried with backoff until delivered
+	receiver.respond(http.StatusServiceUnavailable)
+	_, err = pm.CreatePost(ctx, createTestPostData("alice"))
+	require.NoError(t, err)
+	dispatcher.Wait()
+	assert.Equal(t, []string{"post.created", "post.created"}, receiver.types())
+
+	deliveries, err = pm.ListWebhookDeliveries(adminCtx, endpointID, 2, 0)
+	require.NoError(t, err)
+	require.Len(t, deliveries, 2)
+	assert.Equal(t, 2, deliveries[0].Attempt)
+	assert.True(t, deliveries[0].Succeeded)
+	assert.Equal(t, 1, deliveries[1].Attempt)
+	assert.False(t, deliveries[1].Succeeded)
+	assert.Equal(t, http.StatusServiceUnavailable, deliveries[1].StatusCode)
+	assert.Equal(t, deliveries[0].EventID, deliveries[1].EventID)
+
+	// Test: client errors are not retried and count as failures
+	receiver.respond(http.StatusBadRequest)
+	_, err = pm.CreatePost(ctx, createTestPostData("alice"))
+	require.NoError(t, err)
+	dispatcher.Wait()
+	assert.Len(t, receiver.types(), 1)
+
+	endpoint, err := pm.GetWebhook(adminCtx, endpointID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, endpoint.FailureCount)
+	assert.False(t, endpoint.Disabled)
+
+	// Test: endpoints are disabled after repeated undeliverable events
+	receiver.respond(http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError)
+	_, err = pm.CreatePost(ctx, createTestPostData("alice"))
+	require.NoError(t, err)
+	dispatcher.Wait()
+	assert.Len(t, receiver.types(), 3)
+
+	endpoint, err = pm.GetWebhook(adminCtx, endpointID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, endpoint.FailureCount)
+	assert.True(t, endpoint.Disabled)
+	assert.False(t, endpoint.DisabledAt.IsZero())
+
+	_, err = pm.CreatePost(ctx, createTestPostData("alice"))
+	require.NoError(t, err)
+	dispatcher.Wait()
+	assert.Empty(t, receiver.types())
+
+	// Test: re-enabled endpoints receive events again
+	require.NoError(t, pm.EnableWebhook(adminCtx, endpointID))
+	endpoint, err = pm.GetWebhook(adminCtx, endpointID)
+	require.NoError(t, err)
+	assert.False(t, endpoint.Disabled)
+	assert.Zero(t, endpoint.FailureCount)
+
+	require.NoError(t, pm.AddReaction(ctx, postID, "carol", ReactionLove))
+	dispatcher.Wait()
+	assert.Equal(t, []string{"reaction.added"}, receiver.types())
+
+	// Test: requests signed with another secret are rejected by the receiver
+	receiver.mutex.Lock()
+	receiver.secret = "rotated"
+	receiver.mutex.Unlock()
+	require.NoError(t, dispatcher.Deliver(ctx, newEvent(EventPostCreated, post, Actor{UserID: "alice"})))
+	receiver.mutex.Lock()
+	assert.Equal(t, 1, receiver.invalid)
+	receiver.mutex.Unlock()
+
+	// Test: deleting an endpoint removes its delivery attempts
+	require.NoError(t, pm.DeleteWebhook(adminCtx, endpointID))
+	_, err = pm.ListWebhookDeliveries(adminCtx, endpointID, 10, 0)
+	assert.Equal(t, ErrWebhookNotFound, err)
+}
+
+// TestWebhookDispatcherQueue tests the bounded delivery queue of a dispatcher and closing it
+func TestWebhookDispatcherQueue(t *testing.T) {
+	ctx := context.Background()
+	adminCtx := WithActor(ctx, Actor{UserID: "admin", Role: RoleAdmin})
+	store := NewInMemoryPostStore()
+
+	arrived := make(chan string, 10)
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		var payload WebhookPayload
+		require.NoError(t, json.NewDecoder(req.Body).Decode(&payload))
+		arrived <- payload.Post.Content
+		select {
+		case <-release:
+		case <-req.Context().Done():
+		}
+	}))
+	defer server.Close()
+
+	var mutex sync.Mutex
+	var errs []error
+	dispatcher := NewWebhookDispatcher(store, nil)
+	dispatcher.QueueSize = 1
+	dispatcher.Workers = 1
+	dispatcher.OnError = func(err error) {
+		mutex.Lock()
+		defer mutex.Unlock()
+		errs = append(errs, err)
+	}
+	pm := NewPostManager(store, WithWebhooks(dispatcher))
+
+	_, err := pm.RegisterWebhook(adminCtx, &WebhookEndpoint{URL: server.URL, Events: []EventType{EventPostCreated}})
+	require.NoError(t, err)
+
+	// Test: events wait for the busy worker until the queue is full, then they are dropped
+	first := createTestPostData("alice")
+	first.Content = "first"
+	_, err = pm.CreatePost(ctx, first)
+	require.NoError(t, err)
+	assert.Equal(t, "first", <-arrived)
+
+	queued := createTestPostData("alice")
+	queued.Content = "queued"
+	_, err = pm.CreatePost(ctx, queued)
+	require.NoError(t, err)
+	_, err = pm.CreatePost(ctx, createTestPostData("alice"))
+	require.NoError(t, err)
+
+	mutex.Lock()
+	assert.Equal(t, []error{ErrWebhookQueueFull}, errs)
+	mutex.Unlock()
+
+	// Test: queued events are delivered as they were when queued
+	queued.Content = "changed by the caller"
+
+	// Test: closing delivers queued events, later events are refused
+	close(release)
+	require.NoError(t, dispatcher.Close(ctx))
+	assert.Equal(t, "queued", <-arrived)
+	assert.Empty(t, arrived)
+
+	_, err = pm.CreatePost(ctx, createTestPostData("alice"))
+	require.NoError(t, err)
+	mutex.Lock()
+	assert.Equal(t, []error{ErrWebhookQueueFull, ErrWebhookDispatcherClosed}, errs)
+	mutex.Unlock()
+}
+
+// TestWebhookDispatcherCloseTimeout tests abandoning deliveries when closing takes too long
+func TestWebhookDispatcherCloseTimeout(t *testing.T) {
+	ctx := context.Background()
+	adminCtx := WithActor(ctx, Actor{UserID: "admin", Role: RoleAdmin})
+	store := NewInMemoryPostStore()
+
+	arrived := make(chan struct{}, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, _ = io.Copy(io.Discard, req.Body)
+		arrived <- struct{}{}
+		<-req.Context().Done()
+	}))
+	defer server.Close()
+
+	dispatcher := NewWebhookDispatcher(store, nil)
+	dispatcher.Workers = 1
+	dispatcher.MaxAttempts = 1
+	pm := NewPostManager(store, WithWebhooks(dispatcher))
+
+	_, err := pm.RegisterWebhook(adminCtx, &WebhookEndpoint{URL: server.URL})
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		_, err = pm.CreatePost(ctx, createTestPostData("alice"))
+		require.NoError(t, err)
+	}
+	<-arrived
+
+	// Test: the delivery in progress is cancelled and queued events are dropped
+	closeCtx, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, dispatcher.Close(closeCtx))
+	assert.Empty(t, arrived)
+	dispatcher.Wait()
+}
+
+// TestWebhookDispatcherRetries tests that retries of a failing endpoint do not hold up other endpoints
+func TestWebhookDispatcherRetries(t *testing.T) {
+	ctx := context.Background()
+	adminCtx := WithActor(ctx, Actor{UserID: "admin", Role: RoleAdmin})
+	store := NewInMemoryPostStore()
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	arrived := make(chan struct{}, 10)
+	healthy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		arrived <- struct{}{}
+	}))
+	defer healthy.Close()
+
+	dispatcher := NewWebhookDispatcher(store, nil)
+	dispatcher.Workers = 1
+	dispatcher.MaxAttempts = 3
+	dispatcher.InitialBackoff = 200 * time.Millisecond
+	pm := NewPostManager(store, WithWebhooks(dispatcher))
+
+	failingID, err := pm.RegisterWebhook(adminCtx, &WebhookEndpoint{URL: failing.URL})
+	require.NoError(t, err)
+	_, err = pm.RegisterWebhook(adminCtx, &WebhookEndpoint{URL: healthy.URL})
+	require.NoError(t, err)
+
+	// Test: the single worker keeps delivering while the failing endpoint waits for its retries
+	started := time.Now()
+	for i := 0; i < 2; i++ {
+		_, err = pm.CreatePost(ctx, createTestPostData("alice"))
+		require.NoError(t, err)
+	}
+	for i := 0; i < 2; i++ {
+		select {
+		case <-arrived:
+		case <-time.After(time.Second):
+			t.Fatal("event not delivered")
+		}
+	}
+	assert.Less(t, time.Since(started), dispatcher.InitialBackoff)
+
+	// Test: retries are made and the outcome recorded once they run out
+	dispatcher.Wait()
+	deliveries, err := pm.ListWebhookDeliveries(adminCtx, failingID, 0, 0)
+	require.NoError(t, err)
+	assert.Len(t, deliveries, 6)
+	endpoint, err := pm.GetWebhook(adminCtx, failingID)
+	require.NoError(t, err)
+	assert.Equal(t, 2, endpoint.FailureCount)
+
+	// Test: closing gives up on waiting retries and records them as undeliverable
+	_, err = pm.CreatePost(ctx, createTestPostData("alice"))
+	require.NoError(t, err)
+	<-arrived
+	closeCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, dispatcher.Close(closeCtx))
+	endpoint, err = pm.GetWebhook(adminCtx, failingID)
+	require.NoError(t, err)
+	assert.Equal(t, 3, endpoint.FailureCount)
+}
+
+// TestVerifyWebhook tests verifying webhook signatures
+func TestVerifyWebhook(t *testing.T) {
+	body := []byte(`{"id":"1"}`)
+	now := time.Now().Unix()
+
+	header := http.Header{}
+	header.Set(WebhookTimestampHeader, strconv.FormatInt(now, 10))
+	header.Set(WebhookSignatureHeader, SignWebhook("secret", now, body))
+
+	// Test: valid signatures are accepted
+	assert.NoError(t, VerifyWebhook("secret", header, body, time.Minute))
+
+	// Test: other secrets and bodies are rejected
+	assert.True(t, errors.Is(VerifyWebhook("other", header, body, time.Minute), ErrInvalidSignature))
+	assert.True(t, errors.Is(VerifyWebhook("secret", header, []byte(`{"id":"2"}`), time.Minute), ErrInvalidSignature))
+
+	// Test: stale timestamps are rejected even when correctly signed
+	stale := now - 3600
+	header.Set(WebhookTimestampHeader, strconv.FormatInt(stale, 10))
+	header.Set(WebhookSignatureHeader, SignWebhook("secret", stale, body))
+	assert.True(t, errors.Is(VerifyWebhook("secret", header, body, time.Minute), ErrInvalidSignature))
+
+	// Test: missing headers are rejected
+	assert.True(t, errors.Is(VerifyWebhook("secret", http.Header{}, body, time.Minute), ErrInvalidSignature))
+}